@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dataawsorganizationsorganization"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/organizationsaccount"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/organizationsorganizationalunit"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/organizationspolicy"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/organizationspolicyattachment"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AccountsConfig vends accounts in an existing AWS Organization: organizational units, member
+// accounts in them and service control policies attached to either. Its stack has to be deployed
+// with credentials of the organization's management account, or of a delegated administrator.
+type AccountsConfig struct {
+	OrganizationalUnits []OrganizationalUnitConfig `json:"organizational_units"`
+	Accounts            []MemberAccountConfig      `json:"accounts"`
+	Policies            []ServiceControlPolicy     `json:"policies"`
+}
+
+type OrganizationalUnitConfig struct {
+	Name string `json:"name"`
+	// Parent is an OU listed before this one; the organization's root when empty
+	Parent string `json:"parent"`
+}
+
+type MemberAccountConfig struct {
+	Name  string `json:"name"`
+	Email string `json:"email"` // of the account's root user; unique across AWS
+	OU    string `json:"ou"`    // the organization's root when empty
+	// RoleName is the role the management account can assume in the new account; it defaults to
+	// OrganizationAccountAccessRole and can't be changed once the account exists
+	RoleName string `json:"role_name"`
+	// CloseOnDeletion closes the account, instead of only removing it from the organization, when it
+	// is deleted from the config. Accounts are protected with prevent_destroy either way.
+	CloseOnDeletion        bool `json:"close_on_deletion"`
+	IAMUserAccessToBilling bool `json:"iam_user_access_to_billing"`
+}
+
+// ServiceControlPolicy is an SCP attached to OUs, accounts or the root
+type ServiceControlPolicy struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Document    json.RawMessage `json:"document"` // the policy, as a JSON object
+	// Targets are names of OUs or accounts of this config, or root
+	Targets []string `json:"targets"`
+}
+
+// maxSCPSize is the largest policy document AWS Organizations accepts, in characters
+const maxSCPSize = 5120
+
+// accountsLogicalID is the logical id form of an account, OU or policy name, e.g. data_platform
+// for Data Platform
+func accountsLogicalID(name string) string {
+	return strings.Trim(logicalIDInvalid.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// compactPolicy is the document of an SCP without whitespace, which counts toward its size limit
+func (p ServiceControlPolicy) compactPolicy() (string, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, p.Document); err != nil {
+		return "", err
+	}
+	return compact.String(), nil
+}
+
+func (a *AccountsConfig) validate() error {
+	ids := map[string]string{}
+	unique := func(kind, name string) error {
+		id := accountsLogicalID(name)
+		if id == "" {
+			return fmt.Errorf("%s: %q has no letters or digits", kind, name)
+		}
+		if other, ok := ids[kind+id]; ok {
+			return fmt.Errorf("%s: %q and %q have the same logical id %s", kind, other, name, id)
+		}
+		ids[kind+id] = name
+		return nil
+	}
+
+	ous := map[string]bool{}
+	for i, ou := range a.OrganizationalUnits {
+		if ou.Name == "" || len(ou.Name) > 128 || ou.Name == "root" {
+			return fmt.Errorf("organizational_units[%d]: name must be 1-128 characters, other than root", i)
+		}
+		if err := unique("organizational_units", ou.Name); err != nil {
+			return err
+		}
+		if ou.Parent != "" && !ous[ou.Parent] {
+			return invalidValue(ou.Parent, closestMatch(ou.Parent, slices.Sorted(maps.Keys(ous))),
+				"organizational_units.%s: parent %q must be an OU listed before it", ou.Name, ou.Parent)
+		}
+		ous[ou.Name] = true
+	}
+
+	accounts := map[string]bool{}
+	for i, account := range a.Accounts {
+		if account.Name == "" || len(account.Name) > 50 {
+			return fmt.Errorf("accounts[%d]: name must be 1-50 characters", i)
+		}
+		if err := unique("accounts", account.Name); err != nil {
+			return err
+		}
+		if ous[account.Name] || account.Name == "root" {
+			// Policy targets are found by name
+			return fmt.Errorf("accounts.%s: an OU or the root has the same name", account.Name)
+		}
+		if !emailPattern.MatchString(account.Email) {
+			return invalidValue(account.Email, "", "accounts.%s: email %q is not an email address", account.Name, account.Email)
+		}
+		if account.OU != "" && !ous[account.OU] {
+			return invalidValue(account.OU, closestMatch(account.OU, slices.Sorted(maps.Keys(ous))),
+				"accounts.%s: unknown ou %q", account.Name, account.OU)
+		}
+		accounts[account.Name] = true
+	}
+
+	for i, policy := range a.Policies {
+		if policy.Name == "" || len(policy.Name) > 128 {
+			return fmt.Errorf("policies[%d]: name must be 1-128 characters", i)
+		}
+		if err := unique("policies", policy.Name); err != nil {
+			return err
+		}
+		var document map[string]interface{}
+		if err := json.Unmarshal(policy.Document, &document); err != nil || document == nil {
+			return fmt.Errorf("policies.%s: document must be a JSON policy object", policy.Name)
+		}
+		if _, ok := document["Statement"]; !ok {
+			return fmt.Errorf("policies.%s: document has no Statement", policy.Name)
+		}
+		if content, _ := policy.compactPolicy(); len(content) > maxSCPSize {
+			return invalidValue(len(content), "split it into several policies",
+				"policies.%s: document is %d characters; AWS allows %d", policy.Name, len(content), maxSCPSize)
+		}
+		if len(policy.Targets) == 0 {
+			return fmt.Errorf("policies.%s: targets is required", policy.Name)
+		}
+		for _, target := range policy.Targets {
+			if target != "root" && !ous[target] && !accounts[target] {
+				candidates := append(append([]string{"root"}, slices.Sorted(maps.Keys(ous))...), slices.Sorted(maps.Keys(accounts))...)
+				return invalidValue(target, closestMatch(target, candidates),
+					"policies.%s: target %q is not root or an OU or account of this config", policy.Name, target)
+			}
+		}
+	}
+	return nil
+}
+
+// addAccounts creates the OUs, in order so parents exist first, the member accounts and the SCPs
+// with their attachments
+func addAccounts(stack cdktf.TerraformStack, config Config) {
+	accounts := config.Accounts
+
+	organization := dataawsorganizationsorganization.NewDataAwsOrganizationsOrganization(stack, jsii.String("organization"),
+		&dataawsorganizationsorganization.DataAwsOrganizationsOrganizationConfig{})
+	rootID := organization.Roots().Get(jsii.Number(0)).Id()
+
+	// Targets by name, for the parents, the accounts' OUs and the policy attachments
+	ouIDs := map[string]*string{}
+	accountIDs := map[string]*string{}
+	for _, ou := range accounts.OrganizationalUnits {
+		parentID := rootID
+		if ou.Parent != "" {
+			parentID = ouIDs[ou.Parent]
+		}
+		unit := organizationsorganizationalunit.NewOrganizationsOrganizationalUnit(stack, jsii.String("ou_"+accountsLogicalID(ou.Name)),
+			&organizationsorganizationalunit.OrganizationsOrganizationalUnitConfig{
+				Name:     jsii.String(ou.Name),
+				ParentId: parentID,
+			})
+		ouIDs[ou.Name] = unit.Id()
+	}
+
+	for _, member := range accounts.Accounts {
+		parentID := rootID
+		if member.OU != "" {
+			parentID = ouIDs[member.OU]
+		}
+		roleName := member.RoleName
+		if roleName == "" {
+			roleName = "OrganizationAccountAccessRole"
+		}
+		billing := "DENY"
+		if member.IAMUserAccessToBilling {
+			billing = "ALLOW"
+		}
+		account := organizationsaccount.NewOrganizationsAccount(stack, jsii.String("account_"+accountsLogicalID(member.Name)),
+			&organizationsaccount.OrganizationsAccountConfig{
+				Name:                   jsii.String(member.Name),
+				Email:                  jsii.String(member.Email),
+				ParentId:               parentID,
+				RoleName:               jsii.String(roleName),
+				CloseOnDeletion:        jsii.Bool(member.CloseOnDeletion),
+				IamUserAccessToBilling: jsii.String(billing),
+				// AWS doesn't return these after create, so changing them would replace the account
+				Lifecycle: &cdktf.TerraformResourceLifecycle{
+					PreventDestroy: jsii.Bool(true),
+					IgnoreChanges:  jsii.Strings("role_name", "iam_user_access_to_billing"),
+				},
+			})
+		accountIDs[member.Name] = account.Id()
+	}
+
+	attachments := 0
+	for _, scp := range accounts.Policies {
+		content, _ := scp.compactPolicy()
+		policyConfig := &organizationspolicy.OrganizationsPolicyConfig{
+			Name:    jsii.String(scp.Name),
+			Type:    jsii.String("SERVICE_CONTROL_POLICY"),
+			Content: jsii.String(content),
+		}
+		if scp.Description != "" {
+			policyConfig.Description = jsii.String(scp.Description)
+		}
+		policy := organizationspolicy.NewOrganizationsPolicy(stack, jsii.String("scp_"+accountsLogicalID(scp.Name)), policyConfig)
+		for _, target := range scp.Targets {
+			targetID, id := rootID, "root"
+			if ouID, ok := ouIDs[target]; ok {
+				targetID, id = ouID, "ou_"+accountsLogicalID(target)
+			} else if accountID, ok := accountIDs[target]; ok {
+				targetID, id = accountID, "account_"+accountsLogicalID(target)
+			}
+			organizationspolicyattachment.NewOrganizationsPolicyAttachment(stack, jsii.String(fmt.Sprintf("scp_%s_%s", accountsLogicalID(scp.Name), id)),
+				&organizationspolicyattachment.OrganizationsPolicyAttachmentConfig{
+					PolicyId: policy.Id(),
+					TargetId: targetID,
+				})
+			attachments++
+		}
+	}
+
+	if len(accountIDs) > 0 {
+		cdktf.NewTerraformOutput(stack, jsii.String("account_ids"), &cdktf.TerraformOutputConfig{
+			Value:       accountIDs,
+			Description: jsii.String("The IDs of the member accounts, by name"),
+		})
+	}
+	if len(ouIDs) > 0 {
+		cdktf.NewTerraformOutput(stack, jsii.String("organizational_unit_ids"), &cdktf.TerraformOutputConfig{
+			Value:       ouIDs,
+			Description: jsii.String("The IDs of the organizational units, by name"),
+		})
+	}
+
+	fmt.Printf("  ✓ Organization: %d OU(s), %d account(s), %d SCP(s) with %d attachment(s)\n",
+		len(ouIDs), len(accountIDs), len(accounts.Policies), attachments)
+}
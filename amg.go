@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/grafanaroleassociation"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/grafanaworkspace"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/grafanaworkspacesamlconfiguration"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AMGConfig creates an Amazon Managed Grafana workspace that reads the account's Managed
+// Prometheus workspaces and CloudWatch, with users signing in through IAM Identity Center or a
+// SAML identity provider
+type AMGConfig struct {
+	Name string `json:"name"` // defaults to grafana
+	// Authentication lists AWS_SSO (the default) and SAML; IAM Identity Center has to be enabled
+	// in the organization for AWS_SSO
+	Authentication []string `json:"authentication"`
+	// DataSources are what the workspace role can read: PROMETHEUS, CLOUDWATCH and XRAY; the
+	// first two by default
+	DataSources    []string           `json:"data_sources"`
+	GrafanaVersion string             `json:"grafana_version"` // e.g. 10.4
+	Admins         *GrafanaPrincipals `json:"admins,omitempty"`
+	Editors        *GrafanaPrincipals `json:"editors,omitempty"`
+	SAML           *GrafanaSAMLConfig `json:"saml,omitempty"`
+}
+
+// GrafanaPrincipals are IAM Identity Center users and groups given a role in the workspace;
+// everyone else who can sign in is a viewer
+type GrafanaPrincipals struct {
+	UserIDs  []string `json:"user_ids"`
+	GroupIDs []string `json:"group_ids"`
+}
+
+// GrafanaSAMLConfig maps the assertions of a SAML identity provider to Grafana users and roles
+type GrafanaSAMLConfig struct {
+	IdPMetadataURL   string   `json:"idp_metadata_url"`
+	RoleAssertion    string   `json:"role_assertion"` // the attribute holding the user's roles, e.g. groups
+	AdminRoleValues  []string `json:"admin_role_values"`
+	EditorRoleValues []string `json:"editor_role_values"`
+	LoginAssertion   string   `json:"login_assertion"`
+	EmailAssertion   string   `json:"email_assertion"`
+}
+
+var (
+	grafanaAuthenticationProviders = []string{"AWS_SSO", "SAML"}
+	grafanaDataSources             = []string{"CLOUDWATCH", "PROMETHEUS", "XRAY"}
+	defaultGrafanaDataSources      = []string{"CLOUDWATCH", "PROMETHEUS"}
+)
+
+// grafanaDataSourceActions are the actions the workspace role needs to read each data source
+var grafanaDataSourceActions = map[string][]string{
+	"CLOUDWATCH": {
+		"cloudwatch:DescribeAlarmsForMetric", "cloudwatch:DescribeAlarmHistory", "cloudwatch:DescribeAlarms",
+		"cloudwatch:ListMetrics", "cloudwatch:GetMetricData", "cloudwatch:GetInsightRuleReport",
+		"logs:DescribeLogGroups", "logs:GetLogGroupFields", "logs:StartQuery", "logs:StopQuery", "logs:GetQueryResults", "logs:GetLogEvents",
+		"ec2:DescribeTags", "ec2:DescribeInstances", "ec2:DescribeRegions", "tag:GetResources",
+	},
+	"PROMETHEUS": {"aps:ListWorkspaces", "aps:DescribeWorkspace", "aps:QueryMetrics", "aps:GetLabels", "aps:GetSeries", "aps:GetMetricMetadata"},
+	"XRAY": {
+		"xray:BatchGetTraces", "xray:GetTraceSummaries", "xray:GetTraceGraph", "xray:GetGroups", "xray:GetTimeSeriesServiceStatistics",
+		"xray:GetInsightSummaries", "xray:GetInsight", "xray:GetServiceGraph", "ec2:DescribeRegions",
+	},
+}
+
+func (a *AMGConfig) authentication() []string {
+	if len(a.Authentication) > 0 {
+		return a.Authentication
+	}
+	return []string{"AWS_SSO"}
+}
+
+func (a *AMGConfig) dataSources() []string {
+	if len(a.DataSources) > 0 {
+		return a.DataSources
+	}
+	return defaultGrafanaDataSources
+}
+
+func (a *AMGConfig) validate() error {
+	if a.Name != "" && !nameSegmentPattern.MatchString(a.Name) {
+		return invalidValue(a.Name, "", "name %q must be lowercase letters, digits and hyphens", a.Name)
+	}
+	for _, provider := range a.Authentication {
+		if !slices.Contains(grafanaAuthenticationProviders, provider) {
+			return invalidValue(provider, closestMatch(provider, grafanaAuthenticationProviders),
+				"authentication: unknown provider %q (want AWS_SSO or SAML)", provider)
+		}
+	}
+	for _, source := range a.DataSources {
+		if !slices.Contains(grafanaDataSources, source) {
+			return invalidValue(source, closestMatch(source, grafanaDataSources),
+				"data_sources: unknown data source %q (want PROMETHEUS, CLOUDWATCH or XRAY)", source)
+		}
+	}
+	sso := slices.Contains(a.authentication(), "AWS_SSO")
+	if !sso && (a.Admins != nil || a.Editors != nil) {
+		return fmt.Errorf("admins and editors are IAM Identity Center principals, so authentication needs AWS_SSO")
+	}
+	for _, principals := range []struct {
+		field string
+		value *GrafanaPrincipals
+	}{{"admins", a.Admins}, {"editors", a.Editors}} {
+		if principals.value != nil && len(principals.value.UserIDs) == 0 && len(principals.value.GroupIDs) == 0 {
+			return fmt.Errorf("%s: needs user_ids or group_ids", principals.field)
+		}
+	}
+	saml := slices.Contains(a.authentication(), "SAML")
+	if saml != (a.SAML != nil) {
+		return fmt.Errorf("saml is required with, and only with, SAML authentication")
+	}
+	if a.SAML != nil {
+		if a.SAML.IdPMetadataURL == "" {
+			return fmt.Errorf("saml: idp_metadata_url is required")
+		}
+		if a.SAML.RoleAssertion == "" || len(a.SAML.EditorRoleValues) == 0 {
+			return fmt.Errorf("saml: role_assertion and editor_role_values are required")
+		}
+	}
+	return nil
+}
+
+// addAMG creates the workspace, a role reading its data sources and the SSO role associations or
+// SAML configuration
+func addAMG(stack cdktf.TerraformStack, config Config) {
+	amg := config.AMG
+	name := amg.Name
+	if name == "" {
+		name = "grafana"
+	}
+
+	var actions []string
+	for _, source := range slices.Sorted(slices.Values(amg.dataSources())) {
+		for _, action := range grafanaDataSourceActions[source] {
+			if !slices.Contains(actions, action) {
+				actions = append(actions, action)
+			}
+		}
+	}
+	role := newServiceRole(stack, "grafana_role", resourceName(config, "aws_iam_role", name), "grafana.amazonaws.com", config)
+	addInlinePolicy(stack, "grafana_role_policy", role, map[string]interface{}{
+		"Effect":   "Allow",
+		"Action":   actions,
+		"Resource": "*",
+	})
+
+	workspaceConfig := &grafanaworkspace.GrafanaWorkspaceConfig{
+		Name:                    jsii.String(resourceName(config, "aws_grafana_workspace", name)),
+		AccountAccessType:       jsii.String("CURRENT_ACCOUNT"),
+		AuthenticationProviders: jsii.Strings(amg.authentication()...),
+		PermissionType:          jsii.String("SERVICE_MANAGED"),
+		DataSources:             jsii.Strings(amg.dataSources()...),
+		RoleArn:                 role.Arn(),
+	}
+	if amg.GrafanaVersion != "" {
+		workspaceConfig.GrafanaVersion = jsii.String(amg.GrafanaVersion)
+	}
+	workspace := grafanaworkspace.NewGrafanaWorkspace(stack, jsii.String("grafana_workspace"), workspaceConfig)
+
+	for _, association := range []struct {
+		id, role   string
+		principals *GrafanaPrincipals
+	}{{"grafana_admins", "ADMIN", amg.Admins}, {"grafana_editors", "EDITOR", amg.Editors}} {
+		if association.principals == nil {
+			continue
+		}
+		associationConfig := &grafanaroleassociation.GrafanaRoleAssociationConfig{
+			WorkspaceId: workspace.Id(),
+			Role:        jsii.String(association.role),
+		}
+		if len(association.principals.UserIDs) > 0 {
+			associationConfig.UserIds = jsii.Strings(association.principals.UserIDs...)
+		}
+		if len(association.principals.GroupIDs) > 0 {
+			associationConfig.GroupIds = jsii.Strings(association.principals.GroupIDs...)
+		}
+		grafanaroleassociation.NewGrafanaRoleAssociation(stack, jsii.String(association.id), associationConfig)
+	}
+
+	if saml := amg.SAML; saml != nil {
+		samlConfig := &grafanaworkspacesamlconfiguration.GrafanaWorkspaceSamlConfigurationConfig{
+			WorkspaceId:      workspace.Id(),
+			IdpMetadataUrl:   jsii.String(saml.IdPMetadataURL),
+			RoleAssertion:    jsii.String(saml.RoleAssertion),
+			EditorRoleValues: jsii.Strings(saml.EditorRoleValues...),
+		}
+		if len(saml.AdminRoleValues) > 0 {
+			samlConfig.AdminRoleValues = jsii.Strings(saml.AdminRoleValues...)
+		}
+		if saml.LoginAssertion != "" {
+			samlConfig.LoginAssertion = jsii.String(saml.LoginAssertion)
+		}
+		if saml.EmailAssertion != "" {
+			samlConfig.EmailAssertion = jsii.String(saml.EmailAssertion)
+		}
+		grafanaworkspacesamlconfiguration.NewGrafanaWorkspaceSamlConfiguration(stack, jsii.String("grafana_saml"), samlConfig)
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("grafana_workspace_url"), &cdktf.TerraformOutputConfig{
+		Value:       jsii.String("https://" + *workspace.Endpoint()),
+		Description: jsii.String("The URL users sign in to the Managed Grafana workspace at"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("grafana_workspace_id"), &cdktf.TerraformOutputConfig{
+		Value:       workspace.Id(),
+		Description: jsii.String("The ID of the Managed Grafana workspace"),
+	})
+
+	fmt.Printf("  ✓ Managed Grafana workspace %s reading %d data source(s)\n", name, len(amg.dataSources()))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/prometheusalertmanagerdefinition"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/prometheusrulegroupnamespace"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/prometheusworkspace"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AMPConfig creates Amazon Managed Service for Prometheus workspaces, which the collectors of EKS
+// clusters remote-write to, with their alert manager definitions and rules
+type AMPConfig struct {
+	Workspaces []PrometheusWorkspace `json:"workspaces"`
+}
+
+type PrometheusWorkspace struct {
+	Name string `json:"name"`
+	// AlertManagerFile is the alert manager definition, relative to the config file: YAML with an
+	// alertmanager_config key and optional template_files. AMP alerts only through SNS receivers.
+	AlertManagerFile string `json:"alert_manager_file"`
+	// RuleFiles are Prometheus rule files, relative to the config file; each is a rule group
+	// namespace named after the file
+	RuleFiles []string `json:"rule_files"`
+
+	alertManager string            // read from AlertManagerFile by loadAMPFiles
+	rules        map[string]string // the content of RuleFiles, by namespace
+}
+
+// ruleNamespace is the rule group namespace of a rule file, e.g. api for rules/api.yaml
+func ruleNamespace(file string) string {
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+}
+
+func (a *AMPConfig) validate() error {
+	if len(a.Workspaces) == 0 {
+		return fmt.Errorf("at least one workspace is required")
+	}
+	names := map[string]bool{}
+	for i, workspace := range a.Workspaces {
+		if !nameSegmentPattern.MatchString(workspace.Name) {
+			return invalidValue(workspace.Name, "", "workspaces[%d]: name %q must be lowercase letters, digits and hyphens", i, workspace.Name)
+		}
+		if names[workspace.Name] {
+			return fmt.Errorf("workspaces[%d]: duplicate name %q", i, workspace.Name)
+		}
+		names[workspace.Name] = true
+		namespaces := map[string]bool{}
+		for _, file := range workspace.RuleFiles {
+			namespace := ruleNamespace(file)
+			if !blockLabel.MatchString(namespace) {
+				return fmt.Errorf("workspaces.%s: rule file %s must be named with letters, digits, _ or -", workspace.Name, file)
+			}
+			if namespaces[namespace] {
+				return fmt.Errorf("workspaces.%s: two rule files are named %s", workspace.Name, namespace)
+			}
+			namespaces[namespace] = true
+		}
+	}
+	return nil
+}
+
+// loadAMPFiles reads the alert manager definitions and rule files of the workspaces, relative to
+// the config file in dir
+func loadAMPFiles(amp *AMPConfig, dir string) error {
+	for i := range amp.Workspaces {
+		workspace := &amp.Workspaces[i]
+		if workspace.AlertManagerFile != "" {
+			raw, err := os.ReadFile(filepath.Join(dir, workspace.AlertManagerFile))
+			if err != nil {
+				return fmt.Errorf("amp: %s: reading alert_manager_file: %w", workspace.Name, err)
+			}
+			// AMP rejects a definition without it when the workspace is applied, not at plan
+			if !strings.Contains(string(raw), "alertmanager_config:") {
+				return fmt.Errorf("amp: %s: %s has no alertmanager_config key", workspace.Name, workspace.AlertManagerFile)
+			}
+			workspace.alertManager = escapeInterpolation(string(raw))
+		}
+		workspace.rules = map[string]string{}
+		for _, file := range workspace.RuleFiles {
+			raw, err := os.ReadFile(filepath.Join(dir, file))
+			if err != nil {
+				return fmt.Errorf("amp: %s: reading rule file: %w", workspace.Name, err)
+			}
+			workspace.rules[ruleNamespace(file)] = escapeInterpolation(string(raw))
+		}
+	}
+	return nil
+}
+
+// addAMP creates each workspace with its alert manager definition and rule group namespaces
+func addAMP(stack cdktf.TerraformStack, config Config) {
+	amp := config.AMP
+	for _, prometheus := range amp.Workspaces {
+		workspace := prometheusworkspace.NewPrometheusWorkspace(stack, jsii.String("amp_"+prometheus.Name), &prometheusworkspace.PrometheusWorkspaceConfig{
+			Alias: jsii.String(resourceName(config, "aws_prometheus_workspace", prometheus.Name)),
+		})
+		if prometheus.alertManager != "" {
+			prometheusalertmanagerdefinition.NewPrometheusAlertManagerDefinition(stack, jsii.String("amp_"+prometheus.Name+"_alert_manager"),
+				&prometheusalertmanagerdefinition.PrometheusAlertManagerDefinitionConfig{
+					WorkspaceId: workspace.Id(),
+					Definition:  jsii.String(prometheus.alertManager),
+				})
+		}
+		for _, namespace := range slices.Sorted(maps.Keys(prometheus.rules)) {
+			prometheusrulegroupnamespace.NewPrometheusRuleGroupNamespace(stack, jsii.String(fmt.Sprintf("amp_%s_rules_%s", prometheus.Name, namespace)),
+				&prometheusrulegroupnamespace.PrometheusRuleGroupNamespaceConfig{
+					WorkspaceId: workspace.Id(),
+					Name:        jsii.String(namespace),
+					Data:        jsii.String(prometheus.rules[namespace]),
+				})
+		}
+
+		cdktf.NewTerraformOutput(stack, jsii.String("amp_"+prometheus.Name+"_remote_write_url"), &cdktf.TerraformOutputConfig{
+			Value:       jsii.String(*workspace.PrometheusEndpoint() + "api/v1/remote_write"),
+			Description: jsii.String("The URL collectors remote-write the " + prometheus.Name + " workspace's metrics to"),
+		})
+		cdktf.NewTerraformOutput(stack, jsii.String("amp_"+prometheus.Name+"_query_url"), &cdktf.TerraformOutputConfig{
+			Value:       workspace.PrometheusEndpoint(),
+			Description: jsii.String("The Prometheus endpoint Grafana queries the " + prometheus.Name + " workspace at"),
+		})
+	}
+
+	fmt.Printf("  ✓ %d Managed Prometheus workspace(s)\n", len(amp.Workspaces))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/amplifyapp"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/amplifybranch"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/amplifydomainassociation"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AmplifyConfig describes an Amplify Hosting app connected to a Git repository
+type AmplifyConfig struct {
+	Name         string               `json:"name"`
+	Repository   string               `json:"repository"`
+	BuildSpec    string               `json:"build_spec"` // amplify.yml contents; empty uses the one in the repo
+	Environment  map[string]string    `json:"environment"`
+	Branches     []AmplifyBranch      `json:"branches"`
+	CustomDomain *AmplifyCustomDomain `json:"custom_domain,omitempty"`
+}
+
+type AmplifyBranch struct {
+	Name               string            `json:"name"`
+	Stage              string            `json:"stage"` // PRODUCTION, BETA, DEVELOPMENT, ...
+	Environment        map[string]string `json:"environment"`
+	PullRequestPreview bool              `json:"pull_request_preview"`
+}
+
+type AmplifyCustomDomain struct {
+	Domain     string             `json:"domain"`
+	SubDomains []AmplifySubDomain `json:"sub_domains"`
+}
+
+type AmplifySubDomain struct {
+	Prefix string `json:"prefix"` // empty for the apex domain
+	Branch string `json:"branch"`
+}
+
+func (a *AmplifyConfig) validate() error {
+	if a.Name == "" || a.Repository == "" {
+		return fmt.Errorf("name and repository are required")
+	}
+	if len(a.Branches) == 0 {
+		return fmt.Errorf("at least one branch is required")
+	}
+	branches := map[string]bool{}
+	for i, branch := range a.Branches {
+		if branches[branch.Name] {
+			return fmt.Errorf("branches[%d].name: duplicate %q", i, branch.Name)
+		}
+		branches[branch.Name] = true
+	}
+	if a.CustomDomain != nil {
+		for _, sub := range a.CustomDomain.SubDomains {
+			if !branches[sub.Branch] {
+				return fmt.Errorf("custom_domain sub domain %q points at unknown branch %q", sub.Prefix, sub.Branch)
+			}
+		}
+	}
+	return nil
+}
+
+// addAmplify creates the app, its branches, and the optional custom domain. The repository
+// token is a sensitive Terraform variable so it never lands in config or the synthesized JSON.
+func addAmplify(stack cdktf.TerraformStack, config Config) {
+	amplify := config.Amplify
+
+	accessToken := cdktf.NewTerraformVariable(stack, jsii.String("amplify_access_token"), &cdktf.TerraformVariableConfig{
+		Type:        jsii.String("string"),
+		Sensitive:   jsii.Bool(true),
+		Description: jsii.String("Personal access token Amplify uses to read " + amplify.Repository),
+	})
+
+	appConfig := &amplifyapp.AmplifyAppConfig{
+		Name:                 jsii.String(resourceName(config, "aws_amplify_app", amplify.Name)),
+		Repository:           jsii.String(amplify.Repository),
+		AccessToken:          accessToken.StringValue(),
+		EnvironmentVariables: toStringMap(amplify.Environment),
+	}
+	if amplify.BuildSpec != "" {
+		appConfig.BuildSpec = jsii.String(amplify.BuildSpec)
+	}
+	app := amplifyapp.NewAmplifyApp(stack, jsii.String("amplify_app"), appConfig)
+
+	branches := map[string]amplifybranch.AmplifyBranch{}
+	for _, branch := range amplify.Branches {
+		branchConfig := &amplifybranch.AmplifyBranchConfig{
+			AppId:                    app.Id(),
+			BranchName:               jsii.String(branch.Name),
+			EnableAutoBuild:          jsii.Bool(true),
+			EnablePullRequestPreview: jsii.Bool(branch.PullRequestPreview),
+			EnvironmentVariables:     toStringMap(branch.Environment),
+		}
+		if branch.Stage != "" {
+			branchConfig.Stage = jsii.String(branch.Stage)
+		}
+		branches[branch.Name] = amplifybranch.NewAmplifyBranch(stack, jsii.String("amplify_branch_"+branch.Name), branchConfig)
+	}
+
+	if amplify.CustomDomain != nil {
+		subDomains := []*amplifydomainassociation.AmplifyDomainAssociationSubDomain{}
+		for _, sub := range amplify.CustomDomain.SubDomains {
+			subDomains = append(subDomains, &amplifydomainassociation.AmplifyDomainAssociationSubDomain{
+				Prefix:     jsii.String(sub.Prefix),
+				BranchName: branches[sub.Branch].BranchName(),
+			})
+		}
+		amplifydomainassociation.NewAmplifyDomainAssociation(stack, jsii.String("amplify_domain"),
+			&amplifydomainassociation.AmplifyDomainAssociationConfig{
+				AppId:               app.Id(),
+				DomainName:          jsii.String(amplify.CustomDomain.Domain),
+				SubDomain:           &subDomains,
+				WaitForVerification: jsii.Bool(false),
+			})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("amplify_default_domain"), &cdktf.TerraformOutputConfig{
+		Value:       app.DefaultDomain(),
+		Description: jsii.String("The default amplifyapp.com domain of the Amplify app"),
+	})
+
+	fmt.Printf("  ✓ Amplify app with %d branch(es)\n", len(amplify.Branches))
+}
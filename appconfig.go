@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/appconfigapplication"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/appconfigconfigurationprofile"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/appconfigdeployment"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/appconfigdeploymentstrategy"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/appconfigenvironment"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/appconfighostedconfigurationversion"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AppConfigConfig creates an AWS AppConfig application with an environment named after the
+// config's, and deploys hosted configuration profiles to it, so runtime settings and feature flags
+// ship with the infrastructure that reads them. Each change of a profile's file is a new version,
+// rolled out with the profile's deployment strategy.
+type AppConfigConfig struct {
+	Application string                       `json:"application"` // defaults to the project
+	Profiles    map[string]AppConfigProfile  `json:"profiles"`
+	Strategies  map[string]AppConfigStrategy `json:"strategies"` // custom deployment strategies, by name
+	Alarms      []string                     `json:"alarms"`     // CloudWatch alarm ARNs that roll a deployment back
+}
+
+type AppConfigProfile struct {
+	// File is the content of the profile, relative to the config file
+	File string `json:"file"`
+	// ContentType defaults to application/json for .json files, application/x-yaml for .yaml and
+	// .yml files and text/plain otherwise
+	ContentType string `json:"content_type"`
+	// Type is AWS.Freeform (default) or AWS.AppConfig.FeatureFlags, whose file is in the feature
+	// flags JSON format
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	// Strategy is one of strategies or a predefined strategy; it defaults to AppConfig.AllAtOnce
+	Strategy string `json:"strategy"`
+
+	content string // read from File by loadAppConfigProfiles
+}
+
+// AppConfigStrategy is a custom rollout: the share of targets getting the new version grows by
+// growth_factor percent over deployment_duration_minutes, then AppConfig watches the alarms for
+// final_bake_time_minutes before completing it
+type AppConfigStrategy struct {
+	DeploymentDurationMinutes int     `json:"deployment_duration_minutes"`
+	GrowthFactor              float64 `json:"growth_factor"`
+	GrowthType                string  `json:"growth_type"` // LINEAR (default) or EXPONENTIAL
+	FinalBakeTimeMinutes      int     `json:"final_bake_time_minutes"`
+}
+
+var (
+	appConfigProfileTypes     = []string{"AWS.AppConfig.FeatureFlags", "AWS.Freeform"}
+	appConfigGrowthTypes      = []string{"EXPONENTIAL", "LINEAR"}
+	appConfigNamePattern      = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	cloudWatchAlarmARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:cloudwatch:[a-z0-9-]+:\d{12}:alarm:.+$`)
+	// predefinedAppConfigStrategies are the strategies AWS provides in every account, by ID
+	predefinedAppConfigStrategies = []string{
+		"AppConfig.AllAtOnce",
+		"AppConfig.Canary10Percent20Minutes",
+		"AppConfig.Linear20PercentEvery6Minutes",
+		"AppConfig.Linear50PercentEvery30Seconds",
+	}
+)
+
+func (a *AppConfigConfig) validate() error {
+	if len(a.Application) > 64 {
+		return invalidValue(a.Application, "", "application %q must be at most 64 characters", a.Application)
+	}
+	for _, name := range slices.Sorted(maps.Keys(a.Strategies)) {
+		strategy := a.Strategies[name]
+		if !appConfigNamePattern.MatchString(name) {
+			return fmt.Errorf("strategies: name %q must be 1-64 letters, digits, _ or -", name)
+		}
+		if strategy.DeploymentDurationMinutes < 0 || strategy.DeploymentDurationMinutes > 1440 {
+			return invalidValue(strategy.DeploymentDurationMinutes, "", "strategies.%s: deployment_duration_minutes must be 0-1440", name)
+		}
+		if strategy.GrowthFactor < 1 || strategy.GrowthFactor > 100 {
+			return invalidValue(strategy.GrowthFactor, "", "strategies.%s: growth_factor must be a percentage from 1 to 100", name)
+		}
+		if strategy.GrowthType != "" && !slices.Contains(appConfigGrowthTypes, strategy.GrowthType) {
+			return invalidValue(strategy.GrowthType, closestMatch(strategy.GrowthType, appConfigGrowthTypes),
+				"strategies.%s: growth_type %q must be LINEAR or EXPONENTIAL", name, strategy.GrowthType)
+		}
+		if strategy.FinalBakeTimeMinutes < 0 || strategy.FinalBakeTimeMinutes > 1440 {
+			return invalidValue(strategy.FinalBakeTimeMinutes, "", "strategies.%s: final_bake_time_minutes must be 0-1440", name)
+		}
+	}
+
+	if len(a.Profiles) == 0 {
+		return fmt.Errorf("profiles is required")
+	}
+	strategies := append(slices.Sorted(maps.Keys(a.Strategies)), predefinedAppConfigStrategies...)
+	for _, name := range slices.Sorted(maps.Keys(a.Profiles)) {
+		profile := a.Profiles[name]
+		if !appConfigNamePattern.MatchString(name) {
+			return fmt.Errorf("profiles: name %q must be 1-64 letters, digits, _ or -", name)
+		}
+		if profile.File == "" {
+			return fmt.Errorf("profiles.%s: file is required", name)
+		}
+		if profile.Type != "" && !slices.Contains(appConfigProfileTypes, profile.Type) {
+			return invalidValue(profile.Type, closestMatch(profile.Type, appConfigProfileTypes),
+				"profiles.%s: type %q must be AWS.Freeform or AWS.AppConfig.FeatureFlags", name, profile.Type)
+		}
+		if profile.Type == "AWS.AppConfig.FeatureFlags" && profile.contentType() != "application/json" {
+			return fmt.Errorf("profiles.%s: feature flags are JSON, so content_type must be application/json", name)
+		}
+		if profile.Strategy != "" && !slices.Contains(strategies, profile.Strategy) {
+			return invalidValue(profile.Strategy, closestMatch(profile.Strategy, strategies),
+				"profiles.%s: strategy %q is not one of strategies or a predefined strategy", name, profile.Strategy)
+		}
+	}
+	for _, alarm := range a.Alarms {
+		if !cloudWatchAlarmARNPattern.MatchString(alarm) {
+			return invalidValue(alarm, "", "alarms: %q is not a CloudWatch alarm ARN", alarm)
+		}
+	}
+	return nil
+}
+
+func (p AppConfigProfile) contentType() string {
+	if p.ContentType != "" {
+		return p.ContentType
+	}
+	switch strings.ToLower(filepath.Ext(p.File)) {
+	case ".json":
+		return "application/json"
+	case ".yaml", ".yml":
+		return "application/x-yaml"
+	}
+	return "text/plain"
+}
+
+// loadAppConfigProfiles reads the files of the profiles, relative to the config file in dir
+func loadAppConfigProfiles(appConfig *AppConfigConfig, dir string) error {
+	for _, name := range slices.Sorted(maps.Keys(appConfig.Profiles)) {
+		profile := appConfig.Profiles[name]
+		raw, err := os.ReadFile(filepath.Join(dir, profile.File))
+		if err != nil {
+			return fmt.Errorf("appconfig: %s: reading file: %w", name, err)
+		}
+		// AppConfig validates the hosted content when the version is created; failing here is earlier
+		if profile.contentType() == "application/json" && !json.Valid(raw) {
+			return fmt.Errorf("appconfig: %s: %s is not valid JSON", name, profile.File)
+		}
+		profile.content = escapeInterpolation(string(raw))
+		appConfig.Profiles[name] = profile
+	}
+	return nil
+}
+
+// addAppConfig creates the application, its environment, the custom strategies and, for each
+// profile, a hosted version of its file deployed to the environment
+func addAppConfig(stack cdktf.TerraformStack, config Config) {
+	appConfig := config.AppConfig
+	name := appConfig.Application
+	if name == "" {
+		name = config.Project
+	}
+
+	application := appconfigapplication.NewAppconfigApplication(stack, jsii.String("appconfig_application"), &appconfigapplication.AppconfigApplicationConfig{
+		Name: jsii.String(name),
+	})
+	environmentConfig := &appconfigenvironment.AppconfigEnvironmentConfig{
+		ApplicationId: application.Id(),
+		Name:          jsii.String(config.Environment),
+	}
+	if len(appConfig.Alarms) > 0 {
+		role := newServiceRole(stack, "appconfig_alarm_role", resourceName(config, "aws_iam_role", "appconfig-alarms"), "appconfig.amazonaws.com", config)
+		addInlinePolicy(stack, "appconfig_alarm_policy", role, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   "cloudwatch:DescribeAlarms",
+			"Resource": "*",
+		})
+		var monitors []appconfigenvironment.AppconfigEnvironmentMonitor
+		for _, alarm := range appConfig.Alarms {
+			monitors = append(monitors, appconfigenvironment.AppconfigEnvironmentMonitor{
+				AlarmArn:     jsii.String(alarm),
+				AlarmRoleArn: role.Arn(),
+			})
+		}
+		environmentConfig.Monitor = monitors
+	}
+	environment := appconfigenvironment.NewAppconfigEnvironment(stack, jsii.String("appconfig_environment"), environmentConfig)
+
+	strategyIDs := map[string]*string{}
+	for _, id := range predefinedAppConfigStrategies {
+		strategyIDs[id] = jsii.String(id)
+	}
+	for _, strategyName := range slices.Sorted(maps.Keys(appConfig.Strategies)) {
+		custom := appConfig.Strategies[strategyName]
+		growthType := custom.GrowthType
+		if growthType == "" {
+			growthType = "LINEAR"
+		}
+		strategy := appconfigdeploymentstrategy.NewAppconfigDeploymentStrategy(stack, jsii.String("appconfig_strategy_"+strategyName),
+			&appconfigdeploymentstrategy.AppconfigDeploymentStrategyConfig{
+				Name:                        jsii.String(resourceName(config, "aws_appconfig_deployment_strategy", strategyName)),
+				DeploymentDurationInMinutes: jsii.Number(custom.DeploymentDurationMinutes),
+				GrowthFactor:                jsii.Number(custom.GrowthFactor),
+				GrowthType:                  jsii.String(growthType),
+				FinalBakeTimeInMinutes:      jsii.Number(custom.FinalBakeTimeMinutes),
+				ReplicateTo:                 jsii.String("NONE"),
+			})
+		strategyIDs[strategyName] = strategy.Id()
+	}
+
+	// An environment takes one deployment at a time, so each waits for the one before it
+	var previous cdktf.ITerraformDependable
+	for _, profileName := range slices.Sorted(maps.Keys(appConfig.Profiles)) {
+		profile := appConfig.Profiles[profileName]
+		profileType := profile.Type
+		if profileType == "" {
+			profileType = "AWS.Freeform"
+		}
+		profileConfig := &appconfigconfigurationprofile.AppconfigConfigurationProfileConfig{
+			ApplicationId: application.Id(),
+			Name:          jsii.String(profileName),
+			LocationUri:   jsii.String("hosted"),
+			Type:          jsii.String(profileType),
+		}
+		if profile.Description != "" {
+			profileConfig.Description = jsii.String(profile.Description)
+		}
+		created := appconfigconfigurationprofile.NewAppconfigConfigurationProfile(stack, jsii.String("appconfig_profile_"+profileName), profileConfig)
+		version := appconfighostedconfigurationversion.NewAppconfigHostedConfigurationVersion(stack, jsii.String("appconfig_version_"+profileName),
+			&appconfighostedconfigurationversion.AppconfigHostedConfigurationVersionConfig{
+				ApplicationId:          application.Id(),
+				ConfigurationProfileId: created.ConfigurationProfileId(),
+				Content:                jsii.String(profile.content),
+				ContentType:            jsii.String(profile.contentType()),
+			})
+
+		strategy := profile.Strategy
+		if strategy == "" {
+			strategy = "AppConfig.AllAtOnce"
+		}
+		deploymentConfig := &appconfigdeployment.AppconfigDeploymentConfig{
+			ApplicationId:          application.Id(),
+			EnvironmentId:          environment.EnvironmentId(),
+			ConfigurationProfileId: created.ConfigurationProfileId(),
+			ConfigurationVersion:   cdktf.Token_AsString(version.VersionNumber(), nil),
+			DeploymentStrategyId:   strategyIDs[strategy],
+		}
+		if previous != nil {
+			deploymentConfig.DependsOn = &[]cdktf.ITerraformDependable{previous}
+		}
+		previous = appconfigdeployment.NewAppconfigDeployment(stack, jsii.String("appconfig_deployment_"+profileName), deploymentConfig)
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("appconfig_application_id"), &cdktf.TerraformOutputConfig{
+		Value:       application.Id(),
+		Description: jsii.String("The AppConfig application applications fetch their configuration from"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("appconfig_environment_id"), &cdktf.TerraformOutputConfig{
+		Value:       environment.EnvironmentId(),
+		Description: jsii.String("The AppConfig environment of the config's environment"),
+	})
+
+	fmt.Printf("  ✓ AppConfig application %s with %d profile(s)\n", name, len(appConfig.Profiles))
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/apprunnerautoscalingconfigurationversion"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/apprunnercustomdomainassociation"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/apprunnerobservabilityconfiguration"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/apprunnerservice"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrole"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AppRunnerConfig describes App Runner services built from an ECR image or a source repository
+type AppRunnerConfig struct {
+	Services []AppRunnerService `json:"services"`
+}
+
+type AppRunnerService struct {
+	Name         string                `json:"name"`
+	Image        string                `json:"image"` // ECR or ECR Public image URI
+	Source       *AppRunnerSource      `json:"source,omitempty"`
+	Port         string                `json:"port"`
+	CPU          string                `json:"cpu"`    // e.g. "1 vCPU"
+	Memory       string                `json:"memory"` // e.g. "2 GB"
+	Environment  map[string]string     `json:"environment"`
+	AutoScaling  *AppRunnerAutoScaling `json:"auto_scaling,omitempty"`
+	CustomDomain string                `json:"custom_domain"`
+}
+
+type AppRunnerSource struct {
+	RepositoryURL string `json:"repository_url"`
+	Branch        string `json:"branch"`
+	ConnectionArn string `json:"connection_arn"` // an App Runner GitHub connection, completed in the console
+	Runtime       string `json:"runtime"`        // e.g. PYTHON_3, NODEJS_18
+	BuildCommand  string `json:"build_command"`
+	StartCommand  string `json:"start_command"`
+}
+
+type AppRunnerAutoScaling struct {
+	MinSize        float64 `json:"min_size"`        // defaults to 1
+	MaxSize        float64 `json:"max_size"`        // defaults to 25
+	MaxConcurrency float64 `json:"max_concurrency"` // defaults to 100
+}
+
+// withDefaults fills in App Runner's own defaults for the fields left unset
+func (a AppRunnerAutoScaling) withDefaults() AppRunnerAutoScaling {
+	if a.MinSize == 0 {
+		a.MinSize = 1
+	}
+	if a.MaxSize == 0 {
+		a.MaxSize = 25
+	}
+	if a.MaxConcurrency == 0 {
+		a.MaxConcurrency = 100
+	}
+	return a
+}
+
+func (s AppRunnerService) isPrivateECR() bool {
+	return strings.Contains(s.Image, ".dkr.ecr.")
+}
+
+func (a *AppRunnerConfig) validate() error {
+	names := map[string]bool{}
+	for i, service := range a.Services {
+		if service.Name == "" {
+			return fmt.Errorf("services need a name")
+		}
+		if names[service.Name] {
+			return fmt.Errorf("services[%d].name: duplicate %q", i, service.Name)
+		}
+		names[service.Name] = true
+		if (service.Image == "") == (service.Source == nil) {
+			return fmt.Errorf("service %s needs exactly one of image or source", service.Name)
+		}
+		if service.Image != "" && !service.isPrivateECR() && !strings.HasPrefix(service.Image, "public.ecr.aws/") {
+			return fmt.Errorf("service %s: image must come from ECR or ECR Public", service.Name)
+		}
+		if service.Source != nil && (service.Source.RepositoryURL == "" || service.Source.ConnectionArn == "" || service.Source.Runtime == "") {
+			return fmt.Errorf("service %s: source needs repository_url, connection_arn and runtime", service.Name)
+		}
+		if service.AutoScaling != nil {
+			scaling := service.AutoScaling.withDefaults()
+			if scaling.MinSize < 1 || scaling.MaxSize > 25 || scaling.MinSize > scaling.MaxSize {
+				return fmt.Errorf("service %s: auto_scaling needs 1 <= min_size <= max_size <= 25", service.Name)
+			}
+			if scaling.MaxConcurrency < 1 || scaling.MaxConcurrency > 200 {
+				return fmt.Errorf("service %s: auto_scaling.max_concurrency must be between 1 and 200", service.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// addAppRunner creates one App Runner service per entry, with its own scaling configuration
+func addAppRunner(stack cdktf.TerraformStack, config Config) {
+	apprunner := config.AppRunner
+
+	// Private ECR pulls need an access role; it's shared by every service
+	var accessRole iamrole.IamRole
+	for _, service := range apprunner.Services {
+		if service.isPrivateECR() {
+			accessRole = newServiceRole(stack, "apprunner_access_role", resourceName(config, "aws_iam_role", "apprunner-access"),
+				"build.apprunner.amazonaws.com", config,
+				"arn:aws:iam::aws:policy/service-role/AWSAppRunnerServicePolicyForECRAccess")
+			break
+		}
+	}
+
+	// With tracing, every service sends traces to X-Ray through App Runner's managed ADOT collector
+	var observability *apprunnerservice.ApprunnerServiceObservabilityConfiguration
+	var instanceRole iamrole.IamRole
+	if config.Tracing {
+		tracing := apprunnerobservabilityconfiguration.NewApprunnerObservabilityConfiguration(stack, jsii.String("apprunner_observability"),
+			&apprunnerobservabilityconfiguration.ApprunnerObservabilityConfigurationConfig{
+				ObservabilityConfigurationName: jsii.String(resourceName(config, "aws_apprunner_observability_configuration", "xray")),
+				TraceConfiguration: &apprunnerobservabilityconfiguration.ApprunnerObservabilityConfigurationTraceConfiguration{
+					Vendor: jsii.String("AWSXRAY"),
+				},
+			})
+		observability = &apprunnerservice.ApprunnerServiceObservabilityConfiguration{
+			ObservabilityEnabled:          jsii.Bool(true),
+			ObservabilityConfigurationArn: tracing.Arn(),
+		}
+		instanceRole = newServiceRole(stack, "apprunner_instance_role", resourceName(config, "aws_iam_role", "apprunner-instance"),
+			"tasks.apprunner.amazonaws.com", config, xrayWriteAccess)
+	}
+
+	for _, service := range apprunner.Services {
+		port := service.Port
+		if port == "" {
+			port = "8080"
+		}
+		cpu := service.CPU
+		if cpu == "" {
+			cpu = sizeFor(config).AppRunnerCPU
+		}
+		memory := service.Memory
+		if memory == "" {
+			memory = sizeFor(config).AppRunnerMemory
+		}
+
+		environment := toStringMap(service.Environment)
+
+		source := &apprunnerservice.ApprunnerServiceSourceConfiguration{}
+		if service.Image != "" {
+			repositoryType := "ECR_PUBLIC"
+			if service.isPrivateECR() {
+				repositoryType = "ECR"
+				source.AuthenticationConfiguration = &apprunnerservice.ApprunnerServiceSourceConfigurationAuthenticationConfiguration{
+					AccessRoleArn: accessRole.Arn(),
+				}
+				source.AutoDeploymentsEnabled = jsii.Bool(true)
+			}
+			source.ImageRepository = &apprunnerservice.ApprunnerServiceSourceConfigurationImageRepository{
+				ImageIdentifier:     jsii.String(service.Image),
+				ImageRepositoryType: jsii.String(repositoryType),
+				ImageConfiguration: &apprunnerservice.ApprunnerServiceSourceConfigurationImageRepositoryImageConfiguration{
+					Port:                        jsii.String(port),
+					RuntimeEnvironmentVariables: environment,
+				},
+			}
+		} else {
+			branch := service.Source.Branch
+			if branch == "" {
+				branch = "main"
+			}
+			values := &apprunnerservice.ApprunnerServiceSourceConfigurationCodeRepositoryCodeConfigurationCodeConfigurationValues{
+				Runtime:                     jsii.String(service.Source.Runtime),
+				Port:                        jsii.String(port),
+				RuntimeEnvironmentVariables: environment,
+			}
+			if service.Source.BuildCommand != "" {
+				values.BuildCommand = jsii.String(service.Source.BuildCommand)
+			}
+			if service.Source.StartCommand != "" {
+				values.StartCommand = jsii.String(service.Source.StartCommand)
+			}
+
+			source.AuthenticationConfiguration = &apprunnerservice.ApprunnerServiceSourceConfigurationAuthenticationConfiguration{
+				ConnectionArn: jsii.String(service.Source.ConnectionArn),
+			}
+			source.AutoDeploymentsEnabled = jsii.Bool(true)
+			source.CodeRepository = &apprunnerservice.ApprunnerServiceSourceConfigurationCodeRepository{
+				RepositoryUrl: jsii.String(service.Source.RepositoryURL),
+				SourceCodeVersion: &apprunnerservice.ApprunnerServiceSourceConfigurationCodeRepositorySourceCodeVersion{
+					Type:  jsii.String("BRANCH"),
+					Value: jsii.String(branch),
+				},
+				CodeConfiguration: &apprunnerservice.ApprunnerServiceSourceConfigurationCodeRepositoryCodeConfiguration{
+					ConfigurationSource:     jsii.String("API"),
+					CodeConfigurationValues: values,
+				},
+			}
+		}
+
+		serviceConfig := &apprunnerservice.ApprunnerServiceConfig{
+			ServiceName:         jsii.String(resourceName(config, "aws_apprunner_service", service.Name)),
+			SourceConfiguration: source,
+			InstanceConfiguration: &apprunnerservice.ApprunnerServiceInstanceConfiguration{
+				Cpu:    jsii.String(cpu),
+				Memory: jsii.String(memory),
+			},
+			ObservabilityConfiguration: observability,
+		}
+		if instanceRole != nil {
+			serviceConfig.InstanceConfiguration.InstanceRoleArn = instanceRole.Arn()
+		}
+
+		if service.AutoScaling != nil {
+			settings := service.AutoScaling.withDefaults()
+			scaling := apprunnerautoscalingconfigurationversion.NewApprunnerAutoScalingConfigurationVersion(stack,
+				jsii.String("apprunner_scaling_"+service.Name),
+				&apprunnerautoscalingconfigurationversion.ApprunnerAutoScalingConfigurationVersionConfig{
+					AutoScalingConfigurationName: jsii.String(resourceName(config, "aws_apprunner_auto_scaling_configuration_version", service.Name)),
+					MinSize:                      jsii.Number(settings.MinSize),
+					MaxSize:                      jsii.Number(settings.MaxSize),
+					MaxConcurrency:               jsii.Number(settings.MaxConcurrency),
+				})
+			serviceConfig.AutoScalingConfigurationArn = scaling.Arn()
+		}
+
+		runner := apprunnerservice.NewApprunnerService(stack, jsii.String("apprunner_"+service.Name), serviceConfig)
+
+		if service.CustomDomain != "" {
+			apprunnercustomdomainassociation.NewApprunnerCustomDomainAssociation(stack,
+				jsii.String("apprunner_domain_"+service.Name),
+				&apprunnercustomdomainassociation.ApprunnerCustomDomainAssociationConfig{
+					DomainName:         jsii.String(service.CustomDomain),
+					ServiceArn:         runner.Arn(),
+					EnableWwwSubdomain: jsii.Bool(false),
+				})
+		}
+
+		cdktf.NewTerraformOutput(stack, jsii.String("apprunner_"+service.Name+"_url"), &cdktf.TerraformOutputConfig{
+			Value:       runner.ServiceUrl(),
+			Description: jsii.String("The default URL of the " + service.Name + " App Runner service"),
+		})
+	}
+
+	fmt.Printf("  ✓ App Runner with %d service(s)\n", len(apprunner.Services))
+}
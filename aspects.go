@@ -0,0 +1,266 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/cloudwatchloggroup"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/cloudwatchlogresourcepolicy"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// Compliance profiles, selected by compliance_profile in the config
+const (
+	complianceAudit     = "audit"     // report violations as warnings
+	complianceEnforce   = "enforce"   // fail synth on any violation
+	complianceRemediate = "remediate" // fix what can be fixed, fail on the rest
+)
+
+func validateComplianceProfile(profile string) error {
+	switch profile {
+	case "", complianceAudit, complianceEnforce, complianceRemediate:
+		return nil
+	}
+	return fmt.Errorf("compliance_profile %q must be audit, enforce or remediate", profile)
+}
+
+// tfResource is one resource of a stack, as it will be synthesized
+type tfResource struct {
+	resourceType string
+	id           string
+	attrs        map[string]interface{}
+	construct    cdktf.TerraformResource
+}
+
+// complianceRule checks one resource type. fix is nil for rules that can only be reported.
+type complianceRule struct {
+	resourceType string
+	problem      string
+	ok           func(stack *stackResources, resource tfResource) bool
+	fix          func(stack cdktf.TerraformStack, resource tfResource)
+}
+
+var complianceRules = []complianceRule{
+	{
+		resourceType: "aws_s3_bucket",
+		problem:      "bucket has no default encryption",
+		ok: func(stack *stackResources, resource tfResource) bool {
+			return stack.referenced("aws_s3_bucket_server_side_encryption_configuration", resource)
+		},
+		fix: func(stack cdktf.TerraformStack, resource tfResource) {
+			encryptBucket(stack, resource.id, resource.construct.(s3bucket.S3Bucket))
+		},
+	},
+	{
+		resourceType: "aws_s3_bucket",
+		problem:      "bucket has no public access block",
+		ok: func(stack *stackResources, resource tfResource) bool {
+			return stack.referenced("aws_s3_bucket_public_access_block", resource)
+		},
+		fix: func(stack cdktf.TerraformStack, resource tfResource) {
+			blockPublicAccess(stack, resource.id, resource.construct.(s3bucket.S3Bucket))
+		},
+	},
+	{
+		resourceType: "aws_ebs_volume",
+		problem:      "volume is not encrypted",
+		ok: func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["encrypted"] == true
+		},
+		fix: func(_ cdktf.TerraformStack, resource tfResource) {
+			resource.construct.AddOverride(jsii.String("encrypted"), true)
+		},
+	},
+	{
+		resourceType: "aws_sqs_queue",
+		problem:      "queue is not encrypted",
+		ok: func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["kms_master_key_id"] != nil || resource.attrs["sqs_managed_sse_enabled"] == true
+		},
+		fix: func(_ cdktf.TerraformStack, resource tfResource) {
+			resource.construct.AddOverride(jsii.String("sqs_managed_sse_enabled"), true)
+		},
+	},
+	{
+		resourceType: "aws_sns_topic",
+		problem:      "topic is not encrypted",
+		ok: func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["kms_master_key_id"] != nil
+		},
+		fix: func(_ cdktf.TerraformStack, resource tfResource) {
+			resource.construct.AddOverride(jsii.String("kms_master_key_id"), "alias/aws/sns")
+		},
+	},
+	{
+		resourceType: "aws_opensearch_domain",
+		problem:      "domain does not publish application logs",
+		ok: func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["log_publishing_options"] != nil
+		},
+		fix: func(stack cdktf.TerraformStack, resource tfResource) {
+			logGroup := newComplianceLogGroup(stack, resource, "/aws/opensearch/")
+			cloudwatchlogresourcepolicy.NewCloudwatchLogResourcePolicy(stack, jsii.String(resource.id+"_log_policy"),
+				&cloudwatchlogresourcepolicy.CloudwatchLogResourcePolicyConfig{
+					PolicyName: jsii.String(*stack.Node().Id() + "-" + resource.id + "-logs"),
+					PolicyDocument: jsii.String(policyDocument(map[string]interface{}{
+						"Effect":    "Allow",
+						"Principal": map[string]string{"Service": "es.amazonaws.com"},
+						"Action":    []string{"logs:CreateLogStream", "logs:PutLogEvents"},
+						"Resource":  *logGroup.Arn() + ":*",
+					})),
+				})
+			resource.construct.AddOverride(jsii.String("log_publishing_options"), []map[string]interface{}{{
+				"log_type":                 "ES_APPLICATION_LOGS",
+				"cloudwatch_log_group_arn": logGroup.Arn(),
+			}})
+		},
+	},
+	{
+		resourceType: "aws_msk_cluster",
+		problem:      "cluster does not deliver broker logs",
+		ok: func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["logging_info"] != nil
+		},
+		fix: func(stack cdktf.TerraformStack, resource tfResource) {
+			logGroup := newComplianceLogGroup(stack, resource, "/aws/msk/")
+			resource.construct.AddOverride(jsii.String("logging_info.broker_logs.cloudwatch_logs"), map[string]interface{}{
+				"enabled":   true,
+				"log_group": logGroup.Name(),
+			})
+		},
+	},
+	{
+		resourceType: "aws_redshiftserverless_namespace",
+		problem:      "namespace does not export logs",
+		ok: func(_ *stackResources, resource tfResource) bool {
+			exports, _ := resource.attrs["log_exports"].([]interface{})
+			return len(exports) > 0
+		},
+		fix: func(_ cdktf.TerraformStack, resource tfResource) {
+			resource.construct.AddOverride(jsii.String("log_exports"),
+				[]string{"userlog", "connectionlog", "useractivitylog"})
+		},
+	},
+}
+
+// newComplianceLogGroup creates the log group a remediated resource delivers its logs to
+func newComplianceLogGroup(stack cdktf.TerraformStack, resource tfResource, prefix string) cloudwatchloggroup.CloudwatchLogGroup {
+	return cloudwatchloggroup.NewCloudwatchLogGroup(stack, jsii.String(resource.id+"_logs"),
+		&cloudwatchloggroup.CloudwatchLogGroupConfig{
+			Name:            jsii.String(prefix + *stack.Node().Id() + "/" + resource.id),
+			RetentionInDays: jsii.Number(90),
+		})
+}
+
+// stackResources indexes the resources of a synthesized stack
+type stackResources struct {
+	byType map[string][]tfResource
+}
+
+var referencePattern = regexp.MustCompile(`^\$\{([a-z0-9_]+)\.([A-Za-z0-9_-]+)\.`)
+
+// referenced reports whether a resource of referrerType points at the resource through its bucket
+// attribute, either by reference or by a literal bucket name
+func (s *stackResources) referenced(referrerType string, resource tfResource) bool {
+	for _, referrer := range s.byType[referrerType] {
+		bucket, _ := referrer.attrs["bucket"].(string)
+		if match := referencePattern.FindStringSubmatch(bucket); match != nil {
+			if match[1] == resource.resourceType && match[2] == resource.id {
+				return true
+			}
+		} else if bucket != "" && bucket == resource.attrs["bucket"] {
+			return true
+		}
+	}
+	return false
+}
+
+// loadStackResources synthesizes a stack in memory and pairs each resource with its construct
+func loadStackResources(stack cdktf.TerraformStack) *stackResources {
+	constructsByPath := map[string]cdktf.TerraformResource{}
+	for _, child := range *stack.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+		if resource, ok := child.(cdktf.TerraformResource); ok {
+			constructsByPath[*child.Node().Path()] = resource
+		}
+	}
+
+	raw, _ := json.Marshal(stack.ToTerraform())
+	resources, _ := parseStackResources(raw, constructsByPath)
+	return resources
+}
+
+// parseStackResources indexes the resources of a cdk.tf.json document. constructsByPath may be
+// nil when the constructs aren't available, as when reading back a synthesized stack.
+func parseStackResources(raw []byte, constructsByPath map[string]cdktf.TerraformResource) (*stackResources, error) {
+	var synthesized struct {
+		Resource map[string]map[string]map[string]interface{} `json:"resource"`
+	}
+	if err := json.Unmarshal(raw, &synthesized); err != nil {
+		return nil, err
+	}
+
+	resources := &stackResources{byType: map[string][]tfResource{}}
+	for resourceType, byID := range synthesized.Resource {
+		ids := make([]string, 0, len(byID))
+		for id := range byID {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			attrs := byID[id]
+			path, _ := attr(attrs, "//", "metadata", "path").(string)
+			resources.byType[resourceType] = append(resources.byType[resourceType], tfResource{
+				resourceType: resourceType,
+				id:           id,
+				attrs:        attrs,
+				construct:    constructsByPath[path],
+			})
+		}
+	}
+	return resources, nil
+}
+
+// complianceAspect checks every stack of the app against complianceRules before it is written
+type complianceAspect struct {
+	profile string
+}
+
+func (a *complianceAspect) Visit(node constructs.IConstruct) {
+	stack, ok := node.(cdktf.TerraformStack)
+	if !ok {
+		return
+	}
+
+	resources := loadStackResources(stack)
+	fixed, violations := 0, 0
+	for _, rule := range complianceRules {
+		for _, resource := range resources.byType[rule.resourceType] {
+			if rule.ok(resources, resource) {
+				continue
+			}
+			message := fmt.Sprintf("%s.%s: %s", resource.resourceType, resource.id, rule.problem)
+			switch {
+			case a.profile == complianceRemediate && rule.fix != nil && resource.construct != nil:
+				rule.fix(stack, resource)
+				fixed++
+			case a.profile == complianceAudit:
+				cdktf.Annotations_Of(node).AddWarning(jsii.String(message))
+				violations++
+			default:
+				cdktf.Annotations_Of(node).AddError(jsii.String(message))
+				violations++
+			}
+		}
+	}
+
+	marker := "✓"
+	if violations > 0 {
+		marker = "⚠"
+	}
+	fmt.Printf("  %s Compliance (%s) %s: %d fixed, %d violation(s)\n", marker, a.profile, *stack.Node().Id(), fixed, violations)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/athenaworkgroup"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AthenaConfig describes a workgroup whose query results always land in the config bucket
+type AthenaConfig struct {
+	ResultsPrefix        string  `json:"results_prefix"`
+	Encryption           string  `json:"encryption"` // SSE_S3, SSE_KMS or CSE_KMS
+	KMSKeyArn            string  `json:"kms_key_arn"`
+	MaxScannedMBPerQuery float64 `json:"max_scanned_mb_per_query"`
+	PublishMetrics       bool    `json:"publish_metrics"`
+}
+
+func (a *AthenaConfig) validate() error {
+	switch a.Encryption {
+	case "", "SSE_S3":
+	case "SSE_KMS", "CSE_KMS":
+		if a.KMSKeyArn == "" {
+			return fmt.Errorf("kms_key_arn is required for encryption %s", a.Encryption)
+		}
+	default:
+		return invalidValue(a.Encryption, closestMatch(a.Encryption, []string{"SSE_S3", "SSE_KMS", "CSE_KMS"}),
+			"unknown encryption %q (want SSE_S3, SSE_KMS or CSE_KMS)", a.Encryption)
+	}
+	// Athena rejects cutoffs below 10 MB
+	if a.MaxScannedMBPerQuery != 0 && a.MaxScannedMBPerQuery < 10 {
+		return fmt.Errorf("max_scanned_mb_per_query must be at least 10")
+	}
+	return nil
+}
+
+// addAthena creates a workgroup that enforces its result location and encryption on every query
+func addAthena(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket) {
+	athena := config.Athena
+
+	prefix := athena.ResultsPrefix
+	if prefix == "" {
+		prefix = "athena-results/"
+	}
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	encryption := &athenaworkgroup.AthenaWorkgroupConfigurationResultConfigurationEncryptionConfiguration{
+		EncryptionOption: jsii.String("SSE_S3"),
+	}
+	if athena.Encryption != "" && athena.Encryption != "SSE_S3" {
+		encryption.EncryptionOption = jsii.String(athena.Encryption)
+		encryption.KmsKeyArn = jsii.String(athena.KMSKeyArn)
+	}
+
+	workgroupConfiguration := &athenaworkgroup.AthenaWorkgroupConfiguration{
+		EnforceWorkgroupConfiguration:   jsii.Bool(true),
+		PublishCloudwatchMetricsEnabled: jsii.Bool(athena.PublishMetrics),
+		ResultConfiguration: &athenaworkgroup.AthenaWorkgroupConfigurationResultConfiguration{
+			OutputLocation:          jsii.String("s3://" + *bucket.Bucket() + "/" + strings.TrimPrefix(prefix, "/")),
+			EncryptionConfiguration: encryption,
+		},
+	}
+	if athena.MaxScannedMBPerQuery > 0 {
+		workgroupConfiguration.BytesScannedCutoffPerQuery = jsii.Number(athena.MaxScannedMBPerQuery * 1024 * 1024)
+	}
+
+	workgroup := athenaworkgroup.NewAthenaWorkgroup(stack, jsii.String("athena_workgroup"),
+		&athenaworkgroup.AthenaWorkgroupConfig{
+			Name:          jsii.String(resourceName(config, "aws_athena_workgroup", "athena")),
+			State:         jsii.String("ENABLED"),
+			Configuration: workgroupConfiguration,
+		})
+
+	cdktf.NewTerraformOutput(stack, jsii.String("athena_workgroup_name"), &cdktf.TerraformOutputConfig{
+		Value:       workgroup.Name(),
+		Description: jsii.String("The name of the Athena workgroup"),
+	})
+
+	fmt.Println("  ✓ Athena workgroup with enforced results location")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/provider"
+	"github.com/example/json-to-terraform/cdktftest"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+func TestAthenaWorkgroup(t *testing.T) {
+	config := Config{
+		Project:     "my-app",
+		Environment: "dev",
+		Region:      "us-west-2",
+		Storage:     StorageConfig{BucketName: "my-app-data"},
+		Athena:      &AthenaConfig{ResultsPrefix: "queries", MaxScannedMBPerQuery: 10},
+	}
+
+	app := cdktftest.App(t)
+	stack := cdktf.NewTerraformStack(app, jsii.String("test"))
+	provider.NewAwsProvider(stack, jsii.String("aws"), &provider.AwsProviderConfig{Region: jsii.String(config.Region)})
+	addAthena(stack, config, addStorage(stack, config))
+
+	cdktftest.AssertResourceCount(t, stack, "aws_athena_workgroup", 1)
+	cdktftest.AssertResource(t, stack, "aws_athena_workgroup", map[string]interface{}{
+		"state": "ENABLED",
+		"configuration": map[string]interface{}{
+			"enforce_workgroup_configuration":    true,
+			"bytes_scanned_cutoff_per_query":     10 * 1024 * 1024,
+			"publish_cloudwatch_metrics_enabled": false,
+			"result_configuration": map[string]interface{}{
+				"encryption_configuration": map[string]interface{}{"encryption_option": "SSE_S3"},
+			},
+		},
+	})
+	if output := cdktftest.AssertOutput(t, stack, "athena_workgroup_name"); output == nil {
+		t.Error("athena_workgroup_name has no value")
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AtlasConfig creates a MongoDB Atlas project with a dedicated cluster on AWS, for teams whose
+// data store is Atlas. The provider reads its API keys from MONGODB_ATLAS_PUBLIC_KEY and
+// MONGODB_ATLAS_PRIVATE_KEY.
+type AtlasConfig struct {
+	OrgID   string       `json:"org_id"`
+	Project string       `json:"project"` // defaults to <project>-<env>
+	Cluster AtlasCluster `json:"cluster"`
+	// AccessList lists the CIDR blocks allowed to connect
+	AccessList []string `json:"access_list"`
+	// NATIPsOutput names a stack output listing public IPs to allow, such as the NAT gateway IPs
+	// of a VPC module: {"outputs": {"nat_public_ips": "nat_public_ips"}}
+	NATIPsOutput  string              `json:"nat_ips_output"`
+	DatabaseUsers []AtlasDatabaseUser `json:"database_users"`
+}
+
+// AtlasCluster is a replica set in the config region
+type AtlasCluster struct {
+	Name           string `json:"name"`            // defaults to main
+	Tier           string `json:"tier"`            // a dedicated tier, M10 or larger
+	MongoDBVersion string `json:"mongodb_version"` // e.g. 7.0; defaults to Atlas' current version
+	DiskSizeGB     int    `json:"disk_size_gb"`
+	Nodes          int    `json:"nodes"` // 3 (default), 5 or 7
+}
+
+// AtlasDatabaseUser is an IAM role allowed to sign in, so no database password exists anywhere
+type AtlasDatabaseUser struct {
+	RoleARN string            `json:"role_arn"` // an IAM role ARN or a ${...} reference to one
+	Roles   map[string]string `json:"roles"`    // database -> role, e.g. {"app": "readWrite"}
+}
+
+// defaultAtlasVersion is the mongodbatlas provider constraint unless provider_versions sets one
+const defaultAtlasVersion = "~> 1.0"
+
+var (
+	atlasOrgIDPattern       = regexp.MustCompile(`^[0-9a-f]{24}$`)
+	atlasTierPattern        = regexp.MustCompile(`^M(\d+)$`)
+	atlasClusterNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]{0,63}$`)
+	atlasVersionPattern     = regexp.MustCompile(`^\d+\.\d+$`)
+	atlasRoles              = []string{"read", "readWrite", "dbAdmin", "readAnyDatabase", "readWriteAnyDatabase"}
+)
+
+func (a *AtlasCluster) validate() error {
+	if a.Name != "" && !atlasClusterNamePattern.MatchString(a.Name) {
+		return fmt.Errorf("name %q may only use letters, digits and -", a.Name)
+	}
+	match := atlasTierPattern.FindStringSubmatch(a.Tier)
+	if match == nil {
+		return fmt.Errorf("tier %q must be a tier like M10", a.Tier)
+	}
+	if size, _ := strconv.Atoi(match[1]); size < 10 {
+		return fmt.Errorf("tier %s is a shared tier; use M10 or larger", a.Tier)
+	}
+	if a.MongoDBVersion != "" && !atlasVersionPattern.MatchString(a.MongoDBVersion) {
+		return fmt.Errorf("mongodb_version %q must be a major.minor version like 7.0", a.MongoDBVersion)
+	}
+	if a.DiskSizeGB < 0 {
+		return fmt.Errorf("disk_size_gb can't be negative")
+	}
+	if a.Nodes != 0 && a.Nodes != 3 && a.Nodes != 5 && a.Nodes != 7 {
+		return fmt.Errorf("nodes must be 3, 5 or 7")
+	}
+	return nil
+}
+
+func (a *AtlasConfig) validate() error {
+	if !atlasOrgIDPattern.MatchString(a.OrgID) {
+		return fmt.Errorf("org_id %q must be 24 hex digits", a.OrgID)
+	}
+	if err := a.Cluster.validate(); err != nil {
+		return fmt.Errorf("cluster: %w", err)
+	}
+	for _, cidr := range a.AccessList {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("access_list: %q is not a CIDR block", cidr)
+		}
+	}
+	if a.NATIPsOutput != "" && !blockLabel.MatchString(a.NATIPsOutput) {
+		return fmt.Errorf("nat_ips_output %q is not an output name", a.NATIPsOutput)
+	}
+	for i, user := range a.DatabaseUsers {
+		if !iamRoleARNPattern.MatchString(user.RoleARN) && !expressionPattern.MatchString(user.RoleARN) {
+			return fmt.Errorf("database_users[%d]: role_arn %q must be an IAM role ARN or a ${...} reference", i, user.RoleARN)
+		}
+		if len(user.Roles) == 0 {
+			return fmt.Errorf("database_users[%d]: at least one role is required", i)
+		}
+		for database, role := range user.Roles {
+			if !slices.Contains(atlasRoles, role) {
+				return fmt.Errorf("database_users[%d]: role %q on %s must be one of %s", i, role, database,
+					strings.Join(atlasRoles, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// atlasRegion is the Atlas name of an AWS region, e.g. US_EAST_1 for us-east-1
+func atlasRegion(region string) string {
+	return strings.ToUpper(strings.ReplaceAll(region, "-", "_"))
+}
+
+// addAtlas creates the project, its access list, the cluster and the IAM database users. The NAT
+// IPs are read from the stack that has nat_ips_output, which is deployed first.
+func addAtlas(app cdktf.App, stack cdktf.TerraformStack, config Config) error {
+	atlas := config.Atlas
+	addRawProvider(stack, config, "mongodbatlas", "mongodb/mongodbatlas", defaultAtlasVersion, map[string]interface{}{})
+
+	projectName := atlas.Project
+	if projectName == "" {
+		projectName = stackPrefix(config)
+	}
+	project := newRawResource(stack, "mongodbatlas_project", "atlas_project", map[string]interface{}{
+		"name":   projectName,
+		"org_id": atlas.OrgID,
+	})
+	projectID := *project.GetStringAttribute(jsii.String("id"))
+
+	for i, cidr := range atlas.AccessList {
+		newRawResource(stack, "mongodbatlas_project_ip_access_list", fmt.Sprintf("atlas_access_%d", i), map[string]interface{}{
+			"project_id": projectID,
+			"cidr_block": cidr,
+			"comment":    "From config.json",
+		})
+	}
+	if atlas.NATIPsOutput != "" {
+		output, err := findOutput(app, atlas.NATIPsOutput)
+		if err != nil {
+			return fmt.Errorf("atlas: nat_ips_output: %w", err)
+		}
+		// One entry per IP, however many NAT gateways the VPC has
+		iterator := cdktf.TerraformIterator_FromList(output.Value())
+		access := newRawResource(stack, "mongodbatlas_project_ip_access_list", "atlas_access_nat", map[string]interface{}{
+			"project_id": projectID,
+			"ip_address": iterator.Value(),
+			"comment":    "NAT gateway of " + atlas.NATIPsOutput,
+		})
+		access.SetForEach(iterator)
+	}
+
+	cluster := atlas.Cluster
+	clusterName := cluster.Name
+	if clusterName == "" {
+		clusterName = "main"
+	}
+	nodes := cluster.Nodes
+	if nodes == 0 {
+		nodes = 3
+	}
+	defaults := *defaultTags(config)
+	tags := []map[string]interface{}{}
+	for _, key := range slices.Sorted(maps.Keys(defaults)) {
+		tags = append(tags, map[string]interface{}{"key": key, "value": *defaults[key]})
+	}
+	specs := map[string]interface{}{"instance_size": cluster.Tier, "node_count": nodes}
+	if cluster.DiskSizeGB > 0 {
+		specs["disk_size_gb"] = cluster.DiskSizeGB
+	}
+	attributes := map[string]interface{}{
+		"project_id":     projectID,
+		"name":           clusterName,
+		"cluster_type":   "REPLICASET",
+		"backup_enabled": true,
+		"replication_specs": []map[string]interface{}{{
+			"region_configs": []map[string]interface{}{{
+				"provider_name":   "AWS",
+				"region_name":     atlasRegion(config.Region),
+				"priority":        7,
+				"electable_specs": specs,
+			}},
+		}},
+		"tags": tags,
+	}
+	if cluster.MongoDBVersion != "" {
+		attributes["mongo_db_major_version"] = cluster.MongoDBVersion
+	}
+	advancedCluster := newRawResource(stack, "mongodbatlas_advanced_cluster", "atlas_cluster", attributes)
+
+	for i, user := range atlas.DatabaseUsers {
+		roles := []map[string]interface{}{}
+		for _, database := range slices.Sorted(maps.Keys(user.Roles)) {
+			roles = append(roles, map[string]interface{}{"database_name": database, "role_name": user.Roles[database]})
+		}
+		newRawResource(stack, "mongodbatlas_database_user", fmt.Sprintf("atlas_user_%d", i), map[string]interface{}{
+			"project_id":         projectID,
+			"username":           user.RoleARN,
+			"auth_database_name": "$external",
+			"aws_iam_type":       "ROLE",
+			"roles":              roles,
+			"scopes":             []map[string]interface{}{{"name": clusterName, "type": "CLUSTER"}},
+		})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("atlas_project_id"), &cdktf.TerraformOutputConfig{
+		Value:       projectID,
+		Description: jsii.String("The ID of the Atlas project"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("atlas_connection_string"), &cdktf.TerraformOutputConfig{
+		Value:       advancedCluster.GetStringAttribute(jsii.String("connection_strings[0].standard_srv")),
+		Description: jsii.String("The mongodb+srv connection string of the Atlas cluster"),
+	})
+
+	fmt.Printf("  ✓ MongoDB Atlas %s cluster %s (%d nodes) with %d IAM database user(s)\n", cluster.Tier, clusterName,
+		nodes, len(atlas.DatabaseUsers))
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AuditConfig records every synth, apply and destroy for change audits. Entries are appended to a
+// local JSON Lines file, and also written to a DynamoDB table and an S3 prefix when those are set,
+// with the aws CLI and the credentials it finds.
+type AuditConfig struct {
+	File string `json:"file"` // defaults to audit.jsonl, relative to the working directory
+	// DynamoDBTable gets one item per entry; its partition key must be the string attribute id
+	DynamoDBTable string `json:"dynamodb_table"`
+	// S3Prefix gets one object per entry, under <prefix><date>/<id>.json, e.g. s3://audit-bucket/deploys/
+	S3Prefix string `json:"s3_prefix"`
+}
+
+var dynamoDBTableName = regexp.MustCompile(`^[a-zA-Z0-9_.-]{3,255}$`)
+
+func (a *AuditConfig) validate() error {
+	if a.DynamoDBTable != "" && !dynamoDBTableName.MatchString(a.DynamoDBTable) {
+		return fmt.Errorf("dynamodb_table %q must be 3-255 letters, digits, _, . or -", a.DynamoDBTable)
+	}
+	if a.S3Prefix != "" && (!strings.HasPrefix(a.S3Prefix, "s3://") || len(a.S3Prefix) <= len("s3://")) {
+		return fmt.Errorf("s3_prefix %q must be an s3://bucket/prefix URL", a.S3Prefix)
+	}
+	return nil
+}
+
+func (a *AuditConfig) file() string {
+	if a.File != "" {
+		return a.File
+	}
+	return "audit.jsonl"
+}
+
+// planSummary is the resource counts terraform reports at the end of an apply or destroy
+type planSummary struct {
+	Added     int `json:"added"`
+	Changed   int `json:"changed"`
+	Destroyed int `json:"destroyed"`
+}
+
+// auditEntry is one line of the audit log
+type auditEntry struct {
+	ID          string       `json:"id"`
+	Time        string       `json:"time"`
+	User        string       `json:"user"`
+	Action      string       `json:"action"` // synth, apply or destroy
+	Project     string       `json:"project"`
+	Environment string       `json:"environment"`
+	ConfigHash  string       `json:"config_hash"`
+	GitCommit   string       `json:"git_commit"`
+	Stack       string       `json:"stack,omitempty"`  // the stack applied or destroyed
+	Stacks      []string     `json:"stacks,omitempty"` // the stacks synthesized
+	Status      string       `json:"status"`           // succeeded or failed
+	Error       string       `json:"error,omitempty"`
+	Summary     *planSummary `json:"summary,omitempty"`
+	DurationMS  int64        `json:"duration_ms"`
+}
+
+// auditUser names who ran the command: the CI actor when there is one, otherwise the local user
+func auditUser() string {
+	for _, variable := range []string{"GITHUB_ACTOR", "GITLAB_USER_LOGIN", "BUILD_REQUESTEDFOR"} {
+		if actor := os.Getenv(variable); actor != "" {
+			return actor
+		}
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return "unknown"
+}
+
+// newAuditEntry starts an entry for an action on the config, timed from started
+func newAuditEntry(config Config, action string, started time.Time, err error) auditEntry {
+	entry := auditEntry{
+		Time:        started.UTC().Format(time.RFC3339),
+		User:        auditUser(),
+		Action:      action,
+		Project:     config.Project,
+		Environment: config.Environment,
+		ConfigHash:  config.configHash,
+		GitCommit:   gitOutput("rev-parse", "HEAD"),
+		Status:      "succeeded",
+		DurationMS:  time.Since(started).Milliseconds(),
+	}
+	entry.ID = fmt.Sprintf("%s-%s-%d", config.Project, config.Environment, started.UnixNano())
+	if err != nil {
+		entry.Status, entry.Error = "failed", err.Error()
+	}
+	return entry
+}
+
+// terraformSummary matches the last line of a terraform apply or destroy
+var terraformSummary = regexp.MustCompile(`(?:Apply|Destroy) complete! Resources: (?:(\d+) added, (\d+) changed, )?(\d+) destroyed`)
+
+// parsePlanSummary reads the resource counts from terraform's output, or returns nil when it
+// didn't get to the end
+func parsePlanSummary(output []byte) *planSummary {
+	match := terraformSummary.FindSubmatch(output)
+	if match == nil {
+		return nil
+	}
+	summary := &planSummary{}
+	summary.Added, _ = strconv.Atoi(string(match[1]))
+	summary.Changed, _ = strconv.Atoi(string(match[2]))
+	summary.Destroyed, _ = strconv.Atoi(string(match[3]))
+	return summary
+}
+
+// recordAudit appends an entry to the audit log and writes it to the configured table and prefix.
+// A failure to write the record is reported but doesn't fail the command, whose changes have
+// already been made.
+func recordAudit(config Config, entry auditEntry) {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Printf("⚠️  Audit: %v\n", err)
+		return
+	}
+	path := config.Audit.file()
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err == nil {
+		_, err = file.Write(append(raw, '\n'))
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Printf("⚠️  Audit: writing %s: %v\n", path, err)
+	}
+
+	if config.Audit.DynamoDBTable != "" {
+		if err := putAuditItem(config, entry); err != nil {
+			fmt.Printf("⚠️  Audit: writing to DynamoDB table %s: %v\n", config.Audit.DynamoDBTable, err)
+		}
+	}
+	if config.Audit.S3Prefix != "" {
+		key := strings.TrimSuffix(config.Audit.S3Prefix, "/") + "/" + entry.Time[:len("2006-01-02")] + "/" + entry.ID + ".json"
+		if err := runAWS(config, raw, "s3", "cp", "-", key, "--content-type", "application/json"); err != nil {
+			fmt.Printf("⚠️  Audit: writing to %s: %v\n", key, err)
+		}
+	}
+}
+
+// putAuditItem writes an entry to DynamoDB, with every field as a string attribute apart from the
+// counts and the duration
+func putAuditItem(config Config, entry auditEntry) error {
+	item := map[string]map[string]string{}
+	var fields map[string]interface{}
+	raw, _ := json.Marshal(entry)
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	for name, value := range fields {
+		switch value := value.(type) {
+		case string:
+			item[name] = map[string]string{"S": value}
+		case float64:
+			item[name] = map[string]string{"N": strconv.FormatFloat(value, 'f', -1, 64)}
+		default:
+			// stacks and summary are stored as their JSON
+			encoded, _ := json.Marshal(value)
+			item[name] = map[string]string{"S": string(encoded)}
+		}
+	}
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return runAWS(config, nil, "dynamodb", "put-item", "--table-name", config.Audit.DynamoDBTable, "--item", string(encoded))
+}
+
+// runAWS runs the aws CLI in the config's region, with input on stdin
+func runAWS(config Config, input []byte, args ...string) error {
+	if _, err := exec.LookPath("aws"); err != nil {
+		return fmt.Errorf("aws CLI not found on PATH: %w", err)
+	}
+	command := exec.Command("aws", append(args, "--region", config.Region)...)
+	command.Stdin = bytes.NewReader(input)
+	if output, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w\n%s", err, output)
+	}
+	return nil
+}
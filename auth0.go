@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/secretsmanagersecret"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/secretsmanagersecretversion"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// Auth0Config manages the applications, APIs and rules of an Auth0 tenant. Each application's
+// credentials are stored in Secrets Manager for the stacks that run the app. The provider reads
+// its own credentials from AUTH0_CLIENT_ID and AUTH0_CLIENT_SECRET.
+type Auth0Config struct {
+	Domain       string             `json:"domain"` // the tenant domain, e.g. my-app.eu.auth0.com
+	Applications []Auth0Application `json:"applications"`
+	APIs         []Auth0API         `json:"apis"`
+	Rules        []Auth0Rule        `json:"rules"`
+}
+
+// Auth0Application is a client of the tenant
+type Auth0Application struct {
+	Name              string   `json:"name"`
+	Type              string   `json:"type"` // spa, regular_web, native or non_interactive (machine to machine)
+	Callbacks         []string `json:"callbacks"`
+	AllowedLogoutURLs []string `json:"allowed_logout_urls"`
+	WebOrigins        []string `json:"web_origins"`
+	// APIs grants the application scopes of APIs, by API identifier, for the client credentials flow
+	APIs map[string][]string `json:"apis"`
+}
+
+// Auth0API is a resource server the applications get access tokens for
+type Auth0API struct {
+	Name          string            `json:"name"`
+	Identifier    string            `json:"identifier"` // the token audience, e.g. https://api.my-app.example.com
+	Scopes        map[string]string `json:"scopes"`     // scope -> description
+	TokenLifetime int               `json:"token_lifetime"`
+}
+
+// Auth0Rule runs a JavaScript file on every login. The script is read from a file relative to
+// the config file.
+type Auth0Rule struct {
+	Name       string `json:"name"`
+	ScriptFile string `json:"script_file"`
+	Order      int    `json:"order"`
+	Disabled   bool   `json:"disabled"`
+
+	// script is the content of ScriptFile, read by loadConfig
+	script string
+}
+
+// defaultAuth0Version is the auth0 provider constraint unless provider_versions sets one
+const defaultAuth0Version = "~> 1.0"
+
+var (
+	auth0ApplicationTypes = []string{"spa", "regular_web", "native", "non_interactive"}
+	auth0DomainPattern    = regexp.MustCompile(`^[a-z0-9-]+(\.[a-z0-9-]+)+$`)
+	auth0RuleNamePattern  = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9 -]*[A-Za-z0-9])?$`)
+)
+
+// confidential reports whether an application can keep a client secret, which spa and native
+// applications run on the user's device can't
+func (a *Auth0Application) confidential() bool {
+	return a.Type == "regular_web" || a.Type == "non_interactive"
+}
+
+func (a *Auth0Application) validate(apis map[string]Auth0API) error {
+	if a.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !slices.Contains(auth0ApplicationTypes, a.Type) {
+		return fmt.Errorf("%s: type %q must be one of %s", a.Name, a.Type, strings.Join(auth0ApplicationTypes, ", "))
+	}
+	if len(a.APIs) > 0 && a.Type != "non_interactive" {
+		return fmt.Errorf("%s: only non_interactive applications are granted apis", a.Name)
+	}
+	for _, identifier := range slices.Sorted(maps.Keys(a.APIs)) {
+		api, ok := apis[identifier]
+		if !ok {
+			continue // an API the section doesn't manage
+		}
+		for _, scope := range a.APIs[identifier] {
+			if _, ok := api.Scopes[scope]; !ok {
+				return fmt.Errorf("%s: scope %s isn't a scope of %s", a.Name, scope, identifier)
+			}
+		}
+	}
+	return nil
+}
+
+func (a *Auth0API) validate() error {
+	if a.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if a.Identifier == "" {
+		return fmt.Errorf("%s: identifier is required", a.Name)
+	}
+	if a.TokenLifetime < 0 || a.TokenLifetime > 2592000 {
+		return fmt.Errorf("%s: token_lifetime must be at most 2592000 seconds (30 days)", a.Name)
+	}
+	return nil
+}
+
+func (a *Auth0Config) validate() error {
+	if !auth0DomainPattern.MatchString(a.Domain) {
+		return fmt.Errorf("domain %q must be a tenant domain like my-app.eu.auth0.com", a.Domain)
+	}
+	if len(a.Applications) == 0 && len(a.APIs) == 0 && len(a.Rules) == 0 {
+		return fmt.Errorf("at least one application, api or rule is required")
+	}
+
+	apis := map[string]Auth0API{}
+	ids := map[string]string{}
+	for i, api := range a.APIs {
+		if err := api.validate(); err != nil {
+			return fmt.Errorf("apis[%d]: %w", i, err)
+		}
+		if _, ok := apis[api.Identifier]; ok {
+			return fmt.Errorf("apis[%d]: duplicate identifier %s", i, api.Identifier)
+		}
+		apis[api.Identifier] = api
+		if other, ok := ids["api_"+auth0ID(api.Name)]; ok {
+			return fmt.Errorf("apis[%d]: %s has the same logical id as %s", i, api.Name, other)
+		}
+		ids["api_"+auth0ID(api.Name)] = api.Name
+	}
+	for i, application := range a.Applications {
+		if err := application.validate(apis); err != nil {
+			return fmt.Errorf("applications[%d]: %w", i, err)
+		}
+		if other, ok := ids["client_"+auth0ID(application.Name)]; ok {
+			return fmt.Errorf("applications[%d]: %s has the same logical id as %s", i, application.Name, other)
+		}
+		ids["client_"+auth0ID(application.Name)] = application.Name
+	}
+	for i, rule := range a.Rules {
+		if !auth0RuleNamePattern.MatchString(rule.Name) {
+			return fmt.Errorf("rules[%d]: name %q may only use letters, digits, spaces and -", i, rule.Name)
+		}
+		if rule.Order < 0 {
+			return fmt.Errorf("rules[%d]: %s: order can't be negative", i, rule.Name)
+		}
+		if rule.ScriptFile == "" {
+			return fmt.Errorf("rules[%d]: %s: script_file is required", i, rule.Name)
+		}
+		if other, ok := ids["rule_"+auth0ID(rule.Name)]; ok {
+			return fmt.Errorf("rules[%d]: %s has the same logical id as %s", i, rule.Name, other)
+		}
+		ids["rule_"+auth0ID(rule.Name)] = rule.Name
+	}
+	return nil
+}
+
+// loadAuth0Rules reads the scripts of the rules, relative to the config file in dir
+func loadAuth0Rules(auth0 *Auth0Config, dir string) error {
+	for i := range auth0.Rules {
+		rule := &auth0.Rules[i]
+		raw, err := os.ReadFile(filepath.Join(dir, rule.ScriptFile))
+		if err != nil {
+			return fmt.Errorf("auth0: %s: reading script_file: %w", rule.Name, err)
+		}
+		rule.script = escapeInterpolation(string(raw))
+	}
+	return nil
+}
+
+func auth0ID(name string) string {
+	return strings.Trim(logicalIDInvalid.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// addAuth0 creates the APIs, applications and rules. Every application gets a Secrets Manager
+// secret holding the domain and its client ID, plus the client secret for confidential types,
+// which the app stacks read by the ARN output as auth0_<application>_secret_arn.
+func addAuth0(stack cdktf.TerraformStack, config Config) {
+	auth0 := config.Auth0
+	addRawProvider(stack, config, "auth0", "auth0/auth0", defaultAuth0Version, map[string]interface{}{
+		"domain": auth0.Domain,
+	})
+
+	audiences := map[string]string{}
+	for _, api := range auth0.APIs {
+		tokenLifetime := api.TokenLifetime
+		if tokenLifetime == 0 {
+			tokenLifetime = 86400
+		}
+		server := newRawResource(stack, "auth0_resource_server", "api_"+auth0ID(api.Name), map[string]interface{}{
+			"name":           api.Name,
+			"identifier":     api.Identifier,
+			"signing_alg":    "RS256",
+			"token_lifetime": tokenLifetime,
+		})
+		audiences[api.Identifier] = *server.GetStringAttribute(jsii.String("identifier"))
+
+		if len(api.Scopes) > 0 {
+			scopes := make([]map[string]interface{}, 0, len(api.Scopes))
+			for _, scope := range slices.Sorted(maps.Keys(api.Scopes)) {
+				scopes = append(scopes, map[string]interface{}{"name": scope, "description": api.Scopes[scope]})
+			}
+			newRawResource(stack, "auth0_resource_server_scopes", "api_"+auth0ID(api.Name)+"_scopes", map[string]interface{}{
+				"resource_server_identifier": audiences[api.Identifier],
+				"scopes":                     scopes,
+			})
+		}
+	}
+
+	for _, application := range auth0.Applications {
+		id := auth0ID(application.Name)
+		attributes := map[string]interface{}{
+			"name":            application.Name,
+			"app_type":        application.Type,
+			"oidc_conformant": true,
+		}
+		if application.Type == "non_interactive" {
+			attributes["grant_types"] = []string{"client_credentials"}
+		}
+		for key, urls := range map[string][]string{
+			"callbacks":           application.Callbacks,
+			"allowed_logout_urls": application.AllowedLogoutURLs,
+			"web_origins":         application.WebOrigins,
+		} {
+			if len(urls) > 0 {
+				attributes[key] = urls
+			}
+		}
+		client := newRawResource(stack, "auth0_client", "client_"+id, attributes)
+		clientID := *client.GetStringAttribute(jsii.String("client_id"))
+
+		method := "none"
+		if application.confidential() {
+			method = "client_secret_post"
+		}
+		credentials := newRawResource(stack, "auth0_client_credentials", "client_"+id+"_credentials", map[string]interface{}{
+			"client_id":             clientID,
+			"authentication_method": method,
+		})
+
+		for _, identifier := range slices.Sorted(maps.Keys(application.APIs)) {
+			audience, ok := audiences[identifier]
+			if !ok {
+				audience = identifier
+			}
+			newRawResource(stack, "auth0_client_grant", "client_"+id+"_grant_"+auth0ID(identifier), map[string]interface{}{
+				"client_id": clientID,
+				"audience":  audience,
+				"scopes":    application.APIs[identifier],
+			})
+		}
+
+		values := map[string]interface{}{"domain": auth0.Domain, "client_id": clientID}
+		if application.confidential() {
+			values["client_secret"] = *credentials.GetStringAttribute(jsii.String("client_secret"))
+		}
+		secret := secretsmanagersecret.NewSecretsmanagerSecret(stack, jsii.String("client_"+id+"_secret"),
+			&secretsmanagersecret.SecretsmanagerSecretConfig{
+				Name:        jsii.String(resourceName(config, "aws_secretsmanager_secret", "auth0-"+strings.ReplaceAll(id, "_", "-"))),
+				Description: jsii.String(fmt.Sprintf("Auth0 credentials of the %s application", application.Name)),
+			})
+		secretsmanagersecretversion.NewSecretsmanagerSecretVersion(stack, jsii.String("client_"+id+"_secret_version"),
+			&secretsmanagersecretversion.SecretsmanagerSecretVersionConfig{
+				SecretId:     secret.Id(),
+				SecretString: cdktf.Fn_Jsonencode(values),
+			})
+
+		cdktf.NewTerraformOutput(stack, jsii.String("auth0_"+id+"_client_id"), &cdktf.TerraformOutputConfig{
+			Value:       clientID,
+			Description: jsii.String(fmt.Sprintf("The client ID of the %s application", application.Name)),
+		})
+		cdktf.NewTerraformOutput(stack, jsii.String("auth0_"+id+"_secret_arn"), &cdktf.TerraformOutputConfig{
+			Value:       secret.Arn(),
+			Description: jsii.String(fmt.Sprintf("The ARN of the secret with the %s application's credentials", application.Name)),
+		})
+	}
+
+	for i, rule := range auth0.Rules {
+		order := rule.Order
+		if order == 0 {
+			order = i + 1
+		}
+		newRawResource(stack, "auth0_rule", "rule_"+auth0ID(rule.Name), map[string]interface{}{
+			"name":    rule.Name,
+			"script":  rule.script,
+			"order":   order,
+			"enabled": !rule.Disabled,
+		})
+	}
+
+	fmt.Printf("  ✓ Auth0 tenant %s: %d application(s), %d API(s), %d rule(s)\n", auth0.Domain,
+		len(auth0.Applications), len(auth0.APIs), len(auth0.Rules))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"unicode"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/provider"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AWSProviderSettings tunes how every AWS provider of the config calls the AWS APIs, for accounts
+// that throttle and for runners behind a corporate proxy
+type AWSProviderSettings struct {
+	// MaxRetries is how often a throttled or failed call is retried; the provider's default is 25
+	MaxRetries *int `json:"max_retries,omitempty"`
+	// RetryMode is standard or adaptive, which also rate-limits calls once throttled
+	RetryMode  string `json:"retry_mode,omitempty"`
+	HTTPProxy  string `json:"http_proxy,omitempty"`
+	HTTPSProxy string `json:"https_proxy,omitempty"`
+	NoProxy    string `json:"no_proxy,omitempty"` // comma-separated hosts reached without the proxies
+	// CustomCABundle is a PEM file of the certificates a TLS-inspecting proxy signs with
+	CustomCABundle string `json:"custom_ca_bundle,omitempty"`
+	// UserAgent is appended to the User-Agent of every call, after json-to-terraform/<version>,
+	// e.g. "acme-platform/2.1 (team-data)"
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+func validateProxy(field, value string) error {
+	if value == "" {
+		return nil
+	}
+	if parsed, err := url.Parse(value); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return invalidValue(value, "use a URL such as http://proxy.example.com:3128", "%s %q must be an http or https URL", field, value)
+	}
+	return nil
+}
+
+func (a *AWSProviderSettings) validate() error {
+	if a.MaxRetries != nil && (*a.MaxRetries < 0 || *a.MaxRetries > 100) {
+		return invalidValue(*a.MaxRetries, "", "max_retries must be 0-100")
+	}
+	if a.RetryMode != "" && a.RetryMode != "standard" && a.RetryMode != "adaptive" {
+		return invalidValue(a.RetryMode, closestMatch(a.RetryMode, []string{"standard", "adaptive"}),
+			"retry_mode %q must be standard or adaptive", a.RetryMode)
+	}
+	if err := validateProxy("http_proxy", a.HTTPProxy); err != nil {
+		return err
+	}
+	if err := validateProxy("https_proxy", a.HTTPSProxy); err != nil {
+		return err
+	}
+	// Terraform runs in cdktf.out/stacks/<stack>, so a relative path wouldn't mean what it says
+	if a.CustomCABundle != "" && !filepath.IsAbs(a.CustomCABundle) {
+		return invalidValue(a.CustomCABundle, "", "custom_ca_bundle %q must be an absolute path", a.CustomCABundle)
+	}
+	if len(a.UserAgent) > 256 || strings.ContainsFunc(a.UserAgent, unicode.IsControl) {
+		return invalidValue(a.UserAgent, "", "user_agent must be at most 256 characters, on one line")
+	}
+	return nil
+}
+
+// toolVersion is the version of this program in user agents: its module version when built from
+// a tag, otherwise its git commit. It is a variable so snapshots can pin it.
+var toolVersion = func() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	if commit := gitOutput("rev-parse", "--short", "HEAD"); commit != "unknown" {
+		return commit
+	}
+	return "dev"
+}
+
+// applyAWSProviderSettings sets the config's retry and proxy settings on a provider config
+func applyAWSProviderSettings(providerConfig *provider.AwsProviderConfig, config Config) {
+	settings := config.AWSProvider
+	if settings == nil {
+		return
+	}
+	if settings.MaxRetries != nil {
+		providerConfig.MaxRetries = jsii.Number(*settings.MaxRetries)
+	}
+	if settings.RetryMode != "" {
+		providerConfig.RetryMode = jsii.String(settings.RetryMode)
+	}
+	if settings.HTTPProxy != "" {
+		providerConfig.HttpProxy = jsii.String(settings.HTTPProxy)
+	}
+	if settings.HTTPSProxy != "" {
+		providerConfig.HttpsProxy = jsii.String(settings.HTTPSProxy)
+	}
+	if settings.NoProxy != "" {
+		providerConfig.NoProxy = jsii.String(settings.NoProxy)
+	}
+	if settings.CustomCABundle != "" {
+		providerConfig.CustomCaBundle = jsii.String(settings.CustomCABundle)
+	}
+}
+
+// newAWSProvider creates an AWS provider with the config's user agent and, for resource groups,
+// the stack's name as a default tag. The bindings predate the provider's user_agent argument, so
+// it is set as an override.
+func newAWSProvider(scope constructs.Construct, id string, providerConfig *provider.AwsProviderConfig, config Config) provider.AwsProvider {
+	if tags, ok := providerConfig.DefaultTags.([]provider.AwsProviderDefaultTags); ok && len(tags) > 0 && config.ResourceGroups {
+		(*tags[0].Tags)[stackTag] = cdktf.TerraformStack_Of(scope).Node().Id()
+	}
+	awsProvider := provider.NewAwsProvider(scope, jsii.String(id), providerConfig)
+	if config.AWSProvider != nil && config.AWSProvider.UserAgent != "" {
+		awsProvider.AddOverride(jsii.String("user_agent"), []string{
+			"json-to-terraform/" + toolVersion(),
+			config.AWSProvider.UserAgent,
+		})
+	}
+	return awsProvider
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// AzureConfig holds the azurerm provider settings used with "cloud": "azure". The config region
+// is the Azure location, e.g. westeurope.
+type AzureConfig struct {
+	SubscriptionID string `json:"subscription_id"` // defaults to ARM_SUBSCRIPTION_ID
+	TenantID       string `json:"tenant_id"`       // defaults to ARM_TENANT_ID
+	// ResourceGroup names an existing resource group to use instead of creating one
+	ResourceGroup string `json:"resource_group"`
+	Replication   string `json:"replication"` // LRS (default), ZRS, GRS, RAGRS, GZRS or RAGZRS
+}
+
+// defaultAzurermVersion is the azurerm provider constraint unless provider_versions sets one
+const defaultAzurermVersion = "~> 4.0"
+
+var (
+	azureReplications = []string{"LRS", "ZRS", "GRS", "RAGRS", "GZRS", "RAGZRS"}
+	azureIDPattern    = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	// storageAccountInvalid matches what a storage account name can't contain: anything but
+	// lowercase letters and digits
+	storageAccountInvalid = regexp.MustCompile(`[^a-z0-9]`)
+)
+
+func (a *AzureConfig) validate() error {
+	if a.SubscriptionID != "" && !azureIDPattern.MatchString(a.SubscriptionID) {
+		return fmt.Errorf("subscription_id %q must be a GUID", a.SubscriptionID)
+	}
+	if a.TenantID != "" && !azureIDPattern.MatchString(a.TenantID) {
+		return fmt.Errorf("tenant_id %q must be a GUID", a.TenantID)
+	}
+	if a.Replication != "" && !slices.Contains(azureReplications, a.Replication) {
+		return fmt.Errorf("replication %q must be one of %s", a.Replication, strings.Join(azureReplications, ", "))
+	}
+	return nil
+}
+
+func usesAzure(config Config) bool {
+	return config.Cloud == "azure"
+}
+
+// validateCloud rejects the settings that only exist on AWS when the config targets Azure
+func validateCloud(config Config) error {
+	if config.Cloud != "" && config.Cloud != "aws" && config.Cloud != "azure" {
+		return fmt.Errorf("cloud %q must be aws or azure", config.Cloud)
+	}
+	if !usesAzure(config) {
+		if config.Azure != nil || config.ProviderVersions["azurerm"] != "" {
+			return fmt.Errorf("azure and provider_versions.azurerm are only used with cloud azure")
+		}
+		return nil
+	}
+	if config.Azure != nil {
+		if err := config.Azure.validate(); err != nil {
+			return fmt.Errorf("azure: %w", err)
+		}
+	}
+
+	awsOnly := map[string]bool{
+		"batch":                 config.Batch != nil,
+		"glue":                  config.Glue != nil,
+		"athena":                config.Athena != nil,
+		"atlas":                 config.Atlas != nil,
+		"auth0":                 config.Auth0 != nil,
+		"warehouse":             config.Warehouse != nil,
+		"opensearch":            config.OpenSearch != nil,
+		"kafka":                 config.Kafka != nil,
+		"neptune":               config.Neptune != nil,
+		"documentdb":            config.DocumentDB != nil,
+		"timestream":            config.Timestream != nil,
+		"keyspaces":             len(config.Keyspaces) > 0,
+		"memorydb":              config.MemoryDB != nil,
+		"iot":                   config.IoT != nil,
+		"sagemaker":             config.SageMaker != nil,
+		"bedrock":               config.Bedrock != nil,
+		"pipeline":              config.Pipeline != nil,
+		"codedeploy":            config.CodeDeploy != nil,
+		"ssm_management":        config.SSMManagement != nil,
+		"sftp":                  config.SFTP != nil,
+		"apprunner":             config.AppRunner != nil,
+		"amplify":               config.Amplify != nil,
+		"global_accelerator":    config.GlobalAccelerator != nil,
+		"waf":                   config.WAF != nil,
+		"security_baseline":     config.SecurityBaseline != nil,
+		"compliance":            config.Compliance != nil,
+		"cloudtrail":            config.CloudTrail != nil,
+		"budgets":               config.Budgets != nil,
+		"backup":                config.Backup != nil,
+		"accounts":              config.Accounts != nil,
+		"appconfig":             config.AppConfig != nil,
+		"amp":                   config.AMP != nil,
+		"amg":                   config.AMG != nil,
+		"dms":                   config.DMS != nil,
+		"helm":                  config.Helm != nil,
+		"kubernetes":            config.Kubernetes != nil,
+		"pagerduty":             config.PagerDuty != nil,
+		"monitoring.grafana":    config.Monitoring != nil && config.Monitoring.Grafana != nil,
+		"vault.aws":             config.Vault != nil && config.Vault.AWS != nil,
+		"backend type s3":       config.Backend != nil && config.Backend.kind() == "s3",
+		"remote_state s3":       slices.ContainsFunc(config.RemoteState, func(r RemoteStateConfig) bool { return r.S3 != nil }),
+		"build_tags":            config.BuildTags != nil,
+		"aws_provider":          config.AWSProvider != nil,
+		"audit.dynamodb_table":  config.Audit != nil && config.Audit.DynamoDBTable != "",
+		"service_catalog":       config.ServiceCatalog != nil,
+		"resource_groups":       config.ResourceGroups,
+		"tracing":               config.Tracing,
+		"cloudcontrol":          len(config.CloudControl) > 0,
+		"audit.s3_prefix":       config.Audit != nil && config.Audit.S3Prefix != "",
+		"compliance_profile":    config.ComplianceProfile != "",
+		"outputs.ssm_prefix":    config.Outputs != nil && config.Outputs.SSMPrefix != "",
+		"provider_versions.aws": config.ProviderVersions["aws"] != "",
+	}
+	for _, setting := range slices.Sorted(maps.Keys(awsOnly)) {
+		if awsOnly[setting] {
+			return fmt.Errorf("%s is only supported with cloud aws", setting)
+		}
+	}
+	for name, environment := range config.Environments {
+		if environment.AccountID != "" || environment.DeployRoleARN != "" || environment.LocalStack ||
+			len(environment.Endpoints) > 0 || environment.Profile != "" || environment.WebIdentity != nil ||
+			len(environment.SharedCredentialsFiles) > 0 || len(environment.SharedConfigFiles) > 0 {
+			return fmt.Errorf("environments.%s: only size is supported with cloud azure", name)
+		}
+	}
+	return nil
+}
+
+// azureTags are the tags every Azure resource gets, the same as the AWS provider's default tags
+func azureTags(config Config) map[string]string {
+	tags := map[string]string{}
+	for key, value := range *defaultTags(config) {
+		tags[key] = *value
+	}
+	return tags
+}
+
+// addAzureProvider declares the azurerm provider of a stack
+func addAzureProvider(stack cdktf.TerraformStack, config Config) {
+	settings := map[string]interface{}{"features": map[string]interface{}{}}
+	if config.Azure != nil && config.Azure.SubscriptionID != "" {
+		settings["subscription_id"] = config.Azure.SubscriptionID
+	}
+	if config.Azure != nil && config.Azure.TenantID != "" {
+		settings["tenant_id"] = config.Azure.TenantID
+	}
+	addRawProvider(stack, config, "azurerm", "hashicorp/azurerm", defaultAzurermVersion, settings)
+}
+
+// addAzureStorage builds the storage section on Azure: a resource group, a storage account named
+// like the S3 bucket would be but without hyphens, and a private blob container for bucket_name.
+// enable_versioning turns on blob versioning.
+func addAzureStorage(stack cdktf.TerraformStack, config Config) {
+	tags := azureTags(config)
+
+	var resourceGroup string
+	if config.Azure != nil && config.Azure.ResourceGroup != "" {
+		group := newRawDataSource(stack, "azurerm_resource_group", "resource_group", map[string]interface{}{
+			"name": config.Azure.ResourceGroup,
+		})
+		resourceGroup = *group.GetStringAttribute(jsii.String("name"))
+	} else {
+		group := newRawResource(stack, "azurerm_resource_group", "resource_group", map[string]interface{}{
+			"name":     resourceName(config, "azurerm_resource_group", "rg"),
+			"location": config.Region,
+			"tags":     tags,
+		})
+		resourceGroup = *group.GetStringAttribute(jsii.String("name"))
+	}
+
+	replication := "LRS"
+	if config.Azure != nil && config.Azure.Replication != "" {
+		replication = config.Azure.Replication
+	}
+	accountName := storageAccountInvalid.ReplaceAllString(
+		resourceName(config, "azurerm_storage_account", config.Storage.BucketName), "")
+	account := newRawResource(stack, "azurerm_storage_account", "storage_account", map[string]interface{}{
+		"name":                            accountName,
+		"resource_group_name":             resourceGroup,
+		"location":                        config.Region,
+		"account_tier":                    "Standard",
+		"account_replication_type":        replication,
+		"min_tls_version":                 "TLS1_2",
+		"allow_nested_items_to_be_public": false,
+		"blob_properties": map[string]interface{}{
+			"versioning_enabled": config.Storage.EnableVersioning,
+		},
+		"tags": tags,
+	})
+
+	container := newRawResource(stack, "azurerm_storage_container", "container", map[string]interface{}{
+		"name":                  config.Storage.BucketName,
+		"storage_account_id":    *account.GetStringAttribute(jsii.String("id")),
+		"container_access_type": "private",
+	})
+
+	cdktf.NewTerraformOutput(stack, jsii.String("storage_account_name"), &cdktf.TerraformOutputConfig{
+		Value:       account.GetStringAttribute(jsii.String("name")),
+		Description: jsii.String("The name of the created storage account"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("storage_account_id"), &cdktf.TerraformOutputConfig{
+		Value:       account.GetStringAttribute(jsii.String("id")),
+		Description: jsii.String("The ID of the created storage account"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("container_name"), &cdktf.TerraformOutputConfig{
+		Value:       container.GetStringAttribute(jsii.String("name")),
+		Description: jsii.String("The name of the blob container"),
+	})
+
+	if config.Storage.EnableVersioning {
+		fmt.Printf("  ✓ Azure storage account %s with versioning enabled\n", accountName)
+	} else {
+		fmt.Printf("  ✓ Azure storage account %s (no versioning)\n", accountName)
+	}
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dynamodbtable"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucketversioning"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// BackendConfig describes where Terraform keeps state for the generated stacks. The S3
+// settings sit at the top level; the other backend types have their own blocks.
+type BackendConfig struct {
+	Type          string `json:"type"` // s3 (default), gcs, azurerm or http
+	Bucket        string `json:"bucket"`
+	Region        string `json:"region"` // defaults to the config region
+	DynamoDBTable string `json:"dynamodb_table"`
+	KeyPrefix     string `json:"key_prefix"`
+	KMSKeyID      string `json:"kms_key_id"`
+	Bootstrap     bool   `json:"bootstrap"` // also synthesize a stack that creates the bucket and table
+
+	GCS     *GCSBackendConfig     `json:"gcs,omitempty"`
+	AzureRM *AzureRMBackendConfig `json:"azurerm,omitempty"`
+	HTTP    *HTTPBackendConfig    `json:"http,omitempty"`
+}
+
+type GCSBackendConfig struct {
+	Bucket                    string `json:"bucket"`
+	KMSEncryptionKey          string `json:"kms_encryption_key"`
+	ImpersonateServiceAccount string `json:"impersonate_service_account"`
+}
+
+type AzureRMBackendConfig struct {
+	ResourceGroupName  string `json:"resource_group_name"`
+	StorageAccountName string `json:"storage_account_name"`
+	ContainerName      string `json:"container_name"`
+	SubscriptionID     string `json:"subscription_id"`
+	UseAzureADAuth     bool   `json:"use_azuread_auth"`
+}
+
+// HTTPBackendConfig addresses may contain {stack}, replaced by the stack name, so each stack
+// gets its own state. The password comes from TF_HTTP_PASSWORD at init time.
+type HTTPBackendConfig struct {
+	Address       string `json:"address"`
+	LockAddress   string `json:"lock_address"`
+	UnlockAddress string `json:"unlock_address"`
+	LockMethod    string `json:"lock_method"`
+	UnlockMethod  string `json:"unlock_method"`
+	Username      string `json:"username"`
+}
+
+// backendType pairs the validation and construction of one kind of backend
+type backendType struct {
+	validate func(b *BackendConfig) error
+	add      func(stack cdktf.TerraformStack, config Config, stackName string) string
+}
+
+// backendTypes lists the supported backends by their "type" value
+var backendTypes = map[string]backendType{
+	"s3":      {validate: validateS3Backend, add: addS3Backend},
+	"gcs":     {validate: validateGCSBackend, add: addGCSBackend},
+	"azurerm": {validate: validateAzureRMBackend, add: addAzureRMBackend},
+	"http":    {validate: validateHTTPBackend, add: addHTTPBackend},
+}
+
+func (b *BackendConfig) kind() string {
+	if b.Type == "" {
+		return "s3"
+	}
+	return b.Type
+}
+
+func (b *BackendConfig) validate() error {
+	backend, ok := backendTypes[b.kind()]
+	if !ok {
+		return invalidValue(b.Type, closestMatch(b.Type, []string{"s3", "gcs", "azurerm", "http"}),
+			"unknown type %q (want s3, gcs, azurerm or http)", b.Type)
+	}
+	if b.Bootstrap && b.kind() != "s3" {
+		return fmt.Errorf("bootstrap is only supported for the s3 backend")
+	}
+	return backend.validate(b)
+}
+
+func (b *BackendConfig) region(config Config) string {
+	if b.Region != "" {
+		return b.Region
+	}
+	return config.Region
+}
+
+// stateKey derives the state location for a stack as <prefix>/<project>/<environment>/<stack>.
+// With workspaces the environment is left out; the backend adds the workspace itself.
+func (b *BackendConfig) stateKey(config Config, stackName string) string {
+	parts := []string{config.Project, config.Environment, stackName}
+	if config.Workspaces {
+		parts = []string{config.Project, stackName}
+	}
+	if prefix := strings.Trim(b.KeyPrefix, "/"); prefix != "" {
+		parts = append([]string{prefix}, parts...)
+	}
+	return strings.Join(parts, "/")
+}
+
+// addBackend points a stack's state at the configured backend
+func addBackend(stack cdktf.TerraformStack, config Config, stackName string) {
+	location := backendTypes[config.Backend.kind()].add(stack, config, stackName)
+	fmt.Printf("  ✓ %s backend %s\n", config.Backend.kind(), location)
+}
+
+func validateS3Backend(b *BackendConfig) error {
+	if b.Bucket == "" {
+		return fmt.Errorf("bucket is required")
+	}
+	if b.DynamoDBTable == "" {
+		return fmt.Errorf("dynamodb_table is required for state locking")
+	}
+	return nil
+}
+
+// addS3Backend keeps state in the shared S3 bucket, locked through DynamoDB
+func addS3Backend(stack cdktf.TerraformStack, config Config, stackName string) string {
+	backend := config.Backend
+
+	backendConfig := &cdktf.S3BackendConfig{
+		Bucket:        jsii.String(backend.Bucket),
+		Key:           jsii.String(backend.stateKey(config, stackName) + ".tfstate"),
+		Region:        jsii.String(backend.region(config)),
+		DynamodbTable: jsii.String(backend.DynamoDBTable),
+		Encrypt:       jsii.Bool(true),
+	}
+	if backend.KMSKeyID != "" {
+		backendConfig.KmsKeyId = jsii.String(backend.KMSKeyID)
+	}
+	// Read state with the same credentials as the provider
+	environment := config.Environments[config.Environment]
+	if environment.Profile != "" {
+		backendConfig.Profile = jsii.String(environment.Profile)
+	}
+	if len(environment.SharedCredentialsFiles) > 0 {
+		backendConfig.SharedCredentialsFiles = jsii.Strings(environment.SharedCredentialsFiles...)
+	}
+	if len(environment.SharedConfigFiles) > 0 {
+		backendConfig.SharedConfigFiles = jsii.Strings(environment.SharedConfigFiles...)
+	}
+	if identity := environment.WebIdentity; identity != nil {
+		webIdentity := &cdktf.S3BackendAssumeRoleWithWebIdentityConfig{RoleArn: jsii.String(identity.RoleARN)}
+		if identity.TokenFile != "" {
+			webIdentity.WebIdentityTokenFile = jsii.String(identity.TokenFile)
+		}
+		if identity.SessionName != "" {
+			webIdentity.SessionName = jsii.String(identity.SessionName)
+		}
+		backendConfig.AssumeRoleWithWebIdentity = webIdentity
+	}
+	cdktf.NewS3Backend(stack, backendConfig)
+
+	return fmt.Sprintf("s3://%s/%s", backend.Bucket, *backendConfig.Key)
+}
+
+func validateGCSBackend(b *BackendConfig) error {
+	if b.GCS == nil || b.GCS.Bucket == "" {
+		return fmt.Errorf("gcs.bucket is required")
+	}
+	return nil
+}
+
+// addGCSBackend keeps state in a Cloud Storage bucket, which locks natively
+func addGCSBackend(stack cdktf.TerraformStack, config Config, stackName string) string {
+	gcs := config.Backend.GCS
+
+	backendConfig := &cdktf.GcsBackendConfig{
+		Bucket: jsii.String(gcs.Bucket),
+		Prefix: jsii.String(config.Backend.stateKey(config, stackName)),
+	}
+	if gcs.KMSEncryptionKey != "" {
+		backendConfig.KmsEncryptionKey = jsii.String(gcs.KMSEncryptionKey)
+	}
+	if gcs.ImpersonateServiceAccount != "" {
+		backendConfig.ImpersonateServiceAccount = jsii.String(gcs.ImpersonateServiceAccount)
+	}
+	cdktf.NewGcsBackend(stack, backendConfig)
+
+	return fmt.Sprintf("gs://%s/%s", gcs.Bucket, *backendConfig.Prefix)
+}
+
+func validateAzureRMBackend(b *BackendConfig) error {
+	if b.AzureRM == nil || b.AzureRM.StorageAccountName == "" || b.AzureRM.ContainerName == "" {
+		return fmt.Errorf("azurerm.storage_account_name and azurerm.container_name are required")
+	}
+	return nil
+}
+
+// addAzureRMBackend keeps state as a blob in an Azure storage container, locked by blob leases
+func addAzureRMBackend(stack cdktf.TerraformStack, config Config, stackName string) string {
+	azure := config.Backend.AzureRM
+
+	backendConfig := &cdktf.AzurermBackendConfig{
+		StorageAccountName: jsii.String(azure.StorageAccountName),
+		ContainerName:      jsii.String(azure.ContainerName),
+		Key:                jsii.String(config.Backend.stateKey(config, stackName) + ".tfstate"),
+		UseAzureadAuth:     jsii.Bool(azure.UseAzureADAuth),
+	}
+	if azure.ResourceGroupName != "" {
+		backendConfig.ResourceGroupName = jsii.String(azure.ResourceGroupName)
+	}
+	if azure.SubscriptionID != "" {
+		backendConfig.SubscriptionId = jsii.String(azure.SubscriptionID)
+	}
+	cdktf.NewAzurermBackend(stack, backendConfig)
+
+	return fmt.Sprintf("azurerm://%s/%s/%s", azure.StorageAccountName, azure.ContainerName, *backendConfig.Key)
+}
+
+func validateHTTPBackend(b *BackendConfig) error {
+	if b.HTTP == nil || b.HTTP.Address == "" {
+		return fmt.Errorf("http.address is required")
+	}
+	return nil
+}
+
+// addHTTPBackend keeps state behind a REST endpoint such as GitLab's managed Terraform state
+func addHTTPBackend(stack cdktf.TerraformStack, config Config, stackName string) string {
+	http := config.Backend.HTTP
+	expand := func(address string) *string {
+		return jsii.String(strings.ReplaceAll(address, "{stack}", stackName))
+	}
+
+	backendConfig := &cdktf.HttpBackendConfig{
+		Address: expand(http.Address),
+	}
+	if http.LockAddress != "" {
+		backendConfig.LockAddress = expand(http.LockAddress)
+	}
+	if http.UnlockAddress != "" {
+		backendConfig.UnlockAddress = expand(http.UnlockAddress)
+	}
+	if http.LockMethod != "" {
+		backendConfig.LockMethod = jsii.String(http.LockMethod)
+	}
+	if http.UnlockMethod != "" {
+		backendConfig.UnlockMethod = jsii.String(http.UnlockMethod)
+	}
+	if http.Username != "" {
+		backendConfig.Username = jsii.String(http.Username)
+	}
+	cdktf.NewHttpBackend(stack, backendConfig)
+
+	return *backendConfig.Address
+}
+
+// addBackendBootstrap creates a separate, locally-stated stack holding the state bucket and
+// lock table. Apply it once before the first deploy of any stack that uses the backend.
+func addBackendBootstrap(app cdktf.App, config Config) string {
+	backend := config.Backend
+	stackName := stackPrefix(config) + "-backend"
+	stack := cdktf.NewTerraformStack(app, jsii.String(stackName))
+
+	newAWSProvider(stack, "aws", awsProviderConfig(stack, config, backend.region(config)), config)
+	pinVersions(stack, config)
+
+	bucket, _ := newLogBucket(stack, "state_bucket", backend.Bucket, config,
+		func(bucket s3bucket.S3Bucket) []map[string]interface{} { return nil })
+
+	s3bucketversioning.NewS3BucketVersioningA(stack, jsii.String("state_bucket_versioning"),
+		&s3bucketversioning.S3BucketVersioningAConfig{
+			Bucket: bucket.Id(),
+			VersioningConfiguration: &s3bucketversioning.S3BucketVersioningVersioningConfiguration{
+				Status: jsii.String("Enabled"),
+			},
+		})
+
+	dynamodbtable.NewDynamodbTable(stack, jsii.String("lock_table"), &dynamodbtable.DynamodbTableConfig{
+		Name:        jsii.String(backend.DynamoDBTable),
+		BillingMode: jsii.String("PAY_PER_REQUEST"),
+		HashKey:     jsii.String("LockID"),
+		Attribute: &[]*dynamodbtable.DynamodbTableAttribute{
+			{Name: jsii.String("LockID"), Type: jsii.String("S")},
+		},
+		PointInTimeRecovery: &dynamodbtable.DynamodbTablePointInTimeRecovery{
+			Enabled: jsii.Bool(true),
+		},
+		DeletionProtectionEnabled: jsii.Bool(true),
+	})
+
+	fmt.Printf("  ✓ Backend bootstrap stack %s\n", stackName)
+	return stackName
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/backupplan"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/backupselection"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/backupvault"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/kmskey"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// BackupConfig creates an AWS Backup vault and plan. The plan selects the RDS, DynamoDB and EFS
+// resources tagged with the config's Project and Environment, which every resource the provider
+// creates gets, so new databases and file systems are backed up without being listed.
+type BackupConfig struct {
+	Vault string             `json:"vault"` // defaults to backups
+	Rules []BackupRuleConfig `json:"rules"` // default to one daily rule keeping backups 35 days
+	// ResourceTypes limits the selection to some of rds, dynamodb and efs; all by default
+	ResourceTypes []string `json:"resource_types"`
+}
+
+type BackupRuleConfig struct {
+	Name string `json:"name"`
+	// Schedule is daily, weekly (Sundays) or a cron() expression, in UTC
+	Schedule      string `json:"schedule"`
+	RetentionDays int    `json:"retention_days"`
+	// ColdStorageAfterDays moves backups to cold storage; they then have to be kept 90 days more
+	ColdStorageAfterDays int `json:"cold_storage_after_days"`
+}
+
+// backupResourceARNs are the ARN patterns AWS Backup selects from for each resource type
+var backupResourceARNs = map[string][]string{
+	"rds":      {"arn:aws:rds:*:*:db:*", "arn:aws:rds:*:*:cluster:*"},
+	"dynamodb": {"arn:aws:dynamodb:*:*:table/*"},
+	"efs":      {"arn:aws:elasticfilesystem:*:*:file-system/*"},
+}
+
+var (
+	backupSchedules        = map[string]string{"daily": "cron(0 5 ? * * *)", "weekly": "cron(0 5 ? * SUN *)"}
+	backupRuleNamePattern  = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,50}$`)
+	defaultBackupRules     = []BackupRuleConfig{{Name: "daily", Schedule: "daily", RetentionDays: 35}}
+	backupResourceTypeKeys = []string{"dynamodb", "efs", "rds"}
+)
+
+func (b *BackupConfig) validate() error {
+	if b.Vault != "" && !nameSegmentPattern.MatchString(b.Vault) {
+		return invalidValue(b.Vault, "", "vault %q must be lowercase letters, digits and hyphens", b.Vault)
+	}
+	names := map[string]bool{}
+	for i, rule := range b.Rules {
+		if !backupRuleNamePattern.MatchString(rule.Name) {
+			return invalidValue(rule.Name, "", "rules[%d]: name %q must be 1-50 letters, digits, '.', '_' or '-'", i, rule.Name)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("rules[%d]: duplicate name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+		if _, ok := backupSchedules[rule.Schedule]; !ok && !strings.HasPrefix(rule.Schedule, "cron(") {
+			return invalidValue(rule.Schedule, closestMatch(rule.Schedule, []string{"daily", "weekly"}),
+				"rules.%s: schedule %q must be daily, weekly or a cron() expression", rule.Name, rule.Schedule)
+		}
+		if rule.RetentionDays < 1 {
+			return invalidValue(rule.RetentionDays, "", "rules.%s: retention_days must be at least 1", rule.Name)
+		}
+		if rule.ColdStorageAfterDays < 0 {
+			return invalidValue(rule.ColdStorageAfterDays, "", "rules.%s: cold_storage_after_days can't be negative", rule.Name)
+		}
+		if rule.ColdStorageAfterDays > 0 && rule.RetentionDays < rule.ColdStorageAfterDays+90 {
+			return invalidValue(rule.RetentionDays, fmt.Sprintf("keep them at least %d days", rule.ColdStorageAfterDays+90),
+				"rules.%s: backups in cold storage must be kept at least 90 days", rule.Name)
+		}
+	}
+	for _, resourceType := range b.ResourceTypes {
+		if _, ok := backupResourceARNs[resourceType]; !ok {
+			return invalidValue(resourceType, closestMatch(resourceType, backupResourceTypeKeys),
+				"unknown resource type %q (want rds, dynamodb or efs)", resourceType)
+		}
+	}
+	return nil
+}
+
+// addBackup creates the vault, encrypted with a dedicated KMS key, the plan with its rules and a
+// selection of the config's resources by tag
+func addBackup(stack cdktf.TerraformStack, config Config) {
+	backup := config.Backup
+	vaultName := backup.Vault
+	if vaultName == "" {
+		vaultName = "backups"
+	}
+	rules := backup.Rules
+	if len(rules) == 0 {
+		rules = defaultBackupRules
+	}
+	resourceTypes := backup.ResourceTypes
+	if len(resourceTypes) == 0 {
+		resourceTypes = backupResourceTypeKeys
+	}
+
+	vaultName = resourceName(config, "aws_backup_vault", vaultName)
+	key := kmskey.NewKmsKey(stack, jsii.String("backup_key"), &kmskey.KmsKeyConfig{
+		Description:       jsii.String("Encrypts the recovery points in " + vaultName),
+		EnableKeyRotation: jsii.Bool(true),
+	})
+	vault := backupvault.NewBackupVault(stack, jsii.String("backup_vault"), &backupvault.BackupVaultConfig{
+		Name:      jsii.String(vaultName),
+		KmsKeyArn: key.Arn(),
+	})
+
+	var planRules []backupplan.BackupPlanRule
+	for _, rule := range rules {
+		schedule := rule.Schedule
+		if preset, ok := backupSchedules[schedule]; ok {
+			schedule = preset
+		}
+		lifecycle := &backupplan.BackupPlanRuleLifecycle{DeleteAfter: jsii.Number(rule.RetentionDays)}
+		if rule.ColdStorageAfterDays > 0 {
+			lifecycle.ColdStorageAfter = jsii.Number(rule.ColdStorageAfterDays)
+		}
+		planRules = append(planRules, backupplan.BackupPlanRule{
+			RuleName:        jsii.String(rule.Name),
+			TargetVaultName: vault.Name(),
+			Schedule:        jsii.String(schedule),
+			Lifecycle:       lifecycle,
+			RecoveryPointTags: &map[string]*string{
+				"Project":     jsii.String(config.Project),
+				"Environment": jsii.String(config.Environment),
+			},
+		})
+	}
+	plan := backupplan.NewBackupPlan(stack, jsii.String("backup_plan"), &backupplan.BackupPlanConfig{
+		Name: jsii.String(resourceName(config, "aws_backup_plan", "backups")),
+		Rule: planRules,
+	})
+
+	role := newServiceRole(stack, "backup_role", resourceName(config, "aws_iam_role", "backup"), "backup.amazonaws.com", config,
+		"arn:aws:iam::aws:policy/service-role/AWSBackupServiceRolePolicyForBackup",
+		"arn:aws:iam::aws:policy/service-role/AWSBackupServiceRolePolicyForRestores")
+
+	var resources []string
+	for _, resourceType := range slices.Sorted(slices.Values(resourceTypes)) {
+		resources = append(resources, backupResourceARNs[resourceType]...)
+	}
+	backupselection.NewBackupSelection(stack, jsii.String("backup_selection"), &backupselection.BackupSelectionConfig{
+		Name:       jsii.String(resourceName(config, "aws_backup_selection", "tagged")),
+		PlanId:     plan.Id(),
+		IamRoleArn: role.Arn(),
+		Resources:  jsii.Strings(resources...),
+		// All the conditions have to match
+		Condition: []backupselection.BackupSelectionCondition{{
+			StringEquals: []backupselection.BackupSelectionConditionStringEquals{
+				{Key: jsii.String("aws:ResourceTag/Project"), Value: jsii.String(config.Project)},
+				{Key: jsii.String("aws:ResourceTag/Environment"), Value: jsii.String(config.Environment)},
+			},
+		}},
+	})
+
+	cdktf.NewTerraformOutput(stack, jsii.String("backup_vault_name"), &cdktf.TerraformOutputConfig{
+		Value:       vault.Name(),
+		Description: jsii.String("The vault AWS Backup keeps recovery points in"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("backup_plan_id"), &cdktf.TerraformOutputConfig{
+		Value:       plan.Id(),
+		Description: jsii.String("The backup plan of the config's resources"),
+	})
+
+	fmt.Printf("  ✓ Backup plan with %d rule(s) for %s\n", len(planRules), strings.Join(resourceTypes, ", "))
+}
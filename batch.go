@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/batchcomputeenvironment"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/batchjobdefinition"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/batchjobqueue"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iaminstanceprofile"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// BatchConfig describes a Batch compute environment, its job queue, and the jobs it runs
+type BatchConfig struct {
+	ComputeType    string               `json:"compute_type"` // FARGATE, FARGATE_SPOT, EC2 or SPOT
+	MinVCPUs       float64              `json:"min_vcpus"`
+	MaxVCPUs       float64              `json:"max_vcpus"`
+	InstanceTypes  []string             `json:"instance_types"` // EC2 and SPOT only
+	VPC            VPCConfig            `json:"vpc"`
+	QueuePriority  float64              `json:"queue_priority"`
+	JobDefinitions []BatchJobDefinition `json:"job_definitions"`
+}
+
+type BatchJobDefinition struct {
+	Name           string            `json:"name"`
+	Image          string            `json:"image"`
+	Command        []string          `json:"command"`
+	VCPU           float64           `json:"vcpu"`
+	MemoryMiB      int               `json:"memory_mib"`
+	Environment    map[string]string `json:"environment"`
+	RetryAttempts  float64           `json:"retry_attempts"`
+	TimeoutSeconds float64           `json:"timeout_seconds"`
+}
+
+func (b *BatchConfig) isFargate() bool {
+	return b.ComputeType == "" || b.ComputeType == "FARGATE" || b.ComputeType == "FARGATE_SPOT"
+}
+
+func (b *BatchConfig) validate() error {
+	switch b.ComputeType {
+	case "", "FARGATE", "FARGATE_SPOT", "EC2", "SPOT":
+	default:
+		return invalidValue(b.ComputeType, closestMatch(b.ComputeType, []string{"FARGATE", "FARGATE_SPOT", "EC2", "SPOT"}),
+			"unknown compute_type %q (want FARGATE, FARGATE_SPOT, EC2 or SPOT)", b.ComputeType)
+	}
+	if len(b.VPC.SubnetIDs) == 0 {
+		return fmt.Errorf("vpc.subnet_ids is required")
+	}
+	if !b.isFargate() && len(b.InstanceTypes) == 0 {
+		return fmt.Errorf("instance_types is required for compute_type %s", b.ComputeType)
+	}
+	names := map[string]bool{}
+	for i, job := range b.JobDefinitions {
+		if job.Name == "" || job.Image == "" {
+			return fmt.Errorf("job definitions need a name and an image")
+		}
+		if names[job.Name] {
+			return fmt.Errorf("job_definitions[%d].name: duplicate %q", i, job.Name)
+		}
+		names[job.Name] = true
+	}
+	return nil
+}
+
+// addBatch creates the compute environment, a job queue in front of it, and one job definition per entry
+func addBatch(stack cdktf.TerraformStack, config Config) {
+	batch := config.Batch
+
+	computeType := batch.ComputeType
+	if computeType == "" {
+		computeType = "FARGATE"
+	}
+	maxVCPUs := batch.MaxVCPUs
+	if maxVCPUs == 0 {
+		maxVCPUs = sizeFor(config).BatchMaxVCPUs
+	}
+
+	computeResources := &batchcomputeenvironment.BatchComputeEnvironmentComputeResources{
+		Type:             jsii.String(computeType),
+		MaxVcpus:         jsii.Number(maxVCPUs),
+		Subnets:          jsii.Strings(batch.VPC.SubnetIDs...),
+		SecurityGroupIds: jsii.Strings(batch.VPC.SecurityGroupIDs...),
+	}
+
+	// EC2 capacity needs an instance profile for the ECS agent on each host
+	if !batch.isFargate() {
+		managedPolicies := []string{"arn:aws:iam::aws:policy/service-role/AmazonEC2ContainerServiceforEC2Role"}
+		// Hosts in a patch group register with Systems Manager to be patched
+		patchGroup := ""
+		if config.SSMManagement != nil {
+			patchGroup = config.SSMManagement.BatchPatchGroup
+		}
+		if patchGroup != "" {
+			managedPolicies = append(managedPolicies, "arn:aws:iam::aws:policy/AmazonSSMManagedInstanceCore")
+			computeResources.Tags = &map[string]*string{"Patch Group": jsii.String(patchGroup)}
+		}
+		instanceRole := newServiceRole(stack, "batch_instance_role", resourceName(config, "aws_iam_role", "batch-instance"),
+			"ec2.amazonaws.com", config, managedPolicies...)
+		instanceProfile := iaminstanceprofile.NewIamInstanceProfile(stack, jsii.String("batch_instance_profile"),
+			&iaminstanceprofile.IamInstanceProfileConfig{
+				Name: jsii.String(resourceName(config, "aws_iam_instance_profile", "batch-instance")),
+				Role: instanceRole.Name(),
+			})
+
+		computeResources.InstanceRole = instanceProfile.Arn()
+		computeResources.InstanceType = jsii.Strings(batch.InstanceTypes...)
+		computeResources.MinVcpus = jsii.Number(batch.MinVCPUs)
+		computeResources.AllocationStrategy = jsii.String("BEST_FIT_PROGRESSIVE")
+		if computeType == "SPOT" {
+			computeResources.AllocationStrategy = jsii.String("SPOT_CAPACITY_OPTIMIZED")
+		}
+	}
+
+	computeEnvironment := batchcomputeenvironment.NewBatchComputeEnvironment(stack, jsii.String("batch_compute"),
+		&batchcomputeenvironment.BatchComputeEnvironmentConfig{
+			ComputeEnvironmentName: jsii.String(resourceName(config, "aws_batch_compute_environment", "batch")),
+			Type:                   jsii.String("MANAGED"),
+			ComputeResources:       computeResources,
+		})
+
+	priority := batch.QueuePriority
+	if priority == 0 {
+		priority = 1
+	}
+
+	queue := batchjobqueue.NewBatchJobQueue(stack, jsii.String("batch_queue"), &batchjobqueue.BatchJobQueueConfig{
+		Name:     jsii.String(resourceName(config, "aws_batch_job_queue", "batch-queue")),
+		State:    jsii.String("ENABLED"),
+		Priority: jsii.Number(priority),
+		ComputeEnvironmentOrder: &[]*batchjobqueue.BatchJobQueueComputeEnvironmentOrder{
+			{
+				ComputeEnvironment: computeEnvironment.Arn(),
+				Order:              jsii.Number(1),
+			},
+		},
+	})
+
+	executionRole := newServiceRole(stack, "batch_execution_role", resourceName(config, "aws_iam_role", "batch-execution"),
+		"ecs-tasks.amazonaws.com", config,
+		"arn:aws:iam::aws:policy/service-role/AmazonECSTaskExecutionRolePolicy")
+
+	// With tracing, jobs run with a role that can send traces to X-Ray, from an instrumented job or
+	// the daemon or ADOT collector it starts
+	jobRoleArn := ""
+	if config.Tracing {
+		jobRole := newServiceRole(stack, "batch_job_role", resourceName(config, "aws_iam_role", "batch-job"),
+			"ecs-tasks.amazonaws.com", config, xrayWriteAccess)
+		jobRoleArn = *jobRole.Arn()
+	}
+
+	for _, job := range batch.JobDefinitions {
+		jobConfig := &batchjobdefinition.BatchJobDefinitionConfig{
+			Name:                jsii.String(resourceName(config, "aws_batch_job_definition", job.Name)),
+			Type:                jsii.String("container"),
+			ContainerProperties: jsii.String(batchContainerProperties(batch, job, *executionRole.Arn(), jobRoleArn)),
+			PropagateTags:       jsii.Bool(true),
+		}
+		if batch.isFargate() {
+			jobConfig.PlatformCapabilities = jsii.Strings("FARGATE")
+		} else {
+			jobConfig.PlatformCapabilities = jsii.Strings("EC2")
+		}
+		if job.RetryAttempts > 0 {
+			jobConfig.RetryStrategy = &batchjobdefinition.BatchJobDefinitionRetryStrategy{
+				Attempts: jsii.Number(job.RetryAttempts),
+			}
+		}
+		if job.TimeoutSeconds > 0 {
+			jobConfig.Timeout = &batchjobdefinition.BatchJobDefinitionTimeout{
+				AttemptDurationSeconds: jsii.Number(job.TimeoutSeconds),
+			}
+		}
+
+		batchjobdefinition.NewBatchJobDefinition(stack, jsii.String("batch_job_"+job.Name), jobConfig)
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("batch_job_queue_arn"), &cdktf.TerraformOutputConfig{
+		Value:       queue.Arn(),
+		Description: jsii.String("The ARN of the Batch job queue"),
+	})
+
+	fmt.Printf("  ✓ Batch %s compute environment with %d job definition(s)\n",
+		computeType, len(batch.JobDefinitions))
+}
+
+// batchContainerProperties renders the container_properties JSON for a job definition; jobRoleArn
+// is left out when empty
+func batchContainerProperties(batch *BatchConfig, job BatchJobDefinition, executionRoleArn, jobRoleArn string) string {
+	vcpu := job.VCPU
+	if vcpu == 0 {
+		vcpu = 1
+	}
+	memory := job.MemoryMiB
+	if memory == 0 {
+		memory = 2048
+	}
+
+	names := make([]string, 0, len(job.Environment))
+	for name := range job.Environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	environment := []map[string]string{}
+	for _, name := range names {
+		environment = append(environment, map[string]string{"name": name, "value": job.Environment[name]})
+	}
+
+	properties := map[string]interface{}{
+		"image":            job.Image,
+		"command":          job.Command,
+		"executionRoleArn": executionRoleArn,
+		"environment":      environment,
+		"resourceRequirements": []map[string]string{
+			{"type": "VCPU", "value": strconv.FormatFloat(vcpu, 'f', -1, 64)},
+			{"type": "MEMORY", "value": strconv.Itoa(memory)},
+		},
+	}
+	if jobRoleArn != "" {
+		properties["jobRoleArn"] = jobRoleArn
+	}
+	if batch.isFargate() {
+		properties["networkConfiguration"] = map[string]string{"assignPublicIp": "DISABLED"}
+		properties["fargatePlatformConfiguration"] = map[string]string{"platformVersion": "LATEST"}
+	}
+
+	rendered, _ := json.Marshal(properties)
+	return string(rendered)
+}
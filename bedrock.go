@@ -0,0 +1,368 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/bedrockguardrail"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/bedrockguardrailversion"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/bedrockmodelinvocationloggingconfiguration"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/bedrockprovisionedmodelthroughput"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iampolicy"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrolepolicyattachment"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// BedrockConfig describes who may invoke which Bedrock models, the guardrails applied to them and
+// any throughput bought for them
+type BedrockConfig struct {
+	// Access is a managed IAM policy per team, by name
+	Access map[string]BedrockAccess `json:"access"`
+	// ProvisionedThroughput reserves model units for a foundation model, by name; access entries
+	// list the name to invoke the model through them
+	ProvisionedThroughput map[string]BedrockThroughput `json:"provisioned_throughput"`
+	Guardrails            map[string]BedrockGuardrail  `json:"guardrails"`
+	// InvocationLogsBucket turns on model invocation logging for the region, delivered to a log
+	// bucket of its own. Logging is one setting per account and region.
+	InvocationLogsBucket string `json:"invocation_logs_bucket"`
+}
+
+type BedrockAccess struct {
+	// Models are foundation model IDs, e.g. anthropic.claude-3-5-sonnet-20240620-v1:0, or names
+	// of provisioned_throughput entries
+	Models []string `json:"models"`
+	// Guardrail names a guardrail every invocation must apply; invocations without it are refused
+	Guardrail string `json:"guardrail"`
+	// Roles are existing IAM role names the policy is attached to
+	Roles []string `json:"roles"`
+}
+
+type BedrockThroughput struct {
+	Model      string  `json:"model"`       // the foundation model ID, with its context length where it has several
+	ModelUnits float64 `json:"model_units"` // 1 by default
+	// Commitment is 1_month or 6_months for a discounted term; without one the throughput is
+	// billed hourly and can be deleted at any time
+	Commitment string `json:"commitment"`
+}
+
+type BedrockGuardrail struct {
+	Description string `json:"description"`
+	// BlockedMessage is returned in place of a blocked prompt or response
+	BlockedMessage string `json:"blocked_message"`
+	// ContentFilters are filter strengths by category, e.g. {"HATE": "HIGH"}, applied to both
+	// prompts and responses
+	ContentFilters map[string]string             `json:"content_filters"`
+	DeniedTopics   map[string]BedrockDeniedTopic `json:"denied_topics"`
+	// PIIEntities are the actions (BLOCK or ANONYMIZE) taken on personal information, by entity
+	// type, e.g. {"EMAIL": "ANONYMIZE"}
+	PIIEntities  map[string]string `json:"pii_entities"`
+	BlockedWords []string          `json:"blocked_words"`
+	KMSKeyArn    string            `json:"kms_key_arn"`
+}
+
+type BedrockDeniedTopic struct {
+	Definition string   `json:"definition"`
+	Examples   []string `json:"examples"`
+}
+
+var bedrockContentFilters = []string{"HATE", "INSULTS", "MISCONDUCT", "PROMPT_ATTACK", "SEXUAL", "VIOLENCE"}
+
+var bedrockFilterStrengths = []string{"NONE", "LOW", "MEDIUM", "HIGH"}
+
+var bedrockCommitments = map[string]string{"1_month": "OneMonth", "6_months": "SixMonths"}
+
+func (b *BedrockConfig) validate() error {
+	if len(b.Access) == 0 && len(b.ProvisionedThroughput) == 0 && len(b.Guardrails) == 0 && b.InvocationLogsBucket == "" {
+		return fmt.Errorf("at least one of access, provisioned_throughput, guardrails or invocation_logs_bucket is required")
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.ProvisionedThroughput)) {
+		throughput := b.ProvisionedThroughput[name]
+		if !nameSegmentPattern.MatchString(name) {
+			return invalidValue(name, "", "provisioned_throughput: name %q must be lowercase letters, digits and hyphens", name)
+		}
+		if throughput.Model == "" {
+			return fmt.Errorf("provisioned_throughput.%s: model is required", name)
+		}
+		if throughput.ModelUnits < 0 {
+			return fmt.Errorf("provisioned_throughput.%s: model_units must be at least 1", name)
+		}
+		if _, ok := bedrockCommitments[throughput.Commitment]; throughput.Commitment != "" && !ok {
+			return invalidValue(throughput.Commitment, closestMatch(throughput.Commitment, slices.Sorted(maps.Keys(bedrockCommitments))),
+				"provisioned_throughput.%s: unknown commitment %q (want 1_month or 6_months)", name, throughput.Commitment)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.Guardrails)) {
+		if err := b.Guardrails[name].validate(name); err != nil {
+			return fmt.Errorf("guardrails.%s: %w", name, err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(b.Access)) {
+		access := b.Access[name]
+		if !nameSegmentPattern.MatchString(name) {
+			return invalidValue(name, "", "access: name %q must be lowercase letters, digits and hyphens", name)
+		}
+		if len(access.Models) == 0 {
+			return fmt.Errorf("access.%s: at least one model is required", name)
+		}
+		if access.Guardrail != "" {
+			if _, ok := b.Guardrails[access.Guardrail]; !ok {
+				return invalidValue(access.Guardrail, closestMatch(access.Guardrail, slices.Sorted(maps.Keys(b.Guardrails))),
+					"access.%s: unknown guardrail %q", name, access.Guardrail)
+			}
+		}
+	}
+	return nil
+}
+
+func (g BedrockGuardrail) validate(name string) error {
+	if !nameSegmentPattern.MatchString(name) || len(name) > 50 {
+		return fmt.Errorf("guardrail names must be up to 50 lowercase letters, digits and hyphens")
+	}
+	if len(g.ContentFilters) == 0 && len(g.DeniedTopics) == 0 && len(g.PIIEntities) == 0 && len(g.BlockedWords) == 0 {
+		return fmt.Errorf("at least one of content_filters, denied_topics, pii_entities or blocked_words is required")
+	}
+	for _, filter := range slices.Sorted(maps.Keys(g.ContentFilters)) {
+		if !slices.Contains(bedrockContentFilters, filter) {
+			return invalidValue(filter, closestMatch(filter, bedrockContentFilters), "content_filters: unknown category %q", filter)
+		}
+		if strength := g.ContentFilters[filter]; !slices.Contains(bedrockFilterStrengths, strength) {
+			return invalidValue(strength, closestMatch(strength, bedrockFilterStrengths),
+				"content_filters.%s: strength %q must be NONE, LOW, MEDIUM or HIGH", filter, strength)
+		}
+	}
+	for _, topic := range slices.Sorted(maps.Keys(g.DeniedTopics)) {
+		if g.DeniedTopics[topic].Definition == "" {
+			return fmt.Errorf("denied_topics.%s: definition is required", topic)
+		}
+		if len(g.DeniedTopics[topic].Definition) > 200 {
+			return fmt.Errorf("denied_topics.%s: definition must be at most 200 characters", topic)
+		}
+	}
+	for _, entity := range slices.Sorted(maps.Keys(g.PIIEntities)) {
+		if action := g.PIIEntities[entity]; action != "BLOCK" && action != "ANONYMIZE" {
+			return invalidValue(action, closestMatch(action, []string{"BLOCK", "ANONYMIZE"}),
+				"pii_entities.%s: action %q must be BLOCK or ANONYMIZE", entity, action)
+		}
+	}
+	return nil
+}
+
+// foundationModelArn is the ARN of a foundation model in the config's region
+func foundationModelArn(config Config, model string) string {
+	return fmt.Sprintf("arn:aws:bedrock:%s::foundation-model/%s", config.Region, model)
+}
+
+// addBedrock creates the guardrails, the provisioned throughput and a policy per access entry,
+// attached to the entry's roles
+func addBedrock(stack cdktf.TerraformStack, config Config) {
+	bedrock := config.Bedrock
+
+	// Invocations name a guardrail by ID and version; the version is a snapshot of the guardrail
+	// taken when it's created
+	guardrailArns, guardrailVersions := map[string]string{}, map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(bedrock.Guardrails)) {
+		settings := bedrock.Guardrails[name]
+		id := "bedrock_guardrail_" + strings.ReplaceAll(name, "-", "_")
+
+		blockedMessage := settings.BlockedMessage
+		if blockedMessage == "" {
+			blockedMessage = "Sorry, I can't help with that request."
+		}
+		guardrailConfig := &bedrockguardrail.BedrockGuardrailConfig{
+			Name:                    jsii.String(resourceName(config, "aws_bedrock_guardrail", name)),
+			BlockedInputMessaging:   jsii.String(blockedMessage),
+			BlockedOutputsMessaging: jsii.String(blockedMessage),
+		}
+		if settings.Description != "" {
+			guardrailConfig.Description = jsii.String(settings.Description)
+		}
+		if settings.KMSKeyArn != "" {
+			guardrailConfig.KmsKeyArn = jsii.String(settings.KMSKeyArn)
+		}
+		if len(settings.ContentFilters) > 0 {
+			var filters []*bedrockguardrail.BedrockGuardrailContentPolicyConfigFiltersConfig
+			for _, filter := range slices.Sorted(maps.Keys(settings.ContentFilters)) {
+				// Prompt attacks only happen in prompts, so the filter has no output strength
+				outputStrength := settings.ContentFilters[filter]
+				if filter == "PROMPT_ATTACK" {
+					outputStrength = "NONE"
+				}
+				filters = append(filters, &bedrockguardrail.BedrockGuardrailContentPolicyConfigFiltersConfig{
+					Type:           jsii.String(filter),
+					InputStrength:  jsii.String(settings.ContentFilters[filter]),
+					OutputStrength: jsii.String(outputStrength),
+				})
+			}
+			guardrailConfig.ContentPolicyConfig = []*bedrockguardrail.BedrockGuardrailContentPolicyConfig{{FiltersConfig: &filters}}
+		}
+		if len(settings.DeniedTopics) > 0 {
+			var topics []*bedrockguardrail.BedrockGuardrailTopicPolicyConfigTopicsConfig
+			for _, topic := range slices.Sorted(maps.Keys(settings.DeniedTopics)) {
+				topics = append(topics, &bedrockguardrail.BedrockGuardrailTopicPolicyConfigTopicsConfig{
+					Name:       jsii.String(topic),
+					Type:       jsii.String("DENY"),
+					Definition: jsii.String(settings.DeniedTopics[topic].Definition),
+					Examples:   jsii.Strings(settings.DeniedTopics[topic].Examples...),
+				})
+			}
+			guardrailConfig.TopicPolicyConfig = []*bedrockguardrail.BedrockGuardrailTopicPolicyConfig{{TopicsConfig: &topics}}
+		}
+		if len(settings.PIIEntities) > 0 {
+			var entities []*bedrockguardrail.BedrockGuardrailSensitiveInformationPolicyConfigPiiEntitiesConfig
+			for _, entity := range slices.Sorted(maps.Keys(settings.PIIEntities)) {
+				entities = append(entities, &bedrockguardrail.BedrockGuardrailSensitiveInformationPolicyConfigPiiEntitiesConfig{
+					Type:   jsii.String(entity),
+					Action: jsii.String(settings.PIIEntities[entity]),
+				})
+			}
+			guardrailConfig.SensitiveInformationPolicyConfig = []*bedrockguardrail.BedrockGuardrailSensitiveInformationPolicyConfig{{PiiEntitiesConfig: &entities}}
+		}
+		if len(settings.BlockedWords) > 0 {
+			var words []*bedrockguardrail.BedrockGuardrailWordPolicyConfigWordsConfig
+			for _, word := range settings.BlockedWords {
+				words = append(words, &bedrockguardrail.BedrockGuardrailWordPolicyConfigWordsConfig{Text: jsii.String(word)})
+			}
+			guardrailConfig.WordPolicyConfig = []*bedrockguardrail.BedrockGuardrailWordPolicyConfig{{WordsConfig: &words}}
+		}
+		guardrail := bedrockguardrail.NewBedrockGuardrail(stack, jsii.String(id), guardrailConfig)
+
+		// Old versions are kept so clients pinned to them keep working
+		version := bedrockguardrailversion.NewBedrockGuardrailVersion(stack, jsii.String("bedrock_guardrail_version_"+strings.ReplaceAll(name, "-", "_")),
+			&bedrockguardrailversion.BedrockGuardrailVersionConfig{
+				GuardrailArn: guardrail.GuardrailArn(),
+				SkipDestroy:  jsii.Bool(true),
+			})
+		guardrailArns[name] = *guardrail.GuardrailArn()
+		guardrailVersions[name] = *guardrail.GuardrailArn() + ":" + *version.Version()
+
+		cdktf.NewTerraformOutput(stack, jsii.String(id+"_id"), &cdktf.TerraformOutputConfig{
+			Value:       guardrail.GuardrailId(),
+			Description: jsii.String("The guardrail identifier invocations pass to apply the " + name + " guardrail"),
+		})
+		cdktf.NewTerraformOutput(stack, jsii.String(id+"_version"), &cdktf.TerraformOutputConfig{
+			Value:       version.Version(),
+			Description: jsii.String("The version of the " + name + " guardrail invocations pass with its identifier"),
+		})
+	}
+
+	throughputArns := map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(bedrock.ProvisionedThroughput)) {
+		settings := bedrock.ProvisionedThroughput[name]
+		id := "bedrock_throughput_" + strings.ReplaceAll(name, "-", "_")
+		modelUnits := settings.ModelUnits
+		if modelUnits == 0 {
+			modelUnits = 1
+		}
+		throughputConfig := &bedrockprovisionedmodelthroughput.BedrockProvisionedModelThroughputConfig{
+			ProvisionedModelName: jsii.String(resourceName(config, "aws_bedrock_provisioned_model_throughput", name)),
+			ModelArn:             jsii.String(foundationModelArn(config, settings.Model)),
+			ModelUnits:           jsii.Number(modelUnits),
+		}
+		if settings.Commitment != "" {
+			throughputConfig.CommitmentDuration = jsii.String(bedrockCommitments[settings.Commitment])
+		}
+		throughput := bedrockprovisionedmodelthroughput.NewBedrockProvisionedModelThroughput(stack, jsii.String(id), throughputConfig)
+		throughputArns[name] = *throughput.ProvisionedModelArn()
+
+		cdktf.NewTerraformOutput(stack, jsii.String(id+"_arn"), &cdktf.TerraformOutputConfig{
+			Value:       throughput.ProvisionedModelArn(),
+			Description: jsii.String("The model ID to invoke " + settings.Model + " through the " + name + " provisioned throughput with"),
+		})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(bedrock.Access)) {
+		access := bedrock.Access[name]
+		id := "bedrock_access_" + strings.ReplaceAll(name, "-", "_")
+
+		var models []string
+		for _, model := range access.Models {
+			if arn, ok := throughputArns[model]; ok {
+				// Invoking provisioned throughput is also authorized against its underlying model
+				models = append(models, arn, foundationModelArn(config, bedrock.ProvisionedThroughput[model].Model))
+			} else {
+				models = append(models, foundationModelArn(config, model))
+			}
+		}
+		invoke := map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   []string{"bedrock:InvokeModel", "bedrock:InvokeModelWithResponseStream"},
+			"Resource": models,
+		}
+		statements := []map[string]interface{}{invoke}
+		if access.Guardrail != "" {
+			invoke["Condition"] = map[string]interface{}{
+				"StringEquals": map[string]string{"bedrock:GuardrailIdentifier": guardrailVersions[access.Guardrail]},
+			}
+			statements = append(statements, map[string]interface{}{
+				"Effect":   "Allow",
+				"Action":   "bedrock:ApplyGuardrail",
+				"Resource": guardrailArns[access.Guardrail],
+			})
+		}
+		policy := iampolicy.NewIamPolicy(stack, jsii.String(id), &iampolicy.IamPolicyConfig{
+			Name:        jsii.String(resourceName(config, "aws_iam_policy", "bedrock-"+name)),
+			Description: jsii.String("Bedrock model access for " + name),
+			Policy:      jsii.String(policyDocument(statements...)),
+		})
+		for _, role := range access.Roles {
+			iamrolepolicyattachment.NewIamRolePolicyAttachment(stack, jsii.String(id+"_"+strings.ReplaceAll(role, "-", "_")),
+				&iamrolepolicyattachment.IamRolePolicyAttachmentConfig{
+					Role:      jsii.String(role),
+					PolicyArn: policy.Arn(),
+				})
+		}
+
+		cdktf.NewTerraformOutput(stack, jsii.String(id+"_policy_arn"), &cdktf.TerraformOutputConfig{
+			Value:       policy.Arn(),
+			Description: jsii.String("The managed policy granting " + name + " access to its Bedrock models"),
+		})
+	}
+
+	if bedrock.InvocationLogsBucket != "" {
+		account := *accountID(stack)
+		sourceCondition := map[string]interface{}{
+			"StringEquals": map[string]string{"aws:SourceAccount": account},
+			"ArnLike":      map[string]string{"aws:SourceArn": fmt.Sprintf("arn:aws:bedrock:%s:%s:*", config.Region, account)},
+		}
+		bucket, bucketPolicy := newLogBucket(stack, "bedrock_logs_bucket", resourceName(config, "aws_s3_bucket", bedrock.InvocationLogsBucket), config,
+			func(bucket s3bucket.S3Bucket) []map[string]interface{} {
+				return []map[string]interface{}{
+					{
+						"Sid":       "BedrockPutObject",
+						"Effect":    "Allow",
+						"Principal": map[string]string{"Service": "bedrock.amazonaws.com"},
+						"Action":    "s3:PutObject",
+						"Resource":  *bucket.Arn() + "/AWSLogs/" + account + "/BedrockModelInvocationLogs/*",
+						"Condition": sourceCondition,
+					},
+				}
+			})
+
+		// Bedrock checks it can write to the bucket when logging is turned on
+		bedrockmodelinvocationloggingconfiguration.NewBedrockModelInvocationLoggingConfiguration(stack, jsii.String("bedrock_invocation_logging"),
+			&bedrockmodelinvocationloggingconfiguration.BedrockModelInvocationLoggingConfigurationConfig{
+				LoggingConfig: &bedrockmodelinvocationloggingconfiguration.BedrockModelInvocationLoggingConfigurationLoggingConfig{
+					TextDataDeliveryEnabled:      jsii.Bool(true),
+					ImageDataDeliveryEnabled:     jsii.Bool(false),
+					EmbeddingDataDeliveryEnabled: jsii.Bool(false),
+					VideoDataDeliveryEnabled:     jsii.Bool(false),
+					S3Config: &bedrockmodelinvocationloggingconfiguration.BedrockModelInvocationLoggingConfigurationLoggingConfigS3Config{
+						BucketName: bucket.Bucket(),
+					},
+				},
+				DependsOn: &[]cdktf.ITerraformDependable{bucketPolicy},
+			})
+
+		cdktf.NewTerraformOutput(stack, jsii.String("bedrock_invocation_logs_bucket_name"), &cdktf.TerraformOutputConfig{
+			Value:       bucket.Bucket(),
+			Description: jsii.String("The bucket Bedrock delivers model invocation logs to"),
+		})
+	}
+
+	fmt.Printf("  ✓ Bedrock: %d access policy(ies), %d guardrail(s), %d provisioned throughput(s)\n",
+		len(bedrock.Access), len(bedrock.Guardrails), len(bedrock.ProvisionedThroughput))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// jsiiRequest matches the requests the jsii runtime logs with JSII_DEBUG, capturing the API name
+var jsiiRequest = regexp.MustCompile(`^> \{"api":"([a-z]+)"`)
+
+// benchRun is the measurement of one synth
+type benchRun struct {
+	Elapsed time.Duration `json:"elapsed_ns"`
+	// PeakRSS is the largest resident set of the synth process and the jsii runtime it starts,
+	// in bytes, or 0 where the platform doesn't report it
+	PeakRSS uint64 `json:"peak_rss_bytes"`
+}
+
+// benchResults is what bench reports and writes with -output
+type benchResults struct {
+	Runs []benchRun `json:"runs"`
+	// JSIICalls counts the requests made to the jsii runtime in one synth, by API
+	JSIICalls map[string]int `json:"jsii_calls,omitempty"`
+}
+
+// synthOnce runs this program once to synthesize config.json into a temporary directory, with
+// extra environment variables, returning its output and measurements
+func synthOnce(executable string, env ...string) (benchRun, []byte, error) {
+	outdir, err := os.MkdirTemp("", "bench-")
+	if err != nil {
+		return benchRun{}, nil, err
+	}
+	defer os.RemoveAll(outdir)
+
+	command := exec.Command(executable)
+	command.Env = append(append(os.Environ(), "CDKTF_OUTDIR="+outdir), env...)
+	var output bytes.Buffer
+	command.Stdout = &output
+	command.Stderr = &output
+	start := time.Now()
+	err = command.Run()
+	run := benchRun{Elapsed: time.Since(start)}
+	if err != nil {
+		return run, output.Bytes(), fmt.Errorf("synth failed: %w\n%s", err, output.Bytes())
+	}
+	run.PeakRSS = peakRSS(command.ProcessState)
+	return run, output.Bytes(), nil
+}
+
+// countJSIICalls counts the jsii requests in the output of a synth run with JSII_DEBUG
+func countJSIICalls(output []byte) map[string]int {
+	calls := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		if match := jsiiRequest.FindSubmatch(scanner.Bytes()); match != nil {
+			calls[string(match[1])]++
+		}
+	}
+	return calls
+}
+
+// runBench is the bench command. It synthesizes config.json -n times, each in a process of its
+// own so every run pays the jsii startup a real synth does, and reports wall time and peak memory.
+// One more run with JSII_DEBUG counts the calls into jsii; the runtime logs every request then,
+// which is too slow to time.
+func runBench(args []string) error {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	runs := flags.Int("n", 5, "number of timed synths")
+	countCalls := flags.Bool("jsii-calls", true, "count jsii calls in one extra, untimed synth")
+	outputPath := flags.String("output", "", "also write the results as JSON to this file")
+	flags.Parse(args)
+
+	if *runs < 1 {
+		return fmt.Errorf("-n must be at least 1")
+	}
+	if _, err := loadConfig("config.json"); err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	var results benchResults
+	fmt.Printf("⏱️  Synthesizing config.json %d time(s)...\n", *runs)
+	for i := range *runs {
+		run, _, err := synthOnce(executable)
+		if err != nil {
+			return fmt.Errorf("run %d: %w", i+1, err)
+		}
+		fmt.Printf("  run %d: %s, peak RSS %s\n", i+1, run.Elapsed.Round(time.Millisecond), formatBytes(run.PeakRSS))
+		results.Runs = append(results.Runs, run)
+	}
+	if *countCalls {
+		_, output, err := synthOnce(executable, "JSII_DEBUG=1")
+		if err != nil {
+			return fmt.Errorf("counting jsii calls: %w", err)
+		}
+		results.JSIICalls = countJSIICalls(output)
+	}
+
+	elapsed := make([]time.Duration, len(results.Runs))
+	rss := make([]uint64, len(results.Runs))
+	var totalElapsed time.Duration
+	var totalRSS uint64
+	for i, run := range results.Runs {
+		elapsed[i], rss[i] = run.Elapsed, run.PeakRSS
+		totalElapsed += run.Elapsed
+		totalRSS += run.PeakRSS
+	}
+	count := len(results.Runs)
+	fmt.Printf("\nWall time: min %s, avg %s, max %s\n", slices.Min(elapsed).Round(time.Millisecond),
+		(totalElapsed / time.Duration(count)).Round(time.Millisecond), slices.Max(elapsed).Round(time.Millisecond))
+	fmt.Printf("Peak RSS:  min %s, avg %s, max %s\n", formatBytes(slices.Min(rss)),
+		formatBytes(totalRSS/uint64(count)), formatBytes(slices.Max(rss)))
+	if results.JSIICalls != nil {
+		total := 0
+		for _, calls := range results.JSIICalls {
+			total += calls
+		}
+		fmt.Printf("jsii calls: %d\n", total)
+		for _, api := range slices.Sorted(maps.Keys(results.JSIICalls)) {
+			fmt.Printf("  %-8s %d\n", api, results.JSIICalls[api])
+		}
+	}
+
+	if *outputPath != "" {
+		raw, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*outputPath, append(raw, '\n'), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Results written to %s\n", *outputPath)
+	}
+	return nil
+}
+
+// formatBytes prints a byte count in MiB, or "n/a" for 0
+func formatBytes(bytes uint64) string {
+	if bytes == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f MiB", float64(bytes)/(1<<20))
+}
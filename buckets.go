@@ -0,0 +1,76 @@
+package main
+
+import (
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dataawscalleridentity"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucketpolicy"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucketpublicaccessblock"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucketserversideencryptionconfiguration"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// accountID returns the deploying account's ID, looking it up once per stack
+func accountID(stack cdktf.TerraformStack) *string {
+	if existing := stack.Node().TryFindChild(jsii.String("caller_identity")); existing != nil {
+		return existing.(dataawscalleridentity.DataAwsCallerIdentity).AccountId()
+	}
+	return dataawscalleridentity.NewDataAwsCallerIdentity(stack, jsii.String("caller_identity"),
+		&dataawscalleridentity.DataAwsCallerIdentityConfig{}).AccountId()
+}
+
+// newLogBucket creates a private, encrypted bucket for AWS services to deliver logs or
+// findings into. policyStatements grant those services access; requests without TLS are
+// always denied. The policy is returned so services that verify it on setup can depend on it.
+func newLogBucket(stack cdktf.TerraformStack, id string, name string, config Config,
+	policyStatements func(bucket s3bucket.S3Bucket) []map[string]interface{}) (s3bucket.S3Bucket, s3bucketpolicy.S3BucketPolicy) {
+	bucket := s3bucket.NewS3Bucket(stack, jsii.String(id), &s3bucket.S3BucketConfig{
+		Bucket: jsii.String(name),
+	})
+
+	blockPublicAccess(stack, id, bucket)
+	encryptBucket(stack, id, bucket)
+
+	statements := append(policyStatements(bucket), map[string]interface{}{
+		"Sid":       "DenyInsecureTransport",
+		"Effect":    "Deny",
+		"Principal": "*",
+		"Action":    "s3:*",
+		"Resource":  []string{*bucket.Arn(), *bucket.Arn() + "/*"},
+		"Condition": map[string]interface{}{"Bool": map[string]string{"aws:SecureTransport": "false"}},
+	})
+	policy := s3bucketpolicy.NewS3BucketPolicy(stack, jsii.String(id+"_policy"), &s3bucketpolicy.S3BucketPolicyConfig{
+		Bucket: bucket.Id(),
+		Policy: jsii.String(policyDocument(statements...)),
+	})
+
+	return bucket, policy
+}
+
+// blockPublicAccess turns on all four public access block settings of a bucket
+func blockPublicAccess(stack cdktf.TerraformStack, id string, bucket s3bucket.S3Bucket) {
+	s3bucketpublicaccessblock.NewS3BucketPublicAccessBlock(stack, jsii.String(id+"_public_access"),
+		&s3bucketpublicaccessblock.S3BucketPublicAccessBlockConfig{
+			Bucket:                bucket.Id(),
+			BlockPublicAcls:       jsii.Bool(true),
+			BlockPublicPolicy:     jsii.Bool(true),
+			IgnorePublicAcls:      jsii.Bool(true),
+			RestrictPublicBuckets: jsii.Bool(true),
+		})
+}
+
+// encryptBucket sets SSE-S3 as the default encryption of a bucket
+func encryptBucket(stack cdktf.TerraformStack, id string, bucket s3bucket.S3Bucket) {
+	s3bucketserversideencryptionconfiguration.NewS3BucketServerSideEncryptionConfigurationA(stack,
+		jsii.String(id+"_encryption"),
+		&s3bucketserversideencryptionconfiguration.S3BucketServerSideEncryptionConfigurationAConfig{
+			Bucket: bucket.Id(),
+			Rule: &[]*s3bucketserversideencryptionconfiguration.S3BucketServerSideEncryptionConfigurationRuleA{
+				{
+					ApplyServerSideEncryptionByDefault: &s3bucketserversideencryptionconfiguration.S3BucketServerSideEncryptionConfigurationRuleApplyServerSideEncryptionByDefaultA{
+						SseAlgorithm: jsii.String("AES256"),
+					},
+				},
+			},
+		})
+}
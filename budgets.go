@@ -0,0 +1,214 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/budgetsbudget"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/ceanomalymonitor"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/ceanomalysubscription"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/snstopic"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/snstopicpolicy"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// BudgetsConfig creates a monthly cost budget for the project's spend in the environment, alerting
+// by email and through an SNS topic, and optionally a Cost Anomaly Detection monitor. Spend is
+// matched on the Project tag, which has to be activated as a cost allocation tag, and on the
+// environment's account_id when it is set.
+type BudgetsConfig struct {
+	MonthlyLimit float64 `json:"monthly_limit"` // in USD
+	// Thresholds are percentages of the limit that alert on actual spend; they default to 80 and 100
+	Thresholds []float64 `json:"thresholds"`
+	// ForecastThresholds alert when the spend forecast for the month passes a percentage
+	ForecastThresholds []float64               `json:"forecast_thresholds"`
+	Emails             []string                `json:"emails"`
+	AnomalyDetection   *AnomalyDetectionConfig `json:"anomaly_detection,omitempty"`
+}
+
+// AnomalyDetectionConfig reports cost anomalies of the project whose total impact reaches
+// threshold USD. IMMEDIATE alerts go to the SNS topic, DAILY (the default) and WEEKLY summaries
+// to the emails.
+type AnomalyDetectionConfig struct {
+	Threshold float64 `json:"threshold"`
+	Frequency string  `json:"frequency"`
+}
+
+var (
+	emailPattern         = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	anomalyFrequencies   = []string{"DAILY", "IMMEDIATE", "WEEKLY"}
+	defaultBudgetPercent = []float64{80, 100}
+)
+
+func (b *BudgetsConfig) validate() error {
+	if b.MonthlyLimit <= 0 {
+		return invalidValue(b.MonthlyLimit, "", "monthly_limit must be a positive amount in USD")
+	}
+	for _, thresholds := range []struct {
+		field  string
+		values []float64
+	}{{"thresholds", b.Thresholds}, {"forecast_thresholds", b.ForecastThresholds}} {
+		for _, threshold := range thresholds.values {
+			if threshold <= 0 || threshold > 1000 {
+				return invalidValue(threshold, "", "%s: %v must be a percentage of the limit, above 0 and at most 1000", thresholds.field, threshold)
+			}
+		}
+	}
+	for _, email := range b.Emails {
+		if !emailPattern.MatchString(email) {
+			return invalidValue(email, "", "emails: %q is not an email address", email)
+		}
+	}
+	if anomaly := b.AnomalyDetection; anomaly != nil {
+		if anomaly.Threshold <= 0 {
+			return invalidValue(anomaly.Threshold, "", "anomaly_detection: threshold must be a positive amount in USD")
+		}
+		switch anomaly.Frequency {
+		case "", "DAILY", "WEEKLY":
+			if len(b.Emails) == 0 {
+				return fmt.Errorf("anomaly_detection: DAILY and WEEKLY summaries are sent by email, so emails is required")
+			}
+		case "IMMEDIATE":
+		default:
+			return invalidValue(anomaly.Frequency, closestMatch(anomaly.Frequency, anomalyFrequencies),
+				"anomaly_detection: unknown frequency %q (want DAILY, IMMEDIATE or WEEKLY)", anomaly.Frequency)
+		}
+	}
+	return nil
+}
+
+// formatAmount formats an amount as the Budgets and Cost Explorer APIs take it
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// addBudgets creates the budget, its alert topic and, when configured, the anomaly monitor
+func addBudgets(stack cdktf.TerraformStack, config Config) {
+	budgets := config.Budgets
+	account := *accountID(stack)
+
+	// Left unencrypted: Budgets can't publish to topics encrypted with the AWS managed SNS key
+	topic := snstopic.NewSnsTopic(stack, jsii.String("budget_alerts"), &snstopic.SnsTopicConfig{
+		Name: jsii.String(resourceName(config, "aws_sns_topic", "budget-alerts")),
+	})
+	snstopicpolicy.NewSnsTopicPolicy(stack, jsii.String("budget_alerts_policy"), &snstopicpolicy.SnsTopicPolicyConfig{
+		Arn: topic.Arn(),
+		Policy: jsii.String(policyDocument(map[string]interface{}{
+			"Sid":       "CostAlertsPublish",
+			"Effect":    "Allow",
+			"Principal": map[string]interface{}{"Service": []string{"budgets.amazonaws.com", "costalerts.amazonaws.com"}},
+			"Action":    "SNS:Publish",
+			"Resource":  *topic.Arn(),
+			"Condition": map[string]interface{}{
+				"StringEquals": map[string]string{"aws:SourceAccount": account},
+			},
+		})),
+	})
+
+	var emails *[]*string
+	if len(budgets.Emails) > 0 {
+		emails = jsii.Strings(budgets.Emails...)
+	}
+	thresholds := budgets.Thresholds
+	if len(thresholds) == 0 {
+		thresholds = defaultBudgetPercent
+	}
+	var notifications []budgetsbudget.BudgetsBudgetNotification
+	for _, kinds := range []struct {
+		notificationType string
+		thresholds       []float64
+	}{{"ACTUAL", thresholds}, {"FORECASTED", budgets.ForecastThresholds}} {
+		for _, threshold := range kinds.thresholds {
+			notifications = append(notifications, budgetsbudget.BudgetsBudgetNotification{
+				ComparisonOperator:       jsii.String("GREATER_THAN"),
+				NotificationType:         jsii.String(kinds.notificationType),
+				Threshold:                jsii.Number(threshold),
+				ThresholdType:            jsii.String("PERCENTAGE"),
+				SubscriberEmailAddresses: emails,
+				SubscriberSnsTopicArns:   &[]*string{topic.Arn()},
+			})
+		}
+	}
+
+	filters := []budgetsbudget.BudgetsBudgetCostFilter{{
+		Name:   jsii.String("TagKeyValue"),
+		Values: jsii.Strings("user:Project$" + config.Project),
+	}}
+	if account := config.Environments[config.Environment].AccountID; account != "" {
+		filters = append(filters, budgetsbudget.BudgetsBudgetCostFilter{
+			Name:   jsii.String("LinkedAccount"),
+			Values: jsii.Strings(account),
+		})
+	}
+
+	budget := budgetsbudget.NewBudgetsBudget(stack, jsii.String("monthly_budget"), &budgetsbudget.BudgetsBudgetConfig{
+		Name:         jsii.String(resourceName(config, "aws_budgets_budget", "monthly")),
+		BudgetType:   jsii.String("COST"),
+		TimeUnit:     jsii.String("MONTHLY"),
+		LimitAmount:  jsii.String(formatAmount(budgets.MonthlyLimit)),
+		LimitUnit:    jsii.String("USD"),
+		CostFilter:   filters,
+		Notification: notifications,
+	})
+
+	cdktf.NewTerraformOutput(stack, jsii.String("budget_name"), &cdktf.TerraformOutputConfig{
+		Value:       budget.Name(),
+		Description: jsii.String("The monthly cost budget of the project"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("budget_alerts_topic_arn"), &cdktf.TerraformOutputConfig{
+		Value:       topic.Arn(),
+		Description: jsii.String("The SNS topic budget and cost anomaly alerts are published to"),
+	})
+
+	if anomaly := budgets.AnomalyDetection; anomaly != nil {
+		specification, _ := json.Marshal(map[string]interface{}{
+			"Tags": map[string]interface{}{"Key": "Project", "Values": []string{config.Project}, "MatchOptions": []string{"EQUALS"}},
+		})
+		monitor := ceanomalymonitor.NewCeAnomalyMonitor(stack, jsii.String("cost_anomaly_monitor"), &ceanomalymonitor.CeAnomalyMonitorConfig{
+			Name:                 jsii.String(resourceName(config, "aws_ce_anomaly_monitor", "costs")),
+			MonitorType:          jsii.String("CUSTOM"),
+			MonitorSpecification: jsii.String(string(specification)),
+		})
+
+		frequency := anomaly.Frequency
+		if frequency == "" {
+			frequency = "DAILY"
+		}
+		// Cost Anomaly Detection sends immediate alerts only to SNS and summaries only by email
+		var subscribers []ceanomalysubscription.CeAnomalySubscriptionSubscriber
+		if frequency == "IMMEDIATE" {
+			subscribers = append(subscribers, ceanomalysubscription.CeAnomalySubscriptionSubscriber{
+				Type: jsii.String("SNS"), Address: topic.Arn(),
+			})
+		} else {
+			for _, email := range budgets.Emails {
+				subscribers = append(subscribers, ceanomalysubscription.CeAnomalySubscriptionSubscriber{
+					Type: jsii.String("EMAIL"), Address: jsii.String(email),
+				})
+			}
+		}
+		ceanomalysubscription.NewCeAnomalySubscription(stack, jsii.String("cost_anomaly_subscription"), &ceanomalysubscription.CeAnomalySubscriptionConfig{
+			Name:           jsii.String(resourceName(config, "aws_ce_anomaly_subscription", "costs")),
+			Frequency:      jsii.String(frequency),
+			MonitorArnList: &[]*string{monitor.Arn()},
+			Subscriber:     subscribers,
+			ThresholdExpression: &ceanomalysubscription.CeAnomalySubscriptionThresholdExpression{
+				Dimension: &ceanomalysubscription.CeAnomalySubscriptionThresholdExpressionDimension{
+					Key:          jsii.String("ANOMALY_TOTAL_IMPACT_ABSOLUTE"),
+					MatchOptions: jsii.Strings("GREATER_THAN_OR_EQUAL"),
+					Values:       jsii.Strings(formatAmount(anomaly.Threshold)),
+				},
+			},
+		})
+
+		cdktf.NewTerraformOutput(stack, jsii.String("cost_anomaly_monitor_arn"), &cdktf.TerraformOutputConfig{
+			Value:       monitor.Arn(),
+			Description: jsii.String("The Cost Anomaly Detection monitor of the project"),
+		})
+	}
+
+	fmt.Printf("  ✓ Budget of %s USD a month with %d alert(s)\n", formatAmount(budgets.MonthlyLimit), len(notifications))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// BuildTagsConfig stamps every taggable resource with where its config came from
+type BuildTagsConfig struct {
+	// Exclude leaves tags out, e.g. DeployedAt, which otherwise changes every resource on each apply
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// buildTagNames are the tags set by the build_tags section, in the order they are documented
+var buildTagNames = []string{"GitCommit", "GitRepository", "ConfigHash", "DeployedAt"}
+
+func (b *BuildTagsConfig) validate() error {
+	for _, name := range b.Exclude {
+		if !slices.Contains(buildTagNames, name) {
+			return fmt.Errorf("exclude: %w", invalidValue(name, closestMatch(name, buildTagNames),
+				"unknown tag %q (tags are %s)", name, strings.Join(buildTagNames, ", ")))
+		}
+	}
+	return nil
+}
+
+// buildMetadata returns the build tag values. It is a variable so snapshots can pin the values,
+// which otherwise change on every commit and every run.
+var buildMetadata = func(config Config) map[string]string {
+	return map[string]string{
+		"GitCommit":     gitOutput("rev-parse", "HEAD"),
+		"GitRepository": withoutCredentials(gitOutput("config", "--get", "remote.origin.url")),
+		"ConfigHash":    config.configHash,
+		"DeployedAt":    time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// gitOutput runs git in the working directory and returns its trimmed output, or "unknown" when
+// git isn't installed or this isn't a repository
+func gitOutput(args ...string) string {
+	output, err := exec.Command("git", args...).Output()
+	if value := strings.TrimSpace(string(output)); err == nil && value != "" {
+		return value
+	}
+	return "unknown"
+}
+
+// withoutCredentials drops a user and password from a remote URL, as CI checkouts often have a
+// token in it. scp-style remotes such as git@github.com:org/repo.git are kept as they are.
+func withoutCredentials(remote string) string {
+	parsed, err := url.Parse(remote)
+	if err != nil || parsed.User == nil || parsed.Host == "" {
+		return remote
+	}
+	parsed.User = nil
+	return parsed.String()
+}
+
+// taggable is implemented by the resources that have a tags map
+type taggable interface {
+	SetTags(val *map[string]*string)
+}
+
+// buildTagsAspect adds the build tags to every taggable resource of a stack, replacing a tag of
+// the same name set by the resource itself
+type buildTagsAspect struct {
+	tags map[string]string
+}
+
+func (a *buildTagsAspect) Visit(node constructs.IConstruct) {
+	resource, ok := node.(cdktf.TerraformResource)
+	if !ok {
+		return
+	}
+	if _, ok := resource.(taggable); !ok {
+		return
+	}
+	for _, name := range buildTagNames {
+		if value, ok := a.tags[name]; ok {
+			resource.AddOverride(jsii.String("tags."+name), value)
+		}
+	}
+}
+
+// addBuildTags registers the build tags aspect on every stack of the app
+func addBuildTags(app cdktf.App, config Config) {
+	tags := buildMetadata(config)
+	for _, name := range config.BuildTags.Exclude {
+		delete(tags, name)
+	}
+	for _, child := range *app.Node().Children() {
+		if stack, ok := child.(cdktf.TerraformStack); ok {
+			cdktf.Aspects_Of(stack).Add(&buildTagsAspect{tags: tags})
+		}
+	}
+	fmt.Printf("  ✓ %d build tag(s) on every taggable resource\n", len(tags))
+}
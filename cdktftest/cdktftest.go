@@ -0,0 +1,155 @@
+// Package cdktftest wraps the cdktf Testing helpers for unit tests of the stack builders:
+//
+//	app := cdktftest.App(t)
+//	stack := cdktf.NewTerraformStack(app, jsii.String("test"))
+//	provider.NewAwsProvider(stack, jsii.String("aws"), &provider.AwsProviderConfig{Region: jsii.String("us-west-2")})
+//	addAthena(stack, config, bucket)
+//	cdktftest.AssertResource(t, stack, "aws_athena_workgroup", map[string]interface{}{
+//		"state": "ENABLED",
+//		"configuration": map[string]interface{}{"enforce_workgroup_configuration": true},
+//	})
+//
+// Properties are matched partially: nested objects and lists only need to contain what is given.
+package cdktftest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// App returns a test app with the same context as cdktf.json and a stubbed cdktf version, so
+// logical IDs match what `cdktf synth` writes
+func App(t testing.TB) cdktf.App {
+	t.Helper()
+	return cdktf.Testing_App(&cdktf.TestingAppConfig{
+		StubVersion: jsii.Bool(true),
+		Context: &map[string]interface{}{
+			"excludeStackIdFromLogicalIds": "true",
+			"allowSepCharsInLogicalIds":    "true",
+		},
+	})
+}
+
+// Synth returns the Terraform JSON of a stack, failing the test if the stack doesn't validate
+func Synth(t testing.TB, stack cdktf.TerraformStack) string {
+	t.Helper()
+	var synthesized string
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("synthesizing %s: %v", *stack.Node().Id(), r)
+			}
+		}()
+		synthesized = *cdktf.Testing_Synth(stack, jsii.Bool(true))
+	}()
+	return synthesized
+}
+
+// Resources returns the synthesized resources of one type, keyed by logical ID, for checks the
+// assertions below don't cover
+func Resources(t testing.TB, stack cdktf.TerraformStack, resourceType string) map[string]map[string]interface{} {
+	t.Helper()
+	return blocks(t, Synth(t, stack), "resource", resourceType)
+}
+
+func blocks(t testing.TB, synthesized string, kind string, blockType string) map[string]map[string]interface{} {
+	t.Helper()
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(synthesized), &document); err != nil {
+		t.Fatalf("parsing synthesized stack: %v", err)
+	}
+	var byType map[string]map[string]map[string]interface{}
+	if raw, ok := document[kind]; ok {
+		if err := json.Unmarshal(raw, &byType); err != nil {
+			t.Fatalf("parsing synthesized %s blocks: %v", kind, err)
+		}
+	}
+	return byType[blockType]
+}
+
+// describe lists the synthesized blocks of a type for failure messages
+func describe(blocks map[string]map[string]interface{}) string {
+	if len(blocks) == 0 {
+		return "none"
+	}
+	data, _ := json.MarshalIndent(blocks, "", "  ")
+	return string(data)
+}
+
+// AssertResource fails the test unless the stack has a resource of the type with at least the
+// given properties; nil props only checks that one exists
+func AssertResource(t testing.TB, stack cdktf.TerraformStack, resourceType string, props map[string]interface{}) {
+	t.Helper()
+	synthesized := Synth(t, stack)
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+	if !*cdktf.Testing_ToHaveResourceWithProperties(jsii.String(synthesized), jsii.String(resourceType), &props) {
+		t.Errorf("no %s with properties %v; synthesized %s: %s", resourceType, props, resourceType,
+			describe(blocks(t, synthesized, "resource", resourceType)))
+	}
+}
+
+// AssertNoResource fails the test if the stack has any resource of the type
+func AssertNoResource(t testing.TB, stack cdktf.TerraformStack, resourceType string) {
+	t.Helper()
+	if found := Resources(t, stack, resourceType); len(found) > 0 {
+		t.Errorf("expected no %s, synthesized: %s", resourceType, describe(found))
+	}
+}
+
+// AssertResourceCount fails the test unless the stack has exactly count resources of the type
+func AssertResourceCount(t testing.TB, stack cdktf.TerraformStack, resourceType string, count int) {
+	t.Helper()
+	if found := Resources(t, stack, resourceType); len(found) != count {
+		t.Errorf("expected %d %s, synthesized %d", count, resourceType, len(found))
+	}
+}
+
+// AssertDataSource fails the test unless the stack has a data source of the type with at least the
+// given properties
+func AssertDataSource(t testing.TB, stack cdktf.TerraformStack, dataSourceType string, props map[string]interface{}) {
+	t.Helper()
+	synthesized := Synth(t, stack)
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+	if !*cdktf.Testing_ToHaveDataSourceWithProperties(jsii.String(synthesized), jsii.String(dataSourceType), &props) {
+		t.Errorf("no data source %s with properties %v; synthesized: %s", dataSourceType, props,
+			describe(blocks(t, synthesized, "data", dataSourceType)))
+	}
+}
+
+// AssertProvider fails the test unless the stack configures the provider with at least the given
+// properties
+func AssertProvider(t testing.TB, stack cdktf.TerraformStack, providerType string, props map[string]interface{}) {
+	t.Helper()
+	if props == nil {
+		props = map[string]interface{}{}
+	}
+	if !*cdktf.Testing_ToHaveProviderWithProperties(jsii.String(Synth(t, stack)), jsii.String(providerType), &props) {
+		t.Errorf("no provider %s with properties %v", providerType, props)
+	}
+}
+
+// AssertOutput fails the test unless the stack declares the output, and returns its value
+func AssertOutput(t testing.TB, stack cdktf.TerraformStack, name string) interface{} {
+	t.Helper()
+	var document struct {
+		Output map[string]struct {
+			Value interface{} `json:"value"`
+		} `json:"output"`
+	}
+	if err := json.Unmarshal([]byte(Synth(t, stack)), &document); err != nil {
+		t.Fatalf("parsing synthesized stack: %v", err)
+	}
+	output, ok := document.Output[name]
+	if !ok {
+		t.Errorf("no output %q", name)
+		return nil
+	}
+	return output.Value
+}
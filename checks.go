@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// CheckConfig is a Terraform check block: assertions Terraform evaluates on every plan and apply,
+// reporting a failure as a warning without blocking the run. A condition can use output.<name> to
+// read an output of the stack, as in ${startswith(output.bucket_name, "my-app-")}.
+type CheckConfig struct {
+	Name   string      `json:"name"`
+	Assert []Assertion `json:"assert"`
+}
+
+// Assertion is a condition, a Terraform expression in ${...}, and the message shown when it is false
+type Assertion struct {
+	Condition    string `json:"condition"`
+	ErrorMessage string `json:"error_message"`
+}
+
+func (a Assertion) validate() error {
+	if !strings.HasPrefix(a.Condition, "${") || !strings.HasSuffix(a.Condition, "}") {
+		return fmt.Errorf("condition %q must be an expression in ${...}", a.Condition)
+	}
+	if a.ErrorMessage == "" {
+		return fmt.Errorf("condition %s has no error_message", a.Condition)
+	}
+	return nil
+}
+
+// outputReference matches output.<name> in a condition, which Terraform itself doesn't allow
+var outputReference = regexp.MustCompile(`(^|[^A-Za-z0-9_.])output\.([A-Za-z_][A-Za-z0-9_-]*)`)
+
+// addressReference matches the resource and data source addresses in an expression
+var addressReference = regexp.MustCompile(`(^|[^A-Za-z0-9_.])((?:data\.)?[a-z][a-z0-9_]*\.[A-Za-z_][A-Za-z0-9_-]*)`)
+
+func validateChecks(checks []CheckConfig) error {
+	names := map[string]bool{}
+	for _, check := range checks {
+		if !blockLabel.MatchString(check.Name) {
+			return fmt.Errorf("check name %q must be letters, digits, _ or -", check.Name)
+		}
+		if names[check.Name] {
+			return fmt.Errorf("check %s is declared twice", check.Name)
+		}
+		names[check.Name] = true
+		if len(check.Assert) == 0 {
+			return fmt.Errorf("check %s has no assert", check.Name)
+		}
+		for _, assertion := range check.Assert {
+			if err := assertion.validate(); err != nil {
+				return fmt.Errorf("check %s: %w", check.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// synthesizedBlocks is the part of a synthesized stack checks refer to
+type synthesizedBlocks struct {
+	Resource map[string]map[string]interface{} `json:"resource"`
+	Data     map[string]map[string]interface{} `json:"data"`
+	Output   map[string]struct {
+		Value interface{} `json:"value"`
+	} `json:"output"`
+}
+
+// has reports whether an address names a resource or data source of the stack
+func (b synthesizedBlocks) has(address string) bool {
+	blocks := b.Resource
+	if rest, ok := strings.CutPrefix(address, "data."); ok {
+		blocks, address = b.Data, rest
+	}
+	resourceType, name, _ := strings.Cut(address, ".")
+	_, ok := blocks[resourceType][name]
+	return ok
+}
+
+// resolveOutputs replaces output.<name> with the expression of the stack's output. ok is false
+// when the condition names an output the stack doesn't have.
+func (b synthesizedBlocks) resolveOutputs(condition string) (string, bool) {
+	ok := true
+	resolved := outputReference.ReplaceAllStringFunc(condition, func(match string) string {
+		parts := outputReference.FindStringSubmatch(match)
+		output, found := b.Output[parts[2]]
+		value, isString := output.Value.(string)
+		expression, isExpression := strings.CutPrefix(value, "${")
+		if !found || !isString || !isExpression {
+			ok = false
+			return match
+		}
+		return parts[1] + "(" + strings.TrimSuffix(expression, "}") + ")"
+	})
+	return resolved, ok
+}
+
+// addChecks places each check in the stack whose resources or outputs it refers to
+func addChecks(app cdktf.App, checks []CheckConfig) error {
+	documents := stackDocuments(app)
+	for _, check := range checks {
+		var placed []string
+		for _, document := range documents {
+			var blocks synthesizedBlocks
+			json.Unmarshal([]byte(document.json), &blocks)
+
+			var assertions []map[string]interface{}
+			refersToStack := true
+			for _, assertion := range check.Assert {
+				condition, ok := blocks.resolveOutputs(assertion.Condition)
+				mentions := false
+				for _, match := range addressReference.FindAllStringSubmatch(condition, -1) {
+					if blocks.has(match[2]) {
+						mentions = true
+					}
+				}
+				if !ok || !mentions {
+					refersToStack = false
+					break
+				}
+				assertions = append(assertions, map[string]interface{}{
+					"condition":     condition,
+					"error_message": assertion.ErrorMessage,
+				})
+			}
+			if !refersToStack {
+				continue
+			}
+			document.stack.AddOverride(jsii.String("check."+check.Name+".assert"), assertions)
+			placed = append(placed, *document.stack.Node().Id())
+		}
+
+		switch {
+		case len(placed) == 0:
+			return fmt.Errorf("checks: %s doesn't refer to the resources or outputs of any one stack", check.Name)
+		case len(placed) > 1:
+			return fmt.Errorf("checks: %s matches several stacks (%s); refer to a resource only one of them has",
+				check.Name, strings.Join(placed, ", "))
+		}
+		fmt.Printf("  ✓ Check %s in %s\n", check.Name, placed[0])
+	}
+	return nil
+}
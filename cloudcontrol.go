@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// CloudControlResource creates any resource type the CloudFormation registry knows through the
+// Cloud Control API, with the awscc provider, for services the config doesn't model yet. Its
+// properties are passed as they are, in the type's CloudFormation schema; they can refer to other
+// resources with ${...}.
+type CloudControlResource struct {
+	TypeName   string                 `json:"type_name"` // e.g. AWS::Scheduler::ScheduleGroup
+	Properties map[string]interface{} `json:"properties"`
+	// RoleARN is a role Cloud Control assumes to create the resource, instead of the caller's credentials
+	RoleARN   string   `json:"role_arn"`
+	DependsOn []string `json:"depends_on"` // resource addresses in the same stack
+}
+
+// defaultAWSCCVersion is the awscc provider constraint unless provider_versions sets one
+const defaultAWSCCVersion = "~> 1.0"
+
+// cloudControlTypeName matches a CloudFormation resource type, such as AWS::Scheduler::ScheduleGroup
+var cloudControlTypeName = regexp.MustCompile(`^[A-Za-z0-9]+::[A-Za-z0-9]+::[A-Za-z0-9]+$`)
+
+func validateCloudControl(resources map[string]CloudControlResource) error {
+	for _, name := range slices.Sorted(maps.Keys(resources)) {
+		resource := resources[name]
+		if !blockLabel.MatchString(name) {
+			return fmt.Errorf("name %q must be letters, digits, _ or -", name)
+		}
+		if !cloudControlTypeName.MatchString(resource.TypeName) {
+			return invalidValue(resource.TypeName, "use the CloudFormation type, e.g. AWS::Scheduler::ScheduleGroup",
+				"%s: type_name %q is not a resource type like AWS::Service::Resource", name, resource.TypeName)
+		}
+		if resource.RoleARN != "" && !iamRoleARNPattern.MatchString(resource.RoleARN) {
+			return fmt.Errorf("%s: role_arn %q is not an IAM role ARN", name, resource.RoleARN)
+		}
+		if err := validateDependsOn(name, resource.DependsOn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// desiredState renders properties as the JSON document awscc takes. HTML characters are left
+// as they are, so properties such as policies read the same in the plan.
+func desiredState(properties map[string]interface{}) (string, error) {
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	var state bytes.Buffer
+	encoder := json.NewEncoder(&state)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(properties); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(state.String()), nil
+}
+
+// addCloudControl creates each resource as awscc_cloudcontrolapi_resource.<name>, with an awscc
+// provider in the config's region that assumes the environment's deploy role like the aws one
+func addCloudControl(app cdktf.App, stack cdktf.TerraformStack, config Config) error {
+	settings := map[string]interface{}{"region": config.Region}
+	environment := config.Environments[config.Environment]
+	if environment.DeployRoleARN != "" {
+		assumeRole := map[string]interface{}{"role_arn": environment.DeployRoleARN}
+		if environment.DeployRoleExternalID != "" {
+			assumeRole["external_id"] = environment.DeployRoleExternalID
+		}
+		if environment.DeployRoleSessionName != "" {
+			assumeRole["session_name"] = sessionName(stack, environment.DeployRoleSessionName)
+		}
+		if len(environment.DeployRoleSessionTags) > 0 {
+			assumeRole["tags"] = environment.DeployRoleSessionTags
+		}
+		settings["assume_role"] = assumeRole
+	}
+	addRawProvider(stack, config, "awscc", "hashicorp/awscc", defaultAWSCCVersion, settings)
+
+	var resources map[string][]cdktf.TerraformResource
+	for _, name := range slices.Sorted(maps.Keys(config.CloudControl)) {
+		resource := config.CloudControl[name]
+		state, err := desiredState(resource.Properties)
+		if err != nil {
+			return fmt.Errorf("cloudcontrol: %s: %w", name, err)
+		}
+		attributes := map[string]interface{}{
+			"type_name":     resource.TypeName,
+			"desired_state": state,
+		}
+		if resource.RoleARN != "" {
+			attributes["role_arn"] = resource.RoleARN
+		}
+
+		var dependencies []*string
+		for _, address := range resource.DependsOn {
+			if resources == nil {
+				resources = resourcesByAddress(app)
+			}
+			inStack := slices.ContainsFunc(resources[address], func(other cdktf.TerraformResource) bool {
+				return *cdktf.TerraformStack_Of(other).Node().Id() == *stack.Node().Id()
+			})
+			if !inStack {
+				return fmt.Errorf("cloudcontrol: %s: depends_on %s, which isn't in stack %s", name, address, *stack.Node().Id())
+			}
+			dependencies = append(dependencies, jsii.String(address))
+		}
+		if stack.Node().TryFindChild(jsii.String(name)) != nil {
+			return fmt.Errorf("cloudcontrol: %s: the stack already has a construct named %s", name, name)
+		}
+		created := newRawResource(stack, "awscc_cloudcontrolapi_resource", name, attributes)
+		if len(dependencies) > 0 {
+			created.SetDependsOn(&dependencies)
+		}
+	}
+
+	fmt.Printf("  ✓ %d Cloud Control resource(s)\n", len(config.CloudControl))
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// CloudflareConfig manages DNS records and cache rules in a Cloudflare zone, for public DNS that
+// lives in Cloudflare rather than Route 53. The API token comes from CLOUDFLARE_API_TOKEN.
+type CloudflareConfig struct {
+	Zone       string                `json:"zone"`       // zone name, e.g. example.com
+	ZoneID     string                `json:"zone_id"`    // skips looking the zone up by name
+	AccountID  string                `json:"account_id"` // narrows the zone lookup to one account
+	Records    []CloudflareRecord    `json:"records"`
+	CacheRules []CloudflareCacheRule `json:"cache_rules"`
+}
+
+// CloudflareRecord is a DNS record. Content is a literal or a ${...} reference, such as
+// ${remote.edge.alb_dns_name} or the dns_name of a resource in the same stack.
+type CloudflareRecord struct {
+	Name    string `json:"name"` // relative to the zone; @ is the apex
+	Type    string `json:"type"` // A, AAAA, CNAME or TXT
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"` // seconds, 60-86400; defaults to automatic, which proxied records require
+	Proxied bool   `json:"proxied"`
+	Comment string `json:"comment"`
+}
+
+// CloudflareCacheRule sets the cache settings of the requests matching a rule expression
+type CloudflareCacheRule struct {
+	Description string `json:"description"`
+	Expression  string `json:"expression"` // e.g. starts_with(http.request.uri.path, "/static/")
+	Bypass      bool   `json:"bypass"`     // don't cache matching requests
+	EdgeTTL     int    `json:"edge_ttl"`   // seconds, overriding the origin's Cache-Control
+	BrowserTTL  int    `json:"browser_ttl"`
+}
+
+// defaultCloudflareVersion is the cloudflare provider constraint unless provider_versions sets one
+const defaultCloudflareVersion = "~> 5.0"
+
+var (
+	cloudflareRecordTypes = []string{"A", "AAAA", "CNAME", "TXT"}
+	cloudflareIDPattern   = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	recordNamePattern     = regexp.MustCompile(`^(@|(\*\.)?[a-z0-9_]([a-z0-9_-]*[a-z0-9])?(\.[a-z0-9_]([a-z0-9_-]*[a-z0-9])?)*)$`)
+	// logicalIDInvalid matches what a logical id made from a name can't contain
+	logicalIDInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+func (r *CloudflareRecord) validate() error {
+	if !recordNamePattern.MatchString(r.Name) {
+		return fmt.Errorf("name %q must be @ or a lowercase name relative to the zone", r.Name)
+	}
+	if !slices.Contains(cloudflareRecordTypes, r.Type) {
+		return fmt.Errorf("type %q must be one of %s", r.Type, strings.Join(cloudflareRecordTypes, ", "))
+	}
+	if r.Content == "" {
+		return fmt.Errorf("content is required")
+	}
+	if r.Proxied && r.Type == "TXT" {
+		return fmt.Errorf("TXT records can't be proxied")
+	}
+	if r.Proxied && r.TTL != 0 {
+		return fmt.Errorf("proxied records have an automatic ttl")
+	}
+	if r.TTL != 0 && (r.TTL < 60 || r.TTL > 86400) {
+		return fmt.Errorf("ttl %d must be between 60 and 86400 seconds", r.TTL)
+	}
+	return nil
+}
+
+func (c *CloudflareCacheRule) validate() error {
+	if c.Expression == "" {
+		return fmt.Errorf("expression is required")
+	}
+	if c.Bypass && (c.EdgeTTL != 0 || c.BrowserTTL != 0) {
+		return fmt.Errorf("bypass can't be combined with edge_ttl or browser_ttl")
+	}
+	if c.EdgeTTL < 0 || c.BrowserTTL < 0 {
+		return fmt.Errorf("edge_ttl and browser_ttl can't be negative")
+	}
+	return nil
+}
+
+func (c *CloudflareConfig) validate() error {
+	if c.Zone == "" {
+		return fmt.Errorf("zone is required")
+	}
+	if c.ZoneID != "" && !cloudflareIDPattern.MatchString(c.ZoneID) {
+		return fmt.Errorf("zone_id %q must be 32 hex digits", c.ZoneID)
+	}
+	if c.AccountID != "" && (c.ZoneID != "" || !cloudflareIDPattern.MatchString(c.AccountID)) {
+		return fmt.Errorf("account_id must be 32 hex digits and is only used without zone_id")
+	}
+	if len(c.Records) == 0 && len(c.CacheRules) == 0 {
+		return fmt.Errorf("at least one record or cache rule is required")
+	}
+
+	ids := map[string]bool{}
+	for i, record := range c.Records {
+		if err := record.validate(); err != nil {
+			return fmt.Errorf("records[%d]: %w", i, err)
+		}
+		if ids[record.id()] {
+			return fmt.Errorf("records[%d]: duplicate %s record %s", i, record.Type, record.Name)
+		}
+		ids[record.id()] = true
+	}
+	for i, rule := range c.CacheRules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("cache_rules[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// id is the record's logical id, e.g. record_www_cname, record_apex_a or record_wildcard_api_a
+func (r *CloudflareRecord) id() string {
+	name := strings.Replace(r.Name, "*", "wildcard", 1)
+	if name == "@" {
+		name = "apex"
+	}
+	name = strings.Trim(logicalIDInvalid.ReplaceAllString(name, "_"), "_")
+	return "record_" + name + "_" + strings.ToLower(r.Type)
+}
+
+// rule is the ruleset rule of a cache rule entry
+func (c *CloudflareCacheRule) rule() map[string]interface{} {
+	parameters := map[string]interface{}{"cache": !c.Bypass}
+	if c.EdgeTTL > 0 {
+		parameters["edge_ttl"] = map[string]interface{}{"mode": "override_origin", "default": c.EdgeTTL}
+	}
+	if c.BrowserTTL > 0 {
+		parameters["browser_ttl"] = map[string]interface{}{"mode": "override_origin", "default": c.BrowserTTL}
+	}
+	rule := map[string]interface{}{
+		"action":            "set_cache_settings",
+		"expression":        c.Expression,
+		"action_parameters": parameters,
+		"enabled":           true,
+	}
+	if c.Description != "" {
+		rule["description"] = c.Description
+	}
+	return rule
+}
+
+// addCloudflare creates the records and the zone's cache rules. The zone is looked up by name
+// unless zone_id is given. All cache rules live in the zone's one http_request_cache_settings
+// ruleset, so this section must be the only owner of the zone's cache rules.
+func addCloudflare(stack cdktf.TerraformStack, config Config) {
+	cloudflare := config.Cloudflare
+	addRawProvider(stack, config, "cloudflare", "cloudflare/cloudflare", defaultCloudflareVersion, map[string]interface{}{})
+
+	zoneID := cloudflare.ZoneID
+	if zoneID == "" {
+		filter := map[string]interface{}{"name": cloudflare.Zone}
+		if cloudflare.AccountID != "" {
+			filter["account"] = map[string]interface{}{"id": cloudflare.AccountID}
+		}
+		zone := newRawDataSource(stack, "cloudflare_zone", "zone", map[string]interface{}{"filter": filter})
+		zoneID = *zone.GetStringAttribute(jsii.String("id"))
+	}
+
+	for _, record := range cloudflare.Records {
+		ttl := record.TTL
+		if ttl == 0 {
+			ttl = 1 // automatic
+		}
+		// The API returns full names, so they are written out to keep plans clean
+		name := cloudflare.Zone
+		if record.Name != "@" {
+			name = record.Name + "." + cloudflare.Zone
+		}
+		attributes := map[string]interface{}{
+			"zone_id": zoneID,
+			"name":    name,
+			"type":    record.Type,
+			"content": record.Content,
+			"ttl":     ttl,
+			"proxied": record.Proxied,
+		}
+		if record.Comment != "" {
+			attributes["comment"] = record.Comment
+		}
+		newRawResource(stack, "cloudflare_dns_record", record.id(), attributes)
+	}
+
+	if len(cloudflare.CacheRules) > 0 {
+		rules := make([]map[string]interface{}, 0, len(cloudflare.CacheRules))
+		for _, rule := range cloudflare.CacheRules {
+			rules = append(rules, rule.rule())
+		}
+		newRawResource(stack, "cloudflare_ruleset", "cache_rules", map[string]interface{}{
+			"zone_id": zoneID,
+			"name":    resourceName(config, "cloudflare_ruleset", "cache-rules"),
+			"kind":    "zone",
+			"phase":   "http_request_cache_settings",
+			"rules":   rules,
+		})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("cloudflare_zone_id"), &cdktf.TerraformOutputConfig{
+		Value:       zoneID,
+		Description: jsii.String("The ID of the Cloudflare zone"),
+	})
+
+	fmt.Printf("  ✓ Cloudflare zone %s: %d DNS record(s), %d cache rule(s)\n", cloudflare.Zone,
+		len(cloudflare.Records), len(cloudflare.CacheRules))
+}
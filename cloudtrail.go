@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/cloudtrail"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/kmskey"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// CloudTrailConfig describes a multi-region trail delivering to its own log bucket
+type CloudTrailConfig struct {
+	LogsBucket string `json:"logs_bucket"`
+	DataEvents string `json:"data_events"` // All, ReadOnly or WriteOnly; empty records management events only
+}
+
+func (c *CloudTrailConfig) validate() error {
+	if c.LogsBucket == "" {
+		return fmt.Errorf("logs_bucket is required")
+	}
+	switch c.DataEvents {
+	case "", "All", "ReadOnly", "WriteOnly":
+	default:
+		return invalidValue(c.DataEvents, closestMatch(c.DataEvents, []string{"All", "ReadOnly", "WriteOnly"}),
+			"unknown data_events %q (want All, ReadOnly or WriteOnly)", c.DataEvents)
+	}
+	return nil
+}
+
+// addCloudTrail creates a multi-region trail with log file validation, encrypted with a
+// dedicated KMS key, optionally recording object-level events on the config bucket
+func addCloudTrail(stack cdktf.TerraformStack, config Config, dataBucket s3bucket.S3Bucket) {
+	trailConfig := config.CloudTrail
+	account := *accountID(stack)
+
+	trailName := resourceName(config, "aws_cloudtrail", "trail")
+	trailArn := fmt.Sprintf("arn:aws:cloudtrail:%s:%s:trail/%s", config.Region, account, trailName)
+
+	key := kmskey.NewKmsKey(stack, jsii.String("cloudtrail_key"), &kmskey.KmsKeyConfig{
+		Description:       jsii.String("Encrypts CloudTrail logs for " + trailName),
+		EnableKeyRotation: jsii.Bool(true),
+		Policy: jsii.String(policyDocument(
+			map[string]interface{}{
+				"Sid":       "AccountAdmin",
+				"Effect":    "Allow",
+				"Principal": map[string]string{"AWS": "arn:aws:iam::" + account + ":root"},
+				"Action":    "kms:*",
+				"Resource":  "*",
+			},
+			map[string]interface{}{
+				"Sid":       "CloudTrailEncrypt",
+				"Effect":    "Allow",
+				"Principal": map[string]string{"Service": "cloudtrail.amazonaws.com"},
+				"Action":    []string{"kms:GenerateDataKey*", "kms:DescribeKey"},
+				"Resource":  "*",
+				"Condition": map[string]interface{}{
+					"StringEquals": map[string]string{"aws:SourceArn": trailArn},
+					"StringLike":   map[string]string{"kms:EncryptionContext:aws:cloudtrail:arn": "arn:aws:cloudtrail:*:" + account + ":trail/*"},
+				},
+			},
+			map[string]interface{}{
+				"Sid":       "AccountDecrypt",
+				"Effect":    "Allow",
+				"Principal": map[string]string{"AWS": "*"},
+				"Action":    []string{"kms:Decrypt", "kms:ReEncryptFrom"},
+				"Resource":  "*",
+				"Condition": map[string]interface{}{
+					"StringEquals": map[string]string{"kms:CallerAccount": account},
+					"StringLike":   map[string]string{"kms:EncryptionContext:aws:cloudtrail:arn": "arn:aws:cloudtrail:*:" + account + ":trail/*"},
+				},
+			},
+		)),
+	})
+
+	sourceCondition := map[string]interface{}{"StringEquals": map[string]string{"aws:SourceArn": trailArn}}
+	bucket, bucketPolicy := newLogBucket(stack, "cloudtrail_bucket", resourceName(config, "aws_s3_bucket", trailConfig.LogsBucket), config,
+		func(bucket s3bucket.S3Bucket) []map[string]interface{} {
+			return []map[string]interface{}{
+				{
+					"Sid":       "CloudTrailAclCheck",
+					"Effect":    "Allow",
+					"Principal": map[string]string{"Service": "cloudtrail.amazonaws.com"},
+					"Action":    "s3:GetBucketAcl",
+					"Resource":  *bucket.Arn(),
+					"Condition": sourceCondition,
+				},
+				{
+					"Sid":       "CloudTrailWrite",
+					"Effect":    "Allow",
+					"Principal": map[string]string{"Service": "cloudtrail.amazonaws.com"},
+					"Action":    "s3:PutObject",
+					"Resource":  *bucket.Arn() + "/AWSLogs/" + account + "/*",
+					"Condition": map[string]interface{}{"StringEquals": map[string]string{
+						"s3:x-amz-acl":  "bucket-owner-full-control",
+						"aws:SourceArn": trailArn,
+					}},
+				},
+			}
+		})
+
+	trail := &cloudtrail.CloudtrailConfig{
+		Name:                       jsii.String(trailName),
+		S3BucketName:               bucket.Bucket(),
+		KmsKeyId:                   key.Arn(),
+		IsMultiRegionTrail:         jsii.Bool(true),
+		IncludeGlobalServiceEvents: jsii.Bool(true),
+		EnableLogFileValidation:    jsii.Bool(true),
+		EnableLogging:              jsii.Bool(true),
+		DependsOn:                  &[]cdktf.ITerraformDependable{bucketPolicy},
+	}
+	if trailConfig.DataEvents != "" {
+		trail.EventSelector = &[]*cloudtrail.CloudtrailEventSelector{
+			{
+				ReadWriteType:           jsii.String(trailConfig.DataEvents),
+				IncludeManagementEvents: jsii.Bool(true),
+				DataResource: &[]*cloudtrail.CloudtrailEventSelectorDataResource{
+					{
+						Type:   jsii.String("AWS::S3::Object"),
+						Values: jsii.Strings(*dataBucket.Arn() + "/"),
+					},
+				},
+			},
+		}
+	}
+	cloudtrail.NewCloudtrail(stack, jsii.String("cloudtrail"), trail)
+
+	cdktf.NewTerraformOutput(stack, jsii.String("cloudtrail_bucket_name"), &cdktf.TerraformOutputConfig{
+		Value:       bucket.Bucket(),
+		Description: jsii.String("The bucket CloudTrail delivers logs to"),
+	})
+
+	fmt.Println("  ✓ CloudTrail multi-region trail with log file validation")
+}
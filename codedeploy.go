@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/cloudwatchmetricalarm"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/codedeployapp"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/codedeploydeploymentgroup"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// CodeDeployConfig describes blue/green deployments of ECS services behind an ALB, one deployment
+// group per service by name. This config doesn't define ECS services or load balancers, so the
+// clusters, services, target groups and listeners already exist; the services must use the
+// CODE_DEPLOY deployment controller.
+type CodeDeployConfig struct {
+	Services map[string]CodeDeployService `json:"services"`
+}
+
+type CodeDeployService struct {
+	Cluster string `json:"cluster"` // the ECS cluster name
+	Service string `json:"service"` // the ECS service name; defaults to the deployment group's
+	// TargetGroups are the names of the two ALB target groups the service switches between; the
+	// first one serves traffic before the first deployment
+	TargetGroups []string `json:"target_groups"`
+	ListenerArn  string   `json:"listener_arn"` // the production listener
+	// TestListenerArn routes test traffic to the replacement tasks before production traffic moves
+	TestListenerArn string `json:"test_listener_arn"`
+	// Strategy is how production traffic moves to the replacement tasks: all_at_once (the
+	// default), canary_10_5, canary_10_15, linear_10_1 or linear_10_3
+	Strategy string `json:"strategy"`
+	// TerminationWaitMinutes keeps the original tasks this long after a deployment succeeds, for
+	// a quick rollback, 5 by default
+	TerminationWaitMinutes float64 `json:"termination_wait_minutes"`
+	// Alarms are existing CloudWatch alarm names; a deployment rolls back when any of them fires
+	Alarms []string `json:"alarms"`
+	// Max5xxPerMinute creates an alarm on the load balancer's target 5XX responses that rolls the
+	// deployment back when more than this many are returned in a minute
+	Max5xxPerMinute float64 `json:"max_5xx_per_minute"`
+}
+
+func (s CodeDeployService) service(name string) string {
+	if s.Service != "" {
+		return s.Service
+	}
+	return name
+}
+
+// codedeployStrategies are the predefined ECS deployment configurations by strategy
+var codedeployStrategies = map[string]string{
+	"all_at_once":  "CodeDeployDefault.ECSAllAtOnce",
+	"canary_10_5":  "CodeDeployDefault.ECSCanary10Percent5Minutes",
+	"canary_10_15": "CodeDeployDefault.ECSCanary10Percent15Minutes",
+	"linear_10_1":  "CodeDeployDefault.ECSLinear10PercentEvery1Minutes",
+	"linear_10_3":  "CodeDeployDefault.ECSLinear10PercentEvery3Minutes",
+}
+
+// albListenerArn matches an ALB listener ARN, capturing the load balancer's name and ID
+var albListenerArn = regexp.MustCompile(`^arn:aws[a-z-]*:elasticloadbalancing:[a-z0-9-]+:\d{12}:listener/app/([^/]+/[^/]+)/[^/]+$`)
+
+func (c *CodeDeployConfig) validate() error {
+	if len(c.Services) == 0 {
+		return fmt.Errorf("at least one service is required")
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Services)) {
+		if err := c.Services[name].validate(name); err != nil {
+			return fmt.Errorf("services.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (s CodeDeployService) validate(name string) error {
+	if !nameSegmentPattern.MatchString(name) {
+		return fmt.Errorf("deployment group names must be lowercase letters, digits and hyphens")
+	}
+	if s.Cluster == "" {
+		return fmt.Errorf("cluster is required")
+	}
+	if len(s.TargetGroups) != 2 || s.TargetGroups[0] == s.TargetGroups[1] {
+		return fmt.Errorf("target_groups must be the names of two different target groups")
+	}
+	if !albListenerArn.MatchString(s.ListenerArn) {
+		return fmt.Errorf("listener_arn %q is not an ALB listener ARN", s.ListenerArn)
+	}
+	if s.TestListenerArn != "" {
+		if !albListenerArn.MatchString(s.TestListenerArn) {
+			return fmt.Errorf("test_listener_arn %q is not an ALB listener ARN", s.TestListenerArn)
+		}
+		if s.TestListenerArn == s.ListenerArn {
+			return fmt.Errorf("test_listener_arn must be a listener other than listener_arn")
+		}
+	}
+	if _, ok := codedeployStrategies[s.Strategy]; s.Strategy != "" && !ok {
+		strategies := slices.Sorted(maps.Keys(codedeployStrategies))
+		return invalidValue(s.Strategy, closestMatch(s.Strategy, strategies),
+			"unknown strategy %q (want %s)", s.Strategy, strings.Join(strategies, ", "))
+	}
+	if s.TerminationWaitMinutes < 0 || s.TerminationWaitMinutes > 2880 {
+		return fmt.Errorf("termination_wait_minutes must be between 0 and 2880")
+	}
+	if s.Max5xxPerMinute < 0 {
+		return fmt.Errorf("max_5xx_per_minute must be positive")
+	}
+	// A deployment group watches at most 10 alarms, counting the 5XX alarm
+	alarms := len(s.Alarms)
+	if s.Max5xxPerMinute > 0 {
+		alarms++
+	}
+	if alarms > 10 {
+		return fmt.Errorf("a deployment group rolls back on at most 10 alarms")
+	}
+	return nil
+}
+
+// addCodeDeploy creates the CodeDeploy application, its service role and a blue/green deployment
+// group per service, with the alarms that roll it back
+func addCodeDeploy(stack cdktf.TerraformStack, config Config) {
+	codedeploy := config.CodeDeploy
+
+	app := codedeployapp.NewCodedeployApp(stack, jsii.String("codedeploy"), &codedeployapp.CodedeployAppConfig{
+		Name:            jsii.String(resourceName(config, "aws_codedeploy_app", "ecs")),
+		ComputePlatform: jsii.String("ECS"),
+	})
+	role := newServiceRole(stack, "codedeploy_role", resourceName(config, "aws_iam_role", "codedeploy"),
+		"codedeploy.amazonaws.com", config, "arn:aws:iam::aws:policy/AWSCodeDeployRoleForECS")
+
+	for _, name := range slices.Sorted(maps.Keys(codedeploy.Services)) {
+		settings := codedeploy.Services[name]
+		id := "codedeploy_" + strings.ReplaceAll(name, "-", "_")
+
+		strategy := codedeployStrategies["all_at_once"]
+		if settings.Strategy != "" {
+			strategy = codedeployStrategies[settings.Strategy]
+		}
+		terminationWait := settings.TerminationWaitMinutes
+		if terminationWait == 0 {
+			terminationWait = 5
+		}
+
+		alarms := slices.Clone(settings.Alarms)
+		if settings.Max5xxPerMinute > 0 {
+			// Both target groups sit behind the listener's load balancer, so its 5XX count covers
+			// whichever one the replacement tasks are in
+			loadBalancer := "app/" + albListenerArn.FindStringSubmatch(settings.ListenerArn)[1]
+			alarm := cloudwatchmetricalarm.NewCloudwatchMetricAlarm(stack, jsii.String(id+"_5xx_alarm"), &cloudwatchmetricalarm.CloudwatchMetricAlarmConfig{
+				AlarmName:          jsii.String(resourceName(config, "aws_cloudwatch_metric_alarm", name+"-5xx")),
+				AlarmDescription:   jsii.String("Rolls back deployments of " + settings.service(name) + " returning target 5XX responses"),
+				Namespace:          jsii.String("AWS/ApplicationELB"),
+				MetricName:         jsii.String("HTTPCode_Target_5XX_Count"),
+				Dimensions:         &map[string]*string{"LoadBalancer": jsii.String(loadBalancer)},
+				Statistic:          jsii.String("Sum"),
+				Period:             jsii.Number(60),
+				EvaluationPeriods:  jsii.Number(1),
+				Threshold:          jsii.Number(settings.Max5xxPerMinute),
+				ComparisonOperator: jsii.String("GreaterThanThreshold"),
+				TreatMissingData:   jsii.String("notBreaching"),
+			})
+			alarms = append(alarms, *alarm.AlarmName())
+		}
+
+		rollbackEvents := []string{"DEPLOYMENT_FAILURE"}
+		if len(alarms) > 0 {
+			rollbackEvents = append(rollbackEvents, "DEPLOYMENT_STOP_ON_ALARM")
+		}
+
+		trafficRoute := &codedeploydeploymentgroup.CodedeployDeploymentGroupLoadBalancerInfoTargetGroupPairInfo{
+			ProdTrafficRoute: &codedeploydeploymentgroup.CodedeployDeploymentGroupLoadBalancerInfoTargetGroupPairInfoProdTrafficRoute{
+				ListenerArns: jsii.Strings(settings.ListenerArn),
+			},
+			TargetGroup: []*codedeploydeploymentgroup.CodedeployDeploymentGroupLoadBalancerInfoTargetGroupPairInfoTargetGroup{
+				{Name: jsii.String(settings.TargetGroups[0])},
+				{Name: jsii.String(settings.TargetGroups[1])},
+			},
+		}
+		if settings.TestListenerArn != "" {
+			trafficRoute.TestTrafficRoute = &codedeploydeploymentgroup.CodedeployDeploymentGroupLoadBalancerInfoTargetGroupPairInfoTestTrafficRoute{
+				ListenerArns: jsii.Strings(settings.TestListenerArn),
+			}
+		}
+
+		groupConfig := &codedeploydeploymentgroup.CodedeployDeploymentGroupConfig{
+			AppName:              app.Name(),
+			DeploymentGroupName:  jsii.String(resourceName(config, "aws_codedeploy_deployment_group", name)),
+			ServiceRoleArn:       role.Arn(),
+			DeploymentConfigName: jsii.String(strategy),
+			DeploymentStyle: &codedeploydeploymentgroup.CodedeployDeploymentGroupDeploymentStyle{
+				DeploymentType:   jsii.String("BLUE_GREEN"),
+				DeploymentOption: jsii.String("WITH_TRAFFIC_CONTROL"),
+			},
+			BlueGreenDeploymentConfig: &codedeploydeploymentgroup.CodedeployDeploymentGroupBlueGreenDeploymentConfig{
+				DeploymentReadyOption: &codedeploydeploymentgroup.CodedeployDeploymentGroupBlueGreenDeploymentConfigDeploymentReadyOption{
+					ActionOnTimeout: jsii.String("CONTINUE_DEPLOYMENT"),
+				},
+				TerminateBlueInstancesOnDeploymentSuccess: &codedeploydeploymentgroup.CodedeployDeploymentGroupBlueGreenDeploymentConfigTerminateBlueInstancesOnDeploymentSuccess{
+					Action:                       jsii.String("TERMINATE"),
+					TerminationWaitTimeInMinutes: jsii.Number(terminationWait),
+				},
+			},
+			EcsService: &codedeploydeploymentgroup.CodedeployDeploymentGroupEcsService{
+				ClusterName: jsii.String(settings.Cluster),
+				ServiceName: jsii.String(settings.service(name)),
+			},
+			LoadBalancerInfo: &codedeploydeploymentgroup.CodedeployDeploymentGroupLoadBalancerInfo{TargetGroupPairInfo: trafficRoute},
+			AutoRollbackConfiguration: &codedeploydeploymentgroup.CodedeployDeploymentGroupAutoRollbackConfiguration{
+				Enabled: jsii.Bool(true),
+				Events:  jsii.Strings(rollbackEvents...),
+			},
+		}
+		if len(alarms) > 0 {
+			groupConfig.AlarmConfiguration = &codedeploydeploymentgroup.CodedeployDeploymentGroupAlarmConfiguration{
+				Enabled: jsii.Bool(true),
+				Alarms:  jsii.Strings(alarms...),
+			}
+		}
+		group := codedeploydeploymentgroup.NewCodedeployDeploymentGroup(stack, jsii.String(id), groupConfig)
+
+		cdktf.NewTerraformOutput(stack, jsii.String(id+"_deployment_group_name"), &cdktf.TerraformOutputConfig{
+			Value:       group.DeploymentGroupName(),
+			Description: jsii.String("The deployment group that deploys new task definitions of " + settings.service(name)),
+		})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("codedeploy_application_name"), &cdktf.TerraformOutputConfig{
+		Value:       app.Name(),
+		Description: jsii.String("The CodeDeploy application of the ECS deployment groups"),
+	})
+
+	fmt.Printf("  ✓ CodeDeploy: %d ECS blue/green deployment group(s)\n", len(codedeploy.Services))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// commands are run as `go run . <command>`; most work on an already synthesized cdktf.out
+var commands = map[string]func(args []string) error{
+	"bench":           runBench,
+	"deploy":          runDeploy,
+	"deploy-policy":   runDeployPolicy,
+	"diagram":         runDiagram,
+	"drift":           runDrift,
+	"outputs":         runOutputs,
+	"policy":          runPolicy,
+	"scan":            runScan,
+	"service-catalog": runServiceCatalog,
+	"snapshot":        runSnapshot,
+	"synth-all":       runSynthAll,
+	"telemetry":       runTelemetry,
+	"verify":          runVerify,
+	"workspace":       runWorkspace,
+}
+
+func runCommand(name string, args []string) {
+	command, ok := commands[name]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Printf("Unknown command %q (available: %v)\n", name, names)
+		os.Exit(2)
+	}
+	startTracing()
+	span := startSpan(name)
+	err := command(args)
+	span.finish(err)
+	stopTracing()
+	if err != nil {
+		reportError(err)
+		os.Exit(1)
+	}
+}
+
+// synthesizedStacks returns the stacks of a cdktf output directory that have a cdk.tf.json
+func synthesizedStacks(outdir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(outdir, "stacks"))
+	if err != nil {
+		return nil, fmt.Errorf("reading synthesized stacks (run cdktf synth first): %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(outdir, "stacks", entry.Name(), "cdk.tf.json")); err == nil {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
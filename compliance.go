@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/configconfigrule"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/configconfigurationrecorder"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/configconfigurationrecorderstatus"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/configdeliverychannel"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// ComplianceConfig describes the AWS Config recorder, where it delivers, and which managed rules run
+type ComplianceConfig struct {
+	DeliveryBucket         string   `json:"delivery_bucket"`
+	Rules                  []string `json:"rules"` // managed rule names, e.g. s3-bucket-ssl-requests-only
+	IncludeGlobalResources bool     `json:"include_global_resources"`
+	SnapshotFrequency      string   `json:"snapshot_frequency"` // One_Hour ... TwentyFour_Hours
+}
+
+// managedRules maps the AWS managed rules that run without parameters to their source identifiers.
+// The identifier isn't always the name in upper case, so each one is listed as AWS documents it.
+var managedRules = map[string]string{
+	"access-keys-rotated":                        "ACCESS_KEYS_ROTATED",
+	"acm-certificate-expiration-check":           "ACM_CERTIFICATE_EXPIRATION_CHECK",
+	"alb-http-to-https-redirection-check":        "ALB_HTTP_TO_HTTPS_REDIRECTION_CHECK",
+	"api-gw-execution-logging-enabled":           "API_GW_EXECUTION_LOGGING_ENABLED",
+	"cloud-trail-encryption-enabled":             "CLOUD_TRAIL_ENCRYPTION_ENABLED",
+	"cloud-trail-log-file-validation-enabled":    "CLOUD_TRAIL_LOG_FILE_VALIDATION_ENABLED",
+	"cloudtrail-enabled":                         "CLOUD_TRAIL_ENABLED",
+	"cloudtrail-s3-dataevents-enabled":           "CLOUDTRAIL_S3_DATAEVENTS_ENABLED",
+	"cloudwatch-log-group-encrypted":             "CLOUDWATCH_LOG_GROUP_ENCRYPTED",
+	"cmk-backing-key-rotation-enabled":           "CMK_BACKING_KEY_ROTATION_ENABLED",
+	"db-instance-backup-enabled":                 "DB_INSTANCE_BACKUP_ENABLED",
+	"dynamodb-pitr-enabled":                      "DYNAMODB_PITR_ENABLED",
+	"dynamodb-table-encrypted-kms":               "DYNAMODB_TABLE_ENCRYPTED_KMS",
+	"ebs-snapshot-public-restorable-check":       "EBS_SNAPSHOT_PUBLIC_RESTORABLE_CHECK",
+	"ec2-ebs-encryption-by-default":              "EC2_EBS_ENCRYPTION_BY_DEFAULT",
+	"ec2-imdsv2-check":                           "EC2_IMDSV2_CHECK",
+	"ec2-instance-managed-by-systems-manager":    "EC2_INSTANCE_MANAGED_BY_SSM",
+	"ec2-instance-no-public-ip":                  "EC2_INSTANCE_NO_PUBLIC_IP",
+	"efs-encrypted-check":                        "EFS_ENCRYPTED_CHECK",
+	"eip-attached":                               "EIP_ATTACHED",
+	"elb-logging-enabled":                        "ELB_LOGGING_ENABLED",
+	"encrypted-volumes":                          "ENCRYPTED_VOLUMES",
+	"guardduty-enabled-centralized":              "GUARDDUTY_ENABLED_CENTRALIZED",
+	"iam-password-policy":                        "IAM_PASSWORD_POLICY",
+	"iam-policy-no-statements-with-admin-access": "IAM_POLICY_NO_STATEMENTS_WITH_ADMIN_ACCESS",
+	"iam-root-access-key-check":                  "IAM_ROOT_ACCESS_KEY_CHECK",
+	"iam-user-mfa-enabled":                       "IAM_USER_MFA_ENABLED",
+	"iam-user-no-policies-check":                 "IAM_USER_NO_POLICIES_CHECK",
+	"iam-user-unused-credentials-check":          "IAM_USER_UNUSED_CREDENTIALS_CHECK",
+	"lambda-function-public-access-prohibited":   "LAMBDA_FUNCTION_PUBLIC_ACCESS_PROHIBITED",
+	"mfa-enabled-for-iam-console-access":         "MFA_ENABLED_FOR_IAM_CONSOLE_ACCESS",
+	"multi-region-cloudtrail-enabled":            "MULTI_REGION_CLOUD_TRAIL_ENABLED",
+	"rds-instance-public-access-check":           "RDS_INSTANCE_PUBLIC_ACCESS_CHECK",
+	"rds-multi-az-support":                       "RDS_MULTI_AZ_SUPPORT",
+	"rds-snapshots-public-prohibited":            "RDS_SNAPSHOTS_PUBLIC_PROHIBITED",
+	"rds-storage-encrypted":                      "RDS_STORAGE_ENCRYPTED",
+	"restricted-common-ports":                    "RESTRICTED_INCOMING_TRAFFIC",
+	"restricted-ssh":                             "INCOMING_SSH_DISABLED",
+	"root-account-mfa-enabled":                   "ROOT_ACCOUNT_MFA_ENABLED",
+	"s3-account-level-public-access-blocks":      "S3_ACCOUNT_LEVEL_PUBLIC_ACCESS_BLOCKS",
+	"s3-bucket-logging-enabled":                  "S3_BUCKET_LOGGING_ENABLED",
+	"s3-bucket-public-read-prohibited":           "S3_BUCKET_PUBLIC_READ_PROHIBITED",
+	"s3-bucket-public-write-prohibited":          "S3_BUCKET_PUBLIC_WRITE_PROHIBITED",
+	"s3-bucket-server-side-encryption-enabled":   "S3_BUCKET_SERVER_SIDE_ENCRYPTION_ENABLED",
+	"s3-bucket-ssl-requests-only":                "S3_BUCKET_SSL_REQUESTS_ONLY",
+	"s3-bucket-versioning-enabled":               "S3_BUCKET_VERSIONING_ENABLED",
+	"secretsmanager-rotation-enabled-check":      "SECRETSMANAGER_ROTATION_ENABLED_CHECK",
+	"securityhub-enabled":                        "SECURITYHUB_ENABLED",
+	"sns-encrypted-kms":                          "SNS_ENCRYPTED_KMS",
+	"vpc-default-security-group-closed":          "VPC_DEFAULT_SECURITY_GROUP_CLOSED",
+	"vpc-flow-logs-enabled":                      "VPC_FLOW_LOGS_ENABLED",
+}
+
+func (c *ComplianceConfig) validate() error {
+	if c.DeliveryBucket == "" {
+		return fmt.Errorf("delivery_bucket is required")
+	}
+	names := map[string]bool{}
+	for i, rule := range c.Rules {
+		if _, ok := managedRules[rule]; !ok {
+			return invalidValue(rule, closestMatch(rule, slices.Sorted(maps.Keys(managedRules))),
+				"rules[%d]: unknown managed rule %q", i, rule)
+		}
+		if names[rule] {
+			return fmt.Errorf("rules[%d]: duplicate %q", i, rule)
+		}
+		names[rule] = true
+	}
+	return nil
+}
+
+// addCompliance turns on the Config recorder with a delivery channel to a dedicated bucket
+// and adds the selected managed rules
+func addCompliance(stack cdktf.TerraformStack, config Config) {
+	compliance := config.Compliance
+	account := *accountID(stack)
+
+	bucket, bucketPolicy := newLogBucket(stack, "config_bucket", resourceName(config, "aws_s3_bucket", compliance.DeliveryBucket), config,
+		func(bucket s3bucket.S3Bucket) []map[string]interface{} {
+			return []map[string]interface{}{
+				{
+					"Sid":       "ConfigBucketCheck",
+					"Effect":    "Allow",
+					"Principal": map[string]string{"Service": "config.amazonaws.com"},
+					"Action":    []string{"s3:GetBucketAcl", "s3:ListBucket"},
+					"Resource":  *bucket.Arn(),
+					"Condition": map[string]interface{}{"StringEquals": map[string]string{"AWS:SourceAccount": account}},
+				},
+				{
+					"Sid":       "ConfigDelivery",
+					"Effect":    "Allow",
+					"Principal": map[string]string{"Service": "config.amazonaws.com"},
+					"Action":    "s3:PutObject",
+					"Resource":  *bucket.Arn() + "/AWSLogs/" + account + "/Config/*",
+					"Condition": map[string]interface{}{"StringEquals": map[string]string{
+						"s3:x-amz-acl":      "bucket-owner-full-control",
+						"AWS:SourceAccount": account,
+					}},
+				},
+			}
+		})
+
+	role := newServiceRole(stack, "config_role", resourceName(config, "aws_iam_role", "config"), "config.amazonaws.com", config,
+		"arn:aws:iam::aws:policy/service-role/AWS_ConfigRole")
+
+	recorder := configconfigurationrecorder.NewConfigConfigurationRecorder(stack, jsii.String("config_recorder"),
+		&configconfigurationrecorder.ConfigConfigurationRecorderConfig{
+			Name:    jsii.String(resourceName(config, "aws_config_configuration_recorder", "recorder")),
+			RoleArn: role.Arn(),
+			RecordingGroup: &configconfigurationrecorder.ConfigConfigurationRecorderRecordingGroup{
+				AllSupported:               jsii.Bool(true),
+				IncludeGlobalResourceTypes: jsii.Bool(compliance.IncludeGlobalResources),
+			},
+		})
+
+	frequency := compliance.SnapshotFrequency
+	if frequency == "" {
+		frequency = "TwentyFour_Hours"
+	}
+	channel := configdeliverychannel.NewConfigDeliveryChannel(stack, jsii.String("config_delivery"),
+		&configdeliverychannel.ConfigDeliveryChannelConfig{
+			Name:         jsii.String(resourceName(config, "aws_config_delivery_channel", "delivery")),
+			S3BucketName: bucket.Bucket(),
+			SnapshotDeliveryProperties: &configdeliverychannel.ConfigDeliveryChannelSnapshotDeliveryProperties{
+				DeliveryFrequency: jsii.String(frequency),
+			},
+			DependsOn: &[]cdktf.ITerraformDependable{recorder, bucketPolicy},
+		})
+
+	// Recording can only start once there is somewhere to deliver to
+	status := configconfigurationrecorderstatus.NewConfigConfigurationRecorderStatus(stack,
+		jsii.String("config_recorder_status"),
+		&configconfigurationrecorderstatus.ConfigConfigurationRecorderStatusConfig{
+			Name:      recorder.Name(),
+			IsEnabled: jsii.Bool(true),
+			DependsOn: &[]cdktf.ITerraformDependable{channel},
+		})
+
+	for _, rule := range compliance.Rules {
+		configconfigrule.NewConfigConfigRule(stack, jsii.String("config_rule_"+rule), &configconfigrule.ConfigConfigRuleConfig{
+			Name: jsii.String(resourceName(config, "aws_config_config_rule", rule)),
+			Source: &configconfigrule.ConfigConfigRuleSource{
+				Owner:            jsii.String("AWS"),
+				SourceIdentifier: jsii.String(managedRules[rule]),
+			},
+			DependsOn: &[]cdktf.ITerraformDependable{status},
+		})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("config_bucket_name"), &cdktf.TerraformOutputConfig{
+		Value:       bucket.Bucket(),
+		Description: jsii.String("The bucket AWS Config delivers configuration history to"),
+	})
+
+	fmt.Printf("  ✓ AWS Config recorder with %d managed rule(s)\n", len(compliance.Rules))
+}
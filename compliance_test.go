@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/provider"
+	"github.com/example/json-to-terraform/cdktftest"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+func TestComplianceRuleSourceIdentifiers(t *testing.T) {
+	config := Config{
+		Project:     "my-app",
+		Environment: "dev",
+		Region:      "us-west-2",
+		Compliance: &ComplianceConfig{
+			DeliveryBucket: "config-history",
+			Rules:          []string{"cloudtrail-enabled", "ec2-instance-managed-by-systems-manager"},
+		},
+	}
+
+	app := cdktftest.App(t)
+	stack := cdktf.NewTerraformStack(app, jsii.String("test"))
+	provider.NewAwsProvider(stack, jsii.String("aws"), &provider.AwsProviderConfig{Region: jsii.String(config.Region)})
+	addCompliance(stack, config)
+
+	cdktftest.AssertResourceCount(t, stack, "aws_config_config_rule", 2)
+	for _, identifier := range []string{"CLOUD_TRAIL_ENABLED", "EC2_INSTANCE_MANAGED_BY_SSM"} {
+		cdktftest.AssertResource(t, stack, "aws_config_config_rule", map[string]interface{}{
+			"source": map[string]interface{}{"owner": "AWS", "source_identifier": identifier},
+		})
+	}
+}
+
+func TestComplianceUnknownRule(t *testing.T) {
+	compliance := &ComplianceConfig{DeliveryBucket: "config-history", Rules: []string{"encrypted-volume"}}
+
+	var invalid *ValidationError
+	if err := compliance.validate(); !errors.As(err, &invalid) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if want := `did you mean "encrypted-volumes"?`; invalid.Suggestion != want {
+		t.Errorf("suggestion %q, want %q", invalid.Suggestion, want)
+	}
+}
+
+func TestComplianceDuplicateRule(t *testing.T) {
+	compliance := &ComplianceConfig{DeliveryBucket: "config-history", Rules: []string{"encrypted-volumes", "encrypted-volumes"}}
+
+	err := compliance.validate()
+	if want := `rules[1]: duplicate "encrypted-volumes"`; err == nil || err.Error() != want {
+		t.Errorf("error %v, want %q", err, want)
+	}
+}
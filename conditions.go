@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Any object in the config can carry a condition, e.g. "when": "environment == 'prod'". Objects
+// whose condition is false are dropped before the config is parsed, whether they are a whole
+// section, an entry of a list or a nested block. The condition language has the variables
+// environment, project and region, quoted strings, ==, !=, in [...], !, && and || and parentheses.
+
+// applyConditions evaluates every when in a raw config and returns the config without the objects
+// whose condition is false, and without the when keys
+func applyConditions(raw []byte, variables map[string]string) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	document, keep, err := filterConditions(document, variables, "")
+	if err != nil {
+		return nil, err
+	}
+	if !keep {
+		return nil, fmt.Errorf("the config as a whole can't have a when condition")
+	}
+	return json.Marshal(document)
+}
+
+// filterConditions walks a decoded value and reports whether it is kept
+func filterConditions(value interface{}, variables map[string]string, path string) (interface{}, bool, error) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if condition, ok := value["when"]; ok {
+			expression, ok := condition.(string)
+			if !ok {
+				return nil, false, fmt.Errorf("%s: when must be a string", pathOrRoot(path))
+			}
+			keep, err := evaluateCondition(expression, variables)
+			if err != nil {
+				return nil, false, fmt.Errorf("%s: when %q: %w", pathOrRoot(path), expression, err)
+			}
+			if !keep {
+				return nil, false, nil
+			}
+			delete(value, "when")
+		}
+		for key, child := range value {
+			filtered, keep, err := filterConditions(child, variables, path+"."+key)
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				value[key] = filtered
+			} else {
+				delete(value, key)
+			}
+		}
+		return value, true, nil
+	case []interface{}:
+		kept := []interface{}{}
+		for i, child := range value {
+			filtered, keep, err := filterConditions(child, variables, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, false, err
+			}
+			if keep {
+				kept = append(kept, filtered)
+			}
+		}
+		return kept, true, nil
+	}
+	return value, true, nil
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "config"
+	}
+	return strings.TrimPrefix(path, ".")
+}
+
+// condition parses and evaluates one expression by recursive descent
+type condition struct {
+	tokens    []string
+	position  int
+	variables map[string]string
+}
+
+func evaluateCondition(expression string, variables map[string]string) (bool, error) {
+	tokens, err := tokenizeCondition(expression)
+	if err != nil {
+		return false, err
+	}
+	c := &condition{tokens: tokens, variables: variables}
+	result, err := c.or()
+	if err != nil {
+		return false, err
+	}
+	if c.position < len(c.tokens) {
+		return false, fmt.Errorf("unexpected %s", c.tokens[c.position])
+	}
+	return result, nil
+}
+
+func tokenizeCondition(expression string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expression); {
+		char := rune(expression[i])
+		switch {
+		case unicode.IsSpace(char):
+			i++
+		case strings.HasPrefix(expression[i:], "==") || strings.HasPrefix(expression[i:], "!=") ||
+			strings.HasPrefix(expression[i:], "&&") || strings.HasPrefix(expression[i:], "||"):
+			tokens = append(tokens, expression[i:i+2])
+			i += 2
+		case strings.ContainsRune("!()[],", char):
+			tokens = append(tokens, string(char))
+			i++
+		case char == '\'' || char == '"':
+			end := strings.IndexRune(expression[i+1:], char)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, expression[i:i+end+2])
+			i += end + 2
+		case unicode.IsLetter(char) || char == '_':
+			start := i
+			for i < len(expression) && (unicode.IsLetter(rune(expression[i])) || unicode.IsDigit(rune(expression[i])) || expression[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, expression[start:i])
+		default:
+			return nil, fmt.Errorf("unexpected character %q", char)
+		}
+	}
+	return tokens, nil
+}
+
+func (c *condition) peek() string {
+	if c.position < len(c.tokens) {
+		return c.tokens[c.position]
+	}
+	return ""
+}
+
+func (c *condition) next() string {
+	token := c.peek()
+	c.position++
+	return token
+}
+
+func (c *condition) expect(token string) error {
+	if got := c.next(); got != token {
+		return fmt.Errorf("expected %s, got %q", token, got)
+	}
+	return nil
+}
+
+func (c *condition) or() (bool, error) {
+	result, err := c.and()
+	for err == nil && c.peek() == "||" {
+		c.next()
+		var right bool
+		right, err = c.and()
+		result = result || right
+	}
+	return result, err
+}
+
+func (c *condition) and() (bool, error) {
+	result, err := c.unary()
+	for err == nil && c.peek() == "&&" {
+		c.next()
+		var right bool
+		right, err = c.unary()
+		result = result && right
+	}
+	return result, err
+}
+
+func (c *condition) unary() (bool, error) {
+	switch c.peek() {
+	case "!":
+		c.next()
+		result, err := c.unary()
+		return !result, err
+	case "(":
+		c.next()
+		result, err := c.or()
+		if err != nil {
+			return false, err
+		}
+		return result, c.expect(")")
+	case "true", "false":
+		return c.next() == "true", nil
+	}
+	return c.comparison()
+}
+
+func (c *condition) comparison() (bool, error) {
+	left, err := c.operand()
+	if err != nil {
+		return false, err
+	}
+	switch operator := c.next(); operator {
+	case "==", "!=":
+		right, err := c.operand()
+		return (left == right) == (operator == "=="), err
+	case "in":
+		if err := c.expect("["); err != nil {
+			return false, err
+		}
+		found := false
+		for {
+			value, err := c.operand()
+			if err != nil {
+				return false, err
+			}
+			found = found || value == left
+			if c.peek() != "," {
+				break
+			}
+			c.next()
+		}
+		return found, c.expect("]")
+	default:
+		return false, fmt.Errorf("expected ==, != or in, got %q", operator)
+	}
+}
+
+// operand is a quoted string or a variable
+func (c *condition) operand() (string, error) {
+	token := c.next()
+	if token == "" {
+		return "", fmt.Errorf("unexpected end of condition")
+	}
+	if token[0] == '\'' || token[0] == '"' {
+		return token[1 : len(token)-1], nil
+	}
+	value, ok := c.variables[token]
+	if !ok {
+		return "", fmt.Errorf("unknown variable %s (use environment, project or region)", token)
+	}
+	return value, nil
+}
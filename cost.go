@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+// CostConfig runs Infracost over the synthesized stacks. The infracost CLI must be on the PATH
+// and authenticated (INFRACOST_API_KEY).
+type CostConfig struct {
+	Budget float64 `json:"budget"` // monthly limit across all stacks; 0 only reports
+	Output string  `json:"output"` // defaults to <outdir>/cost-estimate.json
+}
+
+func (c *CostConfig) validate() error {
+	if c.Budget < 0 {
+		return fmt.Errorf("budget must not be negative")
+	}
+	return nil
+}
+
+// infracostBreakdown is the part of `infracost breakdown --format json` that is reported on
+type infracostBreakdown struct {
+	Currency         string `json:"currency"`
+	TotalMonthlyCost string `json:"totalMonthlyCost"`
+	Projects         []struct {
+		Breakdown struct {
+			Resources []struct {
+				Name        string `json:"name"`
+				MonthlyCost string `json:"monthlyCost"`
+			} `json:"resources"`
+		} `json:"breakdown"`
+	} `json:"projects"`
+}
+
+// stackCost is one stack's entry in the exported estimate
+type stackCost struct {
+	Stack       string          `json:"stack"`
+	MonthlyCost float64         `json:"monthly_cost"`
+	Breakdown   json.RawMessage `json:"breakdown"`
+}
+
+// parseCost reads an Infracost amount, which is null for resources it can't price
+func parseCost(amount string) float64 {
+	cost, _ := strconv.ParseFloat(amount, 64)
+	return cost
+}
+
+// estimateCosts prints the monthly cost of each stack, writes the estimate to a JSON file and
+// fails when the total is above the budget
+func estimateCosts(config Config, outdir string, stackNames []string) error {
+	if _, err := exec.LookPath("infracost"); err != nil {
+		return fmt.Errorf("infracost not found on PATH: %w", err)
+	}
+
+	fmt.Println("\n💰 Estimating monthly cost with Infracost...")
+	estimate := struct {
+		Currency         string      `json:"currency"`
+		TotalMonthlyCost float64     `json:"total_monthly_cost"`
+		Budget           float64     `json:"budget,omitempty"`
+		Stacks           []stackCost `json:"stacks"`
+	}{Currency: "USD", Budget: config.Cost.Budget}
+
+	for _, name := range stackNames {
+		output, err := exec.Command("infracost", "breakdown", "--format", "json", "--no-color",
+			"--path", filepath.Join(outdir, "stacks", name)).Output()
+		if err != nil {
+			return fmt.Errorf("infracost breakdown for %s: %w", name, err)
+		}
+
+		var breakdown infracostBreakdown
+		if err := json.Unmarshal(output, &breakdown); err != nil {
+			return fmt.Errorf("parsing infracost output for %s: %w", name, err)
+		}
+		if breakdown.Currency != "" {
+			estimate.Currency = breakdown.Currency
+		}
+
+		cost := parseCost(breakdown.TotalMonthlyCost)
+		estimate.TotalMonthlyCost += cost
+		estimate.Stacks = append(estimate.Stacks, stackCost{Stack: name, MonthlyCost: cost, Breakdown: output})
+
+		fmt.Printf("  %s: %.2f %s/month\n", name, cost, estimate.Currency)
+		type resourceCost struct {
+			name string
+			cost float64
+		}
+		var resources []resourceCost
+		for _, project := range breakdown.Projects {
+			for _, resource := range project.Breakdown.Resources {
+				if cost := parseCost(resource.MonthlyCost); cost > 0 {
+					resources = append(resources, resourceCost{resource.Name, cost})
+				}
+			}
+		}
+		sort.Slice(resources, func(i, j int) bool { return resources[i].cost > resources[j].cost })
+		for _, resource := range resources {
+			fmt.Printf("    %-50s %10.2f\n", resource.name, resource.cost)
+		}
+	}
+	fmt.Printf("  Total: %.2f %s/month\n", estimate.TotalMonthlyCost, estimate.Currency)
+
+	path := config.Cost.Output
+	if path == "" {
+		path = filepath.Join(outdir, "cost-estimate.json")
+	}
+	data, err := json.MarshalIndent(estimate, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	fmt.Printf("  ✓ Estimate written to %s\n", path)
+
+	if config.Cost.Budget > 0 && estimate.TotalMonthlyCost > config.Cost.Budget {
+		return fmt.Errorf("estimated %.2f %s/month is over the budget of %.2f",
+			estimate.TotalMonthlyCost, estimate.Currency, config.Cost.Budget)
+	}
+	return nil
+}
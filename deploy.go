@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// readManifest returns the stacks of cdktf.out/manifest.json, each with the stacks it depends on.
+// cdktf records a dependency for every cross-stack reference and every depends_on.
+func readManifest(outdir string) (map[string][]string, error) {
+	raw, err := os.ReadFile(filepath.Join(outdir, "manifest.json"))
+	if err != nil {
+		return nil, fmt.Errorf("reading the stack manifest (run cdktf synth first): %w", err)
+	}
+	var manifest struct {
+		Stacks map[string]struct {
+			Dependencies []string `json:"dependencies"`
+		} `json:"stacks"`
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("parsing the stack manifest: %w", err)
+	}
+	dependencies := map[string][]string{}
+	for name, stack := range manifest.Stacks {
+		dependencies[name] = stack.Dependencies
+	}
+	return dependencies, nil
+}
+
+// deployOrder sorts stacks so that each comes after the stacks it depends on, breaking ties by
+// name. Dependencies on stacks that aren't in the map are ignored.
+func deployOrder(dependencies map[string][]string) ([]string, error) {
+	placed := map[string]bool{}
+	var order []string
+	for len(order) < len(dependencies) {
+		var ready []string
+		for name, needs := range dependencies {
+			if placed[name] {
+				continue
+			}
+			blocked := false
+			for _, need := range needs {
+				if _, ok := dependencies[need]; ok && !placed[need] {
+					blocked = true
+				}
+			}
+			if !blocked {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			var cycle []string
+			for name := range dependencies {
+				if !placed[name] {
+					cycle = append(cycle, name)
+				}
+			}
+			sort.Strings(cycle)
+			return nil, fmt.Errorf("stacks %s depend on each other", strings.Join(cycle, ", "))
+		}
+		sort.Strings(ready)
+		for _, name := range ready {
+			placed[name] = true
+		}
+		order = append(order, ready...)
+	}
+	return order, nil
+}
+
+// stackResult is the outcome of one stack in a deploy, for the summary
+type stackResult struct {
+	name     string
+	status   string // applied, destroyed, failed or skipped
+	duration time.Duration
+	summary  *planSummary
+}
+
+// notificationStacks lists the results of a deploy for its notification
+func notificationStacks(results []stackResult) []notificationStack {
+	stacks := make([]notificationStack, 0, len(results))
+	for _, result := range results {
+		stacks = append(stacks, notificationStack{Name: result.name, Status: result.status, Summary: result.summary})
+	}
+	return stacks
+}
+
+// runDeploy is the deploy command. It applies the synthesized stacks of the environment after the
+// stacks they depend on, or destroys them in the reverse order with -destroy, and stops at the
+// first stack that fails.
+func runDeploy(args []string) error {
+	flags := flag.NewFlagSet("deploy", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	destroy := flags.Bool("destroy", false, "destroy the stacks, dependents first")
+	autoApprove := flags.Bool("auto-approve", false, "don't ask for approval of each stack's plan")
+	skipInit := flags.Bool("skip-init", false, "don't run terraform init first")
+	bootstrap := flags.Bool("bootstrap", false, "also apply the backend bootstrap stack, before the others")
+	only := flags.String("stack", "", "deploy only this stack")
+	output := flags.String("output", "", "outputs file written after an apply (default outputs.<environment>.json)")
+	showSensitive := flags.Bool("show-sensitive", false, "also write sensitive outputs to the outputs file")
+	flags.Parse(args)
+
+	config, err := loadConfig("config.json")
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("terraform"); err != nil {
+		return fmt.Errorf("terraform not found on PATH: %w", err)
+	}
+	manifest, err := readManifest(*outdir)
+	if err != nil {
+		return err
+	}
+
+	// cdktf.out can still hold the stacks of another environment. The bootstrap stack keeps its
+	// state locally, so it's only applied when asked for and never destroyed here.
+	prefix := stackPrefix(config) + "-"
+	backendStack := prefix + "backend"
+	dependencies := map[string][]string{}
+	for name, needs := range manifest {
+		if !strings.HasPrefix(name, prefix) || (*only != "" && name != *only) {
+			continue
+		}
+		if name == backendStack {
+			if *bootstrap && !*destroy {
+				dependencies[name] = needs
+			}
+			continue
+		}
+		if *bootstrap {
+			needs = append(needs, backendStack)
+		}
+		dependencies[name] = needs
+	}
+	if *only != "" && len(dependencies) == 0 {
+		return fmt.Errorf("no stack %s in %s", *only, *outdir)
+	}
+	order, err := deployOrder(dependencies)
+	if err != nil {
+		return err
+	}
+
+	action, done := "apply", "applied"
+	if *destroy {
+		action, done = "destroy", "destroyed"
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+
+	fmt.Printf("🚀 Running terraform %s on %d stack(s): %s\n", action, len(order), strings.Join(order, " → "))
+	pending := make([]stackResult, 0, len(order))
+	for _, name := range order {
+		pending = append(pending, stackResult{name: name, status: "pending"})
+	}
+	notify(config, newNotification(config, "deploy_started", action, notificationStacks(pending), nil))
+	var results []stackResult
+	var failure error
+	for _, name := range order {
+		if failure != nil {
+			results = append(results, stackResult{name: name, status: "skipped"})
+			continue
+		}
+		fmt.Printf("\n▶ %s\n", name)
+		started := time.Now()
+		span := startSpan(action+" "+name, "stack", name)
+		summary, err := deployStack(filepath.Join(*outdir, "stacks", name), config, name != backendStack, action, *autoApprove, *skipInit)
+		if summary != nil {
+			span.setAttribute("resources.added", fmt.Sprint(summary.Added))
+			span.setAttribute("resources.changed", fmt.Sprint(summary.Changed))
+			span.setAttribute("resources.destroyed", fmt.Sprint(summary.Destroyed))
+		}
+		span.finish(err)
+		result := stackResult{name: name, status: done, duration: time.Since(started).Round(time.Second), summary: summary}
+		if err != nil {
+			result.status = "failed"
+			failure = fmt.Errorf("%s: %w", name, err)
+		}
+		results = append(results, result)
+		if config.Audit != nil {
+			entry := newAuditEntry(config, action, started, err)
+			entry.Stack, entry.Summary = name, summary
+			recordAudit(config, entry)
+		}
+	}
+
+	fmt.Println("\n📋 Deploy summary:")
+	for _, result := range results {
+		switch result.status {
+		case "failed":
+			fmt.Printf("  ✗ %s: failed after %s\n", result.name, result.duration)
+		case "skipped":
+			fmt.Printf("  - %s: skipped\n", result.name)
+		default:
+			fmt.Printf("  ✓ %s: %s in %s\n", result.name, result.status, result.duration)
+		}
+	}
+	event := "deploy_finished"
+	if failure != nil {
+		event = "deploy_failed"
+	}
+	notify(config, newNotification(config, event, action, notificationStacks(results), failure))
+	if failure != nil || *destroy {
+		return failure
+	}
+
+	// The file covers every stack of the environment, as the outputs command writes it, so it
+	// stays complete when only one stack was applied
+	fmt.Println()
+	return writeOutputs(config, *outdir, *output, *skipInit, *showSensitive)
+}
+
+// deployStack initializes one stack directory, selects the environment's workspace when the
+// config uses them, and runs terraform apply or destroy attached to the terminal. It returns the
+// resource counts terraform reported, or nil when it didn't finish.
+func deployStack(dir string, config Config, useWorkspace bool, action string, autoApprove bool, skipInit bool) (*planSummary, error) {
+	if !skipInit {
+		if err := terraformInit(dir); err != nil {
+			return nil, err
+		}
+	}
+	if config.Workspaces && useWorkspace {
+		if err := selectWorkspace(dir, config.Environment); err != nil {
+			return nil, err
+		}
+	}
+
+	// Without -auto-approve terraform asks for approval of the plan, which needs input
+	commandArgs := []string{action}
+	if autoApprove {
+		commandArgs = append(commandArgs, "-input=false", "-auto-approve")
+	}
+	command := exec.Command("terraform", commandArgs...)
+	command.Dir = dir
+	// {user} in a deploy role session name is the deploy_user variable; one set by the caller wins
+	command.Env = os.Environ()
+	if _, ok := os.LookupEnv("TF_VAR_" + deployUserVariable); !ok {
+		if current, err := user.Current(); err == nil {
+			command.Env = append(command.Env, "TF_VAR_"+deployUserVariable+"="+current.Username)
+		}
+	}
+	// The output is also kept for the summary line at its end
+	var output bytes.Buffer
+	command.Stdin = os.Stdin
+	command.Stdout = io.MultiWriter(os.Stdout, &output)
+	command.Stderr = os.Stderr
+	span := startCommandSpan(command)
+	err := command.Run()
+	span.finish(err)
+	if err != nil {
+		return parsePlanSummary(output.Bytes()), fmt.Errorf("terraform %s: %w", action, err)
+	}
+	return parsePlanSummary(output.Bytes()), nil
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Actions shared by the services that place resources in a VPC or create a service-linked role
+var (
+	serviceLinkedRole = []string{"iam:CreateServiceLinkedRole"}
+	describeNetwork   = []string{"ec2:DescribeSubnets", "ec2:DescribeSecurityGroups", "ec2:DescribeVpcs"}
+)
+
+// deployPermissions lists the IAM actions Terraform calls to create, read, update and delete each
+// resource and data source type the platform generates
+var deployPermissions = map[string][]string{
+	"aws_amplify_app":                {"amplify:CreateApp", "amplify:GetApp", "amplify:UpdateApp", "amplify:DeleteApp", "amplify:ListTagsForResource", "amplify:TagResource", "amplify:UntagResource"},
+	"aws_amplify_branch":             {"amplify:CreateBranch", "amplify:GetBranch", "amplify:UpdateBranch", "amplify:DeleteBranch", "amplify:TagResource", "amplify:UntagResource"},
+	"aws_amplify_domain_association": {"amplify:CreateDomainAssociation", "amplify:GetDomainAssociation", "amplify:UpdateDomainAssociation", "amplify:DeleteDomainAssociation"},
+
+	"aws_appconfig_application":                  {"appconfig:CreateApplication", "appconfig:GetApplication", "appconfig:UpdateApplication", "appconfig:DeleteApplication", "appconfig:ListTagsForResource", "appconfig:TagResource", "appconfig:UntagResource"},
+	"aws_appconfig_configuration_profile":        {"appconfig:CreateConfigurationProfile", "appconfig:GetConfigurationProfile", "appconfig:UpdateConfigurationProfile", "appconfig:DeleteConfigurationProfile", "appconfig:ListTagsForResource", "appconfig:TagResource", "appconfig:UntagResource"},
+	"aws_appconfig_deployment":                   {"appconfig:StartDeployment", "appconfig:GetDeployment", "appconfig:StopDeployment", "appconfig:ListTagsForResource", "appconfig:TagResource", "appconfig:UntagResource"},
+	"aws_appconfig_deployment_strategy":          {"appconfig:CreateDeploymentStrategy", "appconfig:GetDeploymentStrategy", "appconfig:UpdateDeploymentStrategy", "appconfig:DeleteDeploymentStrategy", "appconfig:ListTagsForResource", "appconfig:TagResource", "appconfig:UntagResource"},
+	"aws_appconfig_environment":                  {"appconfig:CreateEnvironment", "appconfig:GetEnvironment", "appconfig:UpdateEnvironment", "appconfig:DeleteEnvironment", "iam:PassRole", "appconfig:ListTagsForResource", "appconfig:TagResource", "appconfig:UntagResource"},
+	"aws_appconfig_hosted_configuration_version": {"appconfig:CreateHostedConfigurationVersion", "appconfig:GetHostedConfigurationVersion", "appconfig:DeleteHostedConfigurationVersion"},
+
+	"aws_apprunner_auto_scaling_configuration_version": {"apprunner:CreateAutoScalingConfiguration", "apprunner:DescribeAutoScalingConfiguration", "apprunner:DeleteAutoScalingConfiguration", "apprunner:TagResource", "apprunner:UntagResource", "apprunner:ListTagsForResource"},
+	"aws_apprunner_custom_domain_association":          {"apprunner:AssociateCustomDomain", "apprunner:DescribeCustomDomains", "apprunner:DisassociateCustomDomain"},
+	"aws_apprunner_observability_configuration":        {"apprunner:CreateObservabilityConfiguration", "apprunner:DescribeObservabilityConfiguration", "apprunner:DeleteObservabilityConfiguration", "apprunner:TagResource", "apprunner:UntagResource", "apprunner:ListTagsForResource"},
+	"aws_apprunner_service":                            append([]string{"apprunner:CreateService", "apprunner:DescribeService", "apprunner:UpdateService", "apprunner:DeleteService", "apprunner:ListOperations", "apprunner:TagResource", "apprunner:UntagResource", "apprunner:ListTagsForResource"}, serviceLinkedRole...),
+
+	"aws_athena_workgroup": {"athena:CreateWorkGroup", "athena:GetWorkGroup", "athena:UpdateWorkGroup", "athena:DeleteWorkGroup", "athena:ListTagsForResource", "athena:TagResource", "athena:UntagResource"},
+
+	"aws_batch_compute_environment": append(append([]string{"batch:CreateComputeEnvironment", "batch:DescribeComputeEnvironments", "batch:UpdateComputeEnvironment", "batch:DeleteComputeEnvironment", "batch:TagResource", "batch:UntagResource"}, describeNetwork...), serviceLinkedRole...),
+	"aws_batch_job_definition":      {"batch:RegisterJobDefinition", "batch:DescribeJobDefinitions", "batch:DeregisterJobDefinition", "batch:TagResource", "batch:UntagResource"},
+	"aws_batch_job_queue":           {"batch:CreateJobQueue", "batch:DescribeJobQueues", "batch:UpdateJobQueue", "batch:DeleteJobQueue", "batch:TagResource", "batch:UntagResource"},
+
+	"aws_cloudtrail": {"cloudtrail:CreateTrail", "cloudtrail:GetTrail", "cloudtrail:DescribeTrails", "cloudtrail:GetTrailStatus", "cloudtrail:GetEventSelectors", "cloudtrail:PutEventSelectors", "cloudtrail:GetInsightSelectors", "cloudtrail:UpdateTrail", "cloudtrail:DeleteTrail", "cloudtrail:StartLogging", "cloudtrail:StopLogging", "cloudtrail:AddTags", "cloudtrail:RemoveTags", "cloudtrail:ListTags"},
+
+	"aws_backup_plan":      {"backup:CreateBackupPlan", "backup:GetBackupPlan", "backup:UpdateBackupPlan", "backup:DeleteBackupPlan", "backup:ListTags", "backup:TagResource", "backup:UntagResource"},
+	"aws_backup_selection": {"backup:CreateBackupSelection", "backup:GetBackupSelection", "backup:DeleteBackupSelection", "iam:PassRole"},
+	"aws_backup_vault":     {"backup:CreateBackupVault", "backup:DescribeBackupVault", "backup:DeleteBackupVault", "backup:ListTags", "backup:TagResource", "backup:UntagResource", "backup-storage:MountCapsule", "kms:CreateGrant", "kms:GenerateDataKey", "kms:Decrypt", "kms:RetireGrant", "kms:DescribeKey"},
+
+	"aws_bedrock_guardrail":                              {"bedrock:CreateGuardrail", "bedrock:GetGuardrail", "bedrock:UpdateGuardrail", "bedrock:DeleteGuardrail", "bedrock:ListTagsForResource", "bedrock:TagResource", "bedrock:UntagResource", "kms:DescribeKey", "kms:CreateGrant", "kms:Decrypt", "kms:GenerateDataKey"},
+	"aws_bedrock_guardrail_version":                      {"bedrock:CreateGuardrailVersion", "bedrock:GetGuardrail", "bedrock:DeleteGuardrail"},
+	"aws_bedrock_model_invocation_logging_configuration": {"bedrock:PutModelInvocationLoggingConfiguration", "bedrock:GetModelInvocationLoggingConfiguration", "bedrock:DeleteModelInvocationLoggingConfiguration"},
+	"aws_bedrock_provisioned_model_throughput":           {"bedrock:CreateProvisionedModelThroughput", "bedrock:GetProvisionedModelThroughput", "bedrock:UpdateProvisionedModelThroughput", "bedrock:DeleteProvisionedModelThroughput", "bedrock:ListTagsForResource", "bedrock:TagResource", "bedrock:UntagResource"},
+
+	"aws_budgets_budget": {"budgets:ModifyBudget", "budgets:ViewBudget", "budgets:ListTagsForResource", "budgets:TagResource", "budgets:UntagResource"},
+
+	"aws_ce_anomaly_monitor":      {"ce:CreateAnomalyMonitor", "ce:GetAnomalyMonitors", "ce:UpdateAnomalyMonitor", "ce:DeleteAnomalyMonitor", "ce:ListTagsForResource", "ce:TagResource", "ce:UntagResource"},
+	"aws_ce_anomaly_subscription": {"ce:CreateAnomalySubscription", "ce:GetAnomalySubscriptions", "ce:UpdateAnomalySubscription", "ce:DeleteAnomalySubscription", "ce:ListTagsForResource", "ce:TagResource", "ce:UntagResource"},
+
+	"aws_cloudwatch_event_rule":          {"events:PutRule", "events:DescribeRule", "events:DeleteRule", "events:ListTargetsByRule", "events:ListTagsForResource", "events:TagResource", "events:UntagResource"},
+	"aws_cloudwatch_event_target":        {"events:PutTargets", "events:ListTargetsByRule", "events:RemoveTargets", "iam:PassRole"},
+	"aws_cloudwatch_log_group":           {"logs:CreateLogGroup", "logs:DescribeLogGroups", "logs:DeleteLogGroup", "logs:PutRetentionPolicy", "logs:DeleteRetentionPolicy", "logs:AssociateKmsKey", "logs:ListTagsForResource", "logs:TagResource", "logs:UntagResource"},
+	"aws_cloudwatch_log_resource_policy": {"logs:PutResourcePolicy", "logs:DescribeResourcePolicies", "logs:DeleteResourcePolicy"},
+	"aws_cloudwatch_metric_alarm":        {"cloudwatch:PutMetricAlarm", "cloudwatch:DescribeAlarms", "cloudwatch:DeleteAlarms", "cloudwatch:ListTagsForResource", "cloudwatch:TagResource", "cloudwatch:UntagResource"},
+
+	"aws_codebuild_project": {"codebuild:CreateProject", "codebuild:BatchGetProjects", "codebuild:UpdateProject", "codebuild:DeleteProject", "iam:PassRole"},
+
+	"aws_codedeploy_app":              {"codedeploy:CreateApplication", "codedeploy:GetApplication", "codedeploy:DeleteApplication", "codedeploy:ListTagsForResource", "codedeploy:TagResource", "codedeploy:UntagResource"},
+	"aws_codedeploy_deployment_group": {"codedeploy:CreateDeploymentGroup", "codedeploy:GetDeploymentGroup", "codedeploy:UpdateDeploymentGroup", "codedeploy:DeleteDeploymentGroup", "codedeploy:ListTagsForResource", "codedeploy:TagResource", "codedeploy:UntagResource", "ecs:DescribeServices", "elasticloadbalancing:DescribeTargetGroups", "elasticloadbalancing:DescribeListeners", "iam:PassRole"},
+
+	"aws_codepipeline": {"codepipeline:CreatePipeline", "codepipeline:GetPipeline", "codepipeline:UpdatePipeline", "codepipeline:DeletePipeline", "codepipeline:ListTagsForResource", "codepipeline:TagResource", "codepipeline:UntagResource", "iam:PassRole"},
+
+	"aws_codestarconnections_connection": {"codestar-connections:CreateConnection", "codestar-connections:GetConnection", "codestar-connections:DeleteConnection", "codestar-connections:ListTagsForResource", "codestar-connections:TagResource", "codestar-connections:UntagResource"},
+
+	"aws_config_config_rule":                   {"config:PutConfigRule", "config:DescribeConfigRules", "config:DeleteConfigRule", "config:ListTagsForResource", "config:TagResource", "config:UntagResource"},
+	"aws_config_configuration_recorder":        append([]string{"config:PutConfigurationRecorder", "config:DescribeConfigurationRecorders", "config:DeleteConfigurationRecorder"}, serviceLinkedRole...),
+	"aws_config_configuration_recorder_status": {"config:StartConfigurationRecorder", "config:StopConfigurationRecorder", "config:DescribeConfigurationRecorderStatus"},
+	"aws_config_delivery_channel":              {"config:PutDeliveryChannel", "config:DescribeDeliveryChannels", "config:DeleteDeliveryChannel"},
+
+	"aws_dms_endpoint":                 {"dms:CreateEndpoint", "dms:DescribeEndpoints", "dms:ModifyEndpoint", "dms:DeleteEndpoint", "iam:PassRole", "dms:AddTagsToResource", "dms:RemoveTagsFromResource", "dms:ListTagsForResource"},
+	"aws_dms_replication_config":       {"dms:CreateReplicationConfig", "dms:DescribeReplicationConfigs", "dms:ModifyReplicationConfig", "dms:DeleteReplicationConfig", "dms:StartReplication", "dms:StopReplication", "dms:DescribeReplications", "dms:AddTagsToResource", "dms:RemoveTagsFromResource", "dms:ListTagsForResource"},
+	"aws_dms_replication_instance":     {"dms:CreateReplicationInstance", "dms:DescribeReplicationInstances", "dms:ModifyReplicationInstance", "dms:DeleteReplicationInstance", "dms:AddTagsToResource", "dms:RemoveTagsFromResource", "dms:ListTagsForResource"},
+	"aws_dms_replication_subnet_group": {"dms:CreateReplicationSubnetGroup", "dms:DescribeReplicationSubnetGroups", "dms:ModifyReplicationSubnetGroup", "dms:DeleteReplicationSubnetGroup", "dms:AddTagsToResource", "dms:RemoveTagsFromResource", "dms:ListTagsForResource"},
+	"aws_dms_replication_task":         {"dms:CreateReplicationTask", "dms:DescribeReplicationTasks", "dms:ModifyReplicationTask", "dms:DeleteReplicationTask", "dms:StartReplicationTask", "dms:StopReplicationTask", "dms:AddTagsToResource", "dms:RemoveTagsFromResource", "dms:ListTagsForResource"},
+	"aws_dms_s3_endpoint":              {"dms:CreateEndpoint", "dms:DescribeEndpoints", "dms:ModifyEndpoint", "dms:DeleteEndpoint", "iam:PassRole", "dms:AddTagsToResource", "dms:RemoveTagsFromResource", "dms:ListTagsForResource"},
+
+	"aws_docdb_cluster":                 append(append([]string{"rds:CreateDBCluster", "rds:DescribeDBClusters", "rds:ModifyDBCluster", "rds:DeleteDBCluster", "rds:CreateDBClusterSnapshot", "rds:DescribeDBClusterSnapshots", "rds:AddTagsToResource", "rds:RemoveTagsFromResource", "rds:ListTagsForResource", "kms:DescribeKey", "kms:CreateGrant", "secretsmanager:CreateSecret", "secretsmanager:DescribeSecret", "secretsmanager:RotateSecret", "secretsmanager:DeleteSecret", "secretsmanager:TagResource"}, describeNetwork...), serviceLinkedRole...),
+	"aws_docdb_cluster_instance":        {"rds:CreateDBInstance", "rds:DescribeDBInstances", "rds:ModifyDBInstance", "rds:RebootDBInstance", "rds:DeleteDBInstance", "rds:AddTagsToResource", "rds:RemoveTagsFromResource", "rds:ListTagsForResource"},
+	"aws_docdb_cluster_parameter_group": {"rds:CreateDBClusterParameterGroup", "rds:DescribeDBClusterParameterGroups", "rds:DescribeDBClusterParameters", "rds:ModifyDBClusterParameterGroup", "rds:DeleteDBClusterParameterGroup", "rds:AddTagsToResource", "rds:RemoveTagsFromResource", "rds:ListTagsForResource"},
+	"aws_docdb_subnet_group":            {"rds:CreateDBSubnetGroup", "rds:DescribeDBSubnetGroups", "rds:ModifyDBSubnetGroup", "rds:DeleteDBSubnetGroup", "rds:AddTagsToResource", "rds:RemoveTagsFromResource", "rds:ListTagsForResource"},
+
+	"aws_dynamodb_table": {"dynamodb:CreateTable", "dynamodb:DescribeTable", "dynamodb:UpdateTable", "dynamodb:DeleteTable", "dynamodb:DescribeContinuousBackups", "dynamodb:UpdateContinuousBackups", "dynamodb:DescribeTimeToLive", "dynamodb:ListTagsOfResource", "dynamodb:TagResource", "dynamodb:UntagResource"},
+
+	"aws_globalaccelerator_accelerator":    append([]string{"globalaccelerator:CreateAccelerator", "globalaccelerator:DescribeAccelerator", "globalaccelerator:DescribeAcceleratorAttributes", "globalaccelerator:UpdateAccelerator", "globalaccelerator:UpdateAcceleratorAttributes", "globalaccelerator:DeleteAccelerator", "globalaccelerator:ListTagsForResource", "globalaccelerator:TagResource", "globalaccelerator:UntagResource"}, serviceLinkedRole...),
+	"aws_globalaccelerator_endpoint_group": {"globalaccelerator:CreateEndpointGroup", "globalaccelerator:DescribeEndpointGroup", "globalaccelerator:UpdateEndpointGroup", "globalaccelerator:DeleteEndpointGroup"},
+	"aws_globalaccelerator_listener":       {"globalaccelerator:CreateListener", "globalaccelerator:DescribeListener", "globalaccelerator:UpdateListener", "globalaccelerator:DeleteListener"},
+
+	"aws_glue_catalog_database": {"glue:CreateDatabase", "glue:GetDatabase", "glue:UpdateDatabase", "glue:DeleteDatabase", "glue:GetTags", "glue:TagResource", "glue:UntagResource"},
+	"aws_glue_crawler":          {"glue:CreateCrawler", "glue:GetCrawler", "glue:UpdateCrawler", "glue:DeleteCrawler", "glue:GetTags", "glue:TagResource", "glue:UntagResource"},
+	"aws_glue_job":              {"glue:CreateJob", "glue:GetJob", "glue:UpdateJob", "glue:DeleteJob", "glue:GetTags", "glue:TagResource", "glue:UntagResource"},
+
+	"aws_grafana_role_association":             {"grafana:UpdatePermissions", "grafana:ListPermissions", "sso:DescribeRegisteredRegions", "sso:GetManagedApplicationInstance", "sso:CreateManagedApplicationInstance", "sso:AssociateProfile", "sso:DisassociateProfile", "sso:ListProfileAssociations", "sso:ListProfiles", "sso:GetProfile"},
+	"aws_grafana_workspace":                    {"grafana:CreateWorkspace", "grafana:DescribeWorkspace", "grafana:UpdateWorkspace", "grafana:UpdateWorkspaceAuthentication", "grafana:DeleteWorkspace", "grafana:DescribeWorkspaceConfiguration", "grafana:ListTagsForResource", "grafana:TagResource", "grafana:UntagResource", "iam:PassRole", "organizations:DescribeOrganization", "sso:DescribeRegisteredRegions", "sso:CreateManagedApplicationInstance", "sso:DeleteManagedApplicationInstance", "sso:GetSharedSsoConfiguration"},
+	"aws_grafana_workspace_saml_configuration": {"grafana:UpdateWorkspaceAuthentication", "grafana:DescribeWorkspaceAuthentication"},
+
+	"aws_guardduty_detector":               append([]string{"guardduty:CreateDetector", "guardduty:GetDetector", "guardduty:UpdateDetector", "guardduty:DeleteDetector", "guardduty:ListTagsForResource", "guardduty:TagResource", "guardduty:UntagResource"}, serviceLinkedRole...),
+	"aws_guardduty_detector_feature":       {"guardduty:GetDetector", "guardduty:UpdateDetector"},
+	"aws_guardduty_publishing_destination": {"guardduty:CreatePublishingDestination", "guardduty:DescribePublishingDestination", "guardduty:UpdatePublishingDestination", "guardduty:DeletePublishingDestination"},
+
+	"aws_iam_instance_profile":       {"iam:CreateInstanceProfile", "iam:GetInstanceProfile", "iam:DeleteInstanceProfile", "iam:AddRoleToInstanceProfile", "iam:RemoveRoleFromInstanceProfile", "iam:TagInstanceProfile", "iam:UntagInstanceProfile"},
+	"aws_iam_policy":                 {"iam:CreatePolicy", "iam:GetPolicy", "iam:GetPolicyVersion", "iam:CreatePolicyVersion", "iam:DeletePolicyVersion", "iam:ListPolicyVersions", "iam:DeletePolicy", "iam:TagPolicy", "iam:UntagPolicy"},
+	"aws_iam_role":                   {"iam:CreateRole", "iam:GetRole", "iam:UpdateRole", "iam:UpdateAssumeRolePolicy", "iam:DeleteRole", "iam:PassRole", "iam:ListRolePolicies", "iam:ListAttachedRolePolicies", "iam:ListInstanceProfilesForRole", "iam:TagRole", "iam:UntagRole"},
+	"aws_iam_role_policy":            {"iam:PutRolePolicy", "iam:GetRolePolicy", "iam:DeleteRolePolicy"},
+	"aws_iam_role_policy_attachment": {"iam:AttachRolePolicy", "iam:DetachRolePolicy", "iam:ListAttachedRolePolicies"},
+
+	"aws_iot_policy":     {"iot:CreatePolicy", "iot:GetPolicy", "iot:CreatePolicyVersion", "iot:DeletePolicyVersion", "iot:ListPolicyVersions", "iot:DeletePolicy", "iot:ListTagsForResource", "iot:TagResource", "iot:UntagResource"},
+	"aws_iot_thing":      {"iot:CreateThing", "iot:DescribeThing", "iot:UpdateThing", "iot:DeleteThing"},
+	"aws_iot_thing_type": {"iot:CreateThingType", "iot:DescribeThingType", "iot:DeprecateThingType", "iot:DeleteThingType", "iot:ListTagsForResource", "iot:TagResource", "iot:UntagResource"},
+	"aws_iot_topic_rule": {"iot:CreateTopicRule", "iot:GetTopicRule", "iot:ReplaceTopicRule", "iot:DeleteTopicRule", "iot:EnableTopicRule", "iot:DisableTopicRule", "iot:ListTagsForResource", "iot:TagResource", "iot:UntagResource", "iam:PassRole"},
+
+	"aws_keyspaces_keyspace": {"cassandra:Create", "cassandra:Select", "cassandra:Drop", "cassandra:TagResource", "cassandra:UntagResource", "iam:CreateServiceLinkedRole"},
+	"aws_keyspaces_table":    {"cassandra:Create", "cassandra:Select", "cassandra:Alter", "cassandra:Modify", "cassandra:Drop", "cassandra:TagResource", "cassandra:UntagResource", "kms:DescribeKey", "kms:CreateGrant"},
+
+	"aws_kms_key": {"kms:CreateKey", "kms:DescribeKey", "kms:GetKeyPolicy", "kms:PutKeyPolicy", "kms:GetKeyRotationStatus", "kms:EnableKeyRotation", "kms:DisableKeyRotation", "kms:UpdateKeyDescription", "kms:ScheduleKeyDeletion", "kms:ListResourceTags", "kms:TagResource", "kms:UntagResource"},
+
+	"aws_lambda_permission": {"lambda:AddPermission", "lambda:GetPolicy", "lambda:RemovePermission"},
+
+	"aws_memorydb_acl":          {"memorydb:CreateACL", "memorydb:DescribeACLs", "memorydb:UpdateACL", "memorydb:DeleteACL", "memorydb:ListTags", "memorydb:TagResource", "memorydb:UntagResource"},
+	"aws_memorydb_cluster":      append(append([]string{"memorydb:CreateCluster", "memorydb:DescribeClusters", "memorydb:UpdateCluster", "memorydb:DeleteCluster", "memorydb:CreateSnapshot", "memorydb:DescribeSnapshots", "memorydb:ListTags", "memorydb:TagResource", "memorydb:UntagResource", "kms:DescribeKey", "kms:CreateGrant"}, describeNetwork...), serviceLinkedRole...),
+	"aws_memorydb_subnet_group": {"memorydb:CreateSubnetGroup", "memorydb:DescribeSubnetGroups", "memorydb:UpdateSubnetGroup", "memorydb:DeleteSubnetGroup", "memorydb:ListTags", "memorydb:TagResource", "memorydb:UntagResource", "ec2:DescribeSubnets", "ec2:DescribeVpcs"},
+	"aws_memorydb_user":         {"memorydb:CreateUser", "memorydb:DescribeUsers", "memorydb:UpdateUser", "memorydb:DeleteUser", "memorydb:ListTags", "memorydb:TagResource", "memorydb:UntagResource"},
+
+	"aws_msk_cluster":            append(append([]string{"kafka:CreateCluster", "kafka:DescribeCluster", "kafka:DescribeClusterV2", "kafka:GetBootstrapBrokers", "kafka:UpdateBrokerCount", "kafka:UpdateBrokerStorage", "kafka:UpdateBrokerType", "kafka:UpdateClusterConfiguration", "kafka:UpdateMonitoring", "kafka:UpdateSecurity", "kafka:DeleteCluster", "kafka:ListTagsForResource", "kafka:TagResource", "kafka:UntagResource"}, describeNetwork...), serviceLinkedRole...),
+	"aws_msk_serverless_cluster": append(append([]string{"kafka:CreateClusterV2", "kafka:DescribeClusterV2", "kafka:GetBootstrapBrokers", "kafka:DeleteCluster", "kafka:ListTagsForResource", "kafka:TagResource", "kafka:UntagResource", "ec2:CreateVpcEndpoint", "ec2:DeleteVpcEndpoints", "ec2:DescribeVpcEndpoints"}, describeNetwork...), serviceLinkedRole...),
+
+	"aws_neptune_cluster":                 append(append([]string{"rds:CreateDBCluster", "rds:DescribeDBClusters", "rds:ModifyDBCluster", "rds:DeleteDBCluster", "rds:CreateDBClusterSnapshot", "rds:DescribeDBClusterSnapshots", "rds:AddTagsToResource", "rds:RemoveTagsFromResource", "rds:ListTagsForResource", "kms:DescribeKey", "kms:CreateGrant"}, describeNetwork...), serviceLinkedRole...),
+	"aws_neptune_cluster_instance":        {"rds:CreateDBInstance", "rds:DescribeDBInstances", "rds:ModifyDBInstance", "rds:RebootDBInstance", "rds:DeleteDBInstance", "rds:AddTagsToResource", "rds:RemoveTagsFromResource", "rds:ListTagsForResource"},
+	"aws_neptune_cluster_parameter_group": {"rds:CreateDBClusterParameterGroup", "rds:DescribeDBClusterParameterGroups", "rds:DescribeDBClusterParameters", "rds:ModifyDBClusterParameterGroup", "rds:DeleteDBClusterParameterGroup", "rds:AddTagsToResource", "rds:RemoveTagsFromResource", "rds:ListTagsForResource"},
+	"aws_neptune_subnet_group":            {"rds:CreateDBSubnetGroup", "rds:DescribeDBSubnetGroups", "rds:ModifyDBSubnetGroup", "rds:DeleteDBSubnetGroup", "rds:AddTagsToResource", "rds:RemoveTagsFromResource", "rds:ListTagsForResource"},
+
+	"aws_opensearch_domain":        append([]string{"es:CreateDomain", "es:DescribeDomain", "es:DescribeDomainConfig", "es:UpdateDomainConfig", "es:DeleteDomain", "es:ListTags", "es:AddTags", "es:RemoveTags"}, serviceLinkedRole...),
+	"aws_opensearch_domain_policy": {"es:DescribeDomain", "es:DescribeDomainConfig", "es:UpdateDomainConfig"},
+
+	"aws_organizations_account":             {"organizations:CreateAccount", "organizations:DescribeCreateAccountStatus", "organizations:DescribeAccount", "organizations:MoveAccount", "organizations:ListParents", "organizations:CloseAccount", "organizations:RemoveAccountFromOrganization", "organizations:ListTagsForResource", "organizations:TagResource", "organizations:UntagResource", "iam:CreateServiceLinkedRole"},
+	"aws_organizations_organizational_unit": {"organizations:CreateOrganizationalUnit", "organizations:DescribeOrganizationalUnit", "organizations:UpdateOrganizationalUnit", "organizations:DeleteOrganizationalUnit", "organizations:ListAccountsForParent", "organizations:ListOrganizationalUnitsForParent", "organizations:ListTagsForResource", "organizations:TagResource", "organizations:UntagResource"},
+	"aws_organizations_policy":              {"organizations:CreatePolicy", "organizations:DescribePolicy", "organizations:UpdatePolicy", "organizations:DeletePolicy", "organizations:ListTagsForResource", "organizations:TagResource", "organizations:UntagResource"},
+	"aws_organizations_policy_attachment":   {"organizations:AttachPolicy", "organizations:DetachPolicy", "organizations:ListPoliciesForTarget", "organizations:ListTargetsForPolicy"},
+
+	"aws_redshiftserverless_namespace": append([]string{"redshift-serverless:CreateNamespace", "redshift-serverless:GetNamespace", "redshift-serverless:UpdateNamespace", "redshift-serverless:DeleteNamespace", "redshift-serverless:ListTagsForResource", "redshift-serverless:TagResource", "redshift-serverless:UntagResource", "secretsmanager:CreateSecret", "secretsmanager:DescribeSecret", "secretsmanager:DeleteSecret", "secretsmanager:TagResource"}, serviceLinkedRole...),
+	"aws_redshiftserverless_workgroup": append([]string{"redshift-serverless:CreateWorkgroup", "redshift-serverless:GetWorkgroup", "redshift-serverless:UpdateWorkgroup", "redshift-serverless:DeleteWorkgroup", "redshift-serverless:ListTagsForResource", "redshift-serverless:TagResource", "redshift-serverless:UntagResource", "ec2:DescribeAccountAttributes", "ec2:DescribeAvailabilityZones"}, describeNetwork...),
+
+	"aws_resourcegroups_group": {"resource-groups:CreateGroup", "resource-groups:GetGroup", "resource-groups:GetGroupQuery", "resource-groups:GetGroupConfiguration", "resource-groups:UpdateGroup", "resource-groups:UpdateGroupQuery", "resource-groups:DeleteGroup", "resource-groups:GetTags", "resource-groups:Tag", "resource-groups:Untag"},
+
+	"aws_prometheus_alert_manager_definition": {"aps:CreateAlertManagerDefinition", "aps:DescribeAlertManagerDefinition", "aps:PutAlertManagerDefinition", "aps:DeleteAlertManagerDefinition"},
+	"aws_prometheus_rule_group_namespace":     {"aps:CreateRuleGroupsNamespace", "aps:DescribeRuleGroupsNamespace", "aps:PutRuleGroupsNamespace", "aps:DeleteRuleGroupsNamespace", "aps:ListTagsForResource", "aps:TagResource", "aps:UntagResource"},
+	"aws_prometheus_workspace":                {"aps:CreateWorkspace", "aps:DescribeWorkspace", "aps:UpdateWorkspaceAlias", "aps:DeleteWorkspace", "aps:DescribeLoggingConfiguration", "aps:ListTagsForResource", "aps:TagResource", "aps:UntagResource"},
+
+	"aws_s3_bucket":                                      {"s3:CreateBucket", "s3:ListBucket", "s3:GetBucket*", "s3:GetAccelerateConfiguration", "s3:GetLifecycleConfiguration", "s3:GetReplicationConfiguration", "s3:GetEncryptionConfiguration", "s3:PutBucketTagging", "s3:DeleteBucket"},
+	"aws_s3_bucket_policy":                               {"s3:GetBucketPolicy", "s3:PutBucketPolicy", "s3:DeleteBucketPolicy"},
+	"aws_s3_bucket_public_access_block":                  {"s3:GetBucketPublicAccessBlock", "s3:PutBucketPublicAccessBlock"},
+	"aws_s3_bucket_server_side_encryption_configuration": {"s3:GetEncryptionConfiguration", "s3:PutEncryptionConfiguration"},
+	"aws_s3_bucket_versioning":                           {"s3:GetBucketVersioning", "s3:PutBucketVersioning"},
+
+	"aws_secretsmanager_secret":         {"secretsmanager:CreateSecret", "secretsmanager:DescribeSecret", "secretsmanager:GetResourcePolicy", "secretsmanager:UpdateSecret", "secretsmanager:DeleteSecret", "secretsmanager:TagResource", "secretsmanager:UntagResource"},
+	"aws_secretsmanager_secret_version": {"secretsmanager:PutSecretValue", "secretsmanager:GetSecretValue", "secretsmanager:DescribeSecret", "secretsmanager:UpdateSecretVersionStage"},
+
+	"aws_sagemaker_endpoint":               {"sagemaker:CreateEndpoint", "sagemaker:DescribeEndpoint", "sagemaker:UpdateEndpoint", "sagemaker:DeleteEndpoint", "sagemaker:ListTags", "sagemaker:AddTags", "sagemaker:DeleteTags"},
+	"aws_sagemaker_endpoint_configuration": {"sagemaker:CreateEndpointConfig", "sagemaker:DescribeEndpointConfig", "sagemaker:DeleteEndpointConfig", "sagemaker:ListTags", "sagemaker:AddTags", "sagemaker:DeleteTags", "kms:DescribeKey", "kms:CreateGrant"},
+	"aws_sagemaker_model":                  append([]string{"sagemaker:CreateModel", "sagemaker:DescribeModel", "sagemaker:DeleteModel", "sagemaker:ListTags", "sagemaker:AddTags", "sagemaker:DeleteTags", "iam:PassRole"}, describeNetwork...),
+
+	"aws_sns_topic":              {"sns:CreateTopic", "sns:GetTopicAttributes", "sns:SetTopicAttributes", "sns:DeleteTopic", "sns:ListTagsForResource", "sns:TagResource", "sns:UntagResource"},
+	"aws_sns_topic_policy":       {"sns:SetTopicAttributes", "sns:GetTopicAttributes"},
+	"aws_sns_topic_subscription": {"sns:Subscribe", "sns:GetSubscriptionAttributes", "sns:SetSubscriptionAttributes", "sns:Unsubscribe", "sns:ListSubscriptionsByTopic"},
+
+	"aws_ssm_maintenance_window":        {"ssm:CreateMaintenanceWindow", "ssm:GetMaintenanceWindow", "ssm:UpdateMaintenanceWindow", "ssm:DeleteMaintenanceWindow", "ssm:ListTagsForResource", "ssm:AddTagsToResource", "ssm:RemoveTagsFromResource"},
+	"aws_ssm_maintenance_window_target": {"ssm:RegisterTargetWithMaintenanceWindow", "ssm:DescribeMaintenanceWindowTargets", "ssm:UpdateMaintenanceWindowTarget", "ssm:DeregisterTargetFromMaintenanceWindow"},
+	"aws_ssm_maintenance_window_task":   append([]string{"ssm:RegisterTaskWithMaintenanceWindow", "ssm:GetMaintenanceWindowTask", "ssm:UpdateMaintenanceWindowTask", "ssm:DeregisterTaskFromMaintenanceWindow"}, serviceLinkedRole...),
+	"aws_ssm_parameter":                 {"ssm:PutParameter", "ssm:GetParameter", "ssm:GetParameters", "ssm:DescribeParameters", "ssm:DeleteParameter", "ssm:ListTagsForResource", "ssm:AddTagsToResource", "ssm:RemoveTagsFromResource"},
+
+	"aws_ssm_patch_baseline": {"ssm:CreatePatchBaseline", "ssm:GetPatchBaseline", "ssm:UpdatePatchBaseline", "ssm:DeletePatchBaseline", "ssm:ListTagsForResource", "ssm:AddTagsToResource", "ssm:RemoveTagsFromResource"},
+	"aws_ssm_patch_group":    {"ssm:RegisterPatchBaselineForPatchGroup", "ssm:DescribePatchGroups", "ssm:DeregisterPatchBaselineForPatchGroup"},
+
+	"aws_timestreamwrite_database": {"timestream:CreateDatabase", "timestream:DescribeDatabase", "timestream:UpdateDatabase", "timestream:DeleteDatabase", "timestream:DescribeEndpoints", "timestream:ListTagsForResource", "timestream:TagResource", "timestream:UntagResource", "kms:DescribeKey", "kms:CreateGrant"},
+	"aws_timestreamwrite_table":    {"timestream:CreateTable", "timestream:DescribeTable", "timestream:UpdateTable", "timestream:DeleteTable", "timestream:DescribeEndpoints", "timestream:ListTagsForResource", "timestream:TagResource", "timestream:UntagResource", "s3:GetBucketAcl", "s3:PutObject"},
+
+	"aws_transfer_server":  {"transfer:CreateServer", "transfer:DescribeServer", "transfer:UpdateServer", "transfer:DeleteServer", "transfer:StartServer", "transfer:StopServer", "transfer:ListTagsForResource", "transfer:TagResource", "transfer:UntagResource"},
+	"aws_transfer_ssh_key": {"transfer:ImportSshPublicKey", "transfer:DescribeUser", "transfer:DeleteSshPublicKey"},
+	"aws_transfer_user":    {"transfer:CreateUser", "transfer:DescribeUser", "transfer:UpdateUser", "transfer:DeleteUser", "transfer:TagResource", "transfer:UntagResource"},
+
+	"aws_wafv2_ip_set":              {"wafv2:CreateIPSet", "wafv2:GetIPSet", "wafv2:UpdateIPSet", "wafv2:DeleteIPSet", "wafv2:ListTagsForResource", "wafv2:TagResource", "wafv2:UntagResource"},
+	"aws_wafv2_web_acl":             {"wafv2:CreateWebACL", "wafv2:GetWebACL", "wafv2:UpdateWebACL", "wafv2:DeleteWebACL", "wafv2:ListTagsForResource", "wafv2:TagResource", "wafv2:UntagResource"},
+	"aws_wafv2_web_acl_association": {"wafv2:AssociateWebACL", "wafv2:GetWebACLForResource", "wafv2:DisassociateWebACL", "apprunner:AssociateWebAcl", "apprunner:DescribeWebAclForService", "apprunner:DisassociateWebAcl"},
+
+	"aws_xray_sampling_rule": {"xray:CreateSamplingRule", "xray:GetSamplingRules", "xray:UpdateSamplingRule", "xray:DeleteSamplingRule", "xray:ListTagsForResource", "xray:TagResource", "xray:UntagResource"},
+
+	// Cloud Control calls the resource type's own APIs with the caller's permissions, which depend
+	// on the type; grant those separately, or give the resource a role_arn
+	"awscc_cloudcontrolapi_resource": {"cloudformation:CreateResource", "cloudformation:GetResource", "cloudformation:UpdateResource", "cloudformation:DeleteResource", "cloudformation:GetResourceRequestStatus", "cloudformation:ListResources"},
+
+	// Data sources
+	"data.aws_caller_identity":             {"sts:GetCallerIdentity"},
+	"data.aws_eks_cluster":                 {"eks:DescribeCluster"},
+	"data.aws_eks_cluster_auth":            {}, // a presigned sts:GetCallerIdentity, signed locally
+	"data.aws_iam_openid_connect_provider": {"iam:GetOpenIDConnectProvider", "iam:ListOpenIDConnectProviders"},
+	"data.aws_iam_policy_document":         {}, // rendered by the provider
+	"data.aws_iot_endpoint":                {"iot:DescribeEndpoint"},
+	"data.aws_organizations_organization":  {"organizations:DescribeOrganization", "organizations:ListRoots", "organizations:ListAccounts", "organizations:ListAWSServiceAccessForOrganization", "organizations:ListDelegatedAdministrators"},
+}
+
+// providerActions are called by the AWS provider itself, whatever the stack contains
+var providerActions = []string{"sts:GetCallerIdentity"}
+
+// statementID turns an IAM service prefix into a statement Sid, e.g. redshift-serverless into
+// RedshiftServerless
+func statementID(service string) string {
+	var id strings.Builder
+	for _, word := range strings.Split(service, "-") {
+		if word != "" {
+			id.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return id.String()
+}
+
+// deployPolicy returns the policy document granting the actions of the given resource and data
+// source types, one statement per service. Types without known permissions are returned too.
+func deployPolicy(types map[string]int) (map[string]interface{}, []string) {
+	byService := map[string][]string{}
+	add := func(actions []string) {
+		for _, action := range actions {
+			service, _, _ := strings.Cut(action, ":")
+			if !slices.Contains(byService[service], action) {
+				byService[service] = append(byService[service], action)
+			}
+		}
+	}
+	add(providerActions)
+
+	var unknown []string
+	for _, resourceType := range slices.Sorted(maps.Keys(types)) {
+		actions, ok := deployPermissions[resourceType]
+		if !ok {
+			// Only AWS types need permissions; terraform_data or cloudflare records don't call AWS
+			if strings.HasPrefix(strings.TrimPrefix(resourceType, "data."), "aws_") {
+				unknown = append(unknown, resourceType)
+			}
+			continue
+		}
+		add(actions)
+	}
+
+	var statements []map[string]interface{}
+	for _, service := range slices.Sorted(maps.Keys(byService)) {
+		actions := byService[service]
+		sort.Strings(actions)
+		statements = append(statements, map[string]interface{}{
+			"Sid":      statementID(service),
+			"Effect":   "Allow",
+			"Action":   actions,
+			"Resource": "*",
+		})
+	}
+	return map[string]interface{}{"Version": "2012-10-17", "Statement": statements}, unknown
+}
+
+// managedPolicyLimit is the most characters, excluding whitespace, an IAM managed policy can have
+const managedPolicyLimit = 6144
+
+// splitPolicy splits a policy into documents within managedPolicyLimit, keeping each service's
+// statement whole, so each can be attached to the deploy role as a managed policy
+func splitPolicy(policy map[string]interface{}) []map[string]interface{} {
+	statements, _ := policy["Statement"].([]map[string]interface{})
+	var documents []map[string]interface{}
+	var current []map[string]interface{}
+	for _, statement := range statements {
+		candidate := append(slices.Clone(current), statement)
+		compact, _ := json.Marshal(map[string]interface{}{"Version": policy["Version"], "Statement": candidate})
+		if len(compact) > managedPolicyLimit && len(current) > 0 {
+			documents = append(documents, map[string]interface{}{"Version": policy["Version"], "Statement": current})
+			candidate = []map[string]interface{}{statement}
+		}
+		current = candidate
+	}
+	return append(documents, map[string]interface{}{"Version": policy["Version"], "Statement": current})
+}
+
+// runDeployPolicy is the deploy-policy command. It collects the resource types of every
+// synthesized stack and writes the IAM policy a deploy role needs to manage them.
+func runDeployPolicy(args []string) error {
+	flags := flag.NewFlagSet("deploy-policy", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	output := flags.String("output", "", "policy file (default <outdir>/deploy-policy.json)")
+	only := flags.String("stack", "", "cover only this stack")
+	flags.Parse(args)
+
+	stackNames, err := synthesizedStacks(*outdir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("🔐 Building the deploy role policy...")
+	types := map[string]int{}
+	modules := 0
+	for _, name := range stackNames {
+		if *only != "" && name != *only {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(*outdir, "stacks", name, "cdk.tf.json"))
+		if err != nil {
+			return err
+		}
+		var document struct {
+			Resource map[string]map[string]interface{} `json:"resource"`
+			Data     map[string]map[string]interface{} `json:"data"`
+			Module   map[string]interface{}            `json:"module"`
+		}
+		if err := json.Unmarshal(raw, &document); err != nil {
+			return fmt.Errorf("parsing %s: %w", name, err)
+		}
+		for resourceType, resources := range document.Resource {
+			types[resourceType] += len(resources)
+		}
+		for dataType, sources := range document.Data {
+			types["data."+dataType] += len(sources)
+		}
+		modules += len(document.Module)
+	}
+
+	policy, unknown := deployPolicy(types)
+	for _, resourceType := range unknown {
+		fmt.Printf("  ⚠ No known permissions for %s (%d); add them to deployPermissions\n", resourceType, types[resourceType])
+	}
+	if modules > 0 {
+		fmt.Printf("  ⚠ %d module(s) not covered: their resources aren't in cdk.tf.json\n", modules)
+	}
+
+	path := *output
+	if path == "" {
+		path = filepath.Join(*outdir, "deploy-policy.json")
+	}
+	documents := splitPolicy(policy)
+	var paths []string
+	for i, document := range documents {
+		file := path
+		if len(documents) > 1 {
+			file = strings.TrimSuffix(path, ".json") + fmt.Sprintf("-%d.json", i+1)
+		}
+		data, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, append(data, '\n'), 0o644); err != nil {
+			return fmt.Errorf("writing %s: %w", file, err)
+		}
+		paths = append(paths, file)
+	}
+	statements, _ := policy["Statement"].([]map[string]interface{})
+	fmt.Printf("  ✓ %d resource type(s), %d service statement(s); policy in %s\n", len(types), len(statements),
+		strings.Join(paths, ", "))
+	return nil
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// crossStackInput is the prefix cdktf gives the remote states it reads another stack's outputs from
+const crossStackInput = "cross-stack-reference-input-"
+
+// diagramNode is a resource, data source or module of a synthesized stack
+type diagramNode struct {
+	Address string // as Terraform names it, e.g. aws_s3_bucket.bucket or module.vpc
+	Kind    string // resource, data or module
+}
+
+// diagramEdge is a reference from one node to another it depends on, both as stack/address
+type diagramEdge struct {
+	From, To string
+	// CrossStack is a reference through a cross-stack output
+	CrossStack bool
+}
+
+// diagramStack is the nodes of one stack
+type diagramStack struct {
+	Name  string
+	Nodes []diagramNode
+}
+
+// diagram is the synthesized stacks and the references between their nodes
+type diagram struct {
+	Stacks []diagramStack
+	Edges  []diagramEdge
+}
+
+// referenceCandidate matches what could be a Terraform address in an expression; only those of a
+// node of the stack are kept
+var referenceCandidate = regexp.MustCompile(`(?:data\.)?[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+
+// crossStackReference matches a read of another stack's output
+var crossStackReference = regexp.MustCompile(`data\.terraform_remote_state\.` + crossStackInput + `([A-Za-z0-9_-]+)\.outputs\.([A-Za-z0-9_.-]+)`)
+
+// diagramDocument is the part of a cdk.tf.json the diagram reads
+type diagramDocument struct {
+	Resource map[string]map[string]interface{} `json:"resource"`
+	Data     map[string]map[string]interface{} `json:"data"`
+	Module   map[string]interface{}            `json:"module"`
+	Output   map[string]synthesizedOutput      `json:"output"`
+}
+
+// stringsIn calls found with every string in a decoded JSON value
+func stringsIn(value interface{}, found func(string)) {
+	switch value := value.(type) {
+	case string:
+		found(value)
+	case map[string]interface{}:
+		for _, key := range slices.Sorted(maps.Keys(value)) {
+			stringsIn(value[key], found)
+		}
+	case []interface{}:
+		for _, element := range value {
+			stringsIn(element, found)
+		}
+	}
+}
+
+// buildDiagram reads the named stacks of a cdktf output directory. A reference to another stack's
+// output is drawn to the node the output reads, when that stack is one of those named.
+func buildDiagram(outdir string, stackNames []string) (diagram, error) {
+	documents := map[string]diagramDocument{}
+	for _, name := range stackNames {
+		raw, err := os.ReadFile(filepath.Join(outdir, "stacks", name, "cdk.tf.json"))
+		if err != nil {
+			return diagram{}, err
+		}
+		var document diagramDocument
+		if err := json.Unmarshal(raw, &document); err != nil {
+			return diagram{}, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		documents[name] = document
+	}
+
+	var result diagram
+	addresses := map[string]map[string]bool{}
+	bodies := map[string]map[string]interface{}{}
+	for _, name := range stackNames {
+		document := documents[name]
+		stack := diagramStack{Name: name}
+		addresses[name] = map[string]bool{}
+		bodies[name] = map[string]interface{}{}
+		add := func(address, kind string, body interface{}) {
+			stack.Nodes = append(stack.Nodes, diagramNode{Address: address, Kind: kind})
+			addresses[name][address] = true
+			bodies[name][address] = body
+		}
+		for _, resourceType := range slices.Sorted(maps.Keys(document.Resource)) {
+			for _, id := range slices.Sorted(maps.Keys(document.Resource[resourceType])) {
+				add(resourceType+"."+id, "resource", document.Resource[resourceType][id])
+			}
+		}
+		for _, dataType := range slices.Sorted(maps.Keys(document.Data)) {
+			for _, id := range slices.Sorted(maps.Keys(document.Data[dataType])) {
+				// Cross-stack inputs are drawn as the references they carry
+				if dataType == "terraform_remote_state" && strings.HasPrefix(id, crossStackInput) {
+					continue
+				}
+				add("data."+dataType+"."+id, "data", document.Data[dataType][id])
+			}
+		}
+		for _, id := range slices.Sorted(maps.Keys(document.Module)) {
+			add("module."+id, "module", document.Module[id])
+		}
+		result.Stacks = append(result.Stacks, stack)
+	}
+
+	// references returns the nodes of a stack an expression reads
+	references := func(stack string, expression string) []string {
+		var found []string
+		for _, candidate := range referenceCandidate.FindAllString(expression, -1) {
+			if addresses[stack][candidate] {
+				found = append(found, candidate)
+			}
+		}
+		return found
+	}
+	seen := map[diagramEdge]bool{}
+	for _, stack := range result.Stacks {
+		for _, node := range stack.Nodes {
+			from := stack.Name + "/" + node.Address
+			stringsIn(bodies[stack.Name][node.Address], func(expression string) {
+				for _, to := range references(stack.Name, expression) {
+					if edge := (diagramEdge{From: from, To: stack.Name + "/" + to}); to != node.Address && !seen[edge] {
+						seen[edge] = true
+						result.Edges = append(result.Edges, edge)
+					}
+				}
+				for _, match := range crossStackReference.FindAllStringSubmatch(expression, -1) {
+					producer, output := match[1], match[2]
+					value, ok := documents[producer].Output[output]
+					if !ok {
+						continue
+					}
+					for _, to := range references(producer, fmt.Sprint(value.Value)) {
+						if edge := (diagramEdge{From: from, To: producer + "/" + to, CrossStack: true}); !seen[edge] {
+							seen[edge] = true
+							result.Edges = append(result.Edges, edge)
+						}
+					}
+				}
+			})
+		}
+	}
+	return result, nil
+}
+
+// nodeID is a node's identifier in the diagram formats, which don't allow dots or slashes in them
+func nodeID(stackIndex int, address string) string {
+	return fmt.Sprintf("s%d_%s", stackIndex, strings.NewReplacer(".", "_", "-", "_").Replace(address))
+}
+
+// ids maps each stack/address of a diagram to its node ID
+func (d diagram) ids() map[string]string {
+	ids := map[string]string{}
+	for i, stack := range d.Stacks {
+		for _, node := range stack.Nodes {
+			ids[stack.Name+"/"+node.Address] = nodeID(i, node.Address)
+		}
+	}
+	return ids
+}
+
+// mermaid renders a diagram as a Mermaid flowchart, with a subgraph per stack. Resources are
+// boxes, data sources rounded and modules double-sided; references point to what they read, dashed
+// across stacks.
+func (d diagram) mermaid() string {
+	var text strings.Builder
+	text.WriteString("flowchart LR\n")
+	for i, stack := range d.Stacks {
+		fmt.Fprintf(&text, "  subgraph s%d[\"%s\"]\n", i, stack.Name)
+		for _, node := range stack.Nodes {
+			open, close := "[\"", "\"]"
+			switch node.Kind {
+			case "data":
+				open, close = "([\"", "\"])"
+			case "module":
+				open, close = "[[\"", "\"]]"
+			}
+			fmt.Fprintf(&text, "    %s%s%s%s\n", nodeID(i, node.Address), open, node.Address, close)
+		}
+		text.WriteString("  end\n")
+	}
+	ids := d.ids()
+	for _, edge := range d.Edges {
+		arrow := "-->"
+		if edge.CrossStack {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&text, "  %s %s %s\n", ids[edge.From], arrow, ids[edge.To])
+	}
+	return text.String()
+}
+
+// drawioCell is an mxCell of a draw.io file
+type drawioCell struct {
+	XMLName  xml.Name        `xml:"mxCell"`
+	ID       string          `xml:"id,attr"`
+	Value    string          `xml:"value,attr,omitempty"`
+	Style    string          `xml:"style,attr,omitempty"`
+	Parent   string          `xml:"parent,attr,omitempty"`
+	Source   string          `xml:"source,attr,omitempty"`
+	Target   string          `xml:"target,attr,omitempty"`
+	Vertex   string          `xml:"vertex,attr,omitempty"`
+	Edge     string          `xml:"edge,attr,omitempty"`
+	Geometry *drawioGeometry `xml:"mxGeometry,omitempty"`
+}
+
+type drawioGeometry struct {
+	X        int    `xml:"x,attr,omitempty"`
+	Y        int    `xml:"y,attr,omitempty"`
+	Width    int    `xml:"width,attr,omitempty"`
+	Height   int    `xml:"height,attr,omitempty"`
+	Relative string `xml:"relative,attr,omitempty"`
+	As       string `xml:"as,attr"`
+}
+
+// Sizes of the draw.io layout: nodes are laid out in rows inside a container per stack, and the
+// containers side by side
+const (
+	drawioNodeWidth   = 220
+	drawioNodeHeight  = 40
+	drawioGap         = 20
+	drawioColumns     = 3
+	drawioHeaderSpace = 40
+)
+
+// drawio renders a diagram as a draw.io file. Nodes are laid out in a grid, for tidying up in
+// draw.io; the references are connectors, so they follow the nodes when they are moved.
+func (d diagram) drawio() ([]byte, error) {
+	cells := []drawioCell{{ID: "0"}, {ID: "1", Parent: "0"}}
+	styles := map[string]string{
+		"resource": "rounded=0;whiteSpace=wrap;html=1;",
+		"data":     "rounded=1;whiteSpace=wrap;html=1;dashed=1;",
+		"module":   "shape=process;whiteSpace=wrap;html=1;",
+	}
+	x := 0
+	for i, stack := range d.Stacks {
+		columns := min(drawioColumns, max(len(stack.Nodes), 1))
+		rows := (len(stack.Nodes) + drawioColumns - 1) / drawioColumns
+		width := columns*(drawioNodeWidth+drawioGap) + drawioGap
+		height := drawioHeaderSpace + rows*(drawioNodeHeight+drawioGap) + drawioGap
+		container := fmt.Sprintf("s%d", i)
+		cells = append(cells, drawioCell{
+			ID: container, Value: stack.Name, Style: "swimlane;startSize=30;html=1;", Parent: "1", Vertex: "1",
+			Geometry: &drawioGeometry{X: x, Width: width, Height: height, As: "geometry"},
+		})
+		for j, node := range stack.Nodes {
+			cells = append(cells, drawioCell{
+				ID: nodeID(i, node.Address), Value: node.Address, Style: styles[node.Kind], Parent: container, Vertex: "1",
+				Geometry: &drawioGeometry{
+					X:      drawioGap + (j%drawioColumns)*(drawioNodeWidth+drawioGap),
+					Y:      drawioHeaderSpace + (j/drawioColumns)*(drawioNodeHeight+drawioGap),
+					Width:  drawioNodeWidth,
+					Height: drawioNodeHeight,
+					As:     "geometry",
+				},
+			})
+		}
+		x += width + 4*drawioGap
+	}
+	ids := d.ids()
+	for i, edge := range d.Edges {
+		style := "endArrow=classic;html=1;"
+		if edge.CrossStack {
+			style += "dashed=1;"
+		}
+		cells = append(cells, drawioCell{
+			ID: fmt.Sprintf("e%d", i), Style: style, Parent: "1", Source: ids[edge.From], Target: ids[edge.To], Edge: "1",
+			Geometry: &drawioGeometry{Relative: "1", As: "geometry"},
+		})
+	}
+
+	type mxGraphModel struct {
+		Root []drawioCell `xml:"root>mxCell"`
+	}
+	type diagramPage struct {
+		Name  string       `xml:"name,attr"`
+		Model mxGraphModel `xml:"mxGraphModel"`
+	}
+	file := struct {
+		XMLName xml.Name    `xml:"mxfile"`
+		Diagram diagramPage `xml:"diagram"`
+	}{Diagram: diagramPage{Name: "Architecture", Model: mxGraphModel{Root: cells}}}
+	raw, err := xml.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(raw, '\n'), nil
+}
+
+// runDiagram is the diagram command. It draws the resources of the synthesized stacks and the
+// references between them, as Mermaid or draw.io. An -output ending in .md gets the Mermaid in a
+// fenced block, ready to commit with the architecture docs.
+func runDiagram(args []string) error {
+	flags := flag.NewFlagSet("diagram", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	format := flags.String("format", "mermaid", "mermaid or drawio")
+	output := flags.String("output", "", "diagram file (default <outdir>/architecture.mmd or .drawio)")
+	only := flags.String("stack", "", "draw only this stack")
+	flags.Parse(args)
+
+	stackNames, err := synthesizedStacks(*outdir)
+	if err != nil {
+		return err
+	}
+	if *only != "" {
+		if !slices.Contains(stackNames, *only) {
+			return invalidValue(*only, closestMatch(*only, stackNames), "stack %q isn't synthesized in %s", *only, *outdir)
+		}
+		stackNames = []string{*only}
+	}
+	architecture, err := buildDiagram(*outdir, stackNames)
+	if err != nil {
+		return err
+	}
+
+	path := *output
+	var content []byte
+	switch *format {
+	case "mermaid":
+		if path == "" {
+			path = filepath.Join(*outdir, "architecture.mmd")
+		}
+		content = []byte(architecture.mermaid())
+		if strings.HasSuffix(path, ".md") {
+			content = []byte("```mermaid\n" + string(content) + "```\n")
+		}
+	case "drawio":
+		if path == "" {
+			path = filepath.Join(*outdir, "architecture.drawio")
+		}
+		if content, err = architecture.drawio(); err != nil {
+			return err
+		}
+	default:
+		return invalidValue(*format, closestMatch(*format, []string{"mermaid", "drawio"}), "-format must be mermaid or drawio")
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		return err
+	}
+	nodes := 0
+	for _, stack := range architecture.Stacks {
+		nodes += len(stack.Nodes)
+	}
+	fmt.Printf("🗺️  Wrote %s: %d stack(s), %d node(s), %d reference(s)\n", path, len(architecture.Stacks), nodes, len(architecture.Edges))
+	return nil
+}
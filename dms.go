@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dmsendpoint"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dmsreplicationconfig"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dmsreplicationinstance"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dmsreplicationsubnetgroup"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dmsreplicationtask"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dmss3endpoint"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrole"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/redshiftserverlessworkgroup"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// DMSConfig runs Database Migration Service tasks that copy, and optionally keep replicating,
+// databases into the config's storage bucket or warehouse, or between databases outside it.
+// Tasks run on one replication instance, or use DMS Serverless when serverless is set.
+type DMSConfig struct {
+	VPC              VPCConfig            `json:"vpc"`
+	InstanceClass    string               `json:"instance_class"`    // defaults to dms.t3.medium
+	AllocatedStorage int                  `json:"allocated_storage"` // GiB on the instance, for cached changes and logs
+	Serverless       *DMSServerlessConfig `json:"serverless,omitempty"`
+	// CreateVPCRole creates dms-vpc-role, which DMS needs once per account to manage subnet groups
+	CreateVPCRole bool                   `json:"create_vpc_role"`
+	Endpoints     map[string]DMSEndpoint `json:"endpoints"`
+	Tasks         map[string]DMSTask     `json:"tasks"`
+}
+
+// DMSServerlessConfig bounds the DMS capacity units (DCUs) each task scales between
+type DMSServerlessConfig struct {
+	MinCapacity float64 `json:"min_capacity"`
+	MaxCapacity float64 `json:"max_capacity"`
+}
+
+// DMSEndpoint is a database DMS reads from or writes to. With resource, it is a section of the
+// config: storage, whose bucket tasks write files to, or warehouse. Other databases are named
+// by engine, server_name, port and database.
+type DMSEndpoint struct {
+	Type     string `json:"type"`   // source or target
+	Engine   string `json:"engine"` // e.g. postgres, mysql, aurora-postgresql, oracle, sqlserver
+	Resource string `json:"resource"`
+	// ServerName and Port default to the host and port of the secret; ServerName can refer to a
+	// resource of the same stack with ${...}
+	ServerName string `json:"server_name"`
+	Port       int    `json:"port"`
+	Database   string `json:"database"`
+	// SecretARN is a Secrets Manager secret with the username, password, host and port DMS connects with
+	SecretARN string `json:"secret_arn"`
+	SSLMode   string `json:"ssl_mode"` // none, require, verify-ca or verify-full
+	// Folder is the prefix files are written under in the storage bucket; defaults to dms/<name>
+	Folder string `json:"folder"`
+}
+
+// DMSTask migrates the tables of a source endpoint to a target endpoint
+type DMSTask struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// MigrationType is full-load (the default), cdc, or full-load-and-cdc
+	MigrationType string `json:"migration_type"`
+	// Tables are schema.table patterns to migrate, with % as a wildcard; all tables by default
+	Tables []string `json:"tables"`
+	// TableMappings replaces tables with a DMS table mapping document, for transformation rules
+	TableMappings json.RawMessage `json:"table_mappings"`
+	Start         bool            `json:"start"` // start the task once it is created or changed
+}
+
+var (
+	dmsEngines = []string{
+		"aurora", "aurora-postgresql", "db2", "docdb", "mariadb", "mongodb", "mysql", "oracle", "postgres", "redshift", "sqlserver", "sybase",
+	}
+	dmsResources      = []string{"storage", "warehouse"}
+	dmsMigrationTypes = []string{"cdc", "full-load", "full-load-and-cdc"}
+	dmsSSLModes       = []string{"none", "require", "verify-ca", "verify-full"}
+	dmsCapacityUnits  = []float64{1, 2, 4, 8, 16, 32, 64, 128, 192, 256, 384}
+	dmsTablePattern   = regexp.MustCompile(`^[A-Za-z0-9_%$-]+\.[A-Za-z0-9_%$-]+$`)
+	secretARNPattern  = regexp.MustCompile(`^arn:aws[a-z-]*:secretsmanager:[a-z0-9-]+:\d{12}:secret:.+$`)
+)
+
+func validateDMS(config Config) error {
+	d := config.DMS
+	if len(d.VPC.SubnetIDs) < 2 {
+		return fmt.Errorf("vpc.subnet_ids needs at least two subnets, in different availability zones")
+	}
+	if d.Serverless != nil {
+		for _, capacity := range []struct {
+			field string
+			value float64
+		}{{"min_capacity", d.Serverless.MinCapacity}, {"max_capacity", d.Serverless.MaxCapacity}} {
+			if !slices.Contains(dmsCapacityUnits, capacity.value) {
+				return invalidValue(capacity.value, "", "serverless: %s must be one of 1, 2, 4, 8, 16, 32, 64, 128, 192, 256 or 384", capacity.field)
+			}
+		}
+		if d.Serverless.MinCapacity > d.Serverless.MaxCapacity {
+			return fmt.Errorf("serverless: min_capacity is above max_capacity")
+		}
+	}
+	if d.InstanceClass != "" && !strings.HasPrefix(d.InstanceClass, "dms.") {
+		return invalidValue(d.InstanceClass, "dms."+d.InstanceClass, "instance_class %q must be a DMS class such as dms.t3.medium", d.InstanceClass)
+	}
+
+	endpoints := slices.Sorted(maps.Keys(d.Endpoints))
+	for _, name := range endpoints {
+		endpoint := d.Endpoints[name]
+		if !nameSegmentPattern.MatchString(name) {
+			return fmt.Errorf("endpoints: name %q must be lowercase letters, digits and hyphens", name)
+		}
+		if endpoint.Type != "source" && endpoint.Type != "target" {
+			return invalidValue(endpoint.Type, closestMatch(endpoint.Type, []string{"source", "target"}),
+				"endpoints.%s: type %q must be source or target", name, endpoint.Type)
+		}
+		if endpoint.Resource != "" {
+			if !slices.Contains(dmsResources, endpoint.Resource) {
+				return invalidValue(endpoint.Resource, closestMatch(endpoint.Resource, dmsResources),
+					"endpoints.%s: resource %q must be storage or warehouse", name, endpoint.Resource)
+			}
+			if endpoint.Type != "target" {
+				return fmt.Errorf("endpoints.%s: a %s endpoint can only be a target", name, endpoint.Resource)
+			}
+			if endpoint.Engine != "" || endpoint.ServerName != "" || endpoint.Port != 0 {
+				return fmt.Errorf("endpoints.%s: engine, server_name and port come from the %s section", name, endpoint.Resource)
+			}
+			if endpoint.Resource == "warehouse" && config.Warehouse == nil {
+				return fmt.Errorf("endpoints.%s: the config has no warehouse section", name)
+			}
+			if endpoint.Resource == "warehouse" && endpoint.SecretARN == "" {
+				// The admin secret Redshift manages has no host and port, which DMS reads from the secret
+				return fmt.Errorf("endpoints.%s: a warehouse endpoint needs secret_arn", name)
+			}
+		} else {
+			if !slices.Contains(dmsEngines, endpoint.Engine) {
+				return invalidValue(endpoint.Engine, closestMatch(endpoint.Engine, dmsEngines),
+					"endpoints.%s: unknown engine %q", name, endpoint.Engine)
+			}
+			if endpoint.SecretARN == "" {
+				// DMS takes the password in plain text otherwise, which would end up in the state
+				return fmt.Errorf("endpoints.%s: secret_arn is required, so no password is in the config", name)
+			}
+		}
+		if endpoint.SecretARN != "" && !secretARNPattern.MatchString(endpoint.SecretARN) {
+			return invalidValue(endpoint.SecretARN, "", "endpoints.%s: secret_arn %q is not a Secrets Manager secret ARN", name, endpoint.SecretARN)
+		}
+		if endpoint.SSLMode != "" && !slices.Contains(dmsSSLModes, endpoint.SSLMode) {
+			return invalidValue(endpoint.SSLMode, closestMatch(endpoint.SSLMode, dmsSSLModes),
+				"endpoints.%s: unknown ssl_mode %q", name, endpoint.SSLMode)
+		}
+		if endpoint.Folder != "" && endpoint.Resource != "storage" {
+			return fmt.Errorf("endpoints.%s: folder only applies to storage endpoints", name)
+		}
+	}
+
+	if len(d.Tasks) == 0 {
+		return fmt.Errorf("at least one task is required")
+	}
+	for _, name := range slices.Sorted(maps.Keys(d.Tasks)) {
+		task := d.Tasks[name]
+		if !nameSegmentPattern.MatchString(name) {
+			return fmt.Errorf("tasks: name %q must be lowercase letters, digits and hyphens", name)
+		}
+		for _, side := range []struct{ field, name, want string }{{"source", task.Source, "source"}, {"target", task.Target, "target"}} {
+			endpoint, ok := d.Endpoints[side.name]
+			if !ok {
+				return invalidValue(side.name, closestMatch(side.name, endpoints), "tasks.%s: unknown %s endpoint %q", name, side.field, side.name)
+			}
+			if endpoint.Type != side.want {
+				return fmt.Errorf("tasks.%s: %s endpoint %s is a %s endpoint", name, side.field, side.name, endpoint.Type)
+			}
+		}
+		if task.MigrationType != "" && !slices.Contains(dmsMigrationTypes, task.MigrationType) {
+			return invalidValue(task.MigrationType, closestMatch(task.MigrationType, dmsMigrationTypes),
+				"tasks.%s: migration_type %q must be full-load, cdc or full-load-and-cdc", name, task.MigrationType)
+		}
+		if len(task.Tables) > 0 && task.TableMappings != nil {
+			return fmt.Errorf("tasks.%s: set tables or table_mappings, not both", name)
+		}
+		for _, table := range task.Tables {
+			if !dmsTablePattern.MatchString(table) {
+				return invalidValue(table, "", "tasks.%s: table %q must be schema.table, with %% as a wildcard", name, table)
+			}
+		}
+		if task.TableMappings != nil {
+			var mappings struct {
+				Rules []json.RawMessage `json:"rules"`
+			}
+			if err := json.Unmarshal(task.TableMappings, &mappings); err != nil || len(mappings.Rules) == 0 {
+				return fmt.Errorf("tasks.%s: table_mappings must be a JSON object with rules", name)
+			}
+		}
+	}
+	return nil
+}
+
+// tableMappings renders the task's table mapping document, selecting its tables
+func (t DMSTask) tableMappings() string {
+	if t.TableMappings != nil {
+		var compact bytes.Buffer
+		json.Compact(&compact, t.TableMappings)
+		return compact.String()
+	}
+	tables := t.Tables
+	if len(tables) == 0 {
+		tables = []string{"%.%"}
+	}
+	var rules []map[string]interface{}
+	for i, table := range tables {
+		schema, name, _ := strings.Cut(table, ".")
+		rules = append(rules, map[string]interface{}{
+			"rule-type":      "selection",
+			"rule-id":        fmt.Sprint(i + 1),
+			"rule-name":      fmt.Sprintf("include-%d", i+1),
+			"object-locator": map[string]string{"schema-name": schema, "table-name": name},
+			"rule-action":    "include",
+		})
+	}
+	mappings, _ := json.Marshal(map[string]interface{}{"rules": rules})
+	return string(mappings)
+}
+
+func (t DMSTask) migrationType() string {
+	if t.MigrationType != "" {
+		return t.MigrationType
+	}
+	return "full-load"
+}
+
+// addDMS creates the subnet group, the endpoints with the roles DMS reads their secrets and writes
+// to the bucket with, and each task on the replication instance or as a serverless replication
+func addDMS(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket, warehouse redshiftserverlessworkgroup.RedshiftserverlessWorkgroup) {
+	dms := config.DMS
+
+	subnetGroupConfig := &dmsreplicationsubnetgroup.DmsReplicationSubnetGroupConfig{
+		ReplicationSubnetGroupId:          jsii.String(resourceName(config, "aws_dms_replication_subnet_group", "dms")),
+		ReplicationSubnetGroupDescription: jsii.String("Subnets of the " + config.Project + " " + config.Environment + " DMS tasks"),
+		SubnetIds:                         jsii.Strings(dms.VPC.SubnetIDs...),
+	}
+	if dms.CreateVPCRole {
+		// DMS looks this role up by name, so it can't follow the naming template
+		vpcRole := newServiceRole(stack, "dms_vpc_role", "dms-vpc-role", "dms.amazonaws.com", config,
+			"arn:aws:iam::aws:policy/service-role/AmazonDMSVPCManagementRole")
+		subnetGroupConfig.DependsOn = &[]cdktf.ITerraformDependable{vpcRole}
+	}
+	subnetGroup := dmsreplicationsubnetgroup.NewDmsReplicationSubnetGroup(stack, jsii.String("dms_subnet_group"), subnetGroupConfig)
+
+	var secrets []string
+	writesBucket := false
+	for _, name := range slices.Sorted(maps.Keys(dms.Endpoints)) {
+		endpoint := dms.Endpoints[name]
+		if endpoint.SecretARN != "" && !slices.Contains(secrets, endpoint.SecretARN) {
+			secrets = append(secrets, endpoint.SecretARN)
+		}
+		writesBucket = writesBucket || endpoint.Resource != ""
+	}
+	var secretsRole, bucketRole iamrole.IamRole
+	if len(secrets) > 0 {
+		// DMS assumes the secrets role through its regional service principal
+		secretsRole = newServiceRole(stack, "dms_secrets_role", resourceName(config, "aws_iam_role", "dms-secrets"),
+			"dms."+config.Region+".amazonaws.com", config)
+		addInlinePolicy(stack, "dms_secrets_policy", secretsRole, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   "secretsmanager:GetSecretValue",
+			"Resource": secrets,
+		})
+	}
+	if writesBucket {
+		// Warehouse loads are staged in the bucket too, as files Redshift copies in
+		bucketRole = newServiceRole(stack, "dms_bucket_role", resourceName(config, "aws_iam_role", "dms-bucket"), "dms.amazonaws.com", config)
+		addInlinePolicy(stack, "dms_bucket_policy", bucketRole, bucketAccessStatement(bucket, true))
+	}
+
+	endpointARNs := map[string]*string{}
+	for _, name := range slices.Sorted(maps.Keys(dms.Endpoints)) {
+		endpoint := dms.Endpoints[name]
+		id := "dms_endpoint_" + strings.ReplaceAll(name, "-", "_")
+		endpointID := jsii.String(resourceName(config, "aws_dms_endpoint", name))
+
+		if endpoint.Resource == "storage" {
+			folder := endpoint.Folder
+			if folder == "" {
+				folder = "dms/" + name
+			}
+			created := dmss3endpoint.NewDmsS3Endpoint(stack, jsii.String(id), &dmss3endpoint.DmsS3EndpointConfig{
+				EndpointId:           endpointID,
+				EndpointType:         jsii.String("target"),
+				BucketName:           bucket.Bucket(),
+				BucketFolder:         jsii.String(folder),
+				ServiceAccessRoleArn: bucketRole.Arn(),
+				DataFormat:           jsii.String("parquet"),
+				ParquetVersion:       jsii.String("parquet-2-0"),
+				// Rows of a CDC task say whether they were inserted, updated or deleted
+				IncludeOpForFullLoad: jsii.Bool(true),
+				TimestampColumnName:  jsii.String("dms_commit_time"),
+			})
+			endpointARNs[name] = created.EndpointArn()
+			continue
+		}
+
+		endpointConfig := &dmsendpoint.DmsEndpointConfig{
+			EndpointId:   endpointID,
+			EndpointType: jsii.String(endpoint.Type),
+			EngineName:   jsii.String(endpoint.Engine),
+		}
+		if endpoint.Resource == "warehouse" {
+			address := warehouse.Endpoint().Get(jsii.Number(0))
+			endpointConfig.EngineName = jsii.String("redshift-serverless")
+			endpointConfig.ServerName = address.Address()
+			endpointConfig.Port = address.Port()
+			endpointConfig.DatabaseName = jsii.String(config.Warehouse.database())
+			endpointConfig.RedshiftSettings = &dmsendpoint.DmsEndpointRedshiftSettings{
+				BucketName:           bucket.Bucket(),
+				BucketFolder:         jsii.String("dms/" + name),
+				ServiceAccessRoleArn: bucketRole.Arn(),
+			}
+		} else {
+			if endpoint.ServerName != "" {
+				endpointConfig.ServerName = jsii.String(endpoint.ServerName)
+			}
+			if endpoint.Port != 0 {
+				endpointConfig.Port = jsii.Number(endpoint.Port)
+			}
+		}
+		if endpoint.Database != "" {
+			endpointConfig.DatabaseName = jsii.String(endpoint.Database)
+		}
+		if endpoint.SecretARN != "" {
+			endpointConfig.SecretsManagerArn = jsii.String(endpoint.SecretARN)
+			endpointConfig.SecretsManagerAccessRoleArn = secretsRole.Arn()
+		}
+		if endpoint.SSLMode != "" {
+			endpointConfig.SslMode = jsii.String(endpoint.SSLMode)
+		}
+		endpointARNs[name] = dmsendpoint.NewDmsEndpoint(stack, jsii.String(id), endpointConfig).EndpointArn()
+	}
+
+	var instance dmsreplicationinstance.DmsReplicationInstance
+	if dms.Serverless == nil {
+		instanceClass := dms.InstanceClass
+		if instanceClass == "" {
+			instanceClass = "dms.t3.medium"
+		}
+		instanceConfig := &dmsreplicationinstance.DmsReplicationInstanceConfig{
+			ReplicationInstanceId:    jsii.String(resourceName(config, "aws_dms_replication_instance", "dms")),
+			ReplicationInstanceClass: jsii.String(instanceClass),
+			ReplicationSubnetGroupId: subnetGroup.ReplicationSubnetGroupId(),
+			PubliclyAccessible:       jsii.Bool(false),
+		}
+		if len(dms.VPC.SecurityGroupIDs) > 0 {
+			instanceConfig.VpcSecurityGroupIds = jsii.Strings(dms.VPC.SecurityGroupIDs...)
+		}
+		if dms.AllocatedStorage > 0 {
+			instanceConfig.AllocatedStorage = jsii.Number(dms.AllocatedStorage)
+		}
+		instance = dmsreplicationinstance.NewDmsReplicationInstance(stack, jsii.String("dms_instance"), instanceConfig)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(dms.Tasks)) {
+		task := dms.Tasks[name]
+		id := "dms_task_" + strings.ReplaceAll(name, "-", "_")
+		if dms.Serverless != nil {
+			compute := &dmsreplicationconfig.DmsReplicationConfigComputeConfig{
+				ReplicationSubnetGroupId: subnetGroup.ReplicationSubnetGroupId(),
+				MinCapacityUnits:         jsii.Number(dms.Serverless.MinCapacity),
+				MaxCapacityUnits:         jsii.Number(dms.Serverless.MaxCapacity),
+			}
+			if len(dms.VPC.SecurityGroupIDs) > 0 {
+				compute.VpcSecurityGroupIds = jsii.Strings(dms.VPC.SecurityGroupIDs...)
+			}
+			dmsreplicationconfig.NewDmsReplicationConfig(stack, jsii.String(id), &dmsreplicationconfig.DmsReplicationConfigConfig{
+				ReplicationConfigIdentifier: jsii.String(resourceName(config, "aws_dms_replication_config", name)),
+				ReplicationType:             jsii.String(task.migrationType()),
+				SourceEndpointArn:           endpointARNs[task.Source],
+				TargetEndpointArn:           endpointARNs[task.Target],
+				TableMappings:               jsii.String(task.tableMappings()),
+				ComputeConfig:               compute,
+				StartReplication:            jsii.Bool(task.Start),
+			})
+			continue
+		}
+		dmsreplicationtask.NewDmsReplicationTask(stack, jsii.String(id), &dmsreplicationtask.DmsReplicationTaskConfig{
+			ReplicationTaskId:      jsii.String(resourceName(config, "aws_dms_replication_task", name)),
+			MigrationType:          jsii.String(task.migrationType()),
+			ReplicationInstanceArn: instance.ReplicationInstanceArn(),
+			SourceEndpointArn:      endpointARNs[task.Source],
+			TargetEndpointArn:      endpointARNs[task.Target],
+			TableMappings:          jsii.String(task.tableMappings()),
+			StartReplicationTask:   jsii.Bool(task.Start),
+		})
+	}
+
+	mode := "on a replication instance"
+	if dms.Serverless != nil {
+		mode = "serverless"
+	}
+	fmt.Printf("  ✓ DMS with %d endpoint(s) and %d task(s) %s\n", len(dms.Endpoints), len(dms.Tasks), mode)
+}
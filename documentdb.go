@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/docdbcluster"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/docdbclusterinstance"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/docdbclusterparametergroup"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/docdbsubnetgroup"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// DocumentDBConfig describes a MongoDB-compatible DocumentDB cluster inside the VPC
+type DocumentDBConfig struct {
+	EngineVersion  string  `json:"engine_version"` // 3.6.0, 4.0.0 or 5.0.0
+	InstanceClass  string  `json:"instance_class"`
+	Instances      float64 `json:"instances"`       // the primary and its replicas, 1 by default
+	MasterUsername string  `json:"master_username"` // defaults to docdbadmin
+	// Parameters are set on the cluster parameter group, on top of TLS and the audit log being
+	// enabled; TLS can't be turned off
+	Parameters          map[string]string `json:"parameters"`
+	BackupRetentionDays float64           `json:"backup_retention_days"`
+	KMSKeyArn           string            `json:"kms_key_arn"`
+	VPC                 VPCConfig         `json:"vpc"`
+}
+
+var (
+	documentDBVersionPattern = regexp.MustCompile(`^(\d+\.\d+)\.\d+$`)
+	// DocumentDB reserves these as user names
+	documentDBReservedUsernames = []string{"admin", "root", "serviceadmin"}
+)
+
+// documentDBDefaultParameters require TLS from every client and keep an audit log, exported to
+// CloudWatch Logs
+var documentDBDefaultParameters = map[string]string{"tls": "enabled", "audit_logs": "enabled"}
+
+func (d *DocumentDBConfig) engineVersion() string {
+	if d.EngineVersion != "" {
+		return d.EngineVersion
+	}
+	return "5.0.0"
+}
+
+// parameterGroupFamily is the family of an engine version, e.g. docdb5.0 for 5.0.0
+func (d *DocumentDBConfig) parameterGroupFamily() string {
+	return "docdb" + documentDBVersionPattern.FindStringSubmatch(d.engineVersion())[1]
+}
+
+func (d *DocumentDBConfig) masterUsername() string {
+	if d.MasterUsername != "" {
+		return d.MasterUsername
+	}
+	return "docdbadmin"
+}
+
+func (d *DocumentDBConfig) validate() error {
+	// A subnet group has to span two availability zones
+	if len(d.VPC.SubnetIDs) < 2 {
+		return fmt.Errorf("vpc.subnet_ids needs at least two subnets")
+	}
+	if d.EngineVersion != "" && !documentDBVersionPattern.MatchString(d.EngineVersion) {
+		return fmt.Errorf("engine_version %q must be a DocumentDB version such as 5.0.0", d.EngineVersion)
+	}
+	if d.Instances < 0 || d.Instances > 16 {
+		return fmt.Errorf("instances must be between 1 and 16 (a primary and up to 15 replicas)")
+	}
+	if d.InstanceClass != "" && !strings.HasPrefix(d.InstanceClass, "db.") {
+		return invalidValue(d.InstanceClass, "db."+d.InstanceClass, "instance_class %q must be a db. instance class", d.InstanceClass)
+	}
+	if slices.Contains(documentDBReservedUsernames, strings.ToLower(d.MasterUsername)) {
+		return fmt.Errorf("master_username %q is reserved by DocumentDB", d.MasterUsername)
+	}
+	if d.BackupRetentionDays != 0 && (d.BackupRetentionDays < 1 || d.BackupRetentionDays > 35) {
+		return fmt.Errorf("backup_retention_days must be between 1 and 35")
+	}
+	if d.Parameters["tls"] == "disabled" {
+		return fmt.Errorf("parameters: tls can't be disabled, clients always connect with TLS")
+	}
+	return nil
+}
+
+// addDocumentDB creates the subnet group, cluster parameter group, cluster and its instances. The
+// master password is generated and kept in Secrets Manager by DocumentDB itself, so it never
+// appears in the synthesized JSON.
+func addDocumentDB(stack cdktf.TerraformStack, config Config) {
+	docdb := config.DocumentDB
+
+	instanceClass := docdb.InstanceClass
+	if instanceClass == "" {
+		instanceClass = sizeFor(config).DocumentDBInstanceClass
+	}
+	instances := docdb.Instances
+	if instances == 0 {
+		instances = 1
+	}
+	backupRetention := docdb.BackupRetentionDays
+	if backupRetention == 0 {
+		backupRetention = 7
+	}
+
+	subnetGroup := docdbsubnetgroup.NewDocdbSubnetGroup(stack, jsii.String("documentdb_subnet_group"),
+		&docdbsubnetgroup.DocdbSubnetGroupConfig{
+			Name:      jsii.String(resourceName(config, "aws_docdb_subnet_group", "documents")),
+			SubnetIds: jsii.Strings(docdb.VPC.SubnetIDs...),
+		})
+
+	parameters := maps.Clone(documentDBDefaultParameters)
+	maps.Copy(parameters, docdb.Parameters)
+	var parameterBlocks []*docdbclusterparametergroup.DocdbClusterParameterGroupParameter
+	for _, name := range slices.Sorted(maps.Keys(parameters)) {
+		parameterBlocks = append(parameterBlocks, &docdbclusterparametergroup.DocdbClusterParameterGroupParameter{
+			Name:  jsii.String(name),
+			Value: jsii.String(parameters[name]),
+		})
+	}
+	parameterGroup := docdbclusterparametergroup.NewDocdbClusterParameterGroup(stack, jsii.String("documentdb_parameters"),
+		&docdbclusterparametergroup.DocdbClusterParameterGroupConfig{
+			Name:      jsii.String(resourceName(config, "aws_docdb_cluster_parameter_group", "documents")),
+			Family:    jsii.String(docdb.parameterGroupFamily()),
+			Parameter: &parameterBlocks,
+		})
+
+	clusterIdentifier := resourceName(config, "aws_docdb_cluster", "documents")
+	clusterConfig := &docdbcluster.DocdbClusterConfig{
+		ClusterIdentifier:            jsii.String(clusterIdentifier),
+		Engine:                       jsii.String("docdb"),
+		EngineVersion:                jsii.String(docdb.engineVersion()),
+		MasterUsername:               jsii.String(docdb.masterUsername()),
+		ManageMasterUserPassword:     jsii.Bool(true),
+		DbSubnetGroupName:            subnetGroup.Name(),
+		DbClusterParameterGroupName:  parameterGroup.Name(),
+		VpcSecurityGroupIds:          jsii.Strings(docdb.VPC.SecurityGroupIDs...),
+		StorageEncrypted:             jsii.Bool(true),
+		EnabledCloudwatchLogsExports: jsii.Strings("audit"),
+		BackupRetentionPeriod:        jsii.Number(backupRetention),
+		FinalSnapshotIdentifier:      jsii.String(clusterIdentifier + "-final"),
+	}
+	if docdb.KMSKeyArn != "" {
+		clusterConfig.KmsKeyId = jsii.String(docdb.KMSKeyArn)
+	}
+	cluster := docdbcluster.NewDocdbCluster(stack, jsii.String("documentdb"), clusterConfig)
+
+	for i := 1; i <= int(instances); i++ {
+		docdbclusterinstance.NewDocdbClusterInstance(stack, jsii.String(fmt.Sprintf("documentdb_instance_%d", i)),
+			&docdbclusterinstance.DocdbClusterInstanceConfig{
+				Identifier:        jsii.String(resourceName(config, "aws_docdb_cluster_instance", fmt.Sprintf("documents-%d", i))),
+				ClusterIdentifier: cluster.Id(),
+				InstanceClass:     jsii.String(instanceClass),
+			})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("documentdb_endpoint"), &cdktf.TerraformOutputConfig{
+		Value:       cluster.Endpoint(),
+		Description: jsii.String("The primary endpoint of the DocumentDB cluster"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("documentdb_reader_endpoint"), &cdktf.TerraformOutputConfig{
+		Value:       cluster.ReaderEndpoint(),
+		Description: jsii.String("The endpoint balancing reads across the DocumentDB cluster's replicas"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("documentdb_secret_arn"), &cdktf.TerraformOutputConfig{
+		Value:       cluster.MasterUserSecret().Get(jsii.Number(0)).SecretArn(),
+		Description: jsii.String("The Secrets Manager secret holding the master user's credentials"),
+	})
+
+	fmt.Printf("  ✓ DocumentDB cluster (%v x %s)\n", instances, instanceClass)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// driftedResource is a resource whose real state no longer matches the last apply
+type driftedResource struct {
+	Stack   string `json:"stack"`
+	Address string `json:"address"`
+	Action  string `json:"action"` // update or delete
+}
+
+// terraformMessage is one line of `terraform plan -json`
+type terraformMessage struct {
+	Type   string `json:"type"`
+	Change struct {
+		Resource struct {
+			Addr string `json:"addr"`
+		} `json:"resource"`
+		Action string `json:"action"`
+	} `json:"change"`
+}
+
+// detectDrift runs a refresh-only plan of one synthesized stack and returns the drifted resources
+func detectDrift(dir string, stack string, skipInit bool) ([]driftedResource, error) {
+	if !skipInit {
+		if err := terraformInit(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	plan := exec.Command("terraform", "plan", "-refresh-only", "-input=false", "-no-color", "-json", "-lock=false")
+	plan.Dir = dir
+	var stderr bytes.Buffer
+	plan.Stderr = &stderr
+	span := startCommandSpan(plan)
+	stdout, err := plan.Output()
+	span.finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("terraform plan: %w\n%s", err, stderr.String())
+	}
+
+	var drifted []driftedResource
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var message terraformMessage
+		if json.Unmarshal(scanner.Bytes(), &message) != nil || message.Type != "resource_drift" {
+			continue
+		}
+		drifted = append(drifted, driftedResource{
+			Stack:   stack,
+			Address: message.Change.Resource.Addr,
+			Action:  message.Change.Action,
+		})
+	}
+	return drifted, scanner.Err()
+}
+
+// runDrift is the drift command. It reports resources changed outside the config in every
+// synthesized stack and fails when any are found, so a scheduled CI job can alert on it.
+func runDrift(args []string) error {
+	flags := flag.NewFlagSet("drift", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	output := flags.String("output", "", "report file (default <outdir>/drift-report.json)")
+	only := flags.String("stack", "", "check only this stack")
+	skipInit := flags.Bool("skip-init", false, "don't run terraform init first")
+	flags.Parse(args)
+
+	if _, err := exec.LookPath("terraform"); err != nil {
+		return fmt.Errorf("terraform not found on PATH: %w", err)
+	}
+	stackNames, err := synthesizedStacks(*outdir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("🔍 Checking deployed stacks for drift...")
+	drifted := []driftedResource{}
+	var failed []error
+	for _, name := range stackNames {
+		if *only != "" && name != *only {
+			continue
+		}
+		resources, err := detectDrift(filepath.Join(*outdir, "stacks", name), name, *skipInit)
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", name, err))
+			fmt.Printf("  ✗ %s: could not check\n", name)
+			continue
+		}
+		if len(resources) == 0 {
+			fmt.Printf("  ✓ %s: no drift\n", name)
+			continue
+		}
+		fmt.Printf("  ⚠ %s: %d drifted resource(s)\n", name, len(resources))
+		for _, resource := range resources {
+			fmt.Printf("      %-7s %s\n", resource.Action, resource.Address)
+		}
+		drifted = append(drifted, resources...)
+	}
+
+	path := *output
+	if path == "" {
+		path = filepath.Join(*outdir, "drift-report.json")
+	}
+	data, err := json.MarshalIndent(drifted, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	if len(failed) > 0 {
+		return errors.Join(failed...)
+	}
+	if len(drifted) > 0 {
+		return fmt.Errorf("drift detected in %d resource(s); see %s", len(drifted), path)
+	}
+	return nil
+}
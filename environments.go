@@ -0,0 +1,243 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/provider"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// EnvironmentConfig holds the settings that differ between the environments of one config,
+// keyed by environment name. The entry matching the active environment is used.
+type EnvironmentConfig struct {
+	AccountID     string `json:"account_id"`
+	DeployRoleARN string `json:"deploy_role_arn"` // assumed by the provider to deploy into the account
+	Size          string `json:"size"`            // small, medium (default) or large; see sizes.go
+	// DeployRoleExternalID is passed when assuming the deploy role, for trust policies that require one
+	DeployRoleExternalID string `json:"deploy_role_external_id"`
+	// DeployRoleSessionName names the session in CloudTrail; {user} and {timestamp} are filled in
+	// by Terraform at plan time, so synth output doesn't change between runs
+	DeployRoleSessionName string `json:"deploy_role_session_name"`
+	// DeployRoleSessionTags are attached to the deploy role session, for attribution and ABAC
+	DeployRoleSessionTags map[string]string `json:"deploy_role_session_tags"`
+	// LocalStack points the providers at LocalStack, with test credentials and path-style S3
+	LocalStack         bool   `json:"localstack"`
+	LocalStackEndpoint string `json:"localstack_endpoint"` // default http://localhost:4566
+	// Endpoints sets custom endpoints by provider service name, e.g. {"s3": "http://localhost:4566"}
+	Endpoints map[string]string `json:"endpoints"`
+	// Profile names the AWS profile the provider and the S3 backend read credentials from, in the
+	// default files or the ones listed; paths must be absolute or start with ~
+	Profile                string   `json:"profile"`
+	SharedCredentialsFiles []string `json:"shared_credentials_files"`
+	SharedConfigFiles      []string `json:"shared_config_files"`
+	// WebIdentity gets the provider's and the backend's credentials by assuming a role with an OIDC
+	// token, as on EKS deploy runners; deploy_role_arn, when set, is assumed from that role
+	WebIdentity *WebIdentityConfig `json:"web_identity"`
+}
+
+// WebIdentityConfig is the provider's assume_role_with_web_identity
+type WebIdentityConfig struct {
+	RoleARN string `json:"role_arn"`
+	// TokenFile holds the OIDC token; it defaults to AWS_WEB_IDENTITY_TOKEN_FILE, as set on EKS
+	TokenFile   string `json:"web_identity_token_file"`
+	SessionName string `json:"session_name"`
+}
+
+var (
+	externalIDPattern = regexp.MustCompile(`^[\w+=,.@:/-]+$`)
+	// sessionNameTemplate is a session name, where {user} and {timestamp} take up to 64 characters
+	// once filled in
+	sessionNameTemplate = regexp.MustCompile(`^([\w+=,.@-]|\{user\}|\{timestamp\})+$`)
+)
+
+// deployUserVariable is the Terraform variable {user} reads; the deploy command sets it to the
+// local user
+const deployUserVariable = "deploy_user"
+
+// sessionName turns a deploy_role_session_name template into the session name Terraform
+// evaluates at plan time. {user} is the deploy_user variable, with the characters STS doesn't
+// allow replaced, and {timestamp} the plan time in UTC, as 20060102T150405Z. The variable is
+// declared in the stack the first time a template uses it.
+func sessionName(stack cdktf.TerraformStack, template string) string {
+	if !strings.Contains(template, "{") {
+		return template[:min(len(template), 64)]
+	}
+	if strings.Contains(template, "{user}") && stack.Node().TryFindChild(jsii.String(deployUserVariable)) == nil {
+		cdktf.NewTerraformVariable(stack, jsii.String(deployUserVariable), &cdktf.TerraformVariableConfig{
+			Type:        jsii.String("string"),
+			Default:     jsii.String("unknown"),
+			Description: jsii.String("The user deploying, named in the deploy role session; set by the deploy command"),
+		})
+	}
+	name := strings.NewReplacer(
+		"{user}", `${replace(var.`+deployUserVariable+`, "/[^\\w+=,.@-]/", "-")}`,
+		"{timestamp}", `${formatdate("YYYYMMDD'T'hhmmss'Z'", plantimestamp())}`,
+	).Replace(template)
+	return `${substr("` + name + `", 0, 64)}`
+}
+
+// roleAccount returns the account of an IAM role ARN, and false for anything else
+func roleAccount(arn string) (string, bool) {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "iam" || !strings.HasPrefix(parts[5], "role/") {
+		return "", false
+	}
+	return parts[4], true
+}
+
+func (w *WebIdentityConfig) validate() error {
+	if _, ok := roleAccount(w.RoleARN); !ok {
+		return fmt.Errorf("role_arn %q is not an IAM role ARN", w.RoleARN)
+	}
+	if w.TokenFile != "" && !filepath.IsAbs(w.TokenFile) {
+		return fmt.Errorf("web_identity_token_file %q must be an absolute path", w.TokenFile)
+	}
+	return nil
+}
+
+var accountIDPattern = regexp.MustCompile(`^\d{12}$`)
+
+func (e *EnvironmentConfig) validate() error {
+	if _, ok := sizePresets[e.Size]; e.Size != "" && !ok {
+		return fmt.Errorf("size %q must be small, medium or large", e.Size)
+	}
+	if e.AccountID != "" && !accountIDPattern.MatchString(e.AccountID) {
+		return fmt.Errorf("account_id %q must be 12 digits", e.AccountID)
+	}
+	if e.DeployRoleARN != "" {
+		account, ok := roleAccount(e.DeployRoleARN)
+		if !ok {
+			return fmt.Errorf("deploy_role_arn %q is not an IAM role ARN", e.DeployRoleARN)
+		}
+		if e.AccountID != "" && account != e.AccountID {
+			return fmt.Errorf("deploy_role_arn is in account %s, not account_id %s", account, e.AccountID)
+		}
+	}
+	if e.DeployRoleARN == "" && (e.DeployRoleExternalID != "" || e.DeployRoleSessionName != "" || len(e.DeployRoleSessionTags) > 0) {
+		return fmt.Errorf("deploy_role_external_id, deploy_role_session_name and deploy_role_session_tags need deploy_role_arn")
+	}
+	if id := e.DeployRoleExternalID; id != "" && (len(id) < 2 || len(id) > 1224 || !externalIDPattern.MatchString(id)) {
+		return fmt.Errorf("deploy_role_external_id must be 2-1224 letters, digits or +=,.@:/-")
+	}
+	if e.DeployRoleSessionName != "" {
+		if !sessionNameTemplate.MatchString(e.DeployRoleSessionName) || len(e.DeployRoleSessionName) < 2 {
+			return fmt.Errorf("deploy_role_session_name %q must be letters, digits, +=,.@- and {user} or {timestamp}", e.DeployRoleSessionName)
+		}
+	}
+	if len(e.DeployRoleSessionTags) > maxTags {
+		return fmt.Errorf("deploy_role_session_tags has %d tags; STS allows %d", len(e.DeployRoleSessionTags), maxTags)
+	}
+	for _, key := range slices.Sorted(maps.Keys(e.DeployRoleSessionTags)) {
+		if err := validateTag(key, e.DeployRoleSessionTags[key]); err != nil {
+			return fmt.Errorf("deploy_role_session_tags: %w", err)
+		}
+	}
+	if e.WebIdentity != nil {
+		if err := e.WebIdentity.validate(); err != nil {
+			return fmt.Errorf("web_identity: %w", err)
+		}
+		if e.LocalStack || e.Profile != "" {
+			return fmt.Errorf("web_identity can't be combined with localstack or profile")
+		}
+	}
+	if e.LocalStack && (e.AccountID != "" || e.DeployRoleARN != "") {
+		return fmt.Errorf("localstack can't be combined with account_id or deploy_role_arn")
+	}
+	if e.LocalStack && (e.Profile != "" || len(e.SharedCredentialsFiles) > 0 || len(e.SharedConfigFiles) > 0) {
+		return fmt.Errorf("localstack uses test credentials and can't be combined with a profile or credential files")
+	}
+	for _, path := range append(slices.Clone(e.SharedCredentialsFiles), e.SharedConfigFiles...) {
+		// Terraform runs in cdktf.out/stacks/<stack>, so a relative path wouldn't mean what it says
+		if !filepath.IsAbs(path) && path != "~" && !strings.HasPrefix(path, "~/") {
+			return fmt.Errorf("credential file %q must be an absolute path or start with ~/", path)
+		}
+	}
+	if e.LocalStackEndpoint != "" {
+		if !e.LocalStack {
+			return fmt.Errorf("localstack_endpoint is set without localstack")
+		}
+		if err := validateEndpoints(map[string]string{"s3": e.LocalStackEndpoint}); err != nil {
+			return fmt.Errorf("localstack_endpoint %q must be an http(s) URL", e.LocalStackEndpoint)
+		}
+	}
+	return validateEndpoints(e.Endpoints)
+}
+
+func validateEnvironments(config Config) error {
+	if len(config.Environments) == 0 {
+		return nil
+	}
+	for name, environment := range config.Environments {
+		if err := environment.validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if _, ok := config.Environments[config.Environment]; !ok {
+		return fmt.Errorf("no entry for environment %q", config.Environment)
+	}
+	return nil
+}
+
+// awsProviderConfig returns the provider settings shared by every AWS provider in a stack: the
+// default tags, plus the active environment's account, roles, credentials and endpoints when
+// those are configured
+func awsProviderConfig(stack cdktf.TerraformStack, config Config, region string) *provider.AwsProviderConfig {
+	providerConfig := &provider.AwsProviderConfig{
+		Region: jsii.String(region),
+		DefaultTags: []provider.AwsProviderDefaultTags{{
+			Tags: defaultTags(config),
+		}},
+	}
+
+	environment := config.Environments[config.Environment]
+	if environment.AccountID != "" {
+		// Refuse to plan against any other account, e.g. with the wrong credentials loaded
+		providerConfig.AllowedAccountIds = jsii.Strings(environment.AccountID)
+	}
+	if environment.DeployRoleARN != "" {
+		assumeRole := provider.AwsProviderAssumeRole{RoleArn: jsii.String(environment.DeployRoleARN)}
+		if environment.DeployRoleExternalID != "" {
+			assumeRole.ExternalId = jsii.String(environment.DeployRoleExternalID)
+		}
+		if environment.DeployRoleSessionName != "" {
+			assumeRole.SessionName = jsii.String(sessionName(stack, environment.DeployRoleSessionName))
+		}
+		if len(environment.DeployRoleSessionTags) > 0 {
+			assumeRole.Tags = toStringMap(environment.DeployRoleSessionTags)
+		}
+		providerConfig.AssumeRole = []provider.AwsProviderAssumeRole{assumeRole}
+	}
+	if environment.Profile != "" {
+		providerConfig.Profile = jsii.String(environment.Profile)
+	}
+	if len(environment.SharedCredentialsFiles) > 0 {
+		providerConfig.SharedCredentialsFiles = jsii.Strings(environment.SharedCredentialsFiles...)
+	}
+	if len(environment.SharedConfigFiles) > 0 {
+		providerConfig.SharedConfigFiles = jsii.Strings(environment.SharedConfigFiles...)
+	}
+	if identity := environment.WebIdentity; identity != nil {
+		webIdentity := &provider.AwsProviderAssumeRoleWithWebIdentity{RoleArn: jsii.String(identity.RoleARN)}
+		if identity.TokenFile != "" {
+			webIdentity.WebIdentityTokenFile = jsii.String(identity.TokenFile)
+		}
+		if identity.SessionName != "" {
+			webIdentity.SessionName = jsii.String(identity.SessionName)
+		}
+		providerConfig.AssumeRoleWithWebIdentity = []*provider.AwsProviderAssumeRoleWithWebIdentity{webIdentity}
+	}
+	if environment.LocalStack {
+		useLocalStack(providerConfig)
+	}
+	if endpoints := providerEndpoints(environment); endpoints != nil {
+		providerConfig.Endpoints = []*provider.AwsProviderEndpoints{endpoints}
+	}
+	applyAWSProviderSettings(providerConfig, config)
+	return providerConfig
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// ConfigError is a config file that can't be read or isn't valid JSON
+type ConfigError struct {
+	// Op is what failed, "reading" or "parsing"
+	Op string
+	// Path is the config file
+	Path string
+	// Line and Column locate a JSON error, counting from 1, or are 0
+	Line, Column int
+	Suggestion   string
+	Err          error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s %s:%d:%d: %v", e.Op, e.Path, e.Line, e.Column, e.Err)
+	}
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Path, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error { return e.Err }
+
+// ValidationError is a config value rejected before any constructs are created. Validators
+// return one from invalidValue to carry the value and a suggestion; loadConfig then adds the
+// file and the field, whichever error the validator returned.
+type ValidationError struct {
+	Path string
+	// Field is the dotted config path of the value, such as stacks.data or kafka, as far as the
+	// message names it
+	Field      string
+	Value      interface{}
+	Suggestion string
+	Err        error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("validating %s: %v", e.Path, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// SynthError is a config that validated but failed while its constructs were built (Stage
+// "build") or synthesized (Stage "synth")
+type SynthError struct {
+	Stage      string
+	Suggestion string
+	Err        error
+}
+
+func (e *SynthError) Error() string {
+	if e.Stage == "synth" {
+		return fmt.Sprintf("synthesizing: %v", e.Err)
+	}
+	return e.Err.Error()
+}
+
+func (e *SynthError) Unwrap() error { return e.Err }
+
+// invalidValue returns a validation error carrying the rejected value and, when not empty, a
+// suggestion for fixing it. The message is formatted as with fmt.Errorf.
+func invalidValue(value interface{}, suggestion string, format string, args ...interface{}) error {
+	return &ValidationError{Value: value, Suggestion: suggestion, Err: fmt.Errorf(format, args...)}
+}
+
+// fieldSegment matches the parts of a wrapped validation message that name a config key or
+// entry, as opposed to the message itself
+var fieldSegment = regexp.MustCompile(`^[A-Za-z0-9_.\[\]-]+$`)
+
+// newValidationError qualifies an error of validating the config file at path. The field is read
+// from the "section: key: " prefixes the validators wrap their errors in.
+func newValidationError(path string, err error) *ValidationError {
+	qualified := &ValidationError{Path: path, Err: err}
+	// The last segment is the message, unless the validator's own error follows the prefixes
+	segments := strings.Split(err.Error(), ": ")
+	segments = segments[:len(segments)-1]
+	var inner *ValidationError
+	if errors.As(err, &inner) {
+		qualified.Value, qualified.Suggestion = inner.Value, inner.Suggestion
+		segments = strings.Split(strings.TrimSuffix(err.Error(), inner.Error()), ": ")
+	}
+	var field []string
+	for _, segment := range segments {
+		if !fieldSegment.MatchString(segment) {
+			break
+		}
+		field = append(field, segment)
+	}
+	qualified.Field = strings.Join(field, ".")
+	return qualified
+}
+
+// newConfigError qualifies an error of reading or parsing the config file at path, locating JSON
+// errors in raw
+func newConfigError(op string, path string, raw []byte, err error) *ConfigError {
+	configError := &ConfigError{Op: op, Path: path, Err: err}
+	var offset int64
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		configError.Suggestion = "run from the directory holding the config, or create it from testdata/snapshots/minimal/config.json"
+	case errors.As(err, &syntaxError):
+		offset = syntaxError.Offset
+		configError.Suggestion = "check for a missing comma or quote, or a trailing comma, just before this position"
+	case errors.As(err, &typeError):
+		offset = typeError.Offset
+		configError.Suggestion = fmt.Sprintf("%s must be a %s", typeError.Field, typeError.Type)
+	}
+	if offset > 0 && offset <= int64(len(raw)) {
+		before := raw[:offset]
+		configError.Line = bytes.Count(before, []byte("\n")) + 1
+		configError.Column = int(offset) - bytes.LastIndexByte(before, '\n') - 1
+	}
+	return configError
+}
+
+// closestMatch suggests the candidate nearest to a misspelt name, or returns "" when none is
+// within a third of the name's length
+func closestMatch(name string, candidates []string) string {
+	best, bestDistance := "", len(name)/3+1
+	for _, candidate := range candidates {
+		if distance := editDistance(name, candidate); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("did you mean %q?", best)
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// reportError prints an error the way every command does, with the location, value and
+// suggestion the typed errors carry
+func reportError(err error) {
+	fmt.Printf("Error: %v\n", err)
+	var configError *ConfigError
+	var validationError *ValidationError
+	var synthError *SynthError
+	suggestion := ""
+	switch {
+	case errors.As(err, &configError):
+		suggestion = configError.Suggestion
+	case errors.As(err, &validationError):
+		if validationError.Field != "" {
+			fmt.Printf("  at:    %s in %s\n", validationError.Field, validationError.Path)
+		}
+		if validationError.Value != nil {
+			var value bytes.Buffer
+			encoder := json.NewEncoder(&value)
+			encoder.SetEscapeHTML(false)
+			encoder.Encode(validationError.Value)
+			fmt.Printf("  value: %s", value.String())
+		}
+		suggestion = validationError.Suggestion
+	case errors.As(err, &synthError):
+		suggestion = synthError.Suggestion
+	}
+	if suggestion != "" {
+		fmt.Printf("  hint:  %s\n", suggestion)
+	}
+}
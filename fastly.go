@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// FastlyConfig creates a Fastly VCL service in front of the config's services, for teams that
+// use Fastly instead of CloudFront. The API key comes from FASTLY_API_KEY.
+type FastlyConfig struct {
+	Name     string          `json:"name"` // defaults to <project>-<env>
+	Domains  []string        `json:"domains"`
+	Backends []FastlyBackend `json:"backends"`
+	Snippets []FastlySnippet `json:"snippets"`
+}
+
+// FastlyBackend is an origin of the service: a host, a ${output.<name>} host such as an App
+// Runner URL or load balancer DNS name from any stack, or the storage bucket
+type FastlyBackend struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Storage bool   `json:"storage"` // the storage bucket, instead of address
+	Port    int    `json:"port"`    // defaults to 443
+	// HostHeader overrides the Host header sent to the backend; it defaults to the address, which
+	// is what App Runner, load balancers and S3 route on
+	HostHeader string `json:"host_header"`
+}
+
+// FastlySnippet is a VCL snippet, inline or read from a file relative to the config file
+type FastlySnippet struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // the VCL subroutine, e.g. recv or deliver
+	Content  string `json:"content"`
+	File     string `json:"file"`
+	Priority int    `json:"priority"` // lower runs first; defaults to 100
+}
+
+// defaultFastlyVersion is the fastly provider constraint unless provider_versions sets one
+const defaultFastlyVersion = "~> 5.0"
+
+var (
+	fastlySnippetTypes = []string{"init", "recv", "hash", "hit", "miss", "pass", "fetch", "error", "deliver", "log", "none"}
+	domainPattern      = regexp.MustCompile(`^(\*\.)?[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+	fastlyNamePattern  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+func (f *FastlyBackend) validate() error {
+	if !fastlyNamePattern.MatchString(f.Name) {
+		return fmt.Errorf("name %q may only use letters, digits, _ and -", f.Name)
+	}
+	if (f.Address == "") == !f.Storage {
+		return fmt.Errorf("%s: exactly one of address and storage is required", f.Name)
+	}
+	if strings.Contains(f.Address, "://") || strings.Contains(f.Address, "/") {
+		return fmt.Errorf("%s: address %q must be a host name, without a scheme or path", f.Name, f.Address)
+	}
+	if f.Port < 0 || f.Port > 65535 {
+		return fmt.Errorf("%s: port %d is out of range", f.Name, f.Port)
+	}
+	return nil
+}
+
+func (f *FastlySnippet) validate() error {
+	if !fastlyNamePattern.MatchString(f.Name) {
+		return fmt.Errorf("name %q may only use letters, digits, _ and -", f.Name)
+	}
+	if !slices.Contains(fastlySnippetTypes, f.Type) {
+		return fmt.Errorf("%s: type %q must be one of %s", f.Name, f.Type, strings.Join(fastlySnippetTypes, ", "))
+	}
+	if (f.Content == "") == (f.File == "") {
+		return fmt.Errorf("%s: exactly one of content and file is required", f.Name)
+	}
+	if f.Priority < 0 {
+		return fmt.Errorf("%s: priority can't be negative", f.Name)
+	}
+	return nil
+}
+
+func validateFastly(config Config) error {
+	fastly := config.Fastly
+	if len(fastly.Domains) == 0 {
+		return fmt.Errorf("at least one domain is required")
+	}
+	for _, domain := range fastly.Domains {
+		if !domainPattern.MatchString(domain) {
+			return fmt.Errorf("domain %q is not a lowercase domain name", domain)
+		}
+	}
+	if len(fastly.Backends) == 0 {
+		return fmt.Errorf("at least one backend is required")
+	}
+	names := map[string]bool{}
+	for i, backend := range fastly.Backends {
+		if err := backend.validate(); err != nil {
+			return fmt.Errorf("backends[%d]: %w", i, err)
+		}
+		if backend.Storage && usesAzure(config) {
+			return fmt.Errorf("backends[%d]: %s: storage is only supported with cloud aws", i, backend.Name)
+		}
+		if names[backend.Name] {
+			return fmt.Errorf("backends[%d]: duplicate backend %s", i, backend.Name)
+		}
+		names[backend.Name] = true
+	}
+	snippets := map[string]bool{}
+	for i, snippet := range fastly.Snippets {
+		if err := snippet.validate(); err != nil {
+			return fmt.Errorf("snippets[%d]: %w", i, err)
+		}
+		if snippets[snippet.Name] {
+			return fmt.Errorf("snippets[%d]: duplicate snippet %s", i, snippet.Name)
+		}
+		snippets[snippet.Name] = true
+	}
+	return nil
+}
+
+// loadFastlySnippets reads the snippets given as files, relative to the config file in dir
+func loadFastlySnippets(fastly *FastlyConfig, dir string) error {
+	for i := range fastly.Snippets {
+		snippet := &fastly.Snippets[i]
+		if snippet.File == "" {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, snippet.File))
+		if err != nil {
+			return fmt.Errorf("fastly: %s: reading file: %w", snippet.Name, err)
+		}
+		snippet.Content = escapeInterpolation(string(raw))
+	}
+	return nil
+}
+
+// addFastly creates the service with its domains, backends and snippets, and activates each new
+// version. Backends connect over TLS, checking the certificate against the host header.
+func addFastly(app cdktf.App, stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket) error {
+	fastly := config.Fastly
+	addRawProvider(stack, config, "fastly", "fastly/fastly", defaultFastlyVersion, map[string]interface{}{})
+
+	domains := []map[string]interface{}{}
+	for _, domain := range fastly.Domains {
+		domains = append(domains, map[string]interface{}{"name": domain})
+	}
+
+	backends := []map[string]interface{}{}
+	for _, backend := range fastly.Backends {
+		var address interface{} = backend.Address
+		if backend.Storage {
+			address = *bucket.BucketRegionalDomainName()
+		} else if match := wholeOutputReference.FindStringSubmatch(backend.Address); match != nil {
+			output, err := findOutput(app, match[1])
+			if err != nil {
+				return fmt.Errorf("fastly: backend %s: %w", backend.Name, err)
+			}
+			address = output.Value()
+		}
+		var host interface{} = address
+		if backend.HostHeader != "" {
+			host = backend.HostHeader
+		}
+		port := backend.Port
+		if port == 0 {
+			port = 443
+		}
+		backends = append(backends, map[string]interface{}{
+			"name":              backend.Name,
+			"address":           address,
+			"port":              port,
+			"use_ssl":           port != 80,
+			"ssl_cert_hostname": host,
+			"ssl_sni_hostname":  host,
+			"override_host":     host,
+		})
+	}
+
+	snippets := []map[string]interface{}{}
+	for _, snippet := range fastly.Snippets {
+		priority := snippet.Priority
+		if priority == 0 {
+			priority = 100
+		}
+		snippets = append(snippets, map[string]interface{}{
+			"name":     snippet.Name,
+			"type":     snippet.Type,
+			"content":  snippet.Content,
+			"priority": priority,
+		})
+	}
+
+	name := fastly.Name
+	if name == "" {
+		name = stackPrefix(config)
+	}
+	attributes := map[string]interface{}{
+		"name":    name,
+		"comment": "Managed by CDKTF-JSON-Platform from config.json",
+		"domain":  domains,
+		"backend": backends,
+	}
+	if len(snippets) > 0 {
+		attributes["snippet"] = snippets
+	}
+	service := newRawResource(stack, "fastly_service_vcl", "fastly_service", attributes)
+
+	cdktf.NewTerraformOutput(stack, jsii.String("fastly_service_id"), &cdktf.TerraformOutputConfig{
+		Value:       service.GetStringAttribute(jsii.String("id")),
+		Description: jsii.String("The ID of the Fastly service"),
+	})
+
+	fmt.Printf("  ✓ Fastly service %s: %d domain(s), %d backend(s), %d VCL snippet(s)\n", name,
+		len(fastly.Domains), len(fastly.Backends), len(fastly.Snippets))
+	return nil
+}
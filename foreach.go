@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A list entry in the config can carry "for_each", a list or an object. The entry is replaced by
+// one copy per element, with {each.key}, {each.value} and {each.value.<field>} in its strings
+// replaced by that element. For a list, each.key is the element itself, or its index when the
+// elements are objects.
+
+// eachPlaceholder matches {each.key}, {each.value} and {each.value.<field>}
+var eachPlaceholder = regexp.MustCompile(`\{each\.([A-Za-z0-9_.]+)\}`)
+
+// eachElement is one iteration of a for_each
+type eachElement struct {
+	key   string
+	value interface{}
+}
+
+// expandForEach returns a raw config with every for_each entry expanded
+func expandForEach(raw []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	document, err := expandEntries(document, "")
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(document)
+}
+
+func expandEntries(value interface{}, path string) (interface{}, error) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		if _, ok := value["for_each"]; ok {
+			return nil, fmt.Errorf("%s: for_each is only allowed on list entries", pathOrRoot(path))
+		}
+		for key, child := range value {
+			expanded, err := expandEntries(child, path+"."+key)
+			if err != nil {
+				return nil, err
+			}
+			value[key] = expanded
+		}
+		return value, nil
+	case []interface{}:
+		expanded := []interface{}{}
+		for i, child := range value {
+			entryPath := fmt.Sprintf("%s[%d]", path, i)
+			entry, ok := child.(map[string]interface{})
+			if !ok || entry["for_each"] == nil {
+				child, err := expandEntries(child, entryPath)
+				if err != nil {
+					return nil, err
+				}
+				expanded = append(expanded, child)
+				continue
+			}
+
+			elements, err := eachElements(entry["for_each"])
+			if err != nil {
+				return nil, fmt.Errorf("%s: for_each: %w", pathOrRoot(entryPath), err)
+			}
+			delete(entry, "for_each")
+			for _, element := range elements {
+				copied, err := substituteEach(entry, element)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", pathOrRoot(entryPath), err)
+				}
+				// Nested lists of the copy can have their own for_each
+				copied, err = expandEntries(copied, entryPath)
+				if err != nil {
+					return nil, err
+				}
+				expanded = append(expanded, copied)
+			}
+		}
+		return expanded, nil
+	}
+	return value, nil
+}
+
+// eachElements lists the iterations of a for_each value, objects in key order
+func eachElements(forEach interface{}) ([]eachElement, error) {
+	var elements []eachElement
+	switch forEach := forEach.(type) {
+	case []interface{}:
+		seen := map[string]bool{}
+		for i, value := range forEach {
+			key := fmt.Sprint(i)
+			switch value.(type) {
+			case string, json.Number:
+				key = fmt.Sprint(value)
+			}
+			if seen[key] {
+				return nil, fmt.Errorf("%s is listed twice", key)
+			}
+			seen[key] = true
+			elements = append(elements, eachElement{key, value})
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(forEach))
+		for key := range forEach {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			elements = append(elements, eachElement{key, forEach[key]})
+		}
+	default:
+		return nil, fmt.Errorf("must be a list or an object")
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("is empty")
+	}
+	return elements, nil
+}
+
+// substituteEach deep-copies a value with the placeholders of one element filled in. A string that
+// is only a placeholder takes the element's value as is, so numbers and booleans keep their type.
+func substituteEach(value interface{}, element eachElement) (interface{}, error) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			substituted, err := substituteEach(child, element)
+			if err != nil {
+				return nil, err
+			}
+			copied[key] = substituted
+		}
+		return copied, nil
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, child := range value {
+			substituted, err := substituteEach(child, element)
+			if err != nil {
+				return nil, err
+			}
+			copied[i] = substituted
+		}
+		return copied, nil
+	case string:
+		if match := eachPlaceholder.FindStringSubmatch(value); match != nil && match[0] == value {
+			return lookupEach(match[1], element)
+		}
+		var err error
+		substituted := eachPlaceholder.ReplaceAllStringFunc(value, func(placeholder string) string {
+			found, lookupErr := lookupEach(eachPlaceholder.FindStringSubmatch(placeholder)[1], element)
+			if lookupErr != nil {
+				err = lookupErr
+			}
+			return fmt.Sprint(found)
+		})
+		return substituted, err
+	}
+	return value, nil
+}
+
+// lookupEach resolves key, value or value.<field> of an element
+func lookupEach(name string, element eachElement) (interface{}, error) {
+	if name == "key" {
+		return element.key, nil
+	}
+	fields := strings.Split(name, ".")
+	if fields[0] != "value" {
+		return nil, fmt.Errorf("{each.%s} must be each.key, each.value or each.value.<field>", name)
+	}
+	value := element.value
+	for _, field := range fields[1:] {
+		object, ok := value.(map[string]interface{})
+		if !ok || object[field] == nil {
+			return nil, fmt.Errorf("{each.%s}: %s has no field %s", name, element.key, field)
+		}
+		value = object[field]
+	}
+	return value, nil
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// GitHubConfig sets up the repository that deploys this config: a deploy environment for the
+// synthesized environment, with its secrets and variables, and branch protection. The token comes
+// from GITHUB_TOKEN.
+type GitHubConfig struct {
+	Owner       string            `json:"owner"` // organization or user
+	Repository  string            `json:"repository"`
+	Environment GitHubEnvironment `json:"environment"`
+	// BranchProtection is shared by every environment, so only the stack of
+	// branch_protection_environment manages it; that defaults to the only environment there is
+	BranchProtection            []GitHubBranchProtection `json:"branch_protection"`
+	BranchProtectionEnvironment string                   `json:"branch_protection_environment"`
+}
+
+// GitHubEnvironment is the deploy environment. Secret and variable values may be ${output.<name>},
+// an output of any stack, or text with {project}, {environment}, {region}, {account_id} and
+// {deploy_role_arn} filled in from the config.
+type GitHubEnvironment struct {
+	Name          string            `json:"name"` // defaults to the config environment
+	ReviewerTeams []string          `json:"reviewer_teams"`
+	ReviewerUsers []string          `json:"reviewer_users"`
+	WaitTimer     int               `json:"wait_timer"` // minutes
+	Branches      []string          `json:"branches"`   // branch patterns allowed to deploy; any branch when empty
+	Secrets       map[string]string `json:"secrets"`
+	Variables     map[string]string `json:"variables"`
+}
+
+type GitHubBranchProtection struct {
+	Pattern              string   `json:"pattern"` // e.g. main or release/*
+	RequiredReviews      int      `json:"required_reviews"`
+	RequireCodeOwners    bool     `json:"require_code_owners"`
+	RequiredStatusChecks []string `json:"required_status_checks"`
+	EnforceAdmins        bool     `json:"enforce_admins"`
+}
+
+// defaultGitHubVersion is the github provider constraint unless provider_versions sets one
+const defaultGitHubVersion = "~> 6.0"
+
+var (
+	githubNamePattern   = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	githubSecretPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// wholeOutputReference is a secret or variable value that is one stack output
+	wholeOutputReference = regexp.MustCompile(`^\$\{output\.([A-Za-z_][A-Za-z0-9_-]*)\}$`)
+)
+
+func validateSecretNames(kind string, values map[string]string) error {
+	for name, value := range values {
+		if !githubSecretPattern.MatchString(name) || strings.HasPrefix(strings.ToUpper(name), "GITHUB_") {
+			return fmt.Errorf("%s name %q must be letters, digits and _ and can't start with GITHUB_", kind, name)
+		}
+		if value == "" {
+			return fmt.Errorf("%s %s has no value", kind, name)
+		}
+	}
+	return nil
+}
+
+func validateGitHub(config Config) error {
+	github := config.GitHub
+	if !githubNamePattern.MatchString(github.Owner) || !githubNamePattern.MatchString(github.Repository) {
+		return fmt.Errorf("owner and repository are required and may only use letters, digits and ._-")
+	}
+
+	environment := github.Environment
+	if environment.Name != "" && !githubNamePattern.MatchString(environment.Name) {
+		return fmt.Errorf("environment.name %q may only use letters, digits and ._-", environment.Name)
+	}
+	if len(environment.ReviewerTeams)+len(environment.ReviewerUsers) > 6 {
+		return fmt.Errorf("environment: GitHub allows at most 6 reviewers")
+	}
+	if environment.WaitTimer < 0 || environment.WaitTimer > 43200 {
+		return fmt.Errorf("environment.wait_timer must be 0 to 43200 minutes")
+	}
+	if err := validateSecretNames("secret", environment.Secrets); err != nil {
+		return fmt.Errorf("environment: %w", err)
+	}
+	if err := validateSecretNames("variable", environment.Variables); err != nil {
+		return fmt.Errorf("environment: %w", err)
+	}
+	for _, value := range append(slices.Collect(maps.Values(environment.Secrets)), slices.Collect(maps.Values(environment.Variables))...) {
+		if strings.Contains(value, "{deploy_role_arn}") && config.Environments[config.Environment].DeployRoleARN == "" {
+			return fmt.Errorf("environment: {deploy_role_arn} is used but environment %s has no deploy_role_arn", config.Environment)
+		}
+		if strings.Contains(value, "{account_id}") && config.Environments[config.Environment].AccountID == "" {
+			return fmt.Errorf("environment: {account_id} is used but environment %s has no account_id", config.Environment)
+		}
+	}
+
+	// Names become logical ids, which must not collide
+	for kind, names := range map[string][]string{
+		"secret":   slices.Collect(maps.Keys(environment.Secrets)),
+		"variable": slices.Collect(maps.Keys(environment.Variables)),
+		"branch":   environment.Branches,
+	} {
+		ids := map[string]string{}
+		for _, name := range names {
+			if other, ok := ids[githubID(name)]; ok {
+				return fmt.Errorf("environment: %s names %s and %s differ only in case or punctuation", kind, min(name, other), max(name, other))
+			}
+			ids[githubID(name)] = name
+		}
+	}
+
+	patterns := map[string]string{}
+	for i, rule := range github.BranchProtection {
+		if rule.Pattern == "" {
+			return fmt.Errorf("branch_protection[%d]: pattern is required", i)
+		}
+		if other, ok := patterns[githubID(rule.Pattern)]; ok {
+			return fmt.Errorf("branch_protection[%d]: patterns %s and %s differ only in case or punctuation", i, other, rule.Pattern)
+		}
+		patterns[githubID(rule.Pattern)] = rule.Pattern
+		if rule.RequiredReviews < 0 || rule.RequiredReviews > 6 {
+			return fmt.Errorf("branch_protection[%d]: required_reviews must be 0 to 6", i)
+		}
+	}
+	if name := github.BranchProtectionEnvironment; name != "" {
+		if _, ok := config.Environments[name]; !ok {
+			return fmt.Errorf("branch_protection_environment %q is not in environments", name)
+		}
+	} else if len(github.BranchProtection) > 0 && len(config.Environments) > 1 {
+		return fmt.Errorf("branch_protection_environment is required with several environments, so only one manages branch protection")
+	}
+	return nil
+}
+
+// findOutput returns the output of the one stack in app that has it
+func findOutput(app cdktf.App, name string) (cdktf.TerraformOutput, error) {
+	var found []cdktf.TerraformOutput
+	for _, child := range *app.Node().Children() {
+		if stack, ok := child.(cdktf.TerraformStack); ok {
+			if output, ok := stack.Node().TryFindChild(jsii.String(name)).(cdktf.TerraformOutput); ok {
+				found = append(found, output)
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no output %s in any stack", name)
+	case 1:
+		return found[0], nil
+	}
+	return nil, fmt.Errorf("output %s is in several stacks", name)
+}
+
+// githubValue resolves a secret or variable value. An output of another stack is read through
+// cdktf's cross-stack references, which also orders the deploy after that stack.
+func githubValue(app cdktf.App, config Config, value string) (interface{}, error) {
+	if match := wholeOutputReference.FindStringSubmatch(value); match != nil {
+		output, err := findOutput(app, match[1])
+		if err != nil {
+			return nil, err
+		}
+		return output.Value(), nil
+	}
+	environment := config.Environments[config.Environment]
+	return strings.NewReplacer(
+		"{project}", config.Project,
+		"{environment}", config.Environment,
+		"{region}", config.Region,
+		"{account_id}", environment.AccountID,
+		"{deploy_role_arn}", environment.DeployRoleARN,
+	).Replace(value), nil
+}
+
+// githubID turns a name into part of a logical id, e.g. release/* into release
+func githubID(name string) string {
+	return strings.Trim(logicalIDInvalid.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// addGitHub creates the deploy environment with its reviewers, branch policies, secrets and
+// variables, and the branch protection rules when this environment manages them. Reviewers are
+// looked up by team slug and user login. Secret values are kept in the stack's state.
+func addGitHub(app cdktf.App, stack cdktf.TerraformStack, config Config) error {
+	github := config.GitHub
+	addRawProvider(stack, config, "github", "integrations/github", defaultGitHubVersion,
+		map[string]interface{}{"owner": github.Owner})
+
+	environmentName := github.Environment.Name
+	if environmentName == "" {
+		environmentName = config.Environment
+	}
+	teams := []string{}
+	for _, slug := range github.Environment.ReviewerTeams {
+		team := newRawDataSource(stack, "github_team", "github_team_"+githubID(slug), map[string]interface{}{"slug": slug})
+		teams = append(teams, *team.GetStringAttribute(jsii.String("id")))
+	}
+	users := []string{}
+	for _, login := range github.Environment.ReviewerUsers {
+		user := newRawDataSource(stack, "github_user", "github_user_"+githubID(login), map[string]interface{}{"username": login})
+		users = append(users, *user.GetStringAttribute(jsii.String("id")))
+	}
+
+	attributes := map[string]interface{}{
+		"repository":  github.Repository,
+		"environment": environmentName,
+		"wait_timer":  github.Environment.WaitTimer,
+	}
+	if len(teams) > 0 || len(users) > 0 {
+		attributes["reviewers"] = map[string]interface{}{"teams": teams, "users": users}
+	}
+	if len(github.Environment.Branches) > 0 {
+		attributes["deployment_branch_policy"] = map[string]interface{}{
+			"protected_branches":     false,
+			"custom_branch_policies": true,
+		}
+	}
+	environment := newRawResource(stack, "github_repository_environment", "github_environment", attributes)
+	// Refer to the environment, so the policies, secrets and variables are created after it
+	environmentRef := *environment.GetStringAttribute(jsii.String("environment"))
+
+	for _, pattern := range github.Environment.Branches {
+		newRawResource(stack, "github_repository_environment_deployment_policy", "github_branch_policy_"+githubID(pattern),
+			map[string]interface{}{
+				"repository":     github.Repository,
+				"environment":    environmentRef,
+				"branch_pattern": pattern,
+			})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(github.Environment.Secrets)) {
+		value, err := githubValue(app, config, github.Environment.Secrets[name])
+		if err != nil {
+			return fmt.Errorf("github: secret %s: %w", name, err)
+		}
+		newRawResource(stack, "github_actions_environment_secret", "github_secret_"+githubID(name), map[string]interface{}{
+			"repository":      github.Repository,
+			"environment":     environmentRef,
+			"secret_name":     name,
+			"plaintext_value": value,
+		})
+	}
+	for _, name := range slices.Sorted(maps.Keys(github.Environment.Variables)) {
+		value, err := githubValue(app, config, github.Environment.Variables[name])
+		if err != nil {
+			return fmt.Errorf("github: variable %s: %w", name, err)
+		}
+		newRawResource(stack, "github_actions_environment_variable", "github_variable_"+githubID(name), map[string]interface{}{
+			"repository":    github.Repository,
+			"environment":   environmentRef,
+			"variable_name": name,
+			"value":         value,
+		})
+	}
+
+	protects := github.BranchProtectionEnvironment == config.Environment ||
+		(github.BranchProtectionEnvironment == "" && len(config.Environments) <= 1)
+	if protects {
+		for _, rule := range github.BranchProtection {
+			attributes := map[string]interface{}{
+				"repository_id":  github.Repository,
+				"pattern":        rule.Pattern,
+				"enforce_admins": rule.EnforceAdmins,
+			}
+			if rule.RequiredReviews > 0 || rule.RequireCodeOwners {
+				attributes["required_pull_request_reviews"] = map[string]interface{}{
+					"required_approving_review_count": rule.RequiredReviews,
+					"require_code_owner_reviews":      rule.RequireCodeOwners,
+					"dismiss_stale_reviews":           true,
+				}
+			}
+			if len(rule.RequiredStatusChecks) > 0 {
+				attributes["required_status_checks"] = map[string]interface{}{
+					"strict":   true,
+					"contexts": rule.RequiredStatusChecks,
+				}
+			}
+			newRawResource(stack, "github_branch_protection", "github_branch_protection_"+githubID(rule.Pattern), attributes)
+		}
+	}
+
+	protected := "branch protection managed by " + github.BranchProtectionEnvironment
+	if protects {
+		protected = fmt.Sprintf("%d branch protection rule(s)", len(github.BranchProtection))
+	}
+	fmt.Printf("  ✓ GitHub environment %s in %s/%s with %d secret(s), %d variable(s); %s\n", environmentName,
+		github.Owner, github.Repository, len(github.Environment.Secrets), len(github.Environment.Variables), protected)
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/globalacceleratoraccelerator"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/globalacceleratorendpointgroup"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/globalacceleratorlistener"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// GlobalAcceleratorConfig describes an accelerator routing clients to regional load balancers
+type GlobalAcceleratorConfig struct {
+	Listeners []AcceleratorListener `json:"listeners"`
+}
+
+type AcceleratorListener struct {
+	Protocol       string                     `json:"protocol"` // TCP or UDP
+	Ports          []float64                  `json:"ports"`
+	ClientAffinity string                     `json:"client_affinity"` // NONE or SOURCE_IP
+	EndpointGroups []AcceleratorEndpointGroup `json:"endpoint_groups"`
+}
+
+type AcceleratorEndpointGroup struct {
+	Region                string                `json:"region"`
+	TrafficDialPercentage float64               `json:"traffic_dial_percentage"`
+	HealthCheckPath       string                `json:"health_check_path"`
+	HealthCheckProtocol   string                `json:"health_check_protocol"` // HTTP or HTTPS, defaults to HTTPS
+	Endpoints             []AcceleratorEndpoint `json:"endpoints"`
+}
+
+type AcceleratorEndpoint struct {
+	ARN    string  `json:"arn"` // ALB (or NLB, EIP, instance) in the group's region
+	Weight float64 `json:"weight"`
+}
+
+func (g *GlobalAcceleratorConfig) validate() error {
+	if len(g.Listeners) == 0 {
+		return fmt.Errorf("at least one listener is required")
+	}
+	for i, listener := range g.Listeners {
+		if len(listener.Ports) == 0 {
+			return fmt.Errorf("listener %d has no ports", i)
+		}
+		// A listener has at most one endpoint group per region
+		regions := map[string]bool{}
+		for j, group := range listener.EndpointGroups {
+			if group.Region == "" || len(group.Endpoints) == 0 {
+				return fmt.Errorf("listener %d: endpoint groups need a region and at least one endpoint", i)
+			}
+			if regions[group.Region] {
+				return fmt.Errorf("listeners[%d].endpoint_groups[%d].region: duplicate %q", i, j, group.Region)
+			}
+			regions[group.Region] = true
+		}
+	}
+	return nil
+}
+
+// addGlobalAccelerator creates the accelerator with one listener per entry and an endpoint
+// group per region. The provider always talks to the Global Accelerator API in us-west-2,
+// so this works from a stack in any region.
+func addGlobalAccelerator(stack cdktf.TerraformStack, config Config) {
+	accelerator := globalacceleratoraccelerator.NewGlobalacceleratorAccelerator(stack, jsii.String("accelerator"),
+		&globalacceleratoraccelerator.GlobalacceleratorAcceleratorConfig{
+			Name:          jsii.String(resourceName(config, "aws_globalaccelerator_accelerator", "accelerator")),
+			IpAddressType: jsii.String("IPV4"),
+			Enabled:       jsii.Bool(true),
+		})
+
+	for i, listenerConfig := range config.GlobalAccelerator.Listeners {
+		protocol := listenerConfig.Protocol
+		if protocol == "" {
+			protocol = "TCP"
+		}
+		clientAffinity := listenerConfig.ClientAffinity
+		if clientAffinity == "" {
+			clientAffinity = "NONE"
+		}
+
+		portRanges := []*globalacceleratorlistener.GlobalacceleratorListenerPortRange{}
+		for _, port := range listenerConfig.Ports {
+			portRanges = append(portRanges, &globalacceleratorlistener.GlobalacceleratorListenerPortRange{
+				FromPort: jsii.Number(port),
+				ToPort:   jsii.Number(port),
+			})
+		}
+
+		listener := globalacceleratorlistener.NewGlobalacceleratorListener(stack,
+			jsii.String(fmt.Sprintf("accelerator_listener_%d", i)),
+			&globalacceleratorlistener.GlobalacceleratorListenerConfig{
+				AcceleratorArn: accelerator.Arn(),
+				Protocol:       jsii.String(protocol),
+				ClientAffinity: jsii.String(clientAffinity),
+				PortRange:      &portRanges,
+			})
+
+		for _, group := range listenerConfig.EndpointGroups {
+			endpoints := []*globalacceleratorendpointgroup.GlobalacceleratorEndpointGroupEndpointConfiguration{}
+			for _, endpoint := range group.Endpoints {
+				weight := endpoint.Weight
+				if weight == 0 {
+					weight = 100
+				}
+				endpoints = append(endpoints, &globalacceleratorendpointgroup.GlobalacceleratorEndpointGroupEndpointConfiguration{
+					EndpointId:                  jsii.String(endpoint.ARN),
+					Weight:                      jsii.Number(weight),
+					ClientIpPreservationEnabled: jsii.Bool(true),
+				})
+			}
+
+			dial := group.TrafficDialPercentage
+			if dial == 0 {
+				dial = 100
+			}
+			groupConfig := &globalacceleratorendpointgroup.GlobalacceleratorEndpointGroupConfig{
+				ListenerArn:           listener.Arn(),
+				EndpointGroupRegion:   jsii.String(group.Region),
+				TrafficDialPercentage: jsii.Number(dial),
+				EndpointConfiguration: &endpoints,
+			}
+			if group.HealthCheckPath != "" {
+				healthCheckProtocol := group.HealthCheckProtocol
+				if healthCheckProtocol == "" {
+					healthCheckProtocol = "HTTPS"
+				}
+				groupConfig.HealthCheckPath = jsii.String(group.HealthCheckPath)
+				groupConfig.HealthCheckProtocol = jsii.String(healthCheckProtocol)
+			}
+			globalacceleratorendpointgroup.NewGlobalacceleratorEndpointGroup(stack,
+				jsii.String(fmt.Sprintf("accelerator_listener_%d_%s", i, group.Region)), groupConfig)
+		}
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("accelerator_dns_name"), &cdktf.TerraformOutputConfig{
+		Value:       accelerator.DnsName(),
+		Description: jsii.String("The DNS name of the Global Accelerator"),
+	})
+
+	fmt.Printf("  ✓ Global Accelerator with %d listener(s)\n", len(config.GlobalAccelerator.Listeners))
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/gluecatalogdatabase"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/gluecrawler"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/gluejob"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// GlueConfig describes a catalog database, a crawler over part of the bucket, and ETL jobs
+type GlueConfig struct {
+	Database string             `json:"database"`
+	Crawler  *GlueCrawlerConfig `json:"crawler,omitempty"`
+	Jobs     []GlueJobConfig    `json:"jobs"`
+}
+
+type GlueCrawlerConfig struct {
+	Prefix   string `json:"prefix"`   // key prefix inside the config bucket
+	Schedule string `json:"schedule"` // cron() expression, empty for on-demand
+}
+
+type GlueJobConfig struct {
+	Name            string            `json:"name"`
+	ScriptLocation  string            `json:"script_location"` // s3:// URI, or a key inside the config bucket
+	GlueVersion     string            `json:"glue_version"`
+	WorkerType      string            `json:"worker_type"`
+	NumberOfWorkers float64           `json:"number_of_workers"`
+	Arguments       map[string]string `json:"arguments"`
+}
+
+func (g *GlueConfig) validate() error {
+	if g.Database == "" {
+		return fmt.Errorf("database is required")
+	}
+	names := map[string]bool{}
+	for i, job := range g.Jobs {
+		if job.Name == "" || job.ScriptLocation == "" {
+			return fmt.Errorf("jobs need a name and a script_location")
+		}
+		if names[job.Name] {
+			return fmt.Errorf("jobs[%d].name: duplicate %q", i, job.Name)
+		}
+		names[job.Name] = true
+	}
+	return nil
+}
+
+// addGlue creates the catalog database, crawler, and jobs, all sharing one Glue service role
+func addGlue(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket) {
+	glue := config.Glue
+
+	// Glue database names only allow lowercase letters, digits and underscores
+	databaseName := strings.ReplaceAll(resourceName(config, "aws_glue_catalog_database", glue.Database), "-", "_")
+	database := gluecatalogdatabase.NewGlueCatalogDatabase(stack, jsii.String("glue_database"),
+		&gluecatalogdatabase.GlueCatalogDatabaseConfig{
+			Name: jsii.String(strings.ToLower(databaseName)),
+		})
+
+	role := newServiceRole(stack, "glue_role", resourceName(config, "aws_iam_role", "glue"), "glue.amazonaws.com", config,
+		"arn:aws:iam::aws:policy/service-role/AWSGlueServiceRole")
+	addInlinePolicy(stack, "glue_bucket_access", role, bucketAccessStatement(bucket, true))
+
+	if glue.Crawler != nil {
+		crawlerConfig := &gluecrawler.GlueCrawlerConfig{
+			Name:         jsii.String(resourceName(config, "aws_glue_crawler", "crawler")),
+			DatabaseName: database.Name(),
+			Role:         role.Arn(),
+			S3Target: &[]*gluecrawler.GlueCrawlerS3Target{
+				{Path: jsii.String("s3://" + *bucket.Bucket() + "/" + strings.TrimPrefix(glue.Crawler.Prefix, "/"))},
+			},
+		}
+		if glue.Crawler.Schedule != "" {
+			crawlerConfig.Schedule = jsii.String(glue.Crawler.Schedule)
+		}
+		gluecrawler.NewGlueCrawler(stack, jsii.String("glue_crawler"), crawlerConfig)
+	}
+
+	for _, job := range glue.Jobs {
+		scriptLocation := job.ScriptLocation
+		if !strings.HasPrefix(scriptLocation, "s3://") {
+			scriptLocation = "s3://" + *bucket.Bucket() + "/" + strings.TrimPrefix(scriptLocation, "/")
+		}
+
+		glueVersion := job.GlueVersion
+		if glueVersion == "" {
+			glueVersion = "4.0"
+		}
+		workerType := job.WorkerType
+		if workerType == "" {
+			workerType = sizeFor(config).GlueWorkerType
+		}
+		workers := job.NumberOfWorkers
+		if workers == 0 {
+			workers = sizeFor(config).GlueWorkers
+		}
+
+		arguments := map[string]*string{
+			"--job-language":        jsii.String("python"),
+			"--TempDir":             jsii.String("s3://" + *bucket.Bucket() + "/glue-temp/"),
+			"--enable-metrics":      jsii.String("true"),
+			"--enable-job-insights": jsii.String("true"),
+		}
+		for key, value := range job.Arguments {
+			arguments[key] = jsii.String(value)
+		}
+
+		gluejob.NewGlueJob(stack, jsii.String("glue_job_"+job.Name), &gluejob.GlueJobConfig{
+			Name:    jsii.String(resourceName(config, "aws_glue_job", job.Name)),
+			RoleArn: role.Arn(),
+			Command: &gluejob.GlueJobCommand{
+				Name:           jsii.String("glueetl"),
+				ScriptLocation: jsii.String(scriptLocation),
+				PythonVersion:  jsii.String("3"),
+			},
+			GlueVersion:      jsii.String(glueVersion),
+			WorkerType:       jsii.String(workerType),
+			NumberOfWorkers:  jsii.Number(workers),
+			DefaultArguments: &arguments,
+		})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("glue_database_name"), &cdktf.TerraformOutputConfig{
+		Value:       database.Name(),
+		Description: jsii.String("The name of the Glue catalog database"),
+	})
+
+	fmt.Printf("  ✓ Glue database with %d job(s)\n", len(glue.Jobs))
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// GrafanaConfig generates a standard dashboard for every service the stacks create, plus alert
+// rules for the failures that need a person, in Grafana Cloud or Amazon Managed Grafana. The
+// provider reads its service account token from GRAFANA_AUTH.
+type GrafanaConfig struct {
+	URL string `json:"url"` // the Grafana Cloud stack or AMG workspace URL
+	// CloudWatchDatasourceUID is the UID of the CloudWatch data source the panels and rules query
+	CloudWatchDatasourceUID string `json:"cloudwatch_datasource_uid"`
+	ContactPoint            string `json:"contact_point"` // where alerts go; defaults to the notification policy
+	DisableAlerts           bool   `json:"disable_alerts"`
+}
+
+// defaultGrafanaVersion is the grafana provider constraint unless provider_versions sets one
+const defaultGrafanaVersion = "~> 3.0"
+
+// grafanaMetric is a CloudWatch metric of a standard dashboard
+type grafanaMetric struct {
+	title     string
+	metric    string
+	statistic string
+}
+
+// grafanaAlert fires when a metric's statistic over five minutes is above threshold. Its title
+// says what happened, e.g. "Data disk above 85%".
+type grafanaAlert struct {
+	title     string
+	metric    string
+	statistic string
+	threshold float64
+}
+
+// grafanaTemplate is the standard dashboard and alert of a resource type. The metrics are
+// filtered on dimension, whose value is the resource's attribute.
+type grafanaTemplate struct {
+	namespace string
+	dimension string
+	attribute string
+	metrics   []grafanaMetric
+	alert     *grafanaAlert
+}
+
+// grafanaTemplates lists the resource types that get a dashboard
+var grafanaTemplates = map[string]grafanaTemplate{
+	"aws_apprunner_service": {
+		namespace: "AWS/AppRunner", dimension: "ServiceName", attribute: "service_name",
+		metrics: []grafanaMetric{
+			{"Requests", "Requests", "Sum"},
+			{"5xx responses", "5xxStatusResponses", "Sum"},
+			{"Latency (p99)", "RequestLatency", "p99"},
+			{"Active instances", "ActiveInstances", "Average"},
+		},
+		alert: &grafanaAlert{"More than 10 5xx responses", "5xxStatusResponses", "Sum", 10},
+	},
+	"aws_msk_cluster": {
+		namespace: "AWS/Kafka", dimension: "Cluster Name", attribute: "cluster_name",
+		metrics: []grafanaMetric{
+			{"Broker CPU", "CpuUser", "Average"},
+			{"Data disk used (%)", "KafkaDataLogsDiskUsed", "Maximum"},
+			{"Bytes in", "BytesInPerSec", "Sum"},
+			{"Bytes out", "BytesOutPerSec", "Sum"},
+		},
+		alert: &grafanaAlert{"Data disk above 85%", "KafkaDataLogsDiskUsed", "Maximum", 85},
+	},
+	"aws_opensearch_domain": {
+		namespace: "AWS/ES", dimension: "DomainName", attribute: "domain_name",
+		metrics: []grafanaMetric{
+			{"CPU", "CPUUtilization", "Average"},
+			{"Free storage (MB)", "FreeStorageSpace", "Minimum"},
+			{"JVM memory pressure", "JVMMemoryPressure", "Maximum"},
+			{"Cluster status red", "ClusterStatus.red", "Maximum"},
+		},
+		alert: &grafanaAlert{"Cluster status red", "ClusterStatus.red", "Maximum", 0},
+	},
+	"aws_redshiftserverless_workgroup": {
+		namespace: "AWS/Redshift-Serverless", dimension: "Workgroup", attribute: "workgroup_name",
+		metrics: []grafanaMetric{
+			{"Compute capacity (RPUs)", "ComputeCapacity", "Average"},
+			{"Queries running", "QueriesRunning", "Maximum"},
+			{"Queries queued", "QueriesQueued", "Maximum"},
+		},
+	},
+	"aws_transfer_server": {
+		namespace: "AWS/Transfer", dimension: "ServerId", attribute: "id",
+		metrics: []grafanaMetric{
+			{"Files in", "FilesIn", "Sum"},
+			{"Bytes in", "BytesIn", "Sum"},
+			{"Bytes out", "BytesOut", "Sum"},
+		},
+	},
+	"aws_wafv2_web_acl": {
+		namespace: "AWS/WAFV2", dimension: "WebACL", attribute: "name",
+		metrics: []grafanaMetric{
+			{"Allowed requests", "AllowedRequests", "Sum"},
+			{"Blocked requests", "BlockedRequests", "Sum"},
+		},
+	},
+}
+
+func (g *GrafanaConfig) validate() error {
+	if !strings.HasPrefix(g.URL, "https://") {
+		return fmt.Errorf("url %q must be an https URL", g.URL)
+	}
+	if g.CloudWatchDatasourceUID == "" {
+		return fmt.Errorf("cloudwatch_datasource_uid is required")
+	}
+	if g.ContactPoint != "" && g.DisableAlerts {
+		return fmt.Errorf("contact_point is only used with alerts")
+	}
+	return nil
+}
+
+// cloudWatchQuery is the Grafana CloudWatch query of a metric. Dimensions are matched loosely, so
+// metrics with more dimensions than the one given, such as AWS/ES's ClientId, are found.
+func (t grafanaTemplate) cloudWatchQuery(refID string, metric string, statistic string, value string) map[string]interface{} {
+	return map[string]interface{}{
+		"refId":      refID,
+		"queryMode":  "Metrics",
+		"region":     "default",
+		"namespace":  t.namespace,
+		"metricName": metric,
+		"statistic":  statistic,
+		"dimensions": map[string]interface{}{t.dimension: value},
+		"matchExact": false,
+		"period":     "300",
+	}
+}
+
+// dashboard is the dashboard JSON model of one resource, two panels to a row
+func (t grafanaTemplate) dashboard(config Config, title string, value string) map[string]interface{} {
+	grafana := config.Monitoring.Grafana
+	panels := []map[string]interface{}{}
+	for i, metric := range t.metrics {
+		panels = append(panels, map[string]interface{}{
+			"id":         i + 1,
+			"type":       "timeseries",
+			"title":      metric.title,
+			"gridPos":    map[string]interface{}{"h": 8, "w": 12, "x": (i % 2) * 12, "y": (i / 2) * 8},
+			"datasource": map[string]interface{}{"type": "cloudwatch", "uid": grafana.CloudWatchDatasourceUID},
+			"targets":    []map[string]interface{}{t.cloudWatchQuery("A", metric.metric, metric.statistic, value)},
+		})
+	}
+	return map[string]interface{}{
+		"title":         title,
+		"tags":          []string{config.Project, config.Environment, t.namespace},
+		"time":          map[string]interface{}{"from": "now-6h", "to": "now"},
+		"schemaVersion": 39,
+		"panels":        panels,
+	}
+}
+
+// rule is the Grafana alert rule of one resource: the metric, reduced to its last value, checked
+// against the threshold
+func (t grafanaTemplate) rule(config Config, title string, value string) map[string]interface{} {
+	grafana := config.Monitoring.Grafana
+	alert := t.alert
+	rule := map[string]interface{}{
+		"name":           title + ": " + alert.title,
+		"condition":      "C",
+		"for":            "5m",
+		"no_data_state":  "OK",
+		"exec_err_state": "Error",
+		"annotations":    map[string]string{"summary": fmt.Sprintf("%s on %s in %s", alert.title, title, config.Environment)},
+		"labels":         map[string]string{"project": config.Project, "environment": config.Environment},
+		"data": []map[string]interface{}{
+			{
+				"ref_id":              "A",
+				"datasource_uid":      grafana.CloudWatchDatasourceUID,
+				"relative_time_range": map[string]interface{}{"from": 600, "to": 0},
+				"model":               *cdktf.Fn_Jsonencode(t.cloudWatchQuery("A", alert.metric, alert.statistic, value)),
+			},
+			{
+				"ref_id":              "B",
+				"datasource_uid":      "__expr__",
+				"relative_time_range": map[string]interface{}{"from": 0, "to": 0},
+				"model":               *cdktf.Fn_Jsonencode(map[string]interface{}{"refId": "B", "type": "reduce", "expression": "A", "reducer": "last"}),
+			},
+			{
+				"ref_id":              "C",
+				"datasource_uid":      "__expr__",
+				"relative_time_range": map[string]interface{}{"from": 0, "to": 0},
+				"model": *cdktf.Fn_Jsonencode(map[string]interface{}{
+					"refId": "C", "type": "threshold", "expression": "B",
+					"conditions": []map[string]interface{}{{"evaluator": map[string]interface{}{"type": "gt", "params": []float64{alert.threshold}}}},
+				}),
+			},
+		},
+	}
+	if grafana.ContactPoint != "" {
+		rule["notification_settings"] = map[string]interface{}{"contact_point": grafana.ContactPoint}
+	}
+	return rule
+}
+
+// addGrafana gives each resource of a type in grafanaTemplates a dashboard, in a folder per stack,
+// and puts the stack's alert rules in one rule group of that folder. Everything lives in the
+// resource's stack, so the queries refer to its attributes.
+func addGrafana(resources map[string][]cdktf.TerraformResource, config Config) {
+	grafana := config.Monitoring.Grafana
+
+	type stackResources struct {
+		stack     cdktf.TerraformStack
+		resources []cdktf.TerraformResource
+	}
+	byStack := map[string]*stackResources{}
+	var stackNames []string
+	for _, address := range slices.Sorted(maps.Keys(resources)) {
+		resourceType, _, _ := strings.Cut(address, ".")
+		if _, ok := grafanaTemplates[resourceType]; !ok {
+			continue
+		}
+		for _, resource := range resources[address] {
+			stack := cdktf.TerraformStack_Of(resource)
+			name := *stack.Node().Id()
+			if byStack[name] == nil {
+				byStack[name] = &stackResources{stack: stack}
+				stackNames = append(stackNames, name)
+			}
+			byStack[name].resources = append(byStack[name].resources, resource)
+		}
+	}
+	slices.Sort(stackNames)
+
+	dashboards, rules := 0, 0
+	for _, name := range stackNames {
+		stack := byStack[name].stack
+		addRawProvider(stack, config, "grafana", "grafana/grafana", defaultGrafanaVersion, map[string]interface{}{
+			"url": grafana.URL,
+		})
+		folder := newRawResource(stack, "grafana_folder", "grafana_folder", map[string]interface{}{"title": name})
+		folderUID := *folder.GetStringAttribute(jsii.String("uid"))
+
+		var stackRules []map[string]interface{}
+		for _, resource := range byStack[name].resources {
+			template := grafanaTemplates[*resource.TerraformResourceType()]
+			id := *resource.FriendlyUniqueId()
+			value := *resource.GetStringAttribute(jsii.String(template.attribute))
+			title := fmt.Sprintf("%s %s", strings.TrimPrefix(template.namespace, "AWS/"), id)
+
+			newRawResource(stack, "grafana_dashboard", "grafana_dashboard_"+id, map[string]interface{}{
+				"folder":      folderUID,
+				"config_json": *cdktf.Fn_Jsonencode(template.dashboard(config, title, value)),
+				"overwrite":   true,
+			})
+			dashboards++
+			if template.alert != nil && !grafana.DisableAlerts {
+				stackRules = append(stackRules, template.rule(config, title, value))
+			}
+		}
+		if len(stackRules) > 0 {
+			newRawResource(stack, "grafana_rule_group", "grafana_alerts", map[string]interface{}{
+				"name":             "standard",
+				"folder_uid":       folderUID,
+				"interval_seconds": 60,
+				"rule":             stackRules,
+			})
+			rules += len(stackRules)
+		}
+	}
+
+	fmt.Printf("  ✓ Grafana: %d dashboard(s), %d alert rule(s) in %d folder(s)\n", dashboards, rules, len(stackNames))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/guarddutydetector"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/guarddutydetectorfeature"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/guarddutypublishingdestination"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/kmskey"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// SecurityBaselineConfig groups the account-level security services
+type SecurityBaselineConfig struct {
+	GuardDuty *GuardDutyConfig `json:"guardduty,omitempty"`
+}
+
+type GuardDutyConfig struct {
+	S3Protection        bool   `json:"s3_protection"`
+	EKSProtection       bool   `json:"eks_protection"`
+	MalwareProtection   bool   `json:"malware_protection"`
+	PublishingFrequency string `json:"publishing_frequency"` // FIFTEEN_MINUTES, ONE_HOUR or SIX_HOURS
+	FindingsBucket      string `json:"findings_bucket"`      // created for the export; empty disables it
+}
+
+func (s *SecurityBaselineConfig) validate() error {
+	if s.GuardDuty != nil {
+		switch s.GuardDuty.PublishingFrequency {
+		case "", "FIFTEEN_MINUTES", "ONE_HOUR", "SIX_HOURS":
+		default:
+			frequency := s.GuardDuty.PublishingFrequency
+			return fmt.Errorf("guardduty: %w", invalidValue(frequency, closestMatch(frequency, []string{"FIFTEEN_MINUTES", "ONE_HOUR", "SIX_HOURS"}),
+				"unknown publishing_frequency %q", frequency))
+		}
+	}
+	return nil
+}
+
+// addSecurityBaseline enables the account-level security services that are configured
+func addSecurityBaseline(stack cdktf.TerraformStack, config Config) {
+	if config.SecurityBaseline.GuardDuty != nil {
+		addGuardDuty(stack, config)
+	}
+}
+
+// addGuardDuty enables a detector with the requested protection plans and, when a findings
+// bucket is named, exports findings to it encrypted with a dedicated KMS key
+func addGuardDuty(stack cdktf.TerraformStack, config Config) {
+	guardduty := config.SecurityBaseline.GuardDuty
+
+	frequency := guardduty.PublishingFrequency
+	if frequency == "" {
+		frequency = "SIX_HOURS"
+	}
+
+	detector := guarddutydetector.NewGuarddutyDetector(stack, jsii.String("guardduty"),
+		&guarddutydetector.GuarddutyDetectorConfig{
+			Enable:                     jsii.Bool(true),
+			FindingPublishingFrequency: jsii.String(frequency),
+		})
+
+	features := []struct {
+		name    string
+		enabled bool
+	}{
+		{"S3_DATA_EVENTS", guardduty.S3Protection},
+		{"EKS_AUDIT_LOGS", guardduty.EKSProtection},
+		{"EBS_MALWARE_PROTECTION", guardduty.MalwareProtection},
+	}
+	for _, feature := range features {
+		status := "DISABLED"
+		if feature.enabled {
+			status = "ENABLED"
+		}
+		guarddutydetectorfeature.NewGuarddutyDetectorFeature(stack, jsii.String("guardduty_"+feature.name),
+			&guarddutydetectorfeature.GuarddutyDetectorFeatureConfig{
+				DetectorId: detector.Id(),
+				Name:       jsii.String(feature.name),
+				Status:     jsii.String(status),
+			})
+	}
+
+	if guardduty.FindingsBucket != "" {
+		account := *accountID(stack)
+		sourceCondition := map[string]interface{}{
+			"StringEquals": map[string]string{"aws:SourceAccount": account},
+		}
+
+		key := kmskey.NewKmsKey(stack, jsii.String("guardduty_findings_key"), &kmskey.KmsKeyConfig{
+			Description:       jsii.String("Encrypts GuardDuty findings exported by " + resourceName(config, "aws_guardduty_detector", "guardduty")),
+			EnableKeyRotation: jsii.Bool(true),
+			Policy: jsii.String(policyDocument(
+				map[string]interface{}{
+					"Sid":       "AccountAdmin",
+					"Effect":    "Allow",
+					"Principal": map[string]string{"AWS": "arn:aws:iam::" + account + ":root"},
+					"Action":    "kms:*",
+					"Resource":  "*",
+				},
+				map[string]interface{}{
+					"Sid":       "GuardDutyEncrypt",
+					"Effect":    "Allow",
+					"Principal": map[string]string{"Service": "guardduty.amazonaws.com"},
+					"Action":    "kms:GenerateDataKey",
+					"Resource":  "*",
+					"Condition": sourceCondition,
+				},
+			)),
+		})
+
+		bucket, bucketPolicy := newLogBucket(stack, "guardduty_findings_bucket", resourceName(config, "aws_s3_bucket", guardduty.FindingsBucket), config,
+			func(bucket s3bucket.S3Bucket) []map[string]interface{} {
+				return []map[string]interface{}{
+					{
+						"Sid":       "GuardDutyGetBucketLocation",
+						"Effect":    "Allow",
+						"Principal": map[string]string{"Service": "guardduty.amazonaws.com"},
+						"Action":    "s3:GetBucketLocation",
+						"Resource":  *bucket.Arn(),
+						"Condition": sourceCondition,
+					},
+					{
+						"Sid":       "GuardDutyPutObject",
+						"Effect":    "Allow",
+						"Principal": map[string]string{"Service": "guardduty.amazonaws.com"},
+						"Action":    "s3:PutObject",
+						"Resource":  *bucket.Arn() + "/*",
+						"Condition": sourceCondition,
+					},
+				}
+			})
+
+		// GuardDuty checks the bucket policy when the destination is created
+		guarddutypublishingdestination.NewGuarddutyPublishingDestination(stack, jsii.String("guardduty_export"),
+			&guarddutypublishingdestination.GuarddutyPublishingDestinationConfig{
+				DetectorId:      detector.Id(),
+				DestinationArn:  bucket.Arn(),
+				DestinationType: jsii.String("S3"),
+				KmsKeyArn:       key.Arn(),
+				DependsOn:       &[]cdktf.ITerraformDependable{bucketPolicy},
+			})
+
+		cdktf.NewTerraformOutput(stack, jsii.String("guardduty_findings_bucket_name"), &cdktf.TerraformOutputConfig{
+			Value:       bucket.Bucket(),
+			Description: jsii.String("The bucket GuardDuty exports findings to"),
+		})
+	}
+
+	fmt.Println("  ✓ GuardDuty detector enabled")
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// HelmConfig installs Helm charts into the EKS cluster, for the addons every cluster runs, such as
+// ingress-nginx or external-dns
+type HelmConfig struct {
+	ClusterName string        `json:"cluster_name"` // defaults to kubernetes.cluster_name
+	Releases    []HelmRelease `json:"releases"`
+}
+
+// HelmRelease is one chart installation
+type HelmRelease struct {
+	Name       string `json:"name"`
+	Chart      string `json:"chart"`      // e.g. ingress-nginx
+	Repository string `json:"repository"` // e.g. https://kubernetes.github.io/ingress-nginx or oci://...
+	Version    string `json:"version"`    // the chart version, required so upgrades are deliberate
+	Namespace  string `json:"namespace"`  // defaults to default
+	// CreateNamespace creates the namespace if it doesn't exist
+	CreateNamespace bool `json:"create_namespace"`
+	// ValuesFile is a YAML values file relative to the config file; Values are applied on top of it
+	ValuesFile string                 `json:"values_file"`
+	Values     map[string]interface{} `json:"values"`
+	Timeout    int                    `json:"timeout"` // seconds to wait for the release; defaults to 300
+
+	// valuesFile is the content of ValuesFile, read by loadConfig
+	valuesFile string
+}
+
+// defaultHelmVersion is the helm provider constraint unless provider_versions sets one
+const defaultHelmVersion = "~> 2.0"
+
+// chartRepositoryPattern matches the chart repositories Helm can pull from
+var chartRepositoryPattern = regexp.MustCompile(`^(https|oci)://\S+$`)
+
+// helmCluster is the name of the cluster Helm installs into
+func helmCluster(config Config) string {
+	if config.Helm.ClusterName != "" {
+		return config.Helm.ClusterName
+	}
+	if config.Kubernetes != nil {
+		return config.Kubernetes.ClusterName
+	}
+	return ""
+}
+
+func (h *HelmRelease) validate() error {
+	// Helm keeps release names to 53 characters, leaving room for the suffixes of its objects
+	if len(h.Name) > 53 || !kubernetesNamePattern.MatchString(h.Name) {
+		return fmt.Errorf("name %q must be at most 53 lowercase letters, digits and -", h.Name)
+	}
+	if h.Chart == "" {
+		return fmt.Errorf("%s: chart is required", h.Name)
+	}
+	if h.Repository != "" && !chartRepositoryPattern.MatchString(h.Repository) {
+		return fmt.Errorf("%s: repository %q must be an https:// or oci:// URL", h.Name, h.Repository)
+	}
+	if h.Version == "" {
+		return fmt.Errorf("%s: version is required", h.Name)
+	}
+	if h.Namespace != "" {
+		if err := validateKubernetesName("namespace", h.Namespace); err != nil {
+			return fmt.Errorf("%s: %w", h.Name, err)
+		}
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("%s: timeout can't be negative", h.Name)
+	}
+	return nil
+}
+
+func validateHelm(config Config) error {
+	helm := config.Helm
+	if helmCluster(config) == "" {
+		return fmt.Errorf("cluster_name is required without a kubernetes section")
+	}
+	// Both sections read the cluster under one logical id when they share a stack
+	if config.Kubernetes != nil && helm.ClusterName != "" && helm.ClusterName != config.Kubernetes.ClusterName {
+		return fmt.Errorf("cluster_name %s differs from kubernetes.cluster_name %s", helm.ClusterName, config.Kubernetes.ClusterName)
+	}
+	if len(helm.Releases) == 0 {
+		return fmt.Errorf("at least one release is required")
+	}
+	names := map[string]bool{}
+	for i, release := range helm.Releases {
+		if err := release.validate(); err != nil {
+			return fmt.Errorf("releases[%d]: %w", i, err)
+		}
+		if names[release.Name] {
+			return fmt.Errorf("releases[%d]: duplicate release %s", i, release.Name)
+		}
+		names[release.Name] = true
+	}
+	return nil
+}
+
+// loadHelmValues reads the values files of the releases, relative to the config file in dir
+func loadHelmValues(helm *HelmConfig, dir string) error {
+	for i := range helm.Releases {
+		release := &helm.Releases[i]
+		if release.ValuesFile == "" {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(dir, release.ValuesFile))
+		if err != nil {
+			return fmt.Errorf("helm: %s: reading values_file: %w", release.Name, err)
+		}
+		release.valuesFile = escapeInterpolation(string(raw))
+	}
+	return nil
+}
+
+// addHelm installs the releases with the helm provider. Releases are atomic: a failed install or
+// upgrade is rolled back, so the cluster is never left with a half-applied chart.
+func addHelm(stack cdktf.TerraformStack, config Config) {
+	helm := config.Helm
+	_, connection := eksConnection(stack, helmCluster(config))
+	addRawProvider(stack, config, "helm", "hashicorp/helm", defaultHelmVersion, map[string]interface{}{
+		"kubernetes": connection,
+	})
+
+	for _, release := range helm.Releases {
+		namespace := release.Namespace
+		if namespace == "" {
+			namespace = "default"
+		}
+		timeout := release.Timeout
+		if timeout == 0 {
+			timeout = 300
+		}
+		var values []string
+		if release.valuesFile != "" {
+			values = append(values, release.valuesFile)
+		}
+		if len(release.Values) > 0 {
+			values = append(values, *cdktf.Fn_Yamlencode(release.Values))
+		}
+
+		attributes := map[string]interface{}{
+			"name":             release.Name,
+			"chart":            release.Chart,
+			"version":          release.Version,
+			"namespace":        namespace,
+			"create_namespace": release.CreateNamespace,
+			"atomic":           true,
+			"cleanup_on_fail":  true,
+			"timeout":          timeout,
+		}
+		if release.Repository != "" {
+			attributes["repository"] = release.Repository
+		}
+		if len(values) > 0 {
+			attributes["values"] = values
+		}
+		newRawResource(stack, "helm_release", "release_"+kubernetesID(release.Name), attributes)
+	}
+
+	fmt.Printf("  ✓ Helm: %d release(s) on EKS cluster %s\n", len(helm.Releases), helmCluster(config))
+}
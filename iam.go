@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrole"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrolepolicy"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrolepolicyattachment"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// assumeRolePolicy returns a trust policy letting the given AWS service assume a role
+func assumeRolePolicy(service string) string {
+	policy, _ := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":    "Allow",
+				"Principal": map[string]string{"Service": service},
+				"Action":    "sts:AssumeRole",
+			},
+		},
+	})
+	return string(policy)
+}
+
+// policyDocument renders an IAM policy document from its statements
+func policyDocument(statements ...map[string]interface{}) string {
+	policy, _ := json.Marshal(map[string]interface{}{
+		"Version":   "2012-10-17",
+		"Statement": statements,
+	})
+	return string(policy)
+}
+
+// bucketAccessStatement grants read (and optionally write) access to a bucket and its objects
+func bucketAccessStatement(bucket s3bucket.S3Bucket, write bool) map[string]interface{} {
+	actions := []string{"s3:GetObject", "s3:ListBucket", "s3:GetBucketLocation"}
+	if write {
+		actions = append(actions, "s3:PutObject", "s3:DeleteObject")
+	}
+	return map[string]interface{}{
+		"Effect":   "Allow",
+		"Action":   actions,
+		"Resource": []string{*bucket.Arn(), *bucket.Arn() + "/*"},
+	}
+}
+
+// newServiceRole creates a role assumable by service with the given managed policies attached
+func newServiceRole(stack cdktf.TerraformStack, id string, name string, service string,
+	config Config, managedPolicyArns ...string) iamrole.IamRole {
+	role := iamrole.NewIamRole(stack, jsii.String(id), &iamrole.IamRoleConfig{
+		Name:             jsii.String(name),
+		AssumeRolePolicy: jsii.String(assumeRolePolicy(service)),
+	})
+
+	for i, arn := range managedPolicyArns {
+		iamrolepolicyattachment.NewIamRolePolicyAttachment(stack, jsii.String(fmt.Sprintf("%s_policy_%d", id, i)),
+			&iamrolepolicyattachment.IamRolePolicyAttachmentConfig{
+				Role:      role.Name(),
+				PolicyArn: jsii.String(arn),
+			})
+	}
+
+	return role
+}
+
+// addInlinePolicy attaches an inline policy built from statements to role
+func addInlinePolicy(stack cdktf.TerraformStack, id string, role iamrole.IamRole,
+	statements ...map[string]interface{}) {
+	iamrolepolicy.NewIamRolePolicy(stack, jsii.String(id), &iamrolepolicy.IamRolePolicyConfig{
+		Role:   role.Id(),
+		Policy: jsii.String(policyDocument(statements...)),
+	})
+}
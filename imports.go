@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+func validateImports(imports map[string]string) error {
+	for address, id := range imports {
+		if !resourceAddress.MatchString(address) {
+			return fmt.Errorf("%q is not a resource address like aws_s3_bucket.bucket", address)
+		}
+		if id == "" {
+			return fmt.Errorf("%s: no resource ID given", address)
+		}
+	}
+	return nil
+}
+
+// addImports generates an import block for each existing resource the config adopts, so the first
+// apply takes it over instead of failing to create a duplicate
+func addImports(resources map[string][]cdktf.TerraformResource, imports map[string]string) error {
+	var unknown []string
+	for _, address := range slices.Sorted(maps.Keys(imports)) {
+		switch len(resources[address]) {
+		case 0:
+			unknown = append(unknown, address)
+		case 1:
+			// Import through the resource's own provider, such as the us-east-1 alias for CloudFront WAF
+			resource := resources[address][0]
+			resource.ImportFrom(jsii.String(imports[address]), resource.Provider())
+		default:
+			return fmt.Errorf("imports: %s is in more than one stack", address)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("imports: no resource %s in any stack", strings.Join(unknown, ", "))
+	}
+	fmt.Printf("  ✓ Import blocks for %d existing resource(s)\n", len(imports))
+	return nil
+}
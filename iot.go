@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dataawsiotendpoint"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iotpolicy"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iotthing"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iotthingtype"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iottopicrule"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/lambdapermission"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// IoTConfig registers devices with IoT Core and routes the messages they publish: thing types and
+// things, the policies device certificates are given, and topic rules
+type IoTConfig struct {
+	ThingTypes map[string]IoTThingType `json:"thing_types"`
+	Things     map[string]IoTThing     `json:"things"`
+	// Policies are IoT policy documents by name, as JSON objects. Policy variables such as
+	// ${iot:Connection.Thing.ThingName} are kept as they are.
+	Policies map[string]json.RawMessage `json:"policies"`
+	Rules    map[string]IoTTopicRule    `json:"rules"`
+}
+
+type IoTThingType struct {
+	Description string `json:"description"`
+	// SearchableAttributes are attributes things of the type can be searched by, up to three
+	SearchableAttributes []string `json:"searchable_attributes"`
+}
+
+type IoTThing struct {
+	Type       string            `json:"type"` // one of thing_types
+	Attributes map[string]string `json:"attributes"`
+}
+
+// IoTTopicRule selects messages with an IoT SQL statement and sends them to each of its actions.
+// Messages an action fails to deliver are written to the config bucket under iot-errors/<rule>/.
+type IoTTopicRule struct {
+	SQL         string            `json:"sql"` // e.g. SELECT * FROM 'devices/+/telemetry'
+	Description string            `json:"description"`
+	Kinesis     *IoTKinesisAction `json:"kinesis,omitempty"`
+	Lambda      *IoTLambdaAction  `json:"lambda,omitempty"`
+	S3          *IoTS3Action      `json:"s3,omitempty"`
+}
+
+// IoTKinesisAction puts each message on an existing Kinesis data stream
+type IoTKinesisAction struct {
+	Stream       string `json:"stream"`        // the stream's name
+	PartitionKey string `json:"partition_key"` // a substitution template, ${newuuid()} by default
+}
+
+// IoTLambdaAction invokes an existing Lambda function with each message
+type IoTLambdaAction struct {
+	FunctionARN string `json:"function_arn"`
+}
+
+// IoTS3Action writes each message to the config bucket
+type IoTS3Action struct {
+	// Key is a substitution template, <rule>/${topic()}/${timestamp()}.json by default
+	Key string `json:"key"`
+}
+
+var (
+	iotNamePattern        = regexp.MustCompile(`^[a-zA-Z0-9:_-]{1,128}$`)
+	kinesisStreamPattern  = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,128}$`)
+	lambdaFunctionPattern = regexp.MustCompile(`^arn:aws[a-z-]*:lambda:[a-z0-9-]+:\d{12}:function:[a-zA-Z0-9_-]+(:[a-zA-Z0-9_$-]+)?$`)
+)
+
+// maxIoTPolicySize is the largest policy document IoT Core accepts, in characters
+const maxIoTPolicySize = 2048
+
+// iotRuleName is the name of a topic rule, which can't contain hyphens
+func iotRuleName(config Config, name string) string {
+	return strings.ReplaceAll(resourceName(config, "aws_iot_topic_rule", name), "-", "_")
+}
+
+func (i *IoTConfig) validate() error {
+	for _, name := range slices.Sorted(maps.Keys(i.ThingTypes)) {
+		if !iotNamePattern.MatchString(name) {
+			return fmt.Errorf("thing_types: name %q must be letters, digits, :, _ or -", name)
+		}
+		if len(i.ThingTypes[name].SearchableAttributes) > 3 {
+			return fmt.Errorf("thing_types.%s: a thing type has at most 3 searchable_attributes", name)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(i.Things)) {
+		thing := i.Things[name]
+		if !iotNamePattern.MatchString(name) {
+			return fmt.Errorf("things: name %q must be letters, digits, :, _ or -", name)
+		}
+		if _, ok := i.ThingTypes[thing.Type]; thing.Type != "" && !ok {
+			return invalidValue(thing.Type, closestMatch(thing.Type, slices.Sorted(maps.Keys(i.ThingTypes))),
+				"things.%s: unknown type %q", name, thing.Type)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(i.Policies)) {
+		if !blockLabel.MatchString(name) {
+			return fmt.Errorf("policies: name %q must be letters, digits, _ or -", name)
+		}
+		var document map[string]interface{}
+		if err := json.Unmarshal(i.Policies[name], &document); err != nil || document == nil {
+			return fmt.Errorf("policies.%s: must be a JSON policy object", name)
+		}
+		if _, ok := document["Statement"]; !ok {
+			return fmt.Errorf("policies.%s: document has no Statement", name)
+		}
+		var compact bytes.Buffer
+		json.Compact(&compact, i.Policies[name])
+		if compact.Len() > maxIoTPolicySize {
+			return invalidValue(compact.Len(), "split it into several policies",
+				"policies.%s: document is %d characters; IoT Core allows %d", name, compact.Len(), maxIoTPolicySize)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(i.Rules)) {
+		rule := i.Rules[name]
+		if !blockLabel.MatchString(name) {
+			return fmt.Errorf("rules: name %q must be letters, digits, _ or -", name)
+		}
+		if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(rule.SQL)), "SELECT ") {
+			return fmt.Errorf("rules.%s: sql must be an IoT SQL SELECT statement", name)
+		}
+		if rule.Kinesis == nil && rule.Lambda == nil && rule.S3 == nil {
+			return fmt.Errorf("rules.%s: needs at least one of kinesis, lambda or s3", name)
+		}
+		if rule.Kinesis != nil && !kinesisStreamPattern.MatchString(rule.Kinesis.Stream) {
+			return invalidValue(rule.Kinesis.Stream, "", "rules.%s: kinesis.stream %q is not a stream name", name, rule.Kinesis.Stream)
+		}
+		if rule.Lambda != nil && !lambdaFunctionPattern.MatchString(rule.Lambda.FunctionARN) {
+			return invalidValue(rule.Lambda.FunctionARN, "", "rules.%s: lambda.function_arn %q is not a Lambda function ARN", name, rule.Lambda.FunctionARN)
+		}
+	}
+	return nil
+}
+
+// addIoT creates the thing types, things, policies and topic rules. The rules share a role that
+// can write to their streams and the bucket; Lambda functions are given permission to be invoked
+// by their rule.
+func addIoT(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket) {
+	iot := config.IoT
+
+	thingTypes := map[string]iotthingtype.IotThingType{}
+	for _, name := range slices.Sorted(maps.Keys(iot.ThingTypes)) {
+		thingType := iot.ThingTypes[name]
+		properties := &iotthingtype.IotThingTypeProperties{}
+		if thingType.Description != "" {
+			properties.Description = jsii.String(thingType.Description)
+		}
+		if len(thingType.SearchableAttributes) > 0 {
+			properties.SearchableAttributes = jsii.Strings(thingType.SearchableAttributes...)
+		}
+		thingTypes[name] = iotthingtype.NewIotThingType(stack, jsii.String("iot_thing_type_"+accountsLogicalID(name)),
+			&iotthingtype.IotThingTypeConfig{
+				Name:       jsii.String(name),
+				Properties: properties,
+			})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(iot.Things)) {
+		thing := iot.Things[name]
+		thingConfig := &iotthing.IotThingConfig{Name: jsii.String(name)}
+		if thing.Type != "" {
+			thingConfig.ThingTypeName = thingTypes[thing.Type].Name()
+		}
+		if len(thing.Attributes) > 0 {
+			thingConfig.Attributes = toStringMap(thing.Attributes)
+		}
+		iotthing.NewIotThing(stack, jsii.String("iot_thing_"+accountsLogicalID(name)), thingConfig)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(iot.Policies)) {
+		var document bytes.Buffer
+		json.Compact(&document, iot.Policies[name])
+		iotpolicy.NewIotPolicy(stack, jsii.String("iot_policy_"+accountsLogicalID(name)), &iotpolicy.IotPolicyConfig{
+			Name:   jsii.String(resourceName(config, "aws_iot_policy", name)),
+			Policy: jsii.String(escapeInterpolation(document.String())),
+		})
+	}
+
+	if len(iot.Rules) > 0 {
+		addIoTRules(stack, config, bucket)
+	}
+
+	endpoint := dataawsiotendpoint.NewDataAwsIotEndpoint(stack, jsii.String("iot_endpoint"), &dataawsiotendpoint.DataAwsIotEndpointConfig{
+		EndpointType: jsii.String("iot:Data-ATS"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("iot_data_endpoint"), &cdktf.TerraformOutputConfig{
+		Value:       endpoint.EndpointAddress(),
+		Description: jsii.String("The MQTT endpoint devices connect to"),
+	})
+
+	fmt.Printf("  ✓ IoT Core: %d thing(s), %d polic(ies), %d topic rule(s)\n", len(iot.Things), len(iot.Policies), len(iot.Rules))
+}
+
+func addIoTRules(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket) {
+	iot := config.IoT
+	account := *accountID(stack)
+
+	statements := []map[string]interface{}{{
+		"Effect":   "Allow",
+		"Action":   "s3:PutObject",
+		"Resource": *bucket.Arn() + "/*",
+	}}
+	var streams []string
+	for _, name := range slices.Sorted(maps.Keys(iot.Rules)) {
+		if kinesis := iot.Rules[name].Kinesis; kinesis != nil {
+			arn := fmt.Sprintf("arn:aws:kinesis:%s:%s:stream/%s", config.Region, account, kinesis.Stream)
+			if !slices.Contains(streams, arn) {
+				streams = append(streams, arn)
+			}
+		}
+	}
+	if len(streams) > 0 {
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   []string{"kinesis:PutRecord", "kinesis:PutRecords"},
+			"Resource": streams,
+		})
+	}
+	role := newServiceRole(stack, "iot_rules_role", resourceName(config, "aws_iam_role", "iot-rules"), "iot.amazonaws.com", config)
+	addInlinePolicy(stack, "iot_rules_role_policy", role, statements...)
+
+	for _, name := range slices.Sorted(maps.Keys(iot.Rules)) {
+		rule := iot.Rules[name]
+		id := "iot_rule_" + accountsLogicalID(name)
+
+		ruleConfig := &iottopicrule.IotTopicRuleConfig{
+			Name:       jsii.String(iotRuleName(config, name)),
+			Enabled:    jsii.Bool(true),
+			Sql:        jsii.String(rule.SQL),
+			SqlVersion: jsii.String("2016-03-23"),
+			ErrorAction: &iottopicrule.IotTopicRuleErrorAction{
+				S3: &iottopicrule.IotTopicRuleErrorActionS3{
+					BucketName: bucket.Bucket(),
+					Key:        jsii.String(escapeInterpolation("iot-errors/" + name + "/${newuuid()}.json")),
+					RoleArn:    role.Arn(),
+				},
+			},
+		}
+		if rule.Description != "" {
+			ruleConfig.Description = jsii.String(rule.Description)
+		}
+		if kinesis := rule.Kinesis; kinesis != nil {
+			partitionKey := kinesis.PartitionKey
+			if partitionKey == "" {
+				partitionKey = "${newuuid()}"
+			}
+			ruleConfig.Kinesis = &[]*iottopicrule.IotTopicRuleKinesis{{
+				StreamName:   jsii.String(kinesis.Stream),
+				PartitionKey: jsii.String(escapeInterpolation(partitionKey)),
+				RoleArn:      role.Arn(),
+			}}
+		}
+		if lambda := rule.Lambda; lambda != nil {
+			ruleConfig.Lambda = &[]*iottopicrule.IotTopicRuleLambda{{FunctionArn: jsii.String(lambda.FunctionARN)}}
+		}
+		if s3 := rule.S3; s3 != nil {
+			key := s3.Key
+			if key == "" {
+				key = name + "/${topic()}/${timestamp()}.json"
+			}
+			ruleConfig.S3 = &[]*iottopicrule.IotTopicRuleS3{{
+				BucketName: bucket.Bucket(),
+				Key:        jsii.String(escapeInterpolation(key)),
+				RoleArn:    role.Arn(),
+			}}
+		}
+		topicRule := iottopicrule.NewIotTopicRule(stack, jsii.String(id), ruleConfig)
+
+		if lambda := rule.Lambda; lambda != nil {
+			lambdapermission.NewLambdaPermission(stack, jsii.String(id+"_invoke"), &lambdapermission.LambdaPermissionConfig{
+				StatementId:   jsii.String("iot-rule-" + name),
+				Action:        jsii.String("lambda:InvokeFunction"),
+				FunctionName:  jsii.String(lambda.FunctionARN),
+				Principal:     jsii.String("iot.amazonaws.com"),
+				SourceArn:     topicRule.Arn(),
+				SourceAccount: jsii.String(account),
+			})
+		}
+	}
+}
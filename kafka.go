@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/mskcluster"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/mskserverlesscluster"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// KafkaConfig describes a provisioned or serverless MSK cluster
+type KafkaConfig struct {
+	Serverless   bool      `json:"serverless"`
+	KafkaVersion string    `json:"kafka_version"`
+	BrokerType   string    `json:"broker_type"`
+	BrokerCount  float64   `json:"broker_count"`
+	VolumeSizeGB float64   `json:"volume_size_gb"`
+	ClientAuth   string    `json:"client_auth"` // iam or scram
+	KMSKeyArn    string    `json:"kms_key_arn"`
+	VPC          VPCConfig `json:"vpc"`
+}
+
+func (k *KafkaConfig) validate() error {
+	if len(k.VPC.SubnetIDs) < 2 {
+		return fmt.Errorf("vpc.subnet_ids needs at least two subnets")
+	}
+	switch k.ClientAuth {
+	case "", "iam":
+	case "scram":
+		if k.Serverless {
+			return fmt.Errorf("serverless clusters only support iam client_auth")
+		}
+	default:
+		return invalidValue(k.ClientAuth, closestMatch(k.ClientAuth, []string{"iam", "scram"}),
+			"unknown client_auth %q (want iam or scram)", k.ClientAuth)
+	}
+	// MSK places the same number of brokers in every client subnet
+	if !k.Serverless && k.BrokerCount != 0 && int(k.BrokerCount)%len(k.VPC.SubnetIDs) != 0 {
+		return fmt.Errorf("broker_count must be a multiple of the number of subnets (%d)", len(k.VPC.SubnetIDs))
+	}
+	return nil
+}
+
+// addKafka creates the MSK cluster and exports its bootstrap brokers for the chosen auth method
+func addKafka(stack cdktf.TerraformStack, config Config) {
+	kafka := config.Kafka
+
+	var bootstrapBrokers *string
+	if kafka.Serverless {
+		cluster := mskserverlesscluster.NewMskServerlessCluster(stack, jsii.String("kafka"),
+			&mskserverlesscluster.MskServerlessClusterConfig{
+				ClusterName: jsii.String(resourceName(config, "aws_msk_serverless_cluster", "kafka")),
+				VpcConfig: &[]*mskserverlesscluster.MskServerlessClusterVpcConfig{
+					{
+						SubnetIds:        jsii.Strings(kafka.VPC.SubnetIDs...),
+						SecurityGroupIds: jsii.Strings(kafka.VPC.SecurityGroupIDs...),
+					},
+				},
+				ClientAuthentication: &mskserverlesscluster.MskServerlessClusterClientAuthentication{
+					Sasl: &mskserverlesscluster.MskServerlessClusterClientAuthenticationSasl{
+						Iam: &mskserverlesscluster.MskServerlessClusterClientAuthenticationSaslIam{
+							Enabled: jsii.Bool(true),
+						},
+					},
+				},
+			})
+		bootstrapBrokers = cluster.BootstrapBrokersSaslIam()
+		fmt.Println("  ✓ MSK Serverless cluster")
+	} else {
+		kafkaVersion := kafka.KafkaVersion
+		if kafkaVersion == "" {
+			kafkaVersion = "3.6.0"
+		}
+		brokerType := kafka.BrokerType
+		if brokerType == "" {
+			brokerType = sizeFor(config).KafkaBrokerType
+		}
+		brokerCount := kafka.BrokerCount
+		if brokerCount == 0 {
+			brokerCount = float64(len(kafka.VPC.SubnetIDs))
+		}
+		volumeSize := kafka.VolumeSizeGB
+		if volumeSize == 0 {
+			volumeSize = sizeFor(config).KafkaVolumeGB
+		}
+
+		sasl := &mskcluster.MskClusterClientAuthenticationSasl{Iam: jsii.Bool(true)}
+		if kafka.ClientAuth == "scram" {
+			sasl = &mskcluster.MskClusterClientAuthenticationSasl{Scram: jsii.Bool(true)}
+		}
+
+		encryption := &mskcluster.MskClusterEncryptionInfo{
+			EncryptionInTransit: &mskcluster.MskClusterEncryptionInfoEncryptionInTransit{
+				ClientBroker: jsii.String("TLS"),
+				InCluster:    jsii.Bool(true),
+			},
+		}
+		if kafka.KMSKeyArn != "" {
+			encryption.EncryptionAtRestKmsKeyArn = jsii.String(kafka.KMSKeyArn)
+		}
+
+		cluster := mskcluster.NewMskCluster(stack, jsii.String("kafka"), &mskcluster.MskClusterConfig{
+			ClusterName:         jsii.String(resourceName(config, "aws_msk_cluster", "kafka")),
+			KafkaVersion:        jsii.String(kafkaVersion),
+			NumberOfBrokerNodes: jsii.Number(brokerCount),
+			BrokerNodeGroupInfo: &mskcluster.MskClusterBrokerNodeGroupInfo{
+				InstanceType:   jsii.String(brokerType),
+				ClientSubnets:  jsii.Strings(kafka.VPC.SubnetIDs...),
+				SecurityGroups: jsii.Strings(kafka.VPC.SecurityGroupIDs...),
+				StorageInfo: &mskcluster.MskClusterBrokerNodeGroupInfoStorageInfo{
+					EbsStorageInfo: &mskcluster.MskClusterBrokerNodeGroupInfoStorageInfoEbsStorageInfo{
+						VolumeSize: jsii.Number(volumeSize),
+					},
+				},
+			},
+			ClientAuthentication: &mskcluster.MskClusterClientAuthentication{
+				Sasl:            sasl,
+				Unauthenticated: jsii.Bool(false),
+			},
+			EncryptionInfo: encryption,
+		})
+
+		bootstrapBrokers = cluster.BootstrapBrokersSaslIam()
+		if kafka.ClientAuth == "scram" {
+			bootstrapBrokers = cluster.BootstrapBrokersSaslScram()
+		}
+		fmt.Printf("  ✓ MSK cluster (%v x %s)\n", brokerCount, brokerType)
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("kafka_bootstrap_brokers"), &cdktf.TerraformOutputConfig{
+		Value:       bootstrapBrokers,
+		Description: jsii.String("The bootstrap brokers of the MSK cluster"),
+	})
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/keyspaceskeyspace"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/keyspacestable"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// KeyspaceConfig is an Amazon Keyspaces keyspace and its tables, by table name
+type KeyspaceConfig struct {
+	KMSKeyArn string                    `json:"kms_key_arn"` // encrypts the tables instead of an AWS owned key
+	Tables    map[string]KeyspacesTable `json:"tables"`
+}
+
+// KeyspacesTable is a Cassandra table: its columns by name with their CQL types, its primary key
+// and how its read and write capacity is paid for
+type KeyspacesTable struct {
+	Columns        map[string]string `json:"columns"`
+	PartitionKey   []string          `json:"partition_key"`
+	ClusteringKeys []ClusteringKey   `json:"clustering_keys"`
+	StaticColumns  []string          `json:"static_columns"`
+	// Capacity is on_demand (the default) or provisioned, which needs read and write capacity units
+	Capacity            string  `json:"capacity"`
+	ReadCapacityUnits   float64 `json:"read_capacity_units"`
+	WriteCapacityUnits  float64 `json:"write_capacity_units"`
+	PointInTimeRecovery bool    `json:"point_in_time_recovery"`
+	DefaultTTLSeconds   float64 `json:"default_ttl_seconds"` // expires rows after this long; enables TTL on the table
+}
+
+type ClusteringKey struct {
+	Name    string `json:"name"`
+	OrderBy string `json:"order_by"` // ASC (the default) or DESC
+}
+
+// cassandraIdentifier matches the keyspace and table names Keyspaces accepts
+var cassandraIdentifier = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_]{0,47}$`)
+
+// cqlTypes are the types Keyspaces supports; collections and frozen types name their elements in
+// angle brackets, e.g. map<text, int>
+var cqlTypes = []string{
+	"ascii", "bigint", "blob", "boolean", "counter", "date", "decimal", "double", "float", "frozen", "inet", "int",
+	"list", "map", "set", "smallint", "text", "time", "timestamp", "timeuuid", "tinyint", "tuple", "uuid", "varchar", "varint",
+}
+
+func validateKeyspaces(keyspaces map[string]KeyspaceConfig) error {
+	for _, name := range slices.Sorted(maps.Keys(keyspaces)) {
+		keyspace := keyspaces[name]
+		if !cassandraIdentifier.MatchString(name) {
+			return fmt.Errorf("%s: keyspace names must be up to 48 letters, digits and _", name)
+		}
+		if len(keyspace.Tables) == 0 {
+			return fmt.Errorf("%s: at least one table is required", name)
+		}
+		for _, tableName := range slices.Sorted(maps.Keys(keyspace.Tables)) {
+			if err := keyspace.Tables[tableName].validate(tableName); err != nil {
+				return fmt.Errorf("%s.tables.%s: %w", name, tableName, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (t KeyspacesTable) validate(name string) error {
+	if !cassandraIdentifier.MatchString(name) {
+		return fmt.Errorf("table names must be up to 48 letters, digits and _")
+	}
+	if len(t.Columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+	for column, cqlType := range t.Columns {
+		base := strings.ToLower(strings.TrimSpace(strings.SplitN(cqlType, "<", 2)[0]))
+		if !slices.Contains(cqlTypes, base) {
+			return invalidValue(cqlType, closestMatch(base, cqlTypes), "columns.%s: unknown CQL type %q", column, cqlType)
+		}
+	}
+	if len(t.PartitionKey) == 0 {
+		return fmt.Errorf("partition_key needs at least one column")
+	}
+	keys := map[string]bool{}
+	for _, column := range t.PartitionKey {
+		if _, ok := t.Columns[column]; !ok {
+			return fmt.Errorf("partition_key: %q is not a column", column)
+		}
+		keys[column] = true
+	}
+	for _, key := range t.ClusteringKeys {
+		if _, ok := t.Columns[key.Name]; !ok {
+			return fmt.Errorf("clustering_keys: %q is not a column", key.Name)
+		}
+		if keys[key.Name] {
+			return fmt.Errorf("clustering_keys: %q is already part of the primary key", key.Name)
+		}
+		keys[key.Name] = true
+		if key.OrderBy != "" && key.OrderBy != "ASC" && key.OrderBy != "DESC" {
+			return invalidValue(key.OrderBy, closestMatch(key.OrderBy, []string{"ASC", "DESC"}),
+				"clustering_keys: %s: order_by must be ASC or DESC", key.Name)
+		}
+	}
+	for _, column := range t.StaticColumns {
+		if _, ok := t.Columns[column]; !ok {
+			return fmt.Errorf("static_columns: %q is not a column", column)
+		}
+		if keys[column] {
+			return fmt.Errorf("static_columns: %q is part of the primary key", column)
+		}
+	}
+	// Static columns are shared by the rows of a partition, so they need rows to share them
+	if len(t.StaticColumns) > 0 && len(t.ClusteringKeys) == 0 {
+		return fmt.Errorf("static_columns need clustering_keys")
+	}
+	switch t.Capacity {
+	case "", "on_demand":
+		if t.ReadCapacityUnits != 0 || t.WriteCapacityUnits != 0 {
+			return fmt.Errorf("read_capacity_units and write_capacity_units need provisioned capacity")
+		}
+	case "provisioned":
+		if t.ReadCapacityUnits < 1 || t.WriteCapacityUnits < 1 {
+			return fmt.Errorf("provisioned capacity needs read_capacity_units and write_capacity_units")
+		}
+	default:
+		return invalidValue(t.Capacity, closestMatch(t.Capacity, []string{"on_demand", "provisioned"}),
+			"unknown capacity %q (want on_demand or provisioned)", t.Capacity)
+	}
+	if t.DefaultTTLSeconds < 0 || t.DefaultTTLSeconds > 630720000 {
+		return fmt.Errorf("default_ttl_seconds must be at most 630720000 (20 years)")
+	}
+	return nil
+}
+
+// addKeyspaces creates each keyspace in the config's region and its tables
+func addKeyspaces(stack cdktf.TerraformStack, config Config) {
+	tables := 0
+	for _, name := range slices.Sorted(maps.Keys(config.Keyspaces)) {
+		keyspaceConfig := config.Keyspaces[name]
+		keyspace := keyspaceskeyspace.NewKeyspacesKeyspace(stack, jsii.String("keyspace_"+name), &keyspaceskeyspace.KeyspacesKeyspaceConfig{
+			Name: jsii.String(name),
+		})
+
+		for _, tableName := range slices.Sorted(maps.Keys(keyspaceConfig.Tables)) {
+			table := keyspaceConfig.Tables[tableName]
+
+			var columns []*keyspacestable.KeyspacesTableSchemaDefinitionColumn
+			for _, column := range slices.Sorted(maps.Keys(table.Columns)) {
+				columns = append(columns, &keyspacestable.KeyspacesTableSchemaDefinitionColumn{
+					Name: jsii.String(column),
+					Type: jsii.String(table.Columns[column]),
+				})
+			}
+			var partitionKey []*keyspacestable.KeyspacesTableSchemaDefinitionPartitionKey
+			for _, column := range table.PartitionKey {
+				partitionKey = append(partitionKey, &keyspacestable.KeyspacesTableSchemaDefinitionPartitionKey{Name: jsii.String(column)})
+			}
+			schema := &keyspacestable.KeyspacesTableSchemaDefinition{
+				Column:       &columns,
+				PartitionKey: &partitionKey,
+			}
+			if len(table.ClusteringKeys) > 0 {
+				var clusteringKeys []*keyspacestable.KeyspacesTableSchemaDefinitionClusteringKey
+				for _, key := range table.ClusteringKeys {
+					orderBy := key.OrderBy
+					if orderBy == "" {
+						orderBy = "ASC"
+					}
+					clusteringKeys = append(clusteringKeys, &keyspacestable.KeyspacesTableSchemaDefinitionClusteringKey{
+						Name:    jsii.String(key.Name),
+						OrderBy: jsii.String(orderBy),
+					})
+				}
+				schema.ClusteringKey = &clusteringKeys
+			}
+			if len(table.StaticColumns) > 0 {
+				var staticColumns []*keyspacestable.KeyspacesTableSchemaDefinitionStaticColumn
+				for _, column := range table.StaticColumns {
+					staticColumns = append(staticColumns, &keyspacestable.KeyspacesTableSchemaDefinitionStaticColumn{Name: jsii.String(column)})
+				}
+				schema.StaticColumn = &staticColumns
+			}
+
+			capacity := &keyspacestable.KeyspacesTableCapacitySpecification{ThroughputMode: jsii.String("PAY_PER_REQUEST")}
+			if table.Capacity == "provisioned" {
+				capacity = &keyspacestable.KeyspacesTableCapacitySpecification{
+					ThroughputMode:     jsii.String("PROVISIONED"),
+					ReadCapacityUnits:  jsii.Number(table.ReadCapacityUnits),
+					WriteCapacityUnits: jsii.Number(table.WriteCapacityUnits),
+				}
+			}
+			encryption := &keyspacestable.KeyspacesTableEncryptionSpecification{Type: jsii.String("AWS_OWNED_KMS_KEY")}
+			if keyspaceConfig.KMSKeyArn != "" {
+				encryption = &keyspacestable.KeyspacesTableEncryptionSpecification{
+					Type:             jsii.String("CUSTOMER_MANAGED_KMS_KEY"),
+					KmsKeyIdentifier: jsii.String(keyspaceConfig.KMSKeyArn),
+				}
+			}
+			pointInTimeRecovery := "DISABLED"
+			if table.PointInTimeRecovery {
+				pointInTimeRecovery = "ENABLED"
+			}
+
+			tableConfig := &keyspacestable.KeyspacesTableConfig{
+				KeyspaceName:            keyspace.Name(),
+				TableName:               jsii.String(tableName),
+				SchemaDefinition:        schema,
+				CapacitySpecification:   capacity,
+				EncryptionSpecification: encryption,
+				PointInTimeRecovery:     &keyspacestable.KeyspacesTablePointInTimeRecovery{Status: jsii.String(pointInTimeRecovery)},
+			}
+			if table.DefaultTTLSeconds > 0 {
+				tableConfig.Ttl = &keyspacestable.KeyspacesTableTtl{Status: jsii.String("ENABLED")}
+				tableConfig.DefaultTimeToLive = jsii.Number(table.DefaultTTLSeconds)
+			}
+			keyspacestable.NewKeyspacesTable(stack, jsii.String(fmt.Sprintf("keyspace_%s_%s", name, tableName)), tableConfig)
+			tables++
+		}
+	}
+
+	fmt.Printf("  ✓ %d keyspace(s) with %d table(s)\n", len(config.Keyspaces), tables)
+}
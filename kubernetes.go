@@ -0,0 +1,270 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dataawsekscluster"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dataawseksclusterauth"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dataawsiamopenidconnectprovider"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/dataawsiampolicydocument"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrole"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrolepolicyattachment"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// KubernetesConfig manages namespaced objects in an EKS cluster through the kubernetes provider.
+// The config can't define a cluster, so this targets an existing one by name and signs in with
+// the deploying identity, which needs access to the cluster.
+type KubernetesConfig struct {
+	ClusterName     string                     `json:"cluster_name"`
+	Namespaces      []KubernetesNamespace      `json:"namespaces"`
+	ConfigMaps      []KubernetesConfigMap      `json:"config_maps"`
+	ServiceAccounts []KubernetesServiceAccount `json:"service_accounts"`
+}
+
+// KubernetesNamespace is a namespace created by the section
+type KubernetesNamespace struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+}
+
+// KubernetesConfigMap is a config map. Its values are literals or ${...} references to resources
+// in the same stack, such as ${aws_s3_bucket.bucket.bucket}.
+type KubernetesConfigMap struct {
+	Name      string            `json:"name"`
+	Namespace string            `json:"namespace"`
+	Data      map[string]string `json:"data"`
+}
+
+// KubernetesServiceAccount is a service account whose pods get AWS credentials through IAM roles
+// for service accounts (IRSA): either a role created for it with policy_arns attached, or an
+// existing role_arn.
+type KubernetesServiceAccount struct {
+	Name       string   `json:"name"`
+	Namespace  string   `json:"namespace"`
+	PolicyARNs []string `json:"policy_arns"`
+	RoleARN    string   `json:"role_arn"`
+}
+
+// defaultKubernetesVersion is the kubernetes provider constraint unless provider_versions sets one
+const defaultKubernetesVersion = "~> 2.0"
+
+var (
+	// kubernetesNamePattern matches an RFC 1123 label, the names of namespaces and service accounts
+	kubernetesNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	configMapNamePattern  = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	iamRoleARNPattern     = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/[\w+=,.@/-]+$`)
+	iamPolicyARNPattern   = regexp.MustCompile(`^arn:aws[a-z-]*:iam::(aws|\d{12}):policy/[\w+=,.@/-]+$`)
+)
+
+func validateKubernetesName(kind string, name string) error {
+	if len(name) > 63 || !kubernetesNamePattern.MatchString(name) {
+		return fmt.Errorf("%s %q must be at most 63 lowercase letters, digits and -", kind, name)
+	}
+	return nil
+}
+
+func (k *KubernetesServiceAccount) validate() error {
+	if err := validateKubernetesName("name", k.Name); err != nil {
+		return err
+	}
+	if err := validateKubernetesName("namespace", k.Namespace); err != nil {
+		return fmt.Errorf("%s: %w", k.Name, err)
+	}
+	if len(k.PolicyARNs) > 0 && k.RoleARN != "" {
+		return fmt.Errorf("%s: policy_arns and role_arn can't be combined", k.Name)
+	}
+	if k.RoleARN != "" && !iamRoleARNPattern.MatchString(k.RoleARN) {
+		return fmt.Errorf("%s: role_arn %q is not an IAM role ARN", k.Name, k.RoleARN)
+	}
+	for _, arn := range k.PolicyARNs {
+		if !iamPolicyARNPattern.MatchString(arn) {
+			return fmt.Errorf("%s: %q is not an IAM policy ARN", k.Name, arn)
+		}
+	}
+	return nil
+}
+
+func (k *KubernetesConfig) validate() error {
+	if k.ClusterName == "" {
+		return fmt.Errorf("cluster_name is required")
+	}
+	if len(k.Namespaces) == 0 && len(k.ConfigMaps) == 0 && len(k.ServiceAccounts) == 0 {
+		return fmt.Errorf("at least one namespace, config map or service account is required")
+	}
+
+	ids := map[string]bool{}
+	for i, namespace := range k.Namespaces {
+		if err := validateKubernetesName("name", namespace.Name); err != nil {
+			return fmt.Errorf("namespaces[%d]: %w", i, err)
+		}
+		if ids["namespace_"+kubernetesID(namespace.Name)] {
+			return fmt.Errorf("namespaces[%d]: duplicate namespace %s", i, namespace.Name)
+		}
+		ids["namespace_"+kubernetesID(namespace.Name)] = true
+	}
+	for i, configMap := range k.ConfigMaps {
+		if len(configMap.Name) > 253 || !configMapNamePattern.MatchString(configMap.Name) {
+			return fmt.Errorf("config_maps[%d]: name %q must be lowercase letters, digits, - and .", i, configMap.Name)
+		}
+		if err := validateKubernetesName("namespace", configMap.Namespace); err != nil {
+			return fmt.Errorf("config_maps[%d]: %w", i, err)
+		}
+		id := "config_map_" + kubernetesID(configMap.Namespace, configMap.Name)
+		if ids[id] {
+			return fmt.Errorf("config_maps[%d]: duplicate config map %s/%s", i, configMap.Namespace, configMap.Name)
+		}
+		ids[id] = true
+	}
+	for i, account := range k.ServiceAccounts {
+		if err := account.validate(); err != nil {
+			return fmt.Errorf("service_accounts[%d]: %w", i, err)
+		}
+		id := "service_account_" + kubernetesID(account.Namespace, account.Name)
+		if ids[id] {
+			return fmt.Errorf("service_accounts[%d]: duplicate service account %s/%s", i, account.Namespace, account.Name)
+		}
+		ids[id] = true
+	}
+	return nil
+}
+
+// kubernetesID joins names into a logical id, e.g. data_ingest for data/ingest
+func kubernetesID(names ...string) string {
+	return strings.Trim(logicalIDInvalid.ReplaceAllString(strings.Join(names, "_"), "_"), "_")
+}
+
+// eksConnection reads an EKS cluster and returns it with the host, CA certificate and token that
+// the kubernetes and helm providers connect with. The cluster is read once per stack.
+func eksConnection(stack cdktf.TerraformStack, clusterName string) (dataawsekscluster.DataAwsEksCluster, map[string]interface{}) {
+	cluster, ok := stack.Node().TryFindChild(jsii.String("eks_cluster")).(dataawsekscluster.DataAwsEksCluster)
+	if !ok {
+		cluster = dataawsekscluster.NewDataAwsEksCluster(stack, jsii.String("eks_cluster"),
+			&dataawsekscluster.DataAwsEksClusterConfig{Name: jsii.String(clusterName)})
+	}
+	auth, ok := stack.Node().TryFindChild(jsii.String("eks_cluster_auth")).(dataawseksclusterauth.DataAwsEksClusterAuth)
+	if !ok {
+		auth = dataawseksclusterauth.NewDataAwsEksClusterAuth(stack, jsii.String("eks_cluster_auth"),
+			&dataawseksclusterauth.DataAwsEksClusterAuthConfig{Name: jsii.String(clusterName)})
+	}
+	return cluster, map[string]interface{}{
+		"host":                   *cluster.Endpoint(),
+		"cluster_ca_certificate": *cdktf.Fn_Base64decode(cluster.CertificateAuthority().Get(jsii.Number(0)).Data()),
+		"token":                  *auth.Token(),
+	}
+}
+
+// addKubernetes creates the namespaces, config maps and service accounts in the cluster. Objects
+// in a namespace of the section refer to it, so they are created after it.
+func addKubernetes(stack cdktf.TerraformStack, config Config) {
+	kubernetes := config.Kubernetes
+	cluster, connection := eksConnection(stack, kubernetes.ClusterName)
+	addRawProvider(stack, config, "kubernetes", "hashicorp/kubernetes", defaultKubernetesVersion, connection)
+
+	labels := map[string]string{"app.kubernetes.io/managed-by": "cdktf-json-platform", "project": config.Project,
+		"environment": config.Environment}
+	namespaces := map[string]string{}
+	for _, namespace := range kubernetes.Namespaces {
+		namespaceLabels := map[string]string{}
+		for key, value := range labels {
+			namespaceLabels[key] = value
+		}
+		for key, value := range namespace.Labels {
+			namespaceLabels[key] = value
+		}
+		resource := newRawResource(stack, "kubernetes_namespace_v1", "namespace_"+kubernetesID(namespace.Name),
+			map[string]interface{}{
+				"metadata": map[string]interface{}{"name": namespace.Name, "labels": namespaceLabels},
+			})
+		namespaces[namespace.Name] = *resource.GetStringAttribute(jsii.String("metadata[0].name"))
+	}
+	// namespaceOf refers to a namespace of the section, so the object is created after it
+	namespaceOf := func(name string) string {
+		if reference, ok := namespaces[name]; ok {
+			return reference
+		}
+		return name
+	}
+
+	for _, configMap := range kubernetes.ConfigMaps {
+		newRawResource(stack, "kubernetes_config_map_v1", "config_map_"+kubernetesID(configMap.Namespace, configMap.Name),
+			map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":      configMap.Name,
+					"namespace": namespaceOf(configMap.Namespace),
+					"labels":    labels,
+				},
+				"data": configMap.Data,
+			})
+	}
+
+	var provider dataawsiamopenidconnectprovider.DataAwsIamOpenidConnectProvider
+	roles := 0
+	for _, account := range kubernetes.ServiceAccounts {
+		id := kubernetesID(account.Namespace, account.Name)
+		roleARN := account.RoleARN
+		if len(account.PolicyARNs) > 0 {
+			issuer := cluster.Identity().Get(jsii.Number(0)).Oidc().Get(jsii.Number(0)).Issuer()
+			if provider == nil {
+				provider = dataawsiamopenidconnectprovider.NewDataAwsIamOpenidConnectProvider(stack, jsii.String("eks_oidc_provider"),
+					&dataawsiamopenidconnectprovider.DataAwsIamOpenidConnectProviderConfig{Url: issuer})
+			}
+			// The trust policy's condition keys start with the issuer without its scheme
+			host := *cdktf.Fn_Replace(issuer, jsii.String("https://"), jsii.String(""))
+			trust := dataawsiampolicydocument.NewDataAwsIamPolicyDocument(stack, jsii.String("service_account_"+id+"_trust"),
+				&dataawsiampolicydocument.DataAwsIamPolicyDocumentConfig{
+					Statement: []dataawsiampolicydocument.DataAwsIamPolicyDocumentStatement{{
+						Effect:  jsii.String("Allow"),
+						Actions: jsii.Strings("sts:AssumeRoleWithWebIdentity"),
+						Principals: []dataawsiampolicydocument.DataAwsIamPolicyDocumentStatementPrincipals{{
+							Type:        jsii.String("Federated"),
+							Identifiers: &[]*string{provider.Arn()},
+						}},
+						Condition: []dataawsiampolicydocument.DataAwsIamPolicyDocumentStatementCondition{
+							{
+								Test:     jsii.String("StringEquals"),
+								Variable: jsii.String(host + ":sub"),
+								Values:   jsii.Strings(fmt.Sprintf("system:serviceaccount:%s:%s", account.Namespace, account.Name)),
+							},
+							{
+								Test:     jsii.String("StringEquals"),
+								Variable: jsii.String(host + ":aud"),
+								Values:   jsii.Strings("sts.amazonaws.com"),
+							},
+						},
+					}},
+				})
+			role := iamrole.NewIamRole(stack, jsii.String("service_account_"+id+"_role"), &iamrole.IamRoleConfig{
+				Name:             jsii.String(resourceName(config, "aws_iam_role", account.Namespace+"-"+account.Name)),
+				AssumeRolePolicy: trust.Json(),
+			})
+			for i, arn := range account.PolicyARNs {
+				iamrolepolicyattachment.NewIamRolePolicyAttachment(stack, jsii.String(fmt.Sprintf("service_account_%s_policy_%d", id, i)),
+					&iamrolepolicyattachment.IamRolePolicyAttachmentConfig{
+						Role:      role.Name(),
+						PolicyArn: jsii.String(arn),
+					})
+			}
+			roleARN = *role.Arn()
+			roles++
+		}
+
+		metadata := map[string]interface{}{
+			"name":      account.Name,
+			"namespace": namespaceOf(account.Namespace),
+			"labels":    labels,
+		}
+		if roleARN != "" {
+			metadata["annotations"] = map[string]string{"eks.amazonaws.com/role-arn": roleARN}
+		}
+		newRawResource(stack, "kubernetes_service_account_v1", "service_account_"+id, map[string]interface{}{
+			"metadata": metadata,
+		})
+	}
+
+	fmt.Printf("  ✓ Kubernetes on EKS cluster %s: %d namespace(s), %d config map(s), %d service account(s) (%d IRSA role(s))\n",
+		kubernetes.ClusterName, len(kubernetes.Namespaces), len(kubernetes.ConfigMaps), len(kubernetes.ServiceAccounts), roles)
+}
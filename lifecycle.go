@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// LifecycleConfig sets the lifecycle meta-arguments of one resource
+type LifecycleConfig struct {
+	PreventDestroy      bool `json:"prevent_destroy,omitempty"`
+	CreateBeforeDestroy bool `json:"create_before_destroy,omitempty"`
+	// IgnoreChanges lists attributes Terraform leaves alone after create, or is "all"
+	IgnoreChanges interface{} `json:"ignore_changes,omitempty"`
+	// Preconditions are checked before the resource is planned, postconditions after; a false
+	// condition fails the plan or apply. Only postconditions can refer to the resource as self.
+	Preconditions  []Assertion `json:"preconditions,omitempty"`
+	Postconditions []Assertion `json:"postconditions,omitempty"`
+}
+
+// selfReference matches self.<attribute>, which Terraform only allows in postconditions
+var selfReference = regexp.MustCompile(`(^|[^A-Za-z0-9_.])self\.`)
+
+// ignoredAttribute matches an ignore_changes entry: an attribute, optionally followed by a nested
+// attribute, an index or a map key, such as tags["Owner"]
+var ignoredAttribute = regexp.MustCompile(`^[a-z_][a-z0-9_]*(\.[a-z0-9_]+|\[\d+\]|\["[^"]+"\])*$`)
+
+// ignoredAttributes returns the ignore_changes list, nil for "all"
+func (l LifecycleConfig) ignoredAttributes() []string {
+	values, _ := l.IgnoreChanges.([]interface{})
+	attributes := make([]string, 0, len(values))
+	for _, value := range values {
+		attribute, _ := value.(string)
+		attributes = append(attributes, attribute)
+	}
+	return attributes
+}
+
+func (l LifecycleConfig) validate() error {
+	switch ignore := l.IgnoreChanges.(type) {
+	case nil:
+	case string:
+		if ignore != "all" {
+			return fmt.Errorf(`ignore_changes must be a list of attributes or "all"`)
+		}
+	case []interface{}:
+		for _, value := range ignore {
+			if attribute, ok := value.(string); !ok || !ignoredAttribute.MatchString(attribute) {
+				return fmt.Errorf("ignore_changes: %v is not an attribute like tags or tags[\"Owner\"]", value)
+			}
+		}
+	default:
+		return fmt.Errorf(`ignore_changes must be a list of attributes or "all"`)
+	}
+	for _, precondition := range l.Preconditions {
+		if err := precondition.validate(); err != nil {
+			return fmt.Errorf("preconditions: %w", err)
+		}
+		if selfReference.MatchString(precondition.Condition) {
+			return fmt.Errorf("preconditions: %s refers to self, which only postconditions can", precondition.Condition)
+		}
+	}
+	for _, postcondition := range l.Postconditions {
+		if err := postcondition.validate(); err != nil {
+			return fmt.Errorf("postconditions: %w", err)
+		}
+	}
+	if !l.PreventDestroy && !l.CreateBeforeDestroy && l.IgnoreChanges == nil &&
+		len(l.Preconditions) == 0 && len(l.Postconditions) == 0 {
+		return fmt.Errorf("no lifecycle arguments given")
+	}
+	return nil
+}
+
+// appendCondition adds a custom condition to the possibly unset list of a lifecycle
+func appendCondition[T any](conditions *[]*T, condition *T) *[]*T {
+	if conditions == nil {
+		return &[]*T{condition}
+	}
+	appended := append(*conditions, condition)
+	return &appended
+}
+
+// validateLifecycles checks the lifecycle section. Whether each address exists is only known once
+// the stacks are built, so applyLifecycles checks that.
+func validateLifecycles(lifecycles map[string]LifecycleConfig) error {
+	for _, address := range slices.Sorted(maps.Keys(lifecycles)) {
+		if !resourceAddress.MatchString(address) {
+			return fmt.Errorf("%q is not a resource address like aws_s3_bucket.bucket", address)
+		}
+		if err := lifecycles[address].validate(); err != nil {
+			return fmt.Errorf("%s: %w", address, err)
+		}
+	}
+	return nil
+}
+
+// applyLifecycles sets the lifecycle meta-arguments and custom conditions of the resources,
+// keeping any lifecycle settings they already have. An address applies in every stack that has that resource.
+func applyLifecycles(resources map[string][]cdktf.TerraformResource, lifecycles map[string]LifecycleConfig) error {
+	var unknown []string
+	protected, conditions := 0, 0
+	for _, address := range slices.Sorted(maps.Keys(lifecycles)) {
+		if len(resources[address]) == 0 {
+			unknown = append(unknown, address)
+			continue
+		}
+		config := lifecycles[address]
+		if config.PreventDestroy {
+			protected++
+		}
+		conditions += len(config.Preconditions) + len(config.Postconditions)
+		for _, resource := range resources[address] {
+			lifecycle := resource.Lifecycle()
+			if lifecycle == nil {
+				lifecycle = &cdktf.TerraformResourceLifecycle{}
+			}
+			if config.PreventDestroy {
+				lifecycle.PreventDestroy = jsii.Bool(true)
+			}
+			if config.CreateBeforeDestroy {
+				lifecycle.CreateBeforeDestroy = jsii.Bool(true)
+			}
+			switch {
+			case config.IgnoreChanges == "all":
+				lifecycle.IgnoreChanges = "all"
+			case config.IgnoreChanges != nil:
+				lifecycle.IgnoreChanges = config.ignoredAttributes()
+			}
+			for _, precondition := range config.Preconditions {
+				lifecycle.Precondition = appendCondition(lifecycle.Precondition, &cdktf.Precondition{
+					Condition:    jsii.String(precondition.Condition),
+					ErrorMessage: jsii.String(precondition.ErrorMessage),
+				})
+			}
+			for _, postcondition := range config.Postconditions {
+				lifecycle.Postcondition = appendCondition(lifecycle.Postcondition, &cdktf.Postcondition{
+					Condition:    jsii.String(postcondition.Condition),
+					ErrorMessage: jsii.String(postcondition.ErrorMessage),
+				})
+			}
+			resource.SetLifecycle(lifecycle)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("lifecycle: no resource %s in any stack", strings.Join(unknown, ", "))
+	}
+	fmt.Printf("  ✓ Lifecycle settings on %d resource(s), %d protected from destroy, %d custom condition(s)\n",
+		len(lifecycles), protected, conditions)
+	return nil
+}
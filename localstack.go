@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/provider"
+)
+
+// defaultLocalStackEndpoint is where LocalStack listens unless localstack_endpoint says otherwise
+const defaultLocalStackEndpoint = "http://localhost:4566"
+
+// localStackServices are the provider endpoints pointed at LocalStack: every service the config
+// sections create resources in, plus the ones the provider itself calls
+var localStackServices = []string{
+	"amplify", "apprunner", "athena", "batch", "cloudtrail", "cloudwatch", "configservice", "dynamodb",
+	"ec2", "ecr", "globalaccelerator", "glue", "guardduty", "iam", "kafka", "kms", "logs", "opensearch",
+	"redshiftserverless", "s3", "secretsmanager", "sns", "sqs", "ssm", "sts", "transfer", "wafv2",
+}
+
+// endpointField returns the AwsProviderEndpoints field of a service, by its Terraform name
+func endpointField(endpoints reflect.Value, service string) (reflect.Value, bool) {
+	for i := 0; i < endpoints.NumField(); i++ {
+		tag, _, _ := strings.Cut(endpoints.Type().Field(i).Tag.Get("json"), ",")
+		if strings.EqualFold(tag, service) {
+			return endpoints.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func validateEndpoints(endpoints map[string]string) error {
+	fields := reflect.ValueOf(provider.AwsProviderEndpoints{})
+	for service, endpoint := range endpoints {
+		if _, ok := endpointField(fields, service); !ok {
+			return fmt.Errorf("endpoints: %w", invalidValue(service, "use the service names of the aws provider's endpoints block, such as s3 or dynamodb",
+				"%q is not an AWS provider service", service))
+		}
+		if parsed, err := url.Parse(endpoint); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("endpoints: %s endpoint %q must be an http(s) URL", service, endpoint)
+		}
+	}
+	return nil
+}
+
+// providerEndpoints returns the provider's custom endpoints: LocalStack for every service in
+// localStackServices when localstack is set, then the environment's own endpoints
+func providerEndpoints(environment EnvironmentConfig) *provider.AwsProviderEndpoints {
+	services := map[string]string{}
+	if environment.LocalStack {
+		endpoint := environment.LocalStackEndpoint
+		if endpoint == "" {
+			endpoint = defaultLocalStackEndpoint
+		}
+		for _, service := range localStackServices {
+			services[service] = endpoint
+		}
+	}
+	for service, endpoint := range environment.Endpoints {
+		services[service] = endpoint
+	}
+	if len(services) == 0 {
+		return nil
+	}
+
+	endpoints := &provider.AwsProviderEndpoints{}
+	fields := reflect.ValueOf(endpoints).Elem()
+	for service, endpoint := range services {
+		if field, ok := endpointField(fields, service); ok {
+			field.Set(reflect.ValueOf(jsii.String(endpoint)))
+		}
+	}
+	return endpoints
+}
+
+// useLocalStack configures a provider for LocalStack, which accepts any credentials and has no
+// real account or metadata service behind it
+func useLocalStack(providerConfig *provider.AwsProviderConfig) {
+	providerConfig.AccessKey = jsii.String("test")
+	providerConfig.SecretKey = jsii.String("test")
+	providerConfig.S3UsePathStyle = jsii.Bool(true)
+	providerConfig.SkipCredentialsValidation = jsii.Bool(true)
+	providerConfig.SkipMetadataApiCheck = jsii.String("true")
+	providerConfig.SkipRequestingAccountId = jsii.Bool(true)
+}
+
+// usesLocalStack reports whether the environment being synthesized runs against LocalStack. Its
+// state is kept locally, as the configured backend is real AWS.
+func usesLocalStack(config Config) bool {
+	return config.Environments[config.Environment].LocalStack
+}
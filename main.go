@@ -1,12 +1,18 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/aws/jsii-runtime-go"
-	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/provider"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/redshiftserverlessworkgroup"
 	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
 	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucketversioning"
 	"github.com/hashicorp/terraform-cdk-go/cdktf"
@@ -14,61 +20,252 @@ import (
 
 // Config represents what the developer writes
 type Config struct {
-	Project     string        `json:"project"`
-	Environment string        `json:"environment"`
-	Region      string        `json:"region"`
-	Storage     StorageConfig `json:"storage"`
+	Project           string                   `json:"project"`
+	Environment       string                   `json:"environment"`
+	Region            string                   `json:"region"`
+	Cloud             string                   `json:"cloud,omitempty"` // aws (default) or azure
+	Azure             *AzureConfig             `json:"azure,omitempty"`
+	TerraformVersion  string                   `json:"terraform_version,omitempty"`
+	ProviderVersions  map[string]string        `json:"provider_versions,omitempty"`
+	AWSProvider       *AWSProviderSettings     `json:"aws_provider,omitempty"`
+	DefaultTags       map[string]string        `json:"default_tags,omitempty"`
+	Naming            *NamingConfig            `json:"naming,omitempty"`
+	ComplianceProfile string                   `json:"compliance_profile,omitempty"`
+	Cost              *CostConfig              `json:"cost,omitempty"`
+	Scan              *ScanConfig              `json:"scan,omitempty"`
+	Audit             *AuditConfig             `json:"audit,omitempty"`
+	Notifications     *NotificationsConfig     `json:"notifications,omitempty"`
+	ServiceCatalog    *ServiceCatalogConfig    `json:"service_catalog,omitempty"`
+	Telemetry         *TelemetryConfig         `json:"telemetry,omitempty"`
+	Outputs           *OutputsConfig           `json:"outputs,omitempty"`
+	Storage           StorageConfig            `json:"storage"`
+	Batch             *BatchConfig             `json:"batch,omitempty"`
+	Glue              *GlueConfig              `json:"glue,omitempty"`
+	Athena            *AthenaConfig            `json:"athena,omitempty"`
+	Warehouse         *WarehouseConfig         `json:"warehouse,omitempty"`
+	OpenSearch        *OpenSearchConfig        `json:"opensearch,omitempty"`
+	Kafka             *KafkaConfig             `json:"kafka,omitempty"`
+	Neptune           *NeptuneConfig           `json:"neptune,omitempty"`
+	DocumentDB        *DocumentDBConfig        `json:"documentdb,omitempty"`
+	Timestream        *TimestreamConfig        `json:"timestream,omitempty"`
+	MemoryDB          *MemoryDBConfig          `json:"memorydb,omitempty"`
+	IoT               *IoTConfig               `json:"iot,omitempty"`
+	SageMaker         *SageMakerConfig         `json:"sagemaker,omitempty"`
+	Bedrock           *BedrockConfig           `json:"bedrock,omitempty"`
+	Pipeline          *PipelineConfig          `json:"pipeline,omitempty"`
+	CodeDeploy        *CodeDeployConfig        `json:"codedeploy,omitempty"`
+	SSMManagement     *SSMManagementConfig     `json:"ssm_management,omitempty"`
+	SFTP              *SFTPConfig              `json:"sftp,omitempty"`
+	AppRunner         *AppRunnerConfig         `json:"apprunner,omitempty"`
+	Amplify           *AmplifyConfig           `json:"amplify,omitempty"`
+	GlobalAccelerator *GlobalAcceleratorConfig `json:"global_accelerator,omitempty"`
+	WAF               *WAFConfig               `json:"waf,omitempty"`
+	SecurityBaseline  *SecurityBaselineConfig  `json:"security_baseline,omitempty"`
+	Compliance        *ComplianceConfig        `json:"compliance,omitempty"`
+	CloudTrail        *CloudTrailConfig        `json:"cloudtrail,omitempty"`
+	Budgets           *BudgetsConfig           `json:"budgets,omitempty"`
+	Backup            *BackupConfig            `json:"backup,omitempty"`
+	Accounts          *AccountsConfig          `json:"accounts,omitempty"`
+	AppConfig         *AppConfigConfig         `json:"appconfig,omitempty"`
+	Cloudflare        *CloudflareConfig        `json:"cloudflare,omitempty"`
+	Monitoring        *MonitoringConfig        `json:"monitoring,omitempty"`
+	AMP               *AMPConfig               `json:"amp,omitempty"`
+	AMG               *AMGConfig               `json:"amg,omitempty"`
+	DMS               *DMSConfig               `json:"dms,omitempty"`
+	Kubernetes        *KubernetesConfig        `json:"kubernetes,omitempty"`
+	Helm              *HelmConfig              `json:"helm,omitempty"`
+	Auth0             *Auth0Config             `json:"auth0,omitempty"`
+	Atlas             *AtlasConfig             `json:"atlas,omitempty"`
+	Fastly            *FastlyConfig            `json:"fastly,omitempty"`
+	Vault             *VaultConfig             `json:"vault,omitempty"`
+	Utilities         *UtilitiesConfig         `json:"utilities,omitempty"`
+	// Keyspaces are Amazon Keyspaces (Cassandra) keyspaces by name
+	Keyspaces map[string]KeyspaceConfig `json:"keyspaces,omitempty"`
+	// CloudControl creates resource types the config doesn't model, by name, through the awscc provider
+	CloudControl   map[string]CloudControlResource `json:"cloudcontrol,omitempty"`
+	Plugins        map[string]json.RawMessage      `json:"plugins,omitempty"` // sections of providers added with RegisterProvider
+	GitHub         *GitHubConfig                   `json:"github,omitempty"`
+	PagerDuty      *PagerDutyConfig                `json:"pagerduty,omitempty"`
+	Modules        []ModuleConfig                  `json:"modules,omitempty"`
+	Backend        *BackendConfig                  `json:"backend,omitempty"`
+	Stacks         []StackConfig                   `json:"stacks,omitempty"`
+	Workspaces     bool                            `json:"workspaces,omitempty"`
+	ResourceGroups bool                            `json:"resource_groups,omitempty"` // a resource group per stack, found by a Stack tag
+	Tracing        bool                            `json:"tracing,omitempty"`         // X-Ray tracing of the App Runner services and Batch jobs
+	Environments   map[string]EnvironmentConfig    `json:"environments,omitempty"`
+	RemoteState    []RemoteStateConfig             `json:"remote_state,omitempty"`
+	Variables      map[string]VariableConfig       `json:"variables,omitempty"`
+	Locals         map[string]interface{}          `json:"locals,omitempty"`
+	// Overrides sets raw attributes by resource address, e.g. {"aws_s3_bucket.bucket": {"object_lock_enabled": true}}
+	Overrides map[string]map[string]interface{} `json:"overrides,omitempty"`
+	// Imports adopts existing resources, resource address -> ID, e.g. {"aws_s3_bucket.bucket": "my-app-dev-my-app-data"}
+	Imports map[string]string `json:"imports,omitempty"`
+	// Lifecycle sets lifecycle meta-arguments by resource address, e.g. {"aws_s3_bucket.bucket": {"prevent_destroy": true}}
+	Lifecycle map[string]LifecycleConfig `json:"lifecycle,omitempty"`
+	// Checks are assertions Terraform verifies on every plan and apply
+	Checks []CheckConfig `json:"checks,omitempty"`
+	// BuildTags stamps every taggable resource with the git commit and config it was built from
+	BuildTags *BuildTagsConfig `json:"build_tags,omitempty"`
+	// Moved names a mapping file of renamed resource addresses, relative to the config file
+	Moved string `json:"moved,omitempty"`
+
+	// moves is the content of the Moved file, read by loadConfig
+	moves map[string]string
+	// configHash identifies the config file's content, for the ConfigHash build tag
+	configHash string
 }
 
 type StorageConfig struct {
-	BucketName        string `json:"bucket_name"`
-	EnableVersioning  bool   `json:"enable_versioning"`
+	BucketName       string `json:"bucket_name"`
+	EnableVersioning bool   `json:"enable_versioning"`
 }
 
-func main() {
-	// Step 1: Read the JSON config file
-	fmt.Println("📄 Reading config.json...")
-	configFile, err := os.ReadFile("config.json")
-	if err != nil {
-		fmt.Printf("Error reading config.json: %v\n", err)
-		os.Exit(1)
-	}
+// VPCConfig places a resource into existing subnets and security groups
+type VPCConfig struct {
+	SubnetIDs        []string `json:"subnet_ids"`
+	SecurityGroupIDs []string `json:"security_group_ids"`
+}
 
-	// Step 2: Parse the JSON into our struct
-	var config Config
-	err = json.Unmarshal(configFile, &config)
-	if err != nil {
-		fmt.Printf("Error parsing config.json: %v\n", err)
-		os.Exit(1)
+// toStringMap converts a config map into the pointer map the provider bindings expect
+func toStringMap(values map[string]string) *map[string]*string {
+	converted := map[string]*string{}
+	for key, value := range values {
+		converted[key] = jsii.String(value)
 	}
+	return &converted
+}
 
-	fmt.Printf("✓ Config loaded for project: %s (environment: %s)\n\n",
-		config.Project, config.Environment)
+// reservedTags are set on every resource by the platform and can't be replaced from default_tags
+var reservedTags = []string{"Project", "Environment", "ManagedBy"}
 
-	// Step 3: Create CDKTF app
-	fmt.Println("🏗️  Creating infrastructure from config...")
-	app := cdktf.NewApp(nil)
+// defaultTags returns the tags the AWS provider applies to every resource it creates
+func defaultTags(config Config) *map[string]*string {
+	tags := toStringMap(config.DefaultTags)
+	(*tags)["Project"] = jsii.String(config.Project)
+	(*tags)["Environment"] = jsii.String(config.Environment)
+	(*tags)["ManagedBy"] = jsii.String("CDKTF-JSON-Platform")
+	return tags
+}
 
-	// Step 4: Create a stack
-	stackName := fmt.Sprintf("%s-%s-stack", config.Project, config.Environment)
-	stack := cdktf.NewTerraformStack(app, jsii.String(stackName))
+// synth writes the app's stacks, turning the panic raised for error annotations into an error
+func synth(app cdktf.App) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	app.Synth()
+	return nil
+}
 
-	// Step 5: Add AWS provider (from config)
-	provider.NewAwsProvider(stack, jsii.String("aws"), &provider.AwsProviderConfig{
-		Region: jsii.String(config.Region),
+// loadConfig reads and validates a config file. CDKTF_ENVIRONMENT, when set, replaces the
+// environment so one config can describe every environment. for_each entries are expanded, blocks
+// with a false when condition are left out, and ${remote.<name>.<output>} values are turned into
+// reads of the named remote state. ${var.<name>} and ${local.<name>} must be declared.
+func loadConfig(path string) (Config, error) {
+	var config Config
+	configFile, err := os.ReadFile(path)
+	if err != nil {
+		return config, newConfigError("reading", path, nil, err)
+	}
+	sum := sha256.Sum256(configFile)
+	// Conditions only see these, so they are read before the rest of the config
+	var variables struct {
+		Project     string `json:"project"`
+		Environment string `json:"environment"`
+		Region      string `json:"region"`
+	}
+	if err := json.Unmarshal(configFile, &variables); err != nil {
+		return config, newConfigError("parsing", path, configFile, err)
+	}
+	environment := variables.Environment
+	if override := os.Getenv("CDKTF_ENVIRONMENT"); override != "" {
+		environment = override
+	}
+
+	// Expand for_each entries, drop the blocks whose when condition doesn't hold, then parse the
+	// config that is left
+	if configFile, err = expandForEach(configFile); err != nil {
+		return config, newValidationError(path, err)
+	}
+	configFile, err = applyConditions(configFile, map[string]string{
+		"environment": environment,
+		"project":     variables.Project,
+		"region":      variables.Region,
 	})
+	if err != nil {
+		return config, newValidationError(path, err)
+	}
+	configFile, remoteStates := expandRemoteReferences(configFile)
+	parse := startSpan("config.parse")
+	err = json.Unmarshal(configFile, &config)
+	parse.finish(err)
+	if err != nil {
+		// Offsets are into the expanded config, so only the first parse can locate syntax errors
+		return config, newConfigError("parsing", path, nil, err)
+	}
+	config.Environment = environment
+	config.configHash = hex.EncodeToString(sum[:])[:12]
+	validation := startSpan("config.validate")
+	err = validateConfig(config)
+	if err == nil {
+		if err = validateRemoteStates(config.RemoteState, remoteStates); err != nil {
+			err = fmt.Errorf("remote_state: %w", err)
+		}
+	}
+	if err == nil {
+		if err = validateVariables(config, configFile); err != nil {
+			err = fmt.Errorf("variables: %w", err)
+		}
+	}
+	validation.finish(err)
+	if err != nil {
+		return config, newValidationError(path, err)
+	}
+	if config.Moved != "" {
+		if config.moves, err = loadMoves(filepath.Join(filepath.Dir(path), config.Moved)); err != nil {
+			return config, err
+		}
+	}
+	if config.Auth0 != nil {
+		if err := loadAuth0Rules(config.Auth0, filepath.Dir(path)); err != nil {
+			return config, err
+		}
+	}
+	if config.Fastly != nil {
+		if err := loadFastlySnippets(config.Fastly, filepath.Dir(path)); err != nil {
+			return config, err
+		}
+	}
+	if config.Helm != nil {
+		if err := loadHelmValues(config.Helm, filepath.Dir(path)); err != nil {
+			return config, err
+		}
+	}
+	if config.AppConfig != nil {
+		if err := loadAppConfigProfiles(config.AppConfig, filepath.Dir(path)); err != nil {
+			return config, err
+		}
+	}
+	if config.AMP != nil {
+		if err := loadAMPFiles(config.AMP, filepath.Dir(path)); err != nil {
+			return config, err
+		}
+	}
+	if len(config.Modules) > 0 {
+		if err := resolveModuleSources(config.Modules, filepath.Dir(path)); err != nil {
+			return config, err
+		}
+	}
+	return config, nil
+}
 
-	// Step 6: Create S3 bucket based on config
-	fullBucketName := fmt.Sprintf("%s-%s-%s",
-		config.Project, config.Environment, config.Storage.BucketName)
+// addStorage creates the S3 bucket of the storage section and its outputs
+func addStorage(stack cdktf.TerraformStack, config Config) s3bucket.S3Bucket {
+	fullBucketName := resourceName(config, "aws_s3_bucket", config.Storage.BucketName)
 
 	bucket := s3bucket.NewS3Bucket(stack, jsii.String("bucket"), &s3bucket.S3BucketConfig{
 		Bucket: jsii.String(fullBucketName),
-		Tags: &map[string]*string{
-			"Project":     jsii.String(config.Project),
-			"Environment": jsii.String(config.Environment),
-			"ManagedBy":   jsii.String("CDKTF-JSON-Platform"),
-		},
 	})
 
 	// Step 7: Add versioning if requested
@@ -85,7 +282,6 @@ func main() {
 		fmt.Println("  ✓ S3 Bucket (no versioning)")
 	}
 
-	// Step 8: Add outputs
 	cdktf.NewTerraformOutput(stack, jsii.String("bucket_name"), &cdktf.TerraformOutputConfig{
 		Value:       bucket.Bucket(),
 		Description: jsii.String("The name of the created S3 bucket"),
@@ -95,14 +291,579 @@ func main() {
 		Value:       bucket.Arn(),
 		Description: jsii.String("The ARN of the created S3 bucket"),
 	})
+	return bucket
+}
+
+// buildStacks creates every stack of the config in app and returns them, along with the name of
+// the backend bootstrap stack when one was requested
+func buildStacks(app cdktf.App, config Config) (*stackSet, string, error) {
+	// Step 4: Create stacks on demand; each section lands in the stack the config assigns it to
+	stacks := newStackSet(app, config)
+
+	// Step 5: Create the state bucket and lock table stack if requested
+	bootstrapStackName := ""
+	if config.Backend != nil && config.Backend.Bootstrap && !usesLocalStack(config) {
+		span := startSpan("build backend_bootstrap")
+		bootstrapStackName = addBackendBootstrap(app, config)
+		span.finish(nil)
+	}
+
+	// Step 6: Create the storage. On Azure none of the sections below that use the bucket are
+	// allowed.
+	span := startSpan("build storage")
+	var bucket s3bucket.S3Bucket
+	if usesAzure(config) {
+		addAzureStorage(stacks.forSection("storage"), config)
+	} else {
+		bucket = addStorage(stacks.forSection("storage"), config)
+	}
+	span.finish(nil)
+
+	// Step 8: Add optional sections
+	if config.Batch != nil {
+		span = startSpan("build batch")
+		addBatch(stacks.forSection("batch"), config)
+		span.finish(nil)
+	}
+	if config.Glue != nil {
+		span = startSpan("build glue")
+		addGlue(stacks.forSection("glue"), config, bucket)
+		span.finish(nil)
+	}
+	if config.Athena != nil {
+		span = startSpan("build athena")
+		addAthena(stacks.forSection("athena"), config, bucket)
+		span.finish(nil)
+	}
+	var workgroup redshiftserverlessworkgroup.RedshiftserverlessWorkgroup
+	if config.Warehouse != nil {
+		span = startSpan("build warehouse")
+		workgroup = addWarehouse(stacks.forSection("warehouse"), config)
+		span.finish(nil)
+	}
+	if config.OpenSearch != nil {
+		span = startSpan("build opensearch")
+		addOpenSearch(stacks.forSection("opensearch"), config)
+		span.finish(nil)
+	}
+	if config.Kafka != nil {
+		span = startSpan("build kafka")
+		addKafka(stacks.forSection("kafka"), config)
+		span.finish(nil)
+	}
+	if config.Neptune != nil {
+		span = startSpan("build neptune")
+		addNeptune(stacks.forSection("neptune"), config)
+		span.finish(nil)
+	}
+	if config.DocumentDB != nil {
+		span = startSpan("build documentdb")
+		addDocumentDB(stacks.forSection("documentdb"), config)
+		span.finish(nil)
+	}
+	if config.Timestream != nil {
+		span = startSpan("build timestream")
+		addTimestream(stacks.forSection("timestream"), config, bucket)
+		span.finish(nil)
+	}
+	if len(config.Keyspaces) > 0 {
+		span = startSpan("build keyspaces")
+		addKeyspaces(stacks.forSection("keyspaces"), config)
+		span.finish(nil)
+	}
+	if config.MemoryDB != nil {
+		span = startSpan("build memorydb")
+		addMemoryDB(stacks.forSection("memorydb"), config)
+		span.finish(nil)
+	}
+	if config.IoT != nil {
+		span = startSpan("build iot")
+		addIoT(stacks.forSection("iot"), config, bucket)
+		span.finish(nil)
+	}
+	if config.SageMaker != nil {
+		span = startSpan("build sagemaker")
+		addSageMaker(stacks.forSection("sagemaker"), config, bucket)
+		span.finish(nil)
+	}
+	if config.Bedrock != nil {
+		span = startSpan("build bedrock")
+		addBedrock(stacks.forSection("bedrock"), config)
+		span.finish(nil)
+	}
+	if config.Pipeline != nil {
+		span = startSpan("build pipeline")
+		addPipeline(stacks.forSection("pipeline"), config, bucket)
+		span.finish(nil)
+	}
+	if config.CodeDeploy != nil {
+		span = startSpan("build codedeploy")
+		addCodeDeploy(stacks.forSection("codedeploy"), config)
+		span.finish(nil)
+	}
+	if config.SSMManagement != nil {
+		span = startSpan("build ssm_management")
+		addSSMManagement(stacks.forSection("ssm_management"), config)
+		span.finish(nil)
+	}
+	if config.SFTP != nil {
+		span = startSpan("build sftp")
+		addSFTP(stacks.forSection("sftp"), config, bucket)
+		span.finish(nil)
+	}
+	if config.AppRunner != nil {
+		span = startSpan("build apprunner")
+		addAppRunner(stacks.forSection("apprunner"), config)
+		span.finish(nil)
+	}
+	if config.Amplify != nil {
+		span = startSpan("build amplify")
+		addAmplify(stacks.forSection("amplify"), config)
+		span.finish(nil)
+	}
+	if config.GlobalAccelerator != nil {
+		span = startSpan("build global_accelerator")
+		addGlobalAccelerator(stacks.forSection("global_accelerator"), config)
+		span.finish(nil)
+	}
+	if config.WAF != nil {
+		span = startSpan("build waf")
+		addWAF(stacks.forSection("waf"), config)
+		span.finish(nil)
+	}
+	if config.SecurityBaseline != nil {
+		span = startSpan("build security_baseline")
+		addSecurityBaseline(stacks.forSection("security_baseline"), config)
+		span.finish(nil)
+	}
+	if config.Compliance != nil {
+		span = startSpan("build compliance")
+		addCompliance(stacks.forSection("compliance"), config)
+		span.finish(nil)
+	}
+	if config.CloudTrail != nil {
+		span = startSpan("build cloudtrail")
+		addCloudTrail(stacks.forSection("cloudtrail"), config, bucket)
+		span.finish(nil)
+	}
+	if config.Budgets != nil {
+		span = startSpan("build budgets")
+		addBudgets(stacks.forSection("budgets"), config)
+		span.finish(nil)
+	}
+	if config.Backup != nil {
+		span = startSpan("build backup")
+		addBackup(stacks.forSection("backup"), config)
+		span.finish(nil)
+	}
+	if config.Accounts != nil {
+		span = startSpan("build accounts")
+		addAccounts(stacks.forSection("accounts"), config)
+		span.finish(nil)
+	}
+	if config.AppConfig != nil {
+		span = startSpan("build appconfig")
+		addAppConfig(stacks.forSection("appconfig"), config)
+		span.finish(nil)
+	}
+	if config.AMP != nil {
+		span = startSpan("build amp")
+		addAMP(stacks.forSection("amp"), config)
+		span.finish(nil)
+	}
+	if config.AMG != nil {
+		span = startSpan("build amg")
+		addAMG(stacks.forSection("amg"), config)
+		span.finish(nil)
+	}
+	if config.DMS != nil {
+		span = startSpan("build dms")
+		addDMS(stacks.forSection("dms"), config, bucket, workgroup)
+		span.finish(nil)
+	}
+	if config.Cloudflare != nil {
+		span = startSpan("build cloudflare")
+		addCloudflare(stacks.forSection("cloudflare"), config)
+		span.finish(nil)
+	}
+	if config.Kubernetes != nil {
+		span = startSpan("build kubernetes")
+		addKubernetes(stacks.forSection("kubernetes"), config)
+		span.finish(nil)
+	}
+	if config.Helm != nil {
+		span = startSpan("build helm")
+		addHelm(stacks.forSection("helm"), config)
+		span.finish(nil)
+	}
+	if config.Auth0 != nil {
+		span = startSpan("build auth0")
+		addAuth0(stacks.forSection("auth0"), config)
+		span.finish(nil)
+	}
+	if len(config.Modules) > 0 {
+		span = startSpan("build modules")
+		err := addModules(app, stacks.forSection("modules"), config)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	// Allow the NAT IPs read from the stack that has them
+	if config.Atlas != nil {
+		span = startSpan("build atlas")
+		err := addAtlas(app, stacks.forSection("atlas"), config)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if config.Fastly != nil {
+		span = startSpan("build fastly")
+		err := addFastly(app, stacks.forSection("fastly"), config, bucket)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	// Hand out credentials for IAM roles in any stack
+	if config.Vault != nil {
+		span = startSpan("build vault")
+		err := addVault(app, stacks.forSection("vault"), config)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	// Declare the utilities once the resources they depend on exist
+	if config.Utilities != nil {
+		span = startSpan("build utilities")
+		err := addUtilities(app, stacks.forSection("utilities"), config)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	// Like utilities, Cloud Control resources can depend on any other resource of their stack
+	if len(config.CloudControl) > 0 {
+		span = startSpan("build cloudcontrol")
+		err := addCloudControl(app, stacks.forSection("cloudcontrol"), config)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	// Build the sections of registered providers, which can refer to any built-in one
+	if len(config.Plugins) > 0 {
+		span = startSpan("build plugins")
+		err := addPlugins(stacks, config)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	// Set up the deploying repository once the outputs its secrets read exist
+	if config.GitHub != nil {
+		span = startSpan("build github")
+		err := addGitHub(app, stacks.forSection("github"), config)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	// Page on-call from every stack, once all of them exist
+	if config.PagerDuty != nil {
+		span = startSpan("build pagerduty")
+		err := addPagerDuty(stacks, config)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if config.Tracing {
+		span = startSpan("build tracing")
+		addSamplingRule(stacks.forSection(tracingSection(config)), config)
+		span.finish(nil)
+	}
+	if config.ResourceGroups {
+		span = startSpan("build resource_groups")
+		addResourceGroups(stacks, config)
+		span.finish(nil)
+	}
+	stacks.addDependencies()
+
+	// Index the resources once for the passes that address them; none of those passes adds any,
+	// and each index walks every construct through jsii
+	var resources map[string][]cdktf.TerraformResource
+	if len(config.Overrides) > 0 || len(config.Lifecycle) > 0 || len(config.Imports) > 0 || len(config.moves) > 0 {
+		span = startSpan("build resource_index")
+		resources = resourcesByAddress(app)
+		span.finish(nil)
+	}
+
+	// Apply raw overrides once every resource exists
+	if len(config.Overrides) > 0 {
+		span = startSpan("build overrides")
+		err := applyOverrides(resources, config.Overrides)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if len(config.Lifecycle) > 0 {
+		span = startSpan("build lifecycle")
+		err := applyLifecycles(resources, config.Lifecycle)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Adopt existing resources on the next apply
+	if len(config.Imports) > 0 {
+		span = startSpan("build imports")
+		err := addImports(resources, config.Imports)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Keep the state of renamed resources
+	if len(config.moves) > 0 {
+		span = startSpan("build moved")
+		err := addMoves(resources, config.moves)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Declare the variables and locals the stacks use
+	if len(config.Variables) > 0 || len(config.Locals) > 0 {
+		span = startSpan("build variables")
+		addVariables(app, config)
+		span.finish(nil)
+	}
+
+	// Read remote state in the stacks that use it
+	if len(config.RemoteState) > 0 {
+		span = startSpan("build remote_state")
+		addRemoteStates(app, config)
+		span.finish(nil)
+	}
+
+	// Verify the declared invariants on every plan and apply
+	if len(config.Checks) > 0 {
+		span = startSpan("build checks")
+		err := addChecks(app, config.Checks)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Watch the created resources from the stacks they are in
+	if config.Monitoring != nil {
+		span = startSpan("build monitoring")
+		err := addMonitoring(app, config)
+		span.finish(err)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Mask credentials before the outputs are published anywhere
+	span = startSpan("build sensitive_outputs")
+	outputs, err := markSensitiveOutputs(app, config)
+	span.finish(err)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Publish the outputs once every stack has them
+	if config.Outputs != nil && config.Outputs.SSMPrefix != "" {
+		span = startSpan("build outputs")
+		addOutputParameters(outputs, config)
+		span.finish(nil)
+	}
+
+	// Check each stack against the compliance profile when it is synthesized
+	if config.ComplianceProfile != "" {
+		for _, child := range *app.Node().Children() {
+			if stack, ok := child.(cdktf.TerraformStack); ok {
+				cdktf.Aspects_Of(stack).Add(&complianceAspect{profile: config.ComplianceProfile})
+			}
+		}
+	}
+	// Tag last, so resources added by remediation are tagged too
+	if config.BuildTags != nil {
+		span = startSpan("build build_tags")
+		addBuildTags(app, config)
+		span.finish(nil)
+	}
+
+	return stacks, bootstrapStackName, nil
+}
+
+func main() {
+	// Subcommands work on an existing cdktf.out; without one the config is synthesized
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		runCommand(os.Args[1], os.Args[2:])
+		return
+	}
+	scan := flag.Bool("scan", false, "scan the synthesized stacks for misconfigurations")
+	failOn := flag.String("fail-on", "", "lowest scan severity that fails the run (default high)")
+	profileDir := flag.String("pprof", "", "write CPU, heap and trace profiles of the synth to this directory")
+	flag.Parse()
+
+	startTracing()
+	err := synthesizeConfig("config.json", *scan, *failOn, *profileDir)
+	stopTracing()
+	if err != nil {
+		reportError(err)
+		os.Exit(1)
+	}
+}
+
+// synthesizeConfig synthesizes a config file into the cdktf output directory, then scans and
+// estimates the stacks when asked to. Config problems are returned as a *ConfigError or
+// *ValidationError, and failures to build or synthesize the stacks as a *SynthError.
+func synthesizeConfig(path string, scan bool, failOn string, profileDir string) (err error) {
+	// Closing the jsii runtime waits for it to exit and flush what it logs, such as the requests
+	// JSII_DEBUG prints
+	defer jsii.Close()
+	root := startSpan("synth", "config.path", path)
+	defer func() { root.finish(err) }()
+
+	stopProfiling := func() error { return nil }
+	if profileDir != "" {
+		stop, err := startProfiling(profileDir)
+		if err != nil {
+			return fmt.Errorf("starting profiles: %w", err)
+		}
+		stopProfiling = stop
+	}
+
+	// Telemetry reads its settings itself, to count configs that fail to validate too
+	var stackNames []string
+	var outdir string
+	started := time.Now()
+	defer func() { recordTelemetry(path, started, outdir, stackNames, err) }()
+
+	// Steps 1-2: Read, parse and validate the JSON config file
+	fmt.Printf("📄 Reading %s...\n", path)
+	load := startSpan("config.load")
+	config, err := loadConfig(path)
+	load.finish(err)
+	if err != nil {
+		return err
+	}
+	root.setAttribute("project", config.Project)
+	root.setAttribute("environment", config.Environment)
+
+	fmt.Printf("✓ Config loaded for project: %s (environment: %s)\n\n",
+		config.Project, config.Environment)
+	if config.Audit != nil {
+		defer func() {
+			entry := newAuditEntry(config, "synth", started, err)
+			entry.Stacks = stackNames
+			recordAudit(config, entry)
+		}()
+	}
+	if config.Notifications != nil {
+		defer func() {
+			event, status := "synth_finished", "synthesized"
+			if err != nil {
+				event, status = "synth_failed", "failed"
+			}
+			var synthesized []notificationStack
+			for _, name := range stackNames {
+				synthesized = append(synthesized, notificationStack{Name: name, Status: status})
+			}
+			notify(config, newNotification(config, event, "", synthesized, err))
+		}()
+	}
+
+	// Step 3: Create CDKTF app
+	fmt.Println("🏗️  Creating infrastructure from config...")
+	app := cdktf.NewApp(nil)
+	outdir = *app.Outdir()
+
+	// Steps 4-8: Build every stack from the config
+	build := startSpan("build")
+	stacks, bootstrapStackName, err := buildStacks(app, config)
+	build.finish(err)
+	if err != nil {
+		return &SynthError{Stage: "build", Err: err}
+	}
 
 	// Step 9: Synthesize to Terraform JSON
 	fmt.Println("\n📝 Synthesizing to Terraform JSON...")
-	app.Synth()
+	synthesis := startSpan("app.synth")
+	err = synth(app)
+	synthesis.finish(err)
+	if err != nil {
+		return &SynthError{Stage: "synth", Err: err,
+			Suggestion: "rerun with JSII_DEBUG=1 to see the jsii call that failed"}
+	}
+	// Profiles cover loading the config through synthesis; the scan and cost steps run tools of
+	// their own
+	if err := stopProfiling(); err != nil {
+		return fmt.Errorf("writing profiles: %w", err)
+	}
+
+	// Step 10: Record the checksum of every stack for the pipeline to verify before it applies
+	stackNames = stacks.names
+	if bootstrapStackName != "" {
+		stackNames = append([]string{bootstrapStackName}, stackNames...)
+	}
+	if err := writeArtifactManifest(*app.Outdir(), config, stackNames); err != nil {
+		return fmt.Errorf("writing %s: %w", artifactManifestFile, err)
+	}
 
 	fmt.Println("✓ Done!")
-	fmt.Printf("\n📁 Generated Terraform in: cdktf.out/stacks/%s/\n", stackName)
-	fmt.Println("\nNext steps:")
-	fmt.Printf("  1. Review: cat cdktf.out/stacks/%s/cdk.tf.json\n", stackName)
-	fmt.Println("  2. Deploy: cd cdktf.out/stacks/" + stackName + " && terraform init && terraform apply")
+
+	if scan || config.Scan != nil {
+		if failOn == "" && config.Scan != nil {
+			failOn = config.Scan.FailOn
+		}
+		if err := validateFailOn(failOn); err != nil {
+			return err
+		}
+		span := startSpan("scan")
+		findings, err := scanStacks(*app.Outdir(), "")
+		if err == nil {
+			err = checkFindings(findings, failOn)
+		}
+		span.finish(err)
+		if err != nil {
+			return fmt.Errorf("scanning: %w", err)
+		}
+	}
+
+	if config.Cost != nil {
+		span := startSpan("cost")
+		err := estimateCosts(config, *app.Outdir(), stackNames)
+		span.finish(err)
+		if err != nil {
+			return fmt.Errorf("estimating cost: %w", err)
+		}
+	}
+	// Step 11: Document each stack's backend, outputs and apply steps next to its Terraform
+	order, err := deployOrder(stacks.dependencies())
+	if err != nil {
+		return fmt.Errorf("ordering stacks: %w", err)
+	}
+	if err := writeStackReadmes(*app.Outdir(), config, order, stacks.dependencies(), bootstrapStackName); err != nil {
+		return fmt.Errorf("writing stack READMEs: %w", err)
+	}
+	fmt.Println("\n📁 Generated Terraform in:")
+	for _, name := range stackNames {
+		fmt.Printf("  %s/stacks/%s/\n", *app.Outdir(), name)
+	}
+	fmt.Println("\nNext steps: read each stack's README.md for its outputs, backend and apply steps, then")
+	fmt.Println("  make deploy (or go run . deploy), which applies " + strings.Join(order, " → "))
+	return nil
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// artifactManifestFile is written next to cdktf's own manifest.json, which describes the stacks
+// for the cdktf CLI and has no checksums
+const artifactManifestFile = "artifact-manifest.json"
+
+// artifactManifest records what a synth generated, so a pipeline can check the stacks it applies
+// are the ones synthesized
+type artifactManifest struct {
+	Project     string          `json:"project"`
+	Environment string          `json:"environment"`
+	ConfigHash  string          `json:"config_hash"`
+	GeneratedAt string          `json:"generated_at"`
+	Tool        manifestTool    `json:"tool"`
+	Stacks      []manifestStack `json:"stacks"`
+}
+
+// manifestTool is the build of this program and the bindings it synthesized with
+type manifestTool struct {
+	GitCommit string `json:"git_commit"`
+	Go        string `json:"go"`
+	// Modules maps the cdktf, jsii and provider binding modules to their versions
+	Modules map[string]string `json:"modules"`
+}
+
+// manifestStack is one synthesized stack, with what it creates and the versions it requires
+type manifestStack struct {
+	Name string `json:"name"`
+	// Path is the stack's cdk.tf.json, relative to the output directory
+	Path             string            `json:"path"`
+	SHA256           string            `json:"sha256"`
+	Resources        int               `json:"resources"`
+	DataSources      int               `json:"data_sources"`
+	Modules          int               `json:"modules"`
+	TerraformVersion string            `json:"terraform_version,omitempty"`
+	ProviderVersions map[string]string `json:"provider_versions"`
+	ResourcesByType  map[string]int    `json:"resources_by_type"`
+}
+
+// toolModules returns the versions of the modules the synthesized JSON depends on, from the
+// build information of this binary
+func toolModules() map[string]string {
+	modules := map[string]string{}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return modules
+	}
+	for _, dependency := range info.Deps {
+		if strings.HasPrefix(dependency.Path, "github.com/hashicorp/terraform-cdk-go") ||
+			strings.HasPrefix(dependency.Path, "github.com/cdktf/") ||
+			strings.HasPrefix(dependency.Path, "github.com/aws/jsii-runtime-go") {
+			modules[dependency.Path] = dependency.Version
+		}
+	}
+	return modules
+}
+
+// checksum returns the hex SHA-256 of a file
+func checksum(path string) (string, []byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), raw, nil
+}
+
+// describeStack reads a synthesized stack into its manifest entry
+func describeStack(outdir string, name string) (manifestStack, error) {
+	path := filepath.Join("stacks", name, "cdk.tf.json")
+	sum, raw, err := checksum(filepath.Join(outdir, path))
+	if err != nil {
+		return manifestStack{}, err
+	}
+	var document struct {
+		Terraform struct {
+			RequiredVersion   string `json:"required_version"`
+			RequiredProviders map[string]struct {
+				Source  string `json:"source"`
+				Version string `json:"version"`
+			} `json:"required_providers"`
+		} `json:"terraform"`
+		Resource map[string]map[string]json.RawMessage `json:"resource"`
+		Data     map[string]map[string]json.RawMessage `json:"data"`
+		Module   map[string]json.RawMessage            `json:"module"`
+	}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return manifestStack{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	stack := manifestStack{
+		Name:             name,
+		Path:             path,
+		SHA256:           sum,
+		Modules:          len(document.Module),
+		TerraformVersion: document.Terraform.RequiredVersion,
+		ProviderVersions: map[string]string{},
+		ResourcesByType:  map[string]int{},
+	}
+	for providerName, provider := range document.Terraform.RequiredProviders {
+		stack.ProviderVersions[providerName] = strings.TrimSpace(provider.Source + " " + provider.Version)
+	}
+	for resourceType, byID := range document.Resource {
+		stack.Resources += len(byID)
+		stack.ResourcesByType[resourceType] = len(byID)
+	}
+	for _, byID := range document.Data {
+		stack.DataSources += len(byID)
+	}
+	return stack, nil
+}
+
+// writeArtifactManifest writes <outdir>/artifact-manifest.json for the named stacks, once the app
+// has been synthesized
+func writeArtifactManifest(outdir string, config Config, stackNames []string) error {
+	manifest := artifactManifest{
+		Project:     config.Project,
+		Environment: config.Environment,
+		ConfigHash:  config.configHash,
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Tool: manifestTool{
+			GitCommit: gitOutput("rev-parse", "HEAD"),
+			Go:        runtime.Version(),
+			Modules:   toolModules(),
+		},
+	}
+	for _, name := range stackNames {
+		stack, err := describeStack(outdir, name)
+		if err != nil {
+			return err
+		}
+		manifest.Stacks = append(manifest.Stacks, stack)
+	}
+
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outdir, artifactManifestFile), buffer.Bytes(), 0o644)
+}
+
+// runVerify is the verify command. It checks every stack in artifact-manifest.json against its
+// recorded checksum, failing when a cdk.tf.json was changed or removed after the synth.
+func runVerify(args []string) error {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	flags.Parse(args)
+
+	raw, err := os.ReadFile(filepath.Join(*outdir, artifactManifestFile))
+	if err != nil {
+		return fmt.Errorf("reading %s (synthesize first): %w", artifactManifestFile, err)
+	}
+	var manifest artifactManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return fmt.Errorf("parsing %s: %w", artifactManifestFile, err)
+	}
+
+	fmt.Printf("🔏 Verifying %d stack(s) against %s...\n", len(manifest.Stacks), artifactManifestFile)
+	modified := 0
+	for _, stack := range manifest.Stacks {
+		sum, _, err := checksum(filepath.Join(*outdir, stack.Path))
+		switch {
+		case err != nil:
+			modified++
+			fmt.Printf("  ✗ %s: %v\n", stack.Name, err)
+		case sum != stack.SHA256:
+			modified++
+			fmt.Printf("  ✗ %s: %s was modified after the synth\n", stack.Name, stack.Path)
+		default:
+			fmt.Printf("  ✓ %s\n", stack.Name)
+		}
+	}
+	if modified > 0 {
+		return fmt.Errorf("%d of %d stack(s) don't match %s", modified, len(manifest.Stacks), artifactManifestFile)
+	}
+	return nil
+}
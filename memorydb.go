@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/memorydbacl"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/memorydbcluster"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/memorydbsubnetgroup"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/memorydbuser"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// MemoryDBConfig describes a MemoryDB cluster, Redis with a durable multi-AZ transaction log, inside
+// the VPC. Connections always use TLS.
+type MemoryDBConfig struct {
+	EngineVersion    string   `json:"engine_version"` // e.g. 7.1
+	NodeType         string   `json:"node_type"`
+	Shards           float64  `json:"shards"`                       // 1 by default
+	ReplicasPerShard *float64 `json:"replicas_per_shard,omitempty"` // 0 to 5, 1 by default
+	// SnapshotRetentionDays is how long daily snapshots are kept, 7 by default; 0 turns them off
+	SnapshotRetentionDays *float64 `json:"snapshot_retention_days,omitempty"`
+	SnapshotWindow        string   `json:"snapshot_window"` // UTC, e.g. 05:00-09:00
+	KMSKeyArn             string   `json:"kms_key_arn"`
+	// Users are the cluster's ACL, by user name. Without users the cluster uses the open-access ACL
+	// and anyone who can reach it can run any command.
+	Users map[string]MemoryDBUser `json:"users"`
+	VPC   VPCConfig               `json:"vpc"`
+}
+
+// MemoryDBUser is a user authenticating with an IAM auth token, so no password is ever stored in
+// the state. The access string is Redis ACL syntax, e.g. "on ~app:* +@read".
+type MemoryDBUser struct {
+	AccessString string `json:"access_string"`
+}
+
+func (m *MemoryDBConfig) replicasPerShard() float64 {
+	if m.ReplicasPerShard != nil {
+		return *m.ReplicasPerShard
+	}
+	return 1
+}
+
+func (m *MemoryDBConfig) snapshotRetention() float64 {
+	if m.SnapshotRetentionDays != nil {
+		return *m.SnapshotRetentionDays
+	}
+	return 7
+}
+
+func (m *MemoryDBConfig) validate() error {
+	if len(m.VPC.SubnetIDs) == 0 {
+		return fmt.Errorf("vpc.subnet_ids is required")
+	}
+	if m.Shards < 0 || m.Shards > 500 {
+		return fmt.Errorf("shards must be between 1 and 500")
+	}
+	if replicas := m.replicasPerShard(); replicas < 0 || replicas > 5 {
+		return fmt.Errorf("replicas_per_shard must be between 0 and 5")
+	}
+	if retention := m.snapshotRetention(); retention < 0 || retention > 35 {
+		return fmt.Errorf("snapshot_retention_days must be between 0 and 35")
+	}
+	for _, name := range slices.Sorted(maps.Keys(m.Users)) {
+		// IAM authentication signs tokens for the user name, which MemoryDB wants in lowercase
+		if !nameSegmentPattern.MatchString(name) || len(name) > 40 {
+			return invalidValue(name, "", "users: name %q must be up to 40 lowercase letters, digits and hyphens", name)
+		}
+		if m.Users[name].AccessString == "" {
+			return fmt.Errorf("users.%s: access_string is required", name)
+		}
+	}
+	return nil
+}
+
+// addMemoryDB creates the subnet group, the users and their ACL, and the cluster
+func addMemoryDB(stack cdktf.TerraformStack, config Config) {
+	memorydb := config.MemoryDB
+
+	engineVersion := memorydb.EngineVersion
+	if engineVersion == "" {
+		engineVersion = "7.1"
+	}
+	nodeType := memorydb.NodeType
+	if nodeType == "" {
+		nodeType = sizeFor(config).MemoryDBNodeType
+	}
+	shards := memorydb.Shards
+	if shards == 0 {
+		shards = 1
+	}
+	replicas := memorydb.replicasPerShard()
+
+	subnetGroup := memorydbsubnetgroup.NewMemorydbSubnetGroup(stack, jsii.String("memorydb_subnet_group"),
+		&memorydbsubnetgroup.MemorydbSubnetGroupConfig{
+			Name:      jsii.String(resourceName(config, "aws_memorydb_subnet_group", "redis")),
+			SubnetIds: jsii.Strings(memorydb.VPC.SubnetIDs...),
+		})
+
+	aclName := jsii.String("open-access")
+	if len(memorydb.Users) > 0 {
+		var userNames []*string
+		for _, name := range slices.Sorted(maps.Keys(memorydb.Users)) {
+			user := memorydbuser.NewMemorydbUser(stack, jsii.String("memorydb_user_"+name), &memorydbuser.MemorydbUserConfig{
+				UserName:     jsii.String(name),
+				AccessString: jsii.String(memorydb.Users[name].AccessString),
+				AuthenticationMode: &memorydbuser.MemorydbUserAuthenticationMode{
+					Type: jsii.String("iam"),
+				},
+			})
+			userNames = append(userNames, user.UserName())
+		}
+		acl := memorydbacl.NewMemorydbAcl(stack, jsii.String("memorydb_acl"), &memorydbacl.MemorydbAclConfig{
+			Name:      jsii.String(resourceName(config, "aws_memorydb_acl", "redis")),
+			UserNames: &userNames,
+		})
+		aclName = acl.Name()
+	}
+
+	clusterName := resourceName(config, "aws_memorydb_cluster", "redis")
+	clusterConfig := &memorydbcluster.MemorydbClusterConfig{
+		Name:                   jsii.String(clusterName),
+		Engine:                 jsii.String("redis"),
+		EngineVersion:          jsii.String(engineVersion),
+		NodeType:               jsii.String(nodeType),
+		NumShards:              jsii.Number(shards),
+		NumReplicasPerShard:    jsii.Number(replicas),
+		AclName:                aclName,
+		SubnetGroupName:        subnetGroup.Name(),
+		SecurityGroupIds:       jsii.Strings(memorydb.VPC.SecurityGroupIDs...),
+		TlsEnabled:             jsii.Bool(true),
+		SnapshotRetentionLimit: jsii.Number(memorydb.snapshotRetention()),
+	}
+	if memorydb.snapshotRetention() > 0 {
+		clusterConfig.FinalSnapshotName = jsii.String(clusterName + "-final")
+	}
+	if memorydb.SnapshotWindow != "" {
+		clusterConfig.SnapshotWindow = jsii.String(memorydb.SnapshotWindow)
+	}
+	if memorydb.KMSKeyArn != "" {
+		clusterConfig.KmsKeyArn = jsii.String(memorydb.KMSKeyArn)
+	}
+	cluster := memorydbcluster.NewMemorydbCluster(stack, jsii.String("memorydb"), clusterConfig)
+
+	endpoint := cluster.ClusterEndpoint().Get(jsii.Number(0))
+	cdktf.NewTerraformOutput(stack, jsii.String("memorydb_endpoint"), &cdktf.TerraformOutputConfig{
+		Value:       jsii.String(fmt.Sprintf("%s:%s", *endpoint.Address(), *cdktf.Token_AsString(endpoint.Port(), nil))),
+		Description: jsii.String("The cluster endpoint Redis clients connect to with TLS"),
+	})
+
+	fmt.Printf("  ✓ MemoryDB cluster (%v shard(s), %v replica(s) each, %s)\n", shards, replicas, nodeType)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// ModuleConfig instantiates an existing Terraform module, from the registry, git or a local path
+type ModuleConfig struct {
+	Name    string                 `json:"name"`
+	Source  string                 `json:"source"`  // e.g. terraform-aws-modules/vpc/aws
+	Version string                 `json:"version"` // registry modules only
+	Inputs  map[string]interface{} `json:"inputs"`
+	// Outputs exposes module outputs as stack outputs, stack output name -> module output name
+	Outputs map[string]string `json:"outputs"`
+
+	// path is where a local source is on disk, resolved by loadConfig against the config file
+	path string
+}
+
+// blockLabel is what Terraform accepts as a block label, such as a module, variable or output name
+var blockLabel = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// isLocalModule reports whether a source is a local path, which Terraform can't version
+func isLocalModule(source string) bool {
+	return strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
+}
+
+func validateModules(modules []ModuleConfig) error {
+	names := map[string]bool{}
+	for _, module := range modules {
+		if !blockLabel.MatchString(module.Name) {
+			return fmt.Errorf("module name %q must be letters, digits, _ or -", module.Name)
+		}
+		if names[module.Name] {
+			return fmt.Errorf("module %s is declared twice", module.Name)
+		}
+		names[module.Name] = true
+		if module.Source == "" {
+			return fmt.Errorf("module %s needs a source", module.Name)
+		}
+		if module.Version != "" && isLocalModule(module.Source) {
+			return fmt.Errorf("module %s: version can't be set for a local source", module.Name)
+		}
+		for output, moduleOutput := range module.Outputs {
+			if !blockLabel.MatchString(output) || moduleOutput == "" {
+				return fmt.Errorf("module %s: output %q needs a valid name and a module output", module.Name, output)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveModuleSources finds the local sources of the modules, relative to the config file in dir
+func resolveModuleSources(modules []ModuleConfig, dir string) error {
+	for i, module := range modules {
+		if !isLocalModule(module.Source) {
+			continue
+		}
+		path, err := filepath.Abs(filepath.Join(dir, module.Source))
+		if err != nil {
+			return fmt.Errorf("module %s: resolving source: %w", module.Name, err)
+		}
+		modules[i].path = path
+	}
+	return nil
+}
+
+// addModules creates one module block per entry and maps the requested module outputs to stack outputs
+func addModules(app cdktf.App, stack cdktf.TerraformStack, config Config) error {
+	stackDir, err := filepath.Abs(filepath.Join(*app.Outdir(), "stacks", *stack.Node().Id()))
+	if err != nil {
+		return fmt.Errorf("modules: %w", err)
+	}
+	for _, module := range config.Modules {
+		moduleConfig := &cdktf.TerraformHclModuleConfig{
+			Source: jsii.String(module.Source),
+		}
+		// Terraform runs in the stack directory, so point local sources at the module in place,
+		// relative to that directory, instead of relying on cdktf copying it as an asset
+		if module.path != "" {
+			source, err := filepath.Rel(stackDir, module.path)
+			if err != nil {
+				return fmt.Errorf("module %s: %w", module.Name, err)
+			}
+			source = filepath.ToSlash(source)
+			if !isLocalModule(source) {
+				source = "./" + source
+			}
+			moduleConfig.Source = jsii.String(source)
+			moduleConfig.SkipAssetCreationFromLocalModules = jsii.Bool(true)
+		}
+		if module.Version != "" {
+			moduleConfig.Version = jsii.String(module.Version)
+		}
+		if len(module.Inputs) > 0 {
+			inputs := module.Inputs
+			moduleConfig.Variables = &inputs
+		}
+		hclModule := cdktf.NewTerraformHclModule(stack, jsii.String(module.Name), moduleConfig)
+
+		// Sorted so the outputs synthesize in the same order every run
+		outputs := make([]string, 0, len(module.Outputs))
+		for output := range module.Outputs {
+			outputs = append(outputs, output)
+		}
+		sort.Strings(outputs)
+		for _, output := range outputs {
+			moduleOutput := module.Outputs[output]
+			cdktf.NewTerraformOutput(stack, jsii.String(output), &cdktf.TerraformOutputConfig{
+				Value:       hclModule.Get(jsii.String(moduleOutput)),
+				Description: jsii.String(fmt.Sprintf("Output %s of module %s", moduleOutput, module.Name)),
+			})
+		}
+	}
+	fmt.Printf("  ✓ %d Terraform module(s)\n", len(config.Modules))
+	return nil
+}
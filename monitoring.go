@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// MonitoringConfig provisions observability alongside the infrastructure it watches
+type MonitoringConfig struct {
+	Datadog *DatadogConfig `json:"datadog"`
+	Grafana *GrafanaConfig `json:"grafana"`
+}
+
+// DatadogConfig creates Datadog monitors and a dashboard per service. The API and application
+// keys come from DD_API_KEY and DD_APP_KEY.
+type DatadogConfig struct {
+	APIURL   string           `json:"api_url"` // e.g. https://api.datadoghq.eu/ outside US1
+	Notify   []string         `json:"notify"`  // mentioned in every monitor message, e.g. @slack-data-alerts
+	Monitors []DatadogMonitor `json:"monitors"`
+}
+
+// DatadogMonitor watches one created resource. It is placed in the stack of that resource, so its
+// query and thresholds can use ${...} references to it, such as its name or size.
+type DatadogMonitor struct {
+	Name     string `json:"name"`
+	Resource string `json:"resource"` // address of the watched resource, e.g. aws_msk_cluster.kafka
+	Service  string `json:"service"`  // the dashboard the monitor is on; defaults to the project
+	Type     string `json:"type"`     // metric alert (default), query alert, log alert or service check
+	// Query is the monitor query; {critical} and {warning} are replaced by the thresholds
+	Query string `json:"query"`
+	// Critical and Warning are numbers or ${...} expressions, e.g. "${aws_opensearch_domain.search.ebs_options[0].volume_size * 256}"
+	Critical interface{} `json:"critical"`
+	Warning  interface{} `json:"warning"`
+	Message  string      `json:"message"`
+	Notify   []string    `json:"notify"`   // in addition to datadog.notify
+	Priority int         `json:"priority"` // 1 (highest) to 5
+}
+
+// defaultDatadogVersion is the datadog provider constraint unless provider_versions sets one
+const defaultDatadogVersion = "~> 3.0"
+
+var (
+	datadogMonitorTypes = []string{"metric alert", "query alert", "log alert", "service check"}
+	servicePattern      = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+	expressionPattern   = regexp.MustCompile(`^\$\{.+\}$`)
+)
+
+// threshold returns a threshold as the string the provider takes, or false if it is neither a
+// number nor a ${...} expression
+func threshold(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case string:
+		return value, expressionPattern.MatchString(value)
+	}
+	return "", false
+}
+
+func (m *DatadogMonitor) validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !resourceAddress.MatchString(m.Resource) {
+		return fmt.Errorf("%s: resource %q is not a resource address like aws_msk_cluster.kafka", m.Name, m.Resource)
+	}
+	if m.Service != "" && !servicePattern.MatchString(m.Service) {
+		return fmt.Errorf("%s: service %q may only use lowercase letters, digits and _.-", m.Name, m.Service)
+	}
+	if m.Type != "" && !slices.Contains(datadogMonitorTypes, m.Type) {
+		return fmt.Errorf("%s: type %q must be one of %s", m.Name, m.Type, strings.Join(datadogMonitorTypes, ", "))
+	}
+	if m.Query == "" {
+		return fmt.Errorf("%s: query is required", m.Name)
+	}
+	if _, ok := threshold(m.Critical); !ok {
+		return fmt.Errorf("%s: critical must be a number or a ${...} expression", m.Name)
+	}
+	if _, ok := threshold(m.Warning); m.Warning != nil && !ok {
+		return fmt.Errorf("%s: warning must be a number or a ${...} expression", m.Name)
+	}
+	if strings.Contains(m.Query, "{warning}") && m.Warning == nil {
+		return fmt.Errorf("%s: query uses {warning} but warning is not set", m.Name)
+	}
+	if m.Priority < 0 || m.Priority > 5 {
+		return fmt.Errorf("%s: priority must be 1 to 5", m.Name)
+	}
+	return validateHandles(m.Notify)
+}
+
+func validateHandles(handles []string) error {
+	for _, handle := range handles {
+		if !strings.HasPrefix(handle, "@") || strings.ContainsAny(handle, " \t\n") {
+			return fmt.Errorf("notify handle %q must look like @slack-channel or @team@example.com", handle)
+		}
+	}
+	return nil
+}
+
+func (d *DatadogConfig) validate() error {
+	if d.APIURL != "" && !strings.HasPrefix(d.APIURL, "https://") {
+		return fmt.Errorf("api_url %q must be an https URL", d.APIURL)
+	}
+	if len(d.Monitors) == 0 {
+		return fmt.Errorf("at least one monitor is required")
+	}
+	if err := validateHandles(d.Notify); err != nil {
+		return err
+	}
+	ids := map[string]string{}
+	for i, monitor := range d.Monitors {
+		if err := monitor.validate(); err != nil {
+			return fmt.Errorf("monitors[%d]: %w", i, err)
+		}
+		if other, ok := ids[monitor.id()]; ok {
+			return fmt.Errorf("monitors[%d]: %s has the same logical id as %s", i, monitor.Name, other)
+		}
+		ids[monitor.id()] = monitor.Name
+	}
+	return nil
+}
+
+func (m *MonitoringConfig) validate() error {
+	if m.Datadog == nil && m.Grafana == nil {
+		return fmt.Errorf("datadog or grafana is required")
+	}
+	if m.Datadog != nil {
+		if err := m.Datadog.validate(); err != nil {
+			return fmt.Errorf("datadog: %w", err)
+		}
+	}
+	if m.Grafana != nil {
+		if err := m.Grafana.validate(); err != nil {
+			return fmt.Errorf("grafana: %w", err)
+		}
+	}
+	return nil
+}
+
+// id is the monitor's logical id, e.g. monitor_kafka_disk_used for "Kafka disk used"
+func (m *DatadogMonitor) id() string {
+	return "monitor_" + strings.Trim(logicalIDInvalid.ReplaceAllString(strings.ToLower(m.Name), "_"), "_")
+}
+
+func (m *DatadogMonitor) service(config Config) string {
+	if m.Service == "" {
+		return config.Project
+	}
+	return m.Service
+}
+
+// attributes returns the datadog_monitor attributes of a monitor
+func (m *DatadogMonitor) attributes(config Config) map[string]interface{} {
+	datadog := config.Monitoring.Datadog
+	critical, _ := threshold(m.Critical)
+	thresholds := map[string]interface{}{"critical": critical}
+	query := strings.ReplaceAll(m.Query, "{critical}", critical)
+	if m.Warning != nil {
+		warning, _ := threshold(m.Warning)
+		thresholds["warning"] = warning
+		query = strings.ReplaceAll(query, "{warning}", warning)
+	}
+
+	message := m.Message
+	if message == "" {
+		message = fmt.Sprintf("%s triggered on %s in %s.", m.Name, m.Resource, config.Environment)
+	}
+	if handles := append(slices.Clone(datadog.Notify), m.Notify...); len(handles) > 0 {
+		message += "\n\n" + strings.Join(handles, " ")
+	}
+
+	monitorType := m.Type
+	if monitorType == "" {
+		monitorType = "metric alert"
+	}
+	tags := []string{"project:" + config.Project, "env:" + config.Environment, "service:" + m.service(config)}
+	for _, key := range slices.Sorted(maps.Keys(config.DefaultTags)) {
+		tags = append(tags, strings.ToLower(key)+":"+config.DefaultTags[key])
+	}
+
+	attributes := map[string]interface{}{
+		"name":               fmt.Sprintf("[%s] %s", config.Environment, m.Name),
+		"type":               monitorType,
+		"query":              query,
+		"message":            message,
+		"monitor_thresholds": thresholds,
+		"tags":               tags,
+	}
+	if m.Priority > 0 {
+		attributes["priority"] = strconv.Itoa(m.Priority)
+	}
+	return attributes
+}
+
+// placedMonitor is a created monitor with the stack it was placed in
+type placedMonitor struct {
+	stack    cdktf.TerraformStack
+	resource *rawResource
+	name     string
+}
+
+// addMonitoring adds the configured observability to the stacks of the resources it watches
+func addMonitoring(app cdktf.App, config Config) error {
+	// Both read the resources from one index; the datadog resources added in between aren't watched
+	resources := resourcesByAddress(app)
+	if config.Monitoring.Datadog != nil {
+		if err := addDatadog(resources, config); err != nil {
+			return err
+		}
+	}
+	if config.Monitoring.Grafana != nil {
+		addGrafana(resources, config)
+	}
+	return nil
+}
+
+// addDatadog creates each monitor in the stack of the resource it watches, and a dashboard per
+// service showing its monitors' status over time. A service's monitors must share a stack, since
+// the dashboard refers to them.
+func addDatadog(resources map[string][]cdktf.TerraformResource, config Config) error {
+	datadog := config.Monitoring.Datadog
+	withProvider := map[string]bool{} // stack names
+	services := map[string][]placedMonitor{}
+
+	for _, monitor := range datadog.Monitors {
+		watched := resources[monitor.Resource]
+		switch {
+		case len(watched) == 0:
+			return fmt.Errorf("monitoring: %s watches %s, which isn't in any stack", monitor.Name, monitor.Resource)
+		case len(watched) > 1:
+			return fmt.Errorf("monitoring: %s watches %s, which is in several stacks", monitor.Name, monitor.Resource)
+		}
+		stack := cdktf.TerraformStack_Of(watched[0])
+		if !withProvider[*stack.Node().Id()] {
+			settings := map[string]interface{}{}
+			if datadog.APIURL != "" {
+				settings["api_url"] = datadog.APIURL
+			}
+			addRawProvider(stack, config, "datadog", "DataDog/datadog", defaultDatadogVersion, settings)
+			withProvider[*stack.Node().Id()] = true
+		}
+
+		resource := newRawResource(stack, "datadog_monitor", monitor.id(), monitor.attributes(config))
+		service := monitor.service(config)
+		services[service] = append(services[service], placedMonitor{stack, resource, monitor.Name})
+	}
+
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, service := range names {
+		monitors := services[service]
+		stack := monitors[0].stack
+		widgets := []map[string]interface{}{}
+		for _, monitor := range monitors {
+			if *monitor.stack.Node().Id() != *stack.Node().Id() {
+				return fmt.Errorf("monitoring: service %s has monitors in %s and %s; its dashboard needs them in one stack",
+					service, *stack.Node().Id(), *monitor.stack.Node().Id())
+			}
+			widgets = append(widgets, map[string]interface{}{
+				"alert_graph_definition": map[string]interface{}{
+					"alert_id": *monitor.resource.GetStringAttribute(jsii.String("id")),
+					"title":    monitor.name,
+					"viz_type": "timeseries",
+				},
+			})
+		}
+		newRawResource(stack, "datadog_dashboard", "dashboard_"+strings.Trim(logicalIDInvalid.ReplaceAllString(service, "_"), "_"),
+			map[string]interface{}{
+				"title":       fmt.Sprintf("%s %s (%s)", config.Project, service, config.Environment),
+				"description": "Managed by CDKTF-JSON-Platform from config.json",
+				"layout_type": "ordered",
+				"widget":      widgets,
+			})
+	}
+
+	fmt.Printf("  ✓ Datadog: %d monitor(s), %d dashboard(s)\n", len(datadog.Monitors), len(services))
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// loadMoves reads a moved mapping file: a JSON object from the old resource address to the new
+// one, e.g. {"aws_s3_bucket.data_bucket": "aws_s3_bucket.bucket"}
+func loadMoves(path string) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	var moves map[string]string
+	if err := json.Unmarshal(raw, &moves); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	targets := map[string]string{}
+	for from, to := range moves {
+		if !resourceAddress.MatchString(from) || !resourceAddress.MatchString(to) {
+			return nil, fmt.Errorf("%s: %s -> %s: both sides must be resource addresses like aws_s3_bucket.bucket", path, from, to)
+		}
+		if strings.Split(from, ".")[0] != strings.Split(to, ".")[0] {
+			return nil, fmt.Errorf("%s: %s -> %s: a resource can't move to another type", path, from, to)
+		}
+		if previous, ok := targets[to]; ok {
+			return nil, fmt.Errorf("%s: both %s and %s move to %s", path, previous, from, to)
+		}
+		targets[to] = from
+	}
+	return moves, nil
+}
+
+// addMoves generates a moved block for each mapping, in the stack holding the new address, so
+// Terraform moves the existing state instead of destroying and recreating the resource
+func addMoves(resources map[string][]cdktf.TerraformResource, moves map[string]string) error {
+	var unknown []string
+	// Sorted so the moved blocks synthesize in the same order every run
+	for _, old := range slices.Sorted(maps.Keys(moves)) {
+		address := moves[old]
+		if len(resources[address]) == 0 {
+			unknown = append(unknown, address)
+			continue
+		}
+		for _, resource := range resources[address] {
+			resource.MoveFromId(jsii.String(old))
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("moved: no resource %s in any stack", strings.Join(unknown, ", "))
+	}
+	fmt.Printf("  ✓ Moved blocks for %d resource(s)\n", len(moves))
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// NamingConfig controls how physical resource names are built from the project, environment and
+// the name each section asks for
+type NamingConfig struct {
+	Template   string         `json:"template"` // defaults to {project}-{env}-{resource}
+	Org        string         `json:"org"`
+	MaxLengths map[string]int `json:"max_lengths"` // per Terraform resource type, overriding the AWS limits below
+}
+
+const defaultNameTemplate = "{project}-{env}-{resource}"
+
+// nameMaxLengths are the AWS, Azure and Cloudflare name length limits of every resource type passed
+// to resourceName. A type without a limit is listed with 0, so a new type is a deliberate choice.
+var nameMaxLengths = map[string]int{
+	"aws_amplify_app":                                  255,
+	"aws_appconfig_deployment_strategy":                64,
+	"aws_apprunner_auto_scaling_configuration_version": 32,
+	"aws_apprunner_observability_configuration":        32,
+	"aws_apprunner_service":                            40,
+	"aws_athena_workgroup":                             128,
+	"aws_batch_compute_environment":                    128,
+	"aws_batch_job_definition":                         128,
+	"aws_batch_job_queue":                              128,
+	"aws_backup_plan":                                  50,
+	"aws_backup_selection":                             50,
+	"aws_backup_vault":                                 50,
+	"aws_bedrock_guardrail":                            50,
+	"aws_bedrock_provisioned_model_throughput":         63,
+	"aws_budgets_budget":                               100,
+	"aws_ce_anomaly_monitor":                           1024,
+	"aws_ce_anomaly_subscription":                      1024,
+	"aws_cloudtrail":                                   128,
+	"aws_cloudwatch_event_rule":                        64,
+	"aws_cloudwatch_metric_alarm":                      255,
+	"aws_codebuild_project":                            150,
+	"aws_codedeploy_app":                               100,
+	"aws_codedeploy_deployment_group":                  100,
+	"aws_codepipeline":                                 100,
+	"aws_codestarconnections_connection":               32,
+	"aws_config_config_rule":                           128,
+	"aws_config_configuration_recorder":                256,
+	"aws_config_delivery_channel":                      256,
+	"aws_dms_endpoint":                                 255,
+	"aws_dms_replication_config":                       255,
+	"aws_dms_replication_instance":                     63,
+	"aws_dms_replication_subnet_group":                 255,
+	"aws_dms_replication_task":                         255,
+	"aws_docdb_cluster":                                63,
+	"aws_docdb_cluster_instance":                       63,
+	"aws_docdb_cluster_parameter_group":                255,
+	"aws_docdb_subnet_group":                           255,
+	"aws_globalaccelerator_accelerator":                64,
+	"aws_glue_catalog_database":                        255,
+	"aws_glue_crawler":                                 255,
+	"aws_glue_job":                                     255,
+	"aws_grafana_workspace":                            255,
+	"aws_guardduty_detector":                           8192, // unnamed; the name only goes in the findings key description
+	"aws_iam_instance_profile":                         128,
+	"aws_iam_policy":                                   128,
+	"aws_iam_role":                                     64,
+	"aws_iot_policy":                                   128,
+	"aws_iot_topic_rule":                               128,
+	"aws_memorydb_acl":                                 40,
+	"aws_memorydb_cluster":                             40,
+	"aws_memorydb_subnet_group":                        255,
+	"aws_msk_cluster":                                  64,
+	"aws_msk_serverless_cluster":                       64,
+	"aws_neptune_cluster":                              63,
+	"aws_neptune_cluster_instance":                     63,
+	"aws_neptune_cluster_parameter_group":              255,
+	"aws_neptune_subnet_group":                         255,
+	"aws_opensearch_domain":                            28,
+	"aws_prometheus_workspace":                         100,
+	"aws_redshiftserverless_namespace":                 64,
+	"aws_redshiftserverless_workgroup":                 64,
+	"aws_resourcegroups_group":                         128,
+	"aws_s3_bucket":                                    63,
+	"aws_sagemaker_endpoint":                           63,
+	"aws_sagemaker_endpoint_configuration":             36,
+	"aws_sagemaker_model":                              63,
+	"aws_secretsmanager_secret":                        512,
+	"aws_sns_topic":                                    256,
+	"aws_ssm_maintenance_window":                       128,
+	"aws_ssm_patch_baseline":                           128,
+	"aws_timestreamwrite_database":                     256,
+	"aws_wafv2_ip_set":                                 128,
+	"aws_wafv2_web_acl":                                128,
+	"aws_xray_sampling_rule":                           32,
+	"azurerm_resource_group":                           90,
+	"azurerm_storage_account":                          24,
+	"cloudflare_ruleset":                               0, // no known limit; max_lengths can set one
+}
+
+var (
+	namePlaceholders   = map[string]bool{"{org}": true, "{project}": true, "{env}": true, "{resource}": true, "{region}": true}
+	placeholderPattern = regexp.MustCompile(`\{[a-z]+\}`)
+	// nameSegmentPattern keeps names valid for the strictest resource types, such as S3 buckets
+	nameSegmentPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+)
+
+func (n *NamingConfig) template() string {
+	if n == nil || n.Template == "" {
+		return defaultNameTemplate
+	}
+	return n.Template
+}
+
+func validateNaming(config Config) error {
+	template := config.Naming.template()
+	if !strings.Contains(template, "{resource}") {
+		return fmt.Errorf("template %q must contain {resource}", template)
+	}
+	for _, placeholder := range placeholderPattern.FindAllString(template, -1) {
+		if !namePlaceholders[placeholder] {
+			return fmt.Errorf("template %q: unknown placeholder %s", template, placeholder)
+		}
+	}
+	if strings.Contains(template, "{org}") && (config.Naming == nil || config.Naming.Org == "") {
+		return fmt.Errorf("template uses {org} but org is not set")
+	}
+
+	segments := [][2]string{{"project", config.Project}, {"environment", config.Environment}}
+	if config.Naming != nil && config.Naming.Org != "" {
+		segments = append(segments, [2]string{"org", config.Naming.Org})
+	}
+	for _, segment := range segments {
+		if !nameSegmentPattern.MatchString(segment[1]) {
+			return fmt.Errorf("%s %q may only use lowercase letters, digits and hyphens", segment[0], segment[1])
+		}
+	}
+
+	if config.Naming != nil {
+		for resourceType, maxLength := range config.Naming.MaxLengths {
+			if maxLength < 16 {
+				return fmt.Errorf("max_lengths.%s must be at least 16", resourceType)
+			}
+		}
+	}
+	return nil
+}
+
+// resourceName builds the physical name of a resource from the naming template. Names longer than
+// the resource type allows are truncated and suffixed with a short hash of the full name, so they
+// stay unique and stable between runs.
+func resourceName(config Config, resourceType string, name string) string {
+	org := ""
+	if config.Naming != nil {
+		org = config.Naming.Org
+	}
+	full := strings.NewReplacer(
+		"{org}", org,
+		"{project}", config.Project,
+		"{env}", config.Environment,
+		"{resource}", name,
+		"{region}", config.Region,
+	).Replace(config.Naming.template())
+
+	maxLength := nameMaxLengths[resourceType]
+	if config.Naming != nil && config.Naming.MaxLengths[resourceType] > 0 {
+		maxLength = config.Naming.MaxLengths[resourceType]
+	}
+	if maxLength == 0 || len(full) <= maxLength {
+		return full
+	}
+
+	sum := sha256.Sum256([]byte(full))
+	hash := hex.EncodeToString(sum[:])[:8]
+	return strings.TrimRight(full[:maxLength-len(hash)-1], "-_") + "-" + hash
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// Every resource type a section names must have a length limit, so a new one isn't left unchecked
+func TestNameMaxLengthsCoverResourceNameCalls(t *testing.T) {
+	call := regexp.MustCompile(`resourceName\([\w.]+, "(\w+)"`)
+	files, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, match := range call.FindAllSubmatch(source, -1) {
+			if _, ok := nameMaxLengths[string(match[1])]; !ok {
+				t.Errorf("%s: %s has no entry in nameMaxLengths", file, match[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/neptunecluster"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/neptuneclusterinstance"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/neptuneclusterparametergroup"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/neptunesubnetgroup"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// NeptuneConfig describes a Neptune graph database cluster inside the VPC. Clients always
+// authenticate with IAM, signing their requests for the neptune-db:connect action.
+type NeptuneConfig struct {
+	EngineVersion string  `json:"engine_version"` // e.g. 1.3.2.1
+	InstanceClass string  `json:"instance_class"`
+	Instances     float64 `json:"instances"` // the writer and its read replicas, 1 by default
+	// Parameters are set on the cluster parameter group, on top of the audit log being enabled
+	Parameters          map[string]string `json:"parameters"`
+	BackupRetentionDays float64           `json:"backup_retention_days"`
+	KMSKeyArn           string            `json:"kms_key_arn"`
+	VPC                 VPCConfig         `json:"vpc"`
+}
+
+// neptuneVersionPattern matches the four-part versions Neptune releases under, such as 1.2.1.0
+var neptuneVersionPattern = regexp.MustCompile(`^1\.(\d+)\.\d+\.\d+$`)
+
+// neptuneDefaultParameters keep an audit log of every query; it's exported to CloudWatch Logs
+var neptuneDefaultParameters = map[string]string{"neptune_enable_audit_log": "1"}
+
+func (n *NeptuneConfig) engineVersion() string {
+	if n.EngineVersion != "" {
+		return n.EngineVersion
+	}
+	return "1.3.2.1"
+}
+
+// parameterGroupFamily is the family of an engine version: neptune1 up to 1.1, then one per
+// minor version, e.g. neptune1.3
+func (n *NeptuneConfig) parameterGroupFamily() string {
+	minor := neptuneVersionPattern.FindStringSubmatch(n.engineVersion())[1]
+	if minor == "0" || minor == "1" {
+		return "neptune1"
+	}
+	return "neptune1." + minor
+}
+
+func (n *NeptuneConfig) validate() error {
+	// A subnet group has to span two availability zones
+	if len(n.VPC.SubnetIDs) < 2 {
+		return fmt.Errorf("vpc.subnet_ids needs at least two subnets")
+	}
+	if n.EngineVersion != "" && !neptuneVersionPattern.MatchString(n.EngineVersion) {
+		return fmt.Errorf("engine_version %q must be a Neptune version such as 1.3.2.1", n.EngineVersion)
+	}
+	if n.Instances < 0 || n.Instances > 16 {
+		return fmt.Errorf("instances must be between 1 and 16 (a writer and up to 15 read replicas)")
+	}
+	if n.InstanceClass != "" && !strings.HasPrefix(n.InstanceClass, "db.") {
+		return invalidValue(n.InstanceClass, "db."+n.InstanceClass, "instance_class %q must be a db. instance class", n.InstanceClass)
+	}
+	if n.BackupRetentionDays != 0 && (n.BackupRetentionDays < 1 || n.BackupRetentionDays > 35) {
+		return fmt.Errorf("backup_retention_days must be between 1 and 35")
+	}
+	for name := range n.Parameters {
+		if !strings.HasPrefix(name, "neptune_") {
+			return fmt.Errorf("parameters: %q is not a Neptune cluster parameter", name)
+		}
+	}
+	return nil
+}
+
+// addNeptune creates the subnet group, cluster parameter group, cluster and its instances, with
+// storage encryption, IAM authentication and audit logs always on
+func addNeptune(stack cdktf.TerraformStack, config Config) {
+	neptune := config.Neptune
+
+	instanceClass := neptune.InstanceClass
+	if instanceClass == "" {
+		instanceClass = sizeFor(config).NeptuneInstanceClass
+	}
+	instances := neptune.Instances
+	if instances == 0 {
+		instances = 1
+	}
+	backupRetention := neptune.BackupRetentionDays
+	if backupRetention == 0 {
+		backupRetention = 7
+	}
+
+	subnetGroup := neptunesubnetgroup.NewNeptuneSubnetGroup(stack, jsii.String("neptune_subnet_group"),
+		&neptunesubnetgroup.NeptuneSubnetGroupConfig{
+			Name:      jsii.String(resourceName(config, "aws_neptune_subnet_group", "graph")),
+			SubnetIds: jsii.Strings(neptune.VPC.SubnetIDs...),
+		})
+
+	parameters := maps.Clone(neptuneDefaultParameters)
+	maps.Copy(parameters, neptune.Parameters)
+	var parameterBlocks []*neptuneclusterparametergroup.NeptuneClusterParameterGroupParameter
+	for _, name := range slices.Sorted(maps.Keys(parameters)) {
+		parameterBlocks = append(parameterBlocks, &neptuneclusterparametergroup.NeptuneClusterParameterGroupParameter{
+			Name:  jsii.String(name),
+			Value: jsii.String(parameters[name]),
+		})
+	}
+	parameterGroup := neptuneclusterparametergroup.NewNeptuneClusterParameterGroup(stack, jsii.String("neptune_parameters"),
+		&neptuneclusterparametergroup.NeptuneClusterParameterGroupConfig{
+			Name:      jsii.String(resourceName(config, "aws_neptune_cluster_parameter_group", "graph")),
+			Family:    jsii.String(neptune.parameterGroupFamily()),
+			Parameter: &parameterBlocks,
+		})
+
+	clusterIdentifier := resourceName(config, "aws_neptune_cluster", "graph")
+	clusterConfig := &neptunecluster.NeptuneClusterConfig{
+		ClusterIdentifier:                jsii.String(clusterIdentifier),
+		Engine:                           jsii.String("neptune"),
+		EngineVersion:                    jsii.String(neptune.engineVersion()),
+		NeptuneSubnetGroupName:           subnetGroup.Name(),
+		NeptuneClusterParameterGroupName: parameterGroup.Name(),
+		VpcSecurityGroupIds:              jsii.Strings(neptune.VPC.SecurityGroupIDs...),
+		IamDatabaseAuthenticationEnabled: jsii.Bool(true),
+		StorageEncrypted:                 jsii.Bool(true),
+		EnableCloudwatchLogsExports:      jsii.Strings("audit"),
+		BackupRetentionPeriod:            jsii.Number(backupRetention),
+		CopyTagsToSnapshot:               jsii.Bool(true),
+		FinalSnapshotIdentifier:          jsii.String(clusterIdentifier + "-final"),
+	}
+	if neptune.KMSKeyArn != "" {
+		clusterConfig.KmsKeyArn = jsii.String(neptune.KMSKeyArn)
+	}
+	cluster := neptunecluster.NewNeptuneCluster(stack, jsii.String("neptune"), clusterConfig)
+
+	// The first instance created becomes the writer; the others are read replicas
+	for i := 1; i <= int(instances); i++ {
+		neptuneclusterinstance.NewNeptuneClusterInstance(stack, jsii.String(fmt.Sprintf("neptune_instance_%d", i)),
+			&neptuneclusterinstance.NeptuneClusterInstanceConfig{
+				Identifier:             jsii.String(resourceName(config, "aws_neptune_cluster_instance", fmt.Sprintf("graph-%d", i))),
+				ClusterIdentifier:      cluster.Id(),
+				Engine:                 jsii.String("neptune"),
+				InstanceClass:          jsii.String(instanceClass),
+				NeptuneSubnetGroupName: subnetGroup.Name(),
+			})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("neptune_endpoint"), &cdktf.TerraformOutputConfig{
+		Value:       cluster.Endpoint(),
+		Description: jsii.String("The writer endpoint of the Neptune cluster"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("neptune_reader_endpoint"), &cdktf.TerraformOutputConfig{
+		Value:       cluster.ReaderEndpoint(),
+		Description: jsii.String("The endpoint balancing reads across the Neptune cluster's replicas"),
+	})
+	cdktf.NewTerraformOutput(stack, jsii.String("neptune_connect_arn"), &cdktf.TerraformOutputConfig{
+		Value:       jsii.String(fmt.Sprintf("arn:aws:neptune-db:%s:%s:%s/*", config.Region, *accountID(stack), *cluster.ClusterResourceId())),
+		Description: jsii.String("The resource clients are granted neptune-db:connect on"),
+	})
+
+	fmt.Printf("  ✓ Neptune cluster (%v x %s)\n", instances, instanceClass)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// notificationEvents are the events notifications can be sent for, in the order they happen
+var notificationEvents = []string{"synth_finished", "synth_failed", "deploy_started", "deploy_finished", "deploy_failed"}
+
+// NotificationsConfig posts a summary of synths and deploys to Slack and other HTTP endpoints.
+// Webhook URLs are secrets, so each can be read from an environment variable instead.
+type NotificationsConfig struct {
+	Slack    *SlackNotification    `json:"slack,omitempty"`
+	Webhooks []WebhookNotification `json:"webhooks,omitempty"`
+	// Events limits the notifications to these events; all are sent by default
+	Events []string `json:"events,omitempty"`
+}
+
+// SlackNotification posts a message to a Slack incoming webhook
+type SlackNotification struct {
+	WebhookURL    string `json:"webhook_url"`
+	WebhookURLEnv string `json:"webhook_url_env"` // environment variable holding webhook_url
+}
+
+// WebhookNotification posts the event as JSON to any HTTP endpoint
+type WebhookNotification struct {
+	URL     string            `json:"url"`
+	URLEnv  string            `json:"url_env"` // environment variable holding url
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// notificationURL returns a configured URL, or the one in the named environment variable
+func notificationURL(literal string, variable string) string {
+	if variable != "" {
+		return os.Getenv(variable)
+	}
+	return literal
+}
+
+func validateNotificationURL(literal string, variable string) error {
+	if (literal == "") == (variable == "") {
+		return fmt.Errorf("exactly one of the URL and its _env variable is required")
+	}
+	if literal != "" {
+		if parsed, err := url.Parse(literal); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("%q must be an https URL", literal)
+		}
+	}
+	return nil
+}
+
+func (n *NotificationsConfig) validate() error {
+	if n.Slack == nil && len(n.Webhooks) == 0 {
+		return fmt.Errorf("slack or webhooks is required")
+	}
+	if n.Slack != nil {
+		if err := validateNotificationURL(n.Slack.WebhookURL, n.Slack.WebhookURLEnv); err != nil {
+			return fmt.Errorf("slack: %w", err)
+		}
+	}
+	for i, webhook := range n.Webhooks {
+		if err := validateNotificationURL(webhook.URL, webhook.URLEnv); err != nil {
+			return fmt.Errorf("webhooks[%d]: %w", i, err)
+		}
+	}
+	for _, event := range n.Events {
+		if !slices.Contains(notificationEvents, event) {
+			return invalidValue(event, closestMatch(event, notificationEvents),
+				"events: unknown event %q (events are %s)", event, strings.Join(notificationEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// notificationStack is one stack of a deploy notification
+type notificationStack struct {
+	Name    string       `json:"name"`
+	Status  string       `json:"status"`
+	Summary *planSummary `json:"summary,omitempty"`
+}
+
+// notification is the JSON posted to webhooks
+type notification struct {
+	Event       string              `json:"event"`
+	Project     string              `json:"project"`
+	Environment string              `json:"environment"`
+	Action      string              `json:"action,omitempty"` // apply or destroy, for deploys
+	User        string              `json:"user"`
+	Time        string              `json:"time"`
+	Stacks      []notificationStack `json:"stacks"`
+	// Summary adds up the resource counts of the stacks
+	Summary *planSummary `json:"summary,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// newNotification starts the notification of an event, with the combined summary of the stacks
+func newNotification(config Config, event string, action string, stacks []notificationStack, err error) notification {
+	message := notification{
+		Event:       event,
+		Project:     config.Project,
+		Environment: config.Environment,
+		Action:      action,
+		User:        auditUser(),
+		Time:        time.Now().UTC().Format(time.RFC3339),
+		Stacks:      stacks,
+	}
+	for _, stack := range stacks {
+		if stack.Summary == nil {
+			continue
+		}
+		if message.Summary == nil {
+			message.Summary = &planSummary{}
+		}
+		message.Summary.Added += stack.Summary.Added
+		message.Summary.Changed += stack.Summary.Changed
+		message.Summary.Destroyed += stack.Summary.Destroyed
+	}
+	if err != nil {
+		message.Error = err.Error()
+	}
+	return message
+}
+
+// slackText is the Slack message of a notification
+func slackText(message notification) string {
+	icons := map[string]string{"synth_finished": "✅", "synth_failed": "❌", "deploy_started": "🚀", "deploy_finished": "✅", "deploy_failed": "❌"}
+	what := strings.ReplaceAll(message.Event, "_", " ")
+	if message.Action == "destroy" {
+		what = strings.Replace(what, "deploy", "destroy", 1)
+	}
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s *%s-%s*: %s by %s", icons[message.Event], message.Project, message.Environment, what, message.User)
+	if message.Summary != nil {
+		fmt.Fprintf(&text, " (+%d ~%d -%d)", message.Summary.Added, message.Summary.Changed, message.Summary.Destroyed)
+	}
+	for _, stack := range message.Stacks {
+		fmt.Fprintf(&text, "\n• %s: %s", stack.Name, stack.Status)
+		if stack.Summary != nil {
+			fmt.Fprintf(&text, " (+%d ~%d -%d)", stack.Summary.Added, stack.Summary.Changed, stack.Summary.Destroyed)
+		}
+	}
+	if message.Error != "" {
+		fmt.Fprintf(&text, "\n```%s```", message.Error)
+	}
+	return text.String()
+}
+
+// postJSON posts a JSON body, failing on any status but 2xx
+func postJSON(client *http.Client, target string, headers map[string]string, body interface{}) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	response, err := client.Do(request)
+	if urlError, ok := err.(*url.Error); ok {
+		// Its message names the URL, which holds the webhook's secret
+		return urlError.Err
+	}
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", response.Status)
+	}
+	return nil
+}
+
+// notify sends a notification to every configured endpoint that wants its event. Like the audit
+// log, a failure to send is reported without failing the command.
+func notify(config Config, message notification) {
+	notifications := config.Notifications
+	if notifications == nil || (len(notifications.Events) > 0 && !slices.Contains(notifications.Events, message.Event)) {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	// URLs are left out of the warnings, as they hold the webhook's secret
+	if notifications.Slack != nil {
+		target := notificationURL(notifications.Slack.WebhookURL, notifications.Slack.WebhookURLEnv)
+		if target == "" {
+			fmt.Printf("⚠️  Notifications: %s is not set\n", notifications.Slack.WebhookURLEnv)
+		} else if err := postJSON(client, target, nil, map[string]string{"text": slackText(message)}); err != nil {
+			fmt.Printf("⚠️  Notifications: posting to Slack: %v\n", err)
+		}
+	}
+	for i, webhook := range notifications.Webhooks {
+		target := notificationURL(webhook.URL, webhook.URLEnv)
+		if target == "" {
+			fmt.Printf("⚠️  Notifications: %s is not set\n", webhook.URLEnv)
+		} else if err := postJSON(client, target, webhook.Headers, message); err != nil {
+			fmt.Printf("⚠️  Notifications: posting to webhooks[%d]: %v\n", i, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/opensearchdomain"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/opensearchdomainpolicy"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// OpenSearchConfig describes a VPC-only OpenSearch domain
+type OpenSearchConfig struct {
+	EngineVersion string    `json:"engine_version"`
+	InstanceType  string    `json:"instance_type"`
+	InstanceCount float64   `json:"instance_count"`
+	VolumeSizeGB  float64   `json:"volume_size_gb"`
+	VolumeType    string    `json:"volume_type"`
+	MasterUserArn string    `json:"master_user_arn"` // IAM principal that administers fine-grained access control
+	VPC           VPCConfig `json:"vpc"`
+}
+
+func (o *OpenSearchConfig) validate() error {
+	if len(o.VPC.SubnetIDs) == 0 {
+		return fmt.Errorf("vpc.subnet_ids is required")
+	}
+	if o.MasterUserArn == "" {
+		return fmt.Errorf("master_user_arn is required for fine-grained access control")
+	}
+	return nil
+}
+
+// addOpenSearch creates the domain with encryption, HTTPS and fine-grained access control always on
+func addOpenSearch(stack cdktf.TerraformStack, config Config) {
+	search := config.OpenSearch
+
+	engineVersion := search.EngineVersion
+	if engineVersion == "" {
+		engineVersion = "OpenSearch_2.13"
+	}
+	instanceType := search.InstanceType
+	if instanceType == "" {
+		instanceType = sizeFor(config).OpenSearchInstanceType
+	}
+	instanceCount := search.InstanceCount
+	if instanceCount == 0 {
+		instanceCount = sizeFor(config).OpenSearchInstanceCount
+	}
+	volumeSize := search.VolumeSizeGB
+	if volumeSize == 0 {
+		volumeSize = sizeFor(config).OpenSearchVolumeGB
+	}
+	volumeType := search.VolumeType
+	if volumeType == "" {
+		volumeType = "gp3"
+	}
+
+	clusterConfig := &opensearchdomain.OpensearchDomainClusterConfig{
+		InstanceType:  jsii.String(instanceType),
+		InstanceCount: jsii.Number(instanceCount),
+	}
+
+	// A VPC domain takes exactly one subnet per zone, and spreading over zones needs a node in each
+	subnets := search.VPC.SubnetIDs
+	zones := len(subnets)
+	if zones > 3 {
+		zones = 3
+	}
+	if int(instanceCount) < zones {
+		zones = int(instanceCount)
+	}
+	if zones > 1 {
+		clusterConfig.ZoneAwarenessEnabled = jsii.Bool(true)
+		clusterConfig.ZoneAwarenessConfig = &opensearchdomain.OpensearchDomainClusterConfigZoneAwarenessConfig{
+			AvailabilityZoneCount: jsii.Number(float64(zones)),
+		}
+	}
+
+	domain := opensearchdomain.NewOpensearchDomain(stack, jsii.String("opensearch"),
+		&opensearchdomain.OpensearchDomainConfig{
+			DomainName:    jsii.String(resourceName(config, "aws_opensearch_domain", "search")),
+			EngineVersion: jsii.String(engineVersion),
+			ClusterConfig: clusterConfig,
+			EbsOptions: &opensearchdomain.OpensearchDomainEbsOptions{
+				EbsEnabled: jsii.Bool(true),
+				VolumeSize: jsii.Number(volumeSize),
+				VolumeType: jsii.String(volumeType),
+			},
+			VpcOptions: &opensearchdomain.OpensearchDomainVpcOptions{
+				SubnetIds:        jsii.Strings(subnets[:max(zones, 1)]...),
+				SecurityGroupIds: jsii.Strings(search.VPC.SecurityGroupIDs...),
+			},
+			EncryptAtRest: &opensearchdomain.OpensearchDomainEncryptAtRest{
+				Enabled: jsii.Bool(true),
+			},
+			NodeToNodeEncryption: &opensearchdomain.OpensearchDomainNodeToNodeEncryption{
+				Enabled: jsii.Bool(true),
+			},
+			DomainEndpointOptions: &opensearchdomain.OpensearchDomainDomainEndpointOptions{
+				EnforceHttps:      jsii.Bool(true),
+				TlsSecurityPolicy: jsii.String("Policy-Min-TLS-1-2-2019-07"),
+			},
+			AdvancedSecurityOptions: &opensearchdomain.OpensearchDomainAdvancedSecurityOptions{
+				Enabled:                     jsii.Bool(true),
+				InternalUserDatabaseEnabled: jsii.Bool(false),
+				MasterUserOptions: &opensearchdomain.OpensearchDomainAdvancedSecurityOptionsMasterUserOptions{
+					MasterUserArn: jsii.String(search.MasterUserArn),
+				},
+			},
+		})
+
+	// With fine-grained access control the resource policy stays open and the
+	// domain's own roles decide who can do what
+	opensearchdomainpolicy.NewOpensearchDomainPolicy(stack, jsii.String("opensearch_policy"),
+		&opensearchdomainpolicy.OpensearchDomainPolicyConfig{
+			DomainName: domain.DomainName(),
+			AccessPolicies: jsii.String(policyDocument(map[string]interface{}{
+				"Effect":    "Allow",
+				"Principal": map[string]string{"AWS": "*"},
+				"Action":    "es:ESHttp*",
+				"Resource":  *domain.Arn() + "/*",
+			})),
+		})
+
+	cdktf.NewTerraformOutput(stack, jsii.String("opensearch_endpoint"), &cdktf.TerraformOutputConfig{
+		Value:       domain.Endpoint(),
+		Description: jsii.String("The endpoint of the OpenSearch domain"),
+	})
+
+	fmt.Printf("  ✓ OpenSearch domain (%v x %s)\n", instanceCount, instanceType)
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/ssmparameter"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// OutputsConfig publishes every stack output as an SSM parameter on deploy, so application
+// pipelines can read them without access to the Terraform state
+type OutputsConfig struct {
+	SSMPrefix string `json:"ssm_prefix"` // e.g. /my-app/dev; parameters are <prefix>/<stack>/<output>
+	// Sensitive names outputs to mask, on top of those that read a credential
+	Sensitive []string `json:"sensitive"`
+}
+
+func (o *OutputsConfig) validate() error {
+	if o.SSMPrefix != "" && (!strings.HasPrefix(o.SSMPrefix, "/") || strings.HasSuffix(o.SSMPrefix, "/")) {
+		return fmt.Errorf("ssm_prefix %q must start with / and not end with one", o.SSMPrefix)
+	}
+	return nil
+}
+
+// crossStackOutput is the prefix cdktf gives the outputs it creates to pass values between stacks
+const crossStackOutput = "cross-stack-output-"
+
+// secretAttribute matches attributes that hold a credential, such as a password or a secret value.
+// readsSecret leaves out those that only identify one, such as admin_password_secret_arn.
+var secretAttribute = regexp.MustCompile(`\.(\w*password\w*|secret|secret_string|secret_binary|\w*private_key\w*|\w*token)\b`)
+
+// synthesizedOutput is an output as its stack synthesizes it
+type synthesizedOutput struct {
+	Value     interface{} `json:"value"`
+	Sensitive bool        `json:"sensitive"`
+}
+
+// stackOutputs is the synthesized outputs of a stack, by name
+type stackOutputs struct {
+	stack   cdktf.TerraformStack
+	outputs map[string]synthesizedOutput
+}
+
+// markSensitiveOutputs masks the outputs named in the config and those whose value reads a
+// credential, so Terraform doesn't print them. It returns the outputs of every stack with the
+// masks applied, for addOutputParameters to publish without synthesizing the stacks again.
+func markSensitiveOutputs(app cdktf.App, config Config) ([]stackOutputs, error) {
+	named := map[string]bool{}
+	if config.Outputs != nil {
+		for _, name := range config.Outputs.Sensitive {
+			named[name] = false
+		}
+	}
+
+	marked := 0
+	var stacks []stackOutputs
+	for _, document := range stackDocuments(app) {
+		var synthesized struct {
+			Output map[string]synthesizedOutput `json:"output"`
+		}
+		json.Unmarshal([]byte(document.json), &synthesized)
+
+		for _, name := range slices.Sorted(maps.Keys(synthesized.Output)) {
+			output := synthesized.Output[name]
+			if _, ok := named[name]; ok {
+				named[name] = true
+			}
+			if output.Sensitive || !(named[name] || readsSecret(fmt.Sprint(output.Value))) {
+				continue
+			}
+			if construct, ok := document.stack.Node().TryFindChild(jsii.String(name)).(cdktf.TerraformOutput); ok {
+				construct.SetSensitive(jsii.Bool(true))
+				output.Sensitive = true
+				synthesized.Output[name] = output
+				marked++
+			}
+		}
+		stacks = append(stacks, stackOutputs{document.stack, synthesized.Output})
+	}
+
+	var unknown []string
+	for _, name := range slices.Sorted(maps.Keys(named)) {
+		if !named[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("outputs: sensitive: no output %s in any stack", strings.Join(unknown, ", "))
+	}
+	if marked > 0 {
+		fmt.Printf("  ✓ %d output(s) marked sensitive\n", marked)
+	}
+	return stacks, nil
+}
+
+// readsSecret reports whether an output value reads a credential, including any attribute of a
+// random_password
+func readsSecret(value string) bool {
+	for _, expression := range expressions.FindAllString(value, -1) {
+		if strings.Contains(expression, "random_password.") {
+			return true
+		}
+		for _, match := range secretAttribute.FindAllStringSubmatch(expression, -1) {
+			if !strings.HasSuffix(match[1], "_arn") && !strings.HasSuffix(match[1], "_id") && !strings.HasSuffix(match[1], "_name") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addOutputParameters creates an SSM parameter for each output of every stack. Outputs that
+// aren't strings are stored as JSON.
+func addOutputParameters(stacks []stackOutputs, config Config) {
+	count := 0
+	for _, stack := range stacks {
+		stackName := *stack.stack.Node().Id()
+		for _, name := range slices.Sorted(maps.Keys(stack.outputs)) {
+			if strings.HasPrefix(name, crossStackOutput) {
+				continue
+			}
+			output := stack.outputs[name]
+			value := fmt.Sprint(output.Value)
+			if expression, ok := strings.CutPrefix(value, "${"); ok && strings.HasSuffix(expression, "}") {
+				expression = strings.TrimSuffix(expression, "}")
+				value = fmt.Sprintf("${try(tostring(%[1]s), jsonencode(%[1]s))}", expression)
+			} else if _, ok := output.Value.(string); !ok {
+				encoded, _ := json.Marshal(output.Value)
+				value = string(encoded)
+			}
+
+			parameterType := "String"
+			if output.Sensitive {
+				parameterType = "SecureString"
+			}
+			ssmparameter.NewSsmParameter(stack.stack, jsii.String("output_parameter_"+name), &ssmparameter.SsmParameterConfig{
+				Name:        jsii.String(fmt.Sprintf("%s/%s/%s", config.Outputs.SSMPrefix, stackName, name)),
+				Type:        jsii.String(parameterType),
+				Value:       jsii.String(value),
+				Description: jsii.String(fmt.Sprintf("Output %s of stack %s", name, stackName)),
+			})
+			count++
+		}
+	}
+	fmt.Printf("  ✓ %d output(s) published to SSM under %s\n", count, config.Outputs.SSMPrefix)
+}
+
+// terraformInit initializes a synthesized stack's working directory
+func terraformInit(dir string) error {
+	initCmd := exec.Command("terraform", "init", "-input=false", "-no-color")
+	initCmd.Dir = dir
+	span := startCommandSpan(initCmd)
+	output, err := initCmd.CombinedOutput()
+	span.finish(err)
+	if err != nil {
+		return fmt.Errorf("terraform init: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// stackOutput is one entry of `terraform output -json`
+type stackOutput struct {
+	Value     interface{} `json:"value"`
+	Type      interface{} `json:"type"`
+	Sensitive bool        `json:"sensitive"`
+}
+
+// readOutputs returns the outputs of a deployed stack, without cdktf's cross-stack outputs
+func readOutputs(dir string, skipInit bool) (map[string]stackOutput, error) {
+	if !skipInit {
+		if err := terraformInit(dir); err != nil {
+			return nil, err
+		}
+	}
+	command := exec.Command("terraform", "output", "-json", "-no-color")
+	command.Dir = dir
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	span := startCommandSpan(command)
+	stdout, err := command.Output()
+	span.finish(err)
+	if err != nil {
+		return nil, fmt.Errorf("terraform output: %w\n%s", err, stderr.String())
+	}
+	var outputs map[string]stackOutput
+	if err := json.Unmarshal(stdout, &outputs); err != nil {
+		return nil, fmt.Errorf("parsing terraform output: %w", err)
+	}
+	for name := range outputs {
+		if strings.HasPrefix(name, crossStackOutput) {
+			delete(outputs, name)
+		}
+	}
+	return outputs, nil
+}
+
+// runOutputs is the outputs command. It collects the outputs of every deployed stack of the
+// config's environment into outputs.<environment>.json, keyed by stack and then output name.
+// Sensitive outputs are left out unless -show-sensitive is given.
+func runOutputs(args []string) error {
+	flags := flag.NewFlagSet("outputs", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	output := flags.String("output", "", "outputs file (default outputs.<environment>.json)")
+	skipInit := flags.Bool("skip-init", false, "don't run terraform init first")
+	showSensitive := flags.Bool("show-sensitive", false, "also write sensitive outputs to the file")
+	flags.Parse(args)
+
+	config, err := loadConfig("config.json")
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("terraform"); err != nil {
+		return fmt.Errorf("terraform not found on PATH: %w", err)
+	}
+	return writeOutputs(config, *outdir, *output, *skipInit, *showSensitive)
+}
+
+// writeOutputs writes the outputs of the deployed stacks in outdir to path, by default
+// outputs.<environment>.json. The outputs and deploy commands both write the file with it.
+func writeOutputs(config Config, outdir string, path string, skipInit bool, showSensitive bool) error {
+	if path == "" {
+		path = fmt.Sprintf("outputs.%s.json", config.Environment)
+	}
+	// terraform reads the environment's state from its workspace
+	if config.Workspaces {
+		os.Setenv("TF_WORKSPACE", config.Environment)
+	}
+	stackNames, err := synthesizedStacks(outdir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("📤 Reading stack outputs...")
+	values := map[string]map[string]interface{}{}
+	var failed []error
+	// cdktf.out can still hold the stacks of another environment
+	prefix := stackPrefix(config) + "-"
+	for _, name := range stackNames {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		outputs, err := readOutputs(filepath.Join(outdir, "stacks", name), skipInit)
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", name, err))
+			fmt.Printf("  ✗ %s: could not read outputs\n", name)
+			continue
+		}
+		values[name] = map[string]interface{}{}
+		hidden := 0
+		for outputName, value := range outputs {
+			if value.Sensitive && !showSensitive {
+				hidden++
+				continue
+			}
+			values[name][outputName] = value.Value
+		}
+		if hidden > 0 {
+			fmt.Printf("  ✓ %s: %d output(s), %d sensitive left out\n", name, len(outputs)-hidden, hidden)
+		} else {
+			fmt.Printf("  ✓ %s: %d output(s)\n", name, len(outputs))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Join(failed...)
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	fmt.Printf("  Outputs written to %s\n", path)
+	return nil
+}
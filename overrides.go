@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// resourceAddress matches <type>.<logical id>, as resources appear in cdk.tf.json and plans
+var resourceAddress = regexp.MustCompile(`^[a-z][a-z0-9_]*\.[A-Za-z0-9_-]+$`)
+
+// validateOverrides checks the shape of the overrides section. Whether each address exists is only
+// known once the stacks are built, so applyOverrides checks that.
+func validateOverrides(overrides map[string]map[string]interface{}) error {
+	for address, values := range overrides {
+		if !resourceAddress.MatchString(address) {
+			return fmt.Errorf("%q is not a resource address like aws_s3_bucket.bucket", address)
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("%s: no overrides given", address)
+		}
+		for path := range values {
+			if path == "" || strings.HasPrefix(path, ".") || strings.HasSuffix(path, ".") || strings.Contains(path, "..") {
+				return fmt.Errorf("%s: %q is not an attribute path like lifecycle.prevent_destroy", address, path)
+			}
+		}
+	}
+	return nil
+}
+
+// applyOverrides sets raw attribute overrides on the synthesized resources, for provider
+// attributes the config doesn't model. An address applies in every stack that has that resource.
+func applyOverrides(resources map[string][]cdktf.TerraformResource, overrides map[string]map[string]interface{}) error {
+	var unknown []string
+	for address, values := range overrides {
+		if len(resources[address]) == 0 {
+			unknown = append(unknown, address)
+			continue
+		}
+		for _, resource := range resources[address] {
+			for _, path := range slices.Sorted(maps.Keys(values)) {
+				resource.AddOverride(jsii.String(path), values[path])
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("overrides: no resource %s in any stack", strings.Join(unknown, ", "))
+	}
+	fmt.Printf("  ✓ Raw overrides on %d resource(s)\n", len(overrides))
+	return nil
+}
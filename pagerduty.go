@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/snstopic"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/snstopicsubscription"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// PagerDutyConfig creates a PagerDuty service for each stack, with a CloudWatch integration fed
+// by an SNS topic that the stack's alarms can notify. The API token comes from PAGERDUTY_TOKEN.
+type PagerDutyConfig struct {
+	EscalationPolicy   string `json:"escalation_policy"`    // escalation policy name, e.g. Data Platform On-Call
+	EscalationPolicyID string `json:"escalation_policy_id"` // skips looking the policy up by name
+	Urgency            string `json:"urgency"`              // high (default), low or severity_based
+	// AutoResolveTimeout and AcknowledgementTimeout are in seconds; 0 turns them off
+	AutoResolveTimeout     int `json:"auto_resolve_timeout"`
+	AcknowledgementTimeout int `json:"acknowledgement_timeout"`
+	// AlarmTopics subscribes existing alarm topics to the service of a stack, by stack name
+	// ("stack" is the main one), e.g. {"stack": ["arn:aws:sns:eu-west-1:123456789012:legacy-alarms"]}
+	AlarmTopics map[string][]string `json:"alarm_topics"`
+}
+
+// defaultPagerDutyVersion is the pagerduty provider constraint unless provider_versions sets one
+const defaultPagerDutyVersion = "~> 3.0"
+
+var (
+	pagerDutyUrgencies = []string{"high", "low", "severity_based"}
+	pagerDutyIDPattern = regexp.MustCompile(`^P[A-Z0-9]{6}$`)
+	snsTopicARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:sns:[a-z0-9-]+:\d{12}:[A-Za-z0-9_-]{1,256}$`)
+)
+
+func validatePagerDuty(config Config) error {
+	pagerDuty := config.PagerDuty
+	if (pagerDuty.EscalationPolicy == "") == (pagerDuty.EscalationPolicyID == "") {
+		return fmt.Errorf("exactly one of escalation_policy and escalation_policy_id is required")
+	}
+	if pagerDuty.EscalationPolicyID != "" && !pagerDutyIDPattern.MatchString(pagerDuty.EscalationPolicyID) {
+		return fmt.Errorf("escalation_policy_id %q is not a PagerDuty ID like PABC123", pagerDuty.EscalationPolicyID)
+	}
+	if pagerDuty.Urgency != "" && !slices.Contains(pagerDutyUrgencies, pagerDuty.Urgency) {
+		return fmt.Errorf("urgency %q must be one of %s", pagerDuty.Urgency, strings.Join(pagerDutyUrgencies, ", "))
+	}
+	if pagerDuty.AutoResolveTimeout < 0 || pagerDuty.AcknowledgementTimeout < 0 {
+		return fmt.Errorf("auto_resolve_timeout and acknowledgement_timeout can't be negative")
+	}
+
+	stacks := map[string]bool{"stack": true}
+	for _, stack := range config.Stacks {
+		stacks[stack.Name] = true
+	}
+	for _, name := range slices.Sorted(maps.Keys(pagerDuty.AlarmTopics)) {
+		if !stacks[name] {
+			return fmt.Errorf("alarm_topics: unknown stack %q", name)
+		}
+		for _, arn := range pagerDuty.AlarmTopics[name] {
+			if !snsTopicARNPattern.MatchString(arn) {
+				return fmt.Errorf("alarm_topics.%s: %q is not an SNS topic ARN", name, arn)
+			}
+		}
+	}
+	return nil
+}
+
+// timeout is a service timeout as the provider takes it, where "null" turns it off
+func timeout(seconds int) interface{} {
+	if seconds == 0 {
+		return "null"
+	}
+	return seconds
+}
+
+// addPagerDuty gives every created stack a PagerDuty service named after the stack, integrated
+// with Amazon CloudWatch, and an <stack>-alarms SNS topic subscribed to that integration. Alarms
+// page the service by listing the topic in their alarm_actions and ok_actions.
+func addPagerDuty(stacks *stackSet, config Config) error {
+	pagerDuty := config.PagerDuty
+	urgency := pagerDuty.Urgency
+	if urgency == "" {
+		urgency = "high"
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(pagerDuty.AlarmTopics)) {
+		if _, ok := stacks.stacks[name]; !ok {
+			return fmt.Errorf("pagerduty: alarm_topics names stack %s, which has no sections", name)
+		}
+	}
+
+	subscribed := 0
+	for _, suffix := range slices.Sorted(maps.Keys(stacks.stacks)) {
+		stack := stacks.stacks[suffix]
+		stackName := *stack.Node().Id()
+		addRawProvider(stack, config, "pagerduty", "PagerDuty/pagerduty", defaultPagerDutyVersion, map[string]interface{}{})
+
+		policyID := pagerDuty.EscalationPolicyID
+		if policyID == "" {
+			policy := newRawDataSource(stack, "pagerduty_escalation_policy", "pagerduty_escalation_policy", map[string]interface{}{
+				"name": pagerDuty.EscalationPolicy,
+			})
+			policyID = *policy.GetStringAttribute(jsii.String("id"))
+		}
+		vendor := newRawDataSource(stack, "pagerduty_vendor", "cloudwatch_vendor", map[string]interface{}{
+			"name": "Amazon CloudWatch",
+		})
+
+		service := newRawResource(stack, "pagerduty_service", "pagerduty_service", map[string]interface{}{
+			"name":                    stackName,
+			"description":             fmt.Sprintf("Alerts of %s %s, managed by CDKTF-JSON-Platform", config.Project, config.Environment),
+			"escalation_policy":       policyID,
+			"auto_resolve_timeout":    timeout(pagerDuty.AutoResolveTimeout),
+			"acknowledgement_timeout": timeout(pagerDuty.AcknowledgementTimeout),
+			"incident_urgency_rule":   map[string]interface{}{"type": "constant", "urgency": urgency},
+		})
+		integration := newRawResource(stack, "pagerduty_service_integration", "cloudwatch_integration", map[string]interface{}{
+			"name":    "Amazon CloudWatch",
+			"service": *service.GetStringAttribute(jsii.String("id")),
+			"vendor":  *vendor.GetStringAttribute(jsii.String("id")),
+		})
+		key := integration.GetStringAttribute(jsii.String("integration_key"))
+		endpoint := jsii.String(fmt.Sprintf("https://events.pagerduty.com/integration/%s/enqueue", *key))
+
+		// Left unencrypted: CloudWatch can't publish to topics encrypted with the AWS managed SNS key
+		topic := snstopic.NewSnsTopic(stack, jsii.String("alarm_topic"), &snstopic.SnsTopicConfig{
+			Name: jsii.String(stackName + "-alarms"),
+		})
+		snstopicsubscription.NewSnsTopicSubscription(stack, jsii.String("alarm_topic_pagerduty"),
+			&snstopicsubscription.SnsTopicSubscriptionConfig{
+				TopicArn:             topic.Arn(),
+				Protocol:             jsii.String("https"),
+				Endpoint:             endpoint,
+				EndpointAutoConfirms: jsii.Bool(true),
+			})
+		for i, arn := range pagerDuty.AlarmTopics[suffix] {
+			snstopicsubscription.NewSnsTopicSubscription(stack, jsii.String(fmt.Sprintf("alarm_topic_pagerduty_%d", i)),
+				&snstopicsubscription.SnsTopicSubscriptionConfig{
+					TopicArn:             jsii.String(arn),
+					Protocol:             jsii.String("https"),
+					Endpoint:             endpoint,
+					EndpointAutoConfirms: jsii.Bool(true),
+				})
+			subscribed++
+		}
+
+		cdktf.NewTerraformOutput(stack, jsii.String("pagerduty_service_id"), &cdktf.TerraformOutputConfig{
+			Value:       service.GetStringAttribute(jsii.String("id")),
+			Description: jsii.String("The ID of the stack's PagerDuty service"),
+		})
+		// Published as a SecureString with outputs.ssm_prefix, for alert sources outside the stack
+		cdktf.NewTerraformOutput(stack, jsii.String("pagerduty_integration_key"), &cdktf.TerraformOutputConfig{
+			Value:       key,
+			Description: jsii.String("The key of the service's CloudWatch integration"),
+			Sensitive:   jsii.Bool(true),
+		})
+		cdktf.NewTerraformOutput(stack, jsii.String("alarm_topic_arn"), &cdktf.TerraformOutputConfig{
+			Value:       topic.Arn(),
+			Description: jsii.String("The ARN of the SNS topic that pages the PagerDuty service"),
+		})
+	}
+
+	fmt.Printf("  ✓ PagerDuty: %d service(s), %d existing alarm topic(s) subscribed\n", len(stacks.stacks), subscribed)
+	return nil
+}
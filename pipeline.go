@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/cloudwatcheventrule"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/cloudwatcheventtarget"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/codebuildproject"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/codepipeline"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/codestarconnectionsconnection"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// PipelineConfig describes a CodePipeline that checks out a repository, builds it with CodeBuild
+// and optionally deploys the build with a second CodeBuild project. Artifacts are kept in the
+// config bucket.
+type PipelineConfig struct {
+	Name   string          `json:"name"` // defaults to deploy
+	Source PipelineSource  `json:"source"`
+	Build  PipelineProject `json:"build"`
+	// Deploy runs after the build with both the source and the build's artifacts; its buildspec
+	// defaults to deployspec.yml
+	Deploy *PipelineProject `json:"deploy,omitempty"`
+	// ApproveDeploy holds every run for a manual approval before the deploy stage
+	ApproveDeploy bool `json:"approve_deploy"`
+}
+
+type PipelineSource struct {
+	Provider string `json:"provider"` // github or codecommit
+	// Repository is owner/name on GitHub, or the name of a CodeCommit repository
+	Repository string `json:"repository"`
+	Branch     string `json:"branch"` // defaults to main
+	// ConnectionArn is an existing CodeStar connection to GitHub. Without one a connection is
+	// created, which stays pending until it's authorized in the console.
+	ConnectionArn string `json:"connection_arn"`
+}
+
+// PipelineProject is a CodeBuild project run by a pipeline stage
+type PipelineProject struct {
+	// Buildspec is a path in the repository, buildspec.yml by default, or an inline YAML buildspec
+	Buildspec   string            `json:"buildspec"`
+	Image       string            `json:"image"`        // defaults to the Amazon Linux standard image
+	ComputeType string            `json:"compute_type"` // BUILD_GENERAL1_SMALL by default
+	Privileged  bool              `json:"privileged"`   // runs the Docker daemon, for building images
+	Environment map[string]string `json:"environment"`
+	// TimeoutMinutes stops a build running longer, 5 to 2160, 60 by default
+	TimeoutMinutes float64 `json:"timeout_minutes"`
+	// ManagedPolicyArns are attached to the project's role for the access its commands need
+	ManagedPolicyArns []string `json:"managed_policy_arns"`
+}
+
+var codebuildComputeTypes = []string{
+	"BUILD_GENERAL1_SMALL", "BUILD_GENERAL1_MEDIUM", "BUILD_GENERAL1_LARGE", "BUILD_GENERAL1_XLARGE", "BUILD_GENERAL1_2XLARGE",
+}
+
+func (p *PipelineConfig) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return "deploy"
+}
+
+func (s PipelineSource) branch() string {
+	if s.Branch != "" {
+		return s.Branch
+	}
+	return "main"
+}
+
+func (p *PipelineConfig) validate() error {
+	if p.Name != "" && !nameSegmentPattern.MatchString(p.Name) {
+		return invalidValue(p.Name, "", "name %q must be lowercase letters, digits and hyphens", p.Name)
+	}
+	switch p.Source.Provider {
+	case "github":
+		if owner, name, ok := strings.Cut(p.Source.Repository, "/"); !ok || !githubNamePattern.MatchString(owner) || !githubNamePattern.MatchString(name) {
+			return fmt.Errorf("source: repository %q must be owner/name", p.Source.Repository)
+		}
+		if p.Source.ConnectionArn != "" && !strings.HasPrefix(p.Source.ConnectionArn, "arn:aws:codestar-connections:") &&
+			!strings.HasPrefix(p.Source.ConnectionArn, "arn:aws:codeconnections:") {
+			return fmt.Errorf("source: connection_arn %q is not a CodeStar connection ARN", p.Source.ConnectionArn)
+		}
+	case "codecommit":
+		if !githubNamePattern.MatchString(p.Source.Repository) {
+			return fmt.Errorf("source: repository %q must be a CodeCommit repository name", p.Source.Repository)
+		}
+		if p.Source.ConnectionArn != "" {
+			return fmt.Errorf("source: CodeCommit repositories take no connection_arn")
+		}
+	default:
+		return invalidValue(p.Source.Provider, closestMatch(p.Source.Provider, []string{"github", "codecommit"}),
+			"source: unknown provider %q (want github or codecommit)", p.Source.Provider)
+	}
+	if err := p.Build.validate(); err != nil {
+		return fmt.Errorf("build: %w", err)
+	}
+	if p.Deploy != nil {
+		if err := p.Deploy.validate(); err != nil {
+			return fmt.Errorf("deploy: %w", err)
+		}
+	} else if p.ApproveDeploy {
+		return fmt.Errorf("approve_deploy needs a deploy stage")
+	}
+	return nil
+}
+
+func (p PipelineProject) validate() error {
+	if p.ComputeType != "" && !slices.Contains(codebuildComputeTypes, p.ComputeType) {
+		return invalidValue(p.ComputeType, closestMatch(p.ComputeType, codebuildComputeTypes), "unknown compute_type %q", p.ComputeType)
+	}
+	if p.TimeoutMinutes != 0 && (p.TimeoutMinutes < 5 || p.TimeoutMinutes > 2160) {
+		return fmt.Errorf("timeout_minutes must be between 5 and 2160")
+	}
+	for _, name := range slices.Sorted(maps.Keys(p.Environment)) {
+		if !githubSecretPattern.MatchString(name) || strings.HasPrefix(name, "CODEBUILD_") {
+			return fmt.Errorf("environment: %q is not an environment variable name CodeBuild allows", name)
+		}
+	}
+	return nil
+}
+
+// addPipelineProject creates a CodeBuild project run by the pipeline, with a role of its own
+func addPipelineProject(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket, stage string,
+	project PipelineProject, defaultBuildspec string) codebuildproject.CodebuildProject {
+	name := resourceName(config, "aws_codebuild_project", config.Pipeline.name()+"-"+stage)
+
+	role := newServiceRole(stack, "pipeline_"+stage+"_role", resourceName(config, "aws_iam_role", "pipeline-"+stage),
+		"codebuild.amazonaws.com", config, project.ManagedPolicyArns...)
+	addInlinePolicy(stack, "pipeline_"+stage+"_role_policy", role,
+		bucketAccessStatement(bucket, true),
+		map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   []string{"logs:CreateLogGroup", "logs:CreateLogStream", "logs:PutLogEvents"},
+			"Resource": fmt.Sprintf("arn:aws:logs:%s:%s:log-group:/aws/codebuild/%s*", config.Region, *accountID(stack), name),
+		})
+
+	buildspec := project.Buildspec
+	if buildspec == "" {
+		buildspec = defaultBuildspec
+	}
+	image := project.Image
+	if image == "" {
+		image = "aws/codebuild/amazonlinux-x86_64-standard:5.0"
+	}
+	computeType := project.ComputeType
+	if computeType == "" {
+		computeType = "BUILD_GENERAL1_SMALL"
+	}
+	timeout := project.TimeoutMinutes
+	if timeout == 0 {
+		timeout = 60
+	}
+
+	environment := &codebuildproject.CodebuildProjectEnvironment{
+		Type:                     jsii.String("LINUX_CONTAINER"),
+		Image:                    jsii.String(image),
+		ComputeType:              jsii.String(computeType),
+		ImagePullCredentialsType: jsii.String("CODEBUILD"),
+		PrivilegedMode:           jsii.Bool(project.Privileged),
+	}
+	if len(project.Environment) > 0 {
+		var variables []*codebuildproject.CodebuildProjectEnvironmentEnvironmentVariable
+		for _, variable := range slices.Sorted(maps.Keys(project.Environment)) {
+			variables = append(variables, &codebuildproject.CodebuildProjectEnvironmentEnvironmentVariable{
+				Name:  jsii.String(variable),
+				Value: jsii.String(escapeInterpolation(project.Environment[variable])),
+				Type:  jsii.String("PLAINTEXT"),
+			})
+		}
+		environment.EnvironmentVariable = variables
+	}
+
+	return codebuildproject.NewCodebuildProject(stack, jsii.String("pipeline_"+stage), &codebuildproject.CodebuildProjectConfig{
+		Name:         jsii.String(name),
+		Description:  jsii.String(fmt.Sprintf("The %s stage of the %s pipeline", stage, config.Pipeline.name())),
+		ServiceRole:  role.Arn(),
+		BuildTimeout: jsii.Number(timeout),
+		Environment:  environment,
+		// The pipeline hands the project its input artifacts and collects its output
+		Source: &codebuildproject.CodebuildProjectSource{
+			Type:      jsii.String("CODEPIPELINE"),
+			Buildspec: jsii.String(escapeInterpolation(buildspec)),
+		},
+		Artifacts: &codebuildproject.CodebuildProjectArtifacts{Type: jsii.String("CODEPIPELINE")},
+	})
+}
+
+// addPipeline creates the CodeBuild projects and the pipeline running them, and for GitHub the
+// connection the pipeline checks out through
+func addPipeline(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket) {
+	pipeline := config.Pipeline
+	source := pipeline.Source
+	account := *accountID(stack)
+	pipelineName := resourceName(config, "aws_codepipeline", pipeline.name())
+	repositoryArn := fmt.Sprintf("arn:aws:codecommit:%s:%s:%s", config.Region, account, source.Repository)
+
+	build := addPipelineProject(stack, config, bucket, "build", pipeline.Build, "buildspec.yml")
+	var deploy codebuildproject.CodebuildProject
+	if pipeline.Deploy != nil {
+		deploy = addPipelineProject(stack, config, bucket, "deploy", *pipeline.Deploy, "deployspec.yml")
+	}
+
+	projects := []string{*build.Arn()}
+	if deploy != nil {
+		projects = append(projects, *deploy.Arn())
+	}
+	statements := []map[string]interface{}{
+		bucketAccessStatement(bucket, true),
+		{
+			"Effect":   "Allow",
+			"Action":   []string{"codebuild:StartBuild", "codebuild:BatchGetBuilds"},
+			"Resource": projects,
+		},
+	}
+
+	sourceAction := &codepipeline.CodepipelineStageAction{
+		Name:            jsii.String("Source"),
+		Category:        jsii.String("Source"),
+		Owner:           jsii.String("AWS"),
+		Version:         jsii.String("1"),
+		OutputArtifacts: jsii.Strings("source"),
+	}
+	var connectionArn *string
+	if source.Provider == "github" {
+		connectionArn = jsii.String(source.ConnectionArn)
+		if source.ConnectionArn == "" {
+			connection := codestarconnectionsconnection.NewCodestarconnectionsConnection(stack, jsii.String("pipeline_connection"),
+				&codestarconnectionsconnection.CodestarconnectionsConnectionConfig{
+					Name:         jsii.String(resourceName(config, "aws_codestarconnections_connection", "github")),
+					ProviderType: jsii.String("GitHub"),
+				})
+			connectionArn = connection.Arn()
+
+			cdktf.NewTerraformOutput(stack, jsii.String("pipeline_connection_arn"), &cdktf.TerraformOutputConfig{
+				Value:       connection.Arn(),
+				Description: jsii.String("The GitHub connection the pipeline checks out through; authorize it in the console before the first run"),
+			})
+		}
+		sourceAction.Provider = jsii.String("CodeStarSourceConnection")
+		sourceAction.Configuration = &map[string]*string{
+			"ConnectionArn":        connectionArn,
+			"FullRepositoryId":     jsii.String(source.Repository),
+			"BranchName":           jsii.String(source.branch()),
+			"OutputArtifactFormat": jsii.String("CODE_ZIP"),
+		}
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   []string{"codestar-connections:UseConnection", "codeconnections:UseConnection"},
+			"Resource": *connectionArn,
+		})
+	} else {
+		sourceAction.Provider = jsii.String("CodeCommit")
+		// Pushes start the pipeline through the EventBridge rule below instead of polling
+		sourceAction.Configuration = &map[string]*string{
+			"RepositoryName":       jsii.String(source.Repository),
+			"BranchName":           jsii.String(source.branch()),
+			"PollForSourceChanges": jsii.String("false"),
+		}
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   []string{"codecommit:GetBranch", "codecommit:GetCommit", "codecommit:UploadArchive", "codecommit:GetUploadArchiveStatus", "codecommit:CancelUploadArchive"},
+			"Resource": repositoryArn,
+		})
+	}
+
+	stages := []*codepipeline.CodepipelineStage{
+		{
+			Name:   jsii.String("Source"),
+			Action: []*codepipeline.CodepipelineStageAction{sourceAction},
+		},
+		{
+			Name: jsii.String("Build"),
+			Action: []*codepipeline.CodepipelineStageAction{{
+				Name:            jsii.String("Build"),
+				Category:        jsii.String("Build"),
+				Owner:           jsii.String("AWS"),
+				Provider:        jsii.String("CodeBuild"),
+				Version:         jsii.String("1"),
+				InputArtifacts:  jsii.Strings("source"),
+				OutputArtifacts: jsii.Strings("build"),
+				Configuration:   &map[string]*string{"ProjectName": build.Name()},
+			}},
+		},
+	}
+	if pipeline.ApproveDeploy {
+		stages = append(stages, &codepipeline.CodepipelineStage{
+			Name: jsii.String("Approve"),
+			Action: []*codepipeline.CodepipelineStageAction{{
+				Name:     jsii.String("Approve"),
+				Category: jsii.String("Approval"),
+				Owner:    jsii.String("AWS"),
+				Provider: jsii.String("Manual"),
+				Version:  jsii.String("1"),
+			}},
+		})
+	}
+	if deploy != nil {
+		stages = append(stages, &codepipeline.CodepipelineStage{
+			Name: jsii.String("Deploy"),
+			Action: []*codepipeline.CodepipelineStageAction{{
+				Name:           jsii.String("Deploy"),
+				Category:       jsii.String("Build"),
+				Owner:          jsii.String("AWS"),
+				Provider:       jsii.String("CodeBuild"),
+				Version:        jsii.String("1"),
+				InputArtifacts: jsii.Strings("source", "build"),
+				// The deploy commands run in the checkout; the build's output is in CODEBUILD_SRC_DIR_build
+				Configuration: &map[string]*string{"ProjectName": deploy.Name(), "PrimarySource": jsii.String("source")},
+			}},
+		})
+	}
+
+	role := newServiceRole(stack, "pipeline_role", resourceName(config, "aws_iam_role", "pipeline"), "codepipeline.amazonaws.com", config)
+	addInlinePolicy(stack, "pipeline_role_policy", role, statements...)
+
+	codepipeline.NewCodepipeline(stack, jsii.String("pipeline"), &codepipeline.CodepipelineConfig{
+		Name:         jsii.String(pipelineName),
+		RoleArn:      role.Arn(),
+		PipelineType: jsii.String("V2"),
+		// Runs wait for the one ahead of them rather than replacing it, so a deploy is never cut short
+		ExecutionMode: jsii.String("QUEUED"),
+		ArtifactStore: []*codepipeline.CodepipelineArtifactStore{{
+			Type:     jsii.String("S3"),
+			Location: bucket.Bucket(),
+		}},
+		Stage: stages,
+	})
+
+	if source.Provider == "codecommit" {
+		pipelineArn := fmt.Sprintf("arn:aws:codepipeline:%s:%s:%s", config.Region, account, pipelineName)
+		eventsRole := newServiceRole(stack, "pipeline_events_role", resourceName(config, "aws_iam_role", "pipeline-events"),
+			"events.amazonaws.com", config)
+		addInlinePolicy(stack, "pipeline_events_role_policy", eventsRole, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   "codepipeline:StartPipelineExecution",
+			"Resource": pipelineArn,
+		})
+		pattern, _ := json.Marshal(map[string]interface{}{
+			"source":      []string{"aws.codecommit"},
+			"detail-type": []string{"CodeCommit Repository State Change"},
+			"resources":   []string{repositoryArn},
+			"detail": map[string]interface{}{
+				"event":         []string{"referenceCreated", "referenceUpdated"},
+				"referenceType": []string{"branch"},
+				"referenceName": []string{source.branch()},
+			},
+		})
+		rule := cloudwatcheventrule.NewCloudwatchEventRule(stack, jsii.String("pipeline_trigger"), &cloudwatcheventrule.CloudwatchEventRuleConfig{
+			Name:         jsii.String(resourceName(config, "aws_cloudwatch_event_rule", pipeline.name()+"-trigger")),
+			Description:  jsii.String("Starts the " + pipelineName + " pipeline on pushes to " + source.branch()),
+			EventPattern: jsii.String(string(pattern)),
+		})
+		cloudwatcheventtarget.NewCloudwatchEventTarget(stack, jsii.String("pipeline_trigger_target"), &cloudwatcheventtarget.CloudwatchEventTargetConfig{
+			Rule:    rule.Name(),
+			Arn:     jsii.String(pipelineArn),
+			RoleArn: eventsRole.Arn(),
+		})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("pipeline_name"), &cdktf.TerraformOutputConfig{
+		Value:       jsii.String(pipelineName),
+		Description: jsii.String("The CodePipeline pipeline building and deploying " + source.Repository),
+	})
+
+	fmt.Printf("  ✓ Pipeline %s (%d stage(s), %s source)\n", pipelineName, len(stages), source.Provider)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// ProviderBuilder builds the section of a registered provider into its stack. settings is the
+// section's JSON from plugins.<name>, for the builder to decode into its own type.
+type ProviderBuilder func(stack cdktf.TerraformStack, config Config, settings json.RawMessage) error
+
+// providerRegistry maps the names passed to RegisterProvider to their builders
+var providerRegistry = map[string]ProviderBuilder{}
+
+// RegisterProvider adds an org-specific provider without changing the builders in this tree. Call
+// it from the init function of a file of its own, typically behind a build tag so builds without
+// the provider's bindings are unaffected:
+//
+//	//go:build acme
+//
+//	func init() {
+//		RegisterProvider("acme", func(stack cdktf.TerraformStack, config Config, settings json.RawMessage) error {
+//			addRawProvider(stack, config, "acme", "acme/acme", "~> 1.0", map[string]interface{}{})
+//			...
+//		})
+//	}
+//
+// The section is then configured as plugins.acme and can be assigned to a stack by its name.
+// provider_versions.acme is accepted too, so the name should be the provider's local name, which
+// is also the prefix newRawResource expects of its resource types.
+func RegisterProvider(name string, builder ProviderBuilder) {
+	if !blockLabel.MatchString(name) {
+		panic(fmt.Sprintf("RegisterProvider: %q must be letters, digits, _ or -", name))
+	}
+	if slices.Contains(stackSections, name) {
+		panic(fmt.Sprintf("RegisterProvider: %s is a built-in section", name))
+	}
+	if _, ok := providerRegistry[name]; ok {
+		panic(fmt.Sprintf("RegisterProvider: %s is already registered", name))
+	}
+	providerRegistry[name] = builder
+	versionedProviders[name] = true
+}
+
+func validatePlugins(plugins map[string]json.RawMessage) error {
+	for _, name := range slices.Sorted(maps.Keys(plugins)) {
+		if _, ok := providerRegistry[name]; !ok {
+			suggestion := closestMatch(name, slices.Sorted(maps.Keys(providerRegistry)))
+			if suggestion == "" {
+				suggestion = "build with the file that calls RegisterProvider for it, and its build tag"
+			}
+			return invalidValue(name, suggestion, "%s is not a registered provider; build with the file that registers it", name)
+		}
+	}
+	return nil
+}
+
+// addPlugins builds the configured sections of registered providers, in name order, once every
+// built-in section exists
+func addPlugins(stacks *stackSet, config Config) error {
+	for _, name := range slices.Sorted(maps.Keys(config.Plugins)) {
+		if err := providerRegistry[name](stacks.forSection(name), config, config.Plugins[name]); err != nil {
+			return fmt.Errorf("plugins: %s: %w", name, err)
+		}
+		fmt.Printf("  ✓ Registered provider %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// policyViolation is one failed or warned rule, as written to the results file
+type policyViolation struct {
+	Stack     string                 `json:"stack"`
+	Namespace string                 `json:"namespace"`
+	Severity  string                 `json:"severity"` // deny or warn
+	Message   string                 `json:"message"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// conftestResult is one entry of `conftest test --output json`
+type conftestResult struct {
+	Filename  string `json:"filename"`
+	Namespace string `json:"namespace"`
+	Successes int    `json:"successes"`
+	Failures  []struct {
+		Msg      string                 `json:"msg"`
+		Metadata map[string]interface{} `json:"metadata"`
+	} `json:"failures"`
+	Warnings []struct {
+		Msg      string                 `json:"msg"`
+		Metadata map[string]interface{} `json:"metadata"`
+	} `json:"warnings"`
+}
+
+// runPolicy evaluates every synthesized stack against the Rego policies with conftest, writes the
+// violations as JSON and fails when any deny rule matched
+func runPolicy(args []string) error {
+	flags := flag.NewFlagSet("policy", flag.ExitOnError)
+	policyDir := flags.String("policy", "policy", "directory of Rego policies")
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	output := flags.String("output", "", "results file (default <outdir>/policy-results.json)")
+	flags.Parse(args)
+
+	if _, err := exec.LookPath("conftest"); err != nil {
+		return fmt.Errorf("conftest not found on PATH: %w", err)
+	}
+	stackNames, err := synthesizedStacks(*outdir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔎 Checking %d stack(s) against %s/...\n", len(stackNames), *policyDir)
+	files := map[string]string{}
+	commandArgs := []string{"test", "--policy", *policyDir, "--all-namespaces", "--output", "json", "--no-color"}
+	for _, name := range stackNames {
+		file := filepath.Join(*outdir, "stacks", name, "cdk.tf.json")
+		files[file] = name
+		commandArgs = append(commandArgs, file)
+	}
+
+	// conftest exits 1 when a policy fails, with the results still on stdout
+	stdout, err := exec.Command("conftest", commandArgs...).Output()
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(stdout) > 0) {
+		if exitErr != nil {
+			return fmt.Errorf("running conftest: %w: %s", err, exitErr.Stderr)
+		}
+		return fmt.Errorf("running conftest: %w", err)
+	}
+	var results []conftestResult
+	if err := json.Unmarshal(stdout, &results); err != nil {
+		return fmt.Errorf("parsing conftest output: %w", err)
+	}
+
+	violations := []policyViolation{}
+	denied, passed := 0, 0
+	for _, result := range results {
+		stack := files[result.Filename]
+		passed += result.Successes
+		for _, failure := range result.Failures {
+			violations = append(violations, policyViolation{stack, result.Namespace, "deny", failure.Msg, failure.Metadata})
+			fmt.Printf("  ✗ [%s] %s\n", stack, failure.Msg)
+			denied++
+		}
+		for _, warning := range result.Warnings {
+			violations = append(violations, policyViolation{stack, result.Namespace, "warn", warning.Msg, warning.Metadata})
+			fmt.Printf("  ⚠ [%s] %s\n", stack, warning.Msg)
+		}
+	}
+
+	path := *output
+	if path == "" {
+		path = filepath.Join(*outdir, "policy-results.json")
+	}
+	data, err := json.MarshalIndent(violations, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+
+	fmt.Printf("  %d passed, %d denied, %d warning(s); results in %s\n", passed, denied, len(violations)-denied, path)
+	if denied > 0 {
+		return fmt.Errorf("%d policy violation(s)", denied)
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+# Inline and managed policies never grant every action on every resource.
+package iam
+
+import rego.v1
+
+policy_types := {"aws_iam_role_policy", "aws_iam_policy", "aws_iam_user_policy", "aws_iam_group_policy"}
+
+as_list(value) := value if is_array(value)
+
+as_list(value) := [value] if not is_array(value)
+
+deny contains msg if {
+	some type in policy_types
+	some name, policy in object.get(input.resource, type, {})
+	document := json.unmarshal(policy.policy)
+	some statement in as_list(document.Statement)
+	statement.Effect == "Allow"
+	"*" in as_list(statement.Action)
+	"*" in as_list(statement.Resource)
+	msg := sprintf("%s.%s allows * on *", [type, name])
+}
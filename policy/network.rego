@@ -0,0 +1,39 @@
+# Administrative ports are never open to the internet.
+package network
+
+import rego.v1
+
+admin_ports := {22, 3389}
+
+open_to_world(rule) if "0.0.0.0/0" in rule.cidr_blocks
+
+open_to_world(rule) if "::/0" in rule.ipv6_cidr_blocks
+
+exposes_admin_port(rule) if {
+	some port in admin_ports
+	rule.from_port <= port
+	port <= rule.to_port
+}
+
+deny contains msg if {
+	some name, group in input.resource.aws_security_group
+	some rule in object.get(group, "ingress", [])
+	open_to_world(rule)
+	exposes_admin_port(rule)
+	msg := sprintf("aws_security_group.%s opens ports %d-%d to the internet", [name, rule.from_port, rule.to_port])
+}
+
+deny contains msg if {
+	some name, rule in input.resource.aws_security_group_rule
+	rule.type == "ingress"
+	open_to_world(rule)
+	exposes_admin_port(rule)
+	msg := sprintf("aws_security_group_rule.%s opens ports %d-%d to the internet", [name, rule.from_port, rule.to_port])
+}
+
+deny contains msg if {
+	some name, rule in input.resource.aws_vpc_security_group_ingress_rule
+	rule.cidr_ipv4 == "0.0.0.0/0"
+	exposes_admin_port(rule)
+	msg := sprintf("aws_vpc_security_group_ingress_rule.%s opens ports %d-%d to the internet", [name, rule.from_port, rule.to_port])
+}
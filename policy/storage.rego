@@ -0,0 +1,31 @@
+# Buckets stay private and state is never kept unencrypted.
+package storage
+
+import rego.v1
+
+public_acls := {"public-read", "public-read-write", "authenticated-read"}
+
+deny contains msg if {
+	some name, acl in input.resource.aws_s3_bucket_acl
+	acl.acl in public_acls
+	msg := sprintf("aws_s3_bucket_acl.%s grants %s", [name, acl.acl])
+}
+
+deny contains msg if {
+	some name, block in input.resource.aws_s3_bucket_public_access_block
+	some setting in ["block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"]
+	block[setting] == false
+	msg := sprintf("aws_s3_bucket_public_access_block.%s disables %s", [name, setting])
+}
+
+deny contains msg if {
+	backend := input.terraform.backend.s3
+	not backend.encrypt
+	msg := "the s3 backend must set encrypt"
+}
+
+warn contains msg if {
+	some name, bucket in input.resource.aws_s3_bucket
+	bucket.force_destroy == true
+	msg := sprintf("aws_s3_bucket.%s sets force_destroy", [name])
+}
@@ -0,0 +1,14 @@
+# Every AWS provider must stamp the platform tags on the resources it creates.
+package tagging
+
+import rego.v1
+
+required_tags := {"Project", "Environment", "ManagedBy"}
+
+deny contains msg if {
+	some provider in input.provider.aws
+	tags := object.get(provider, ["default_tags", 0, "tags"], {})
+	missing := required_tags - object.keys(tags)
+	count(missing) > 0
+	msg := sprintf("aws provider %s is missing default tags %v", [object.get(provider, "alias", "default"), missing])
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// startProfiling writes a CPU profile and an execution trace of the synth to dir, and returns the
+// function that stops them and adds a heap profile. Most synth time is spent in the jsii runtime,
+// a node process of its own, so the Go profiles show it as time waiting on jsii calls; the trace
+// shows which builder made them.
+func startProfiling(dir string) (func() error, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	cpuFile, err := os.Create(filepath.Join(dir, "cpu.pprof"))
+	if err != nil {
+		return nil, err
+	}
+	if err := pprof.StartCPUProfile(cpuFile); err != nil {
+		cpuFile.Close()
+		return nil, err
+	}
+	traceFile, err := os.Create(filepath.Join(dir, "trace.out"))
+	if err != nil {
+		pprof.StopCPUProfile()
+		cpuFile.Close()
+		return nil, err
+	}
+	if err := trace.Start(traceFile); err != nil {
+		pprof.StopCPUProfile()
+		cpuFile.Close()
+		traceFile.Close()
+		return nil, err
+	}
+
+	return func() error {
+		trace.Stop()
+		pprof.StopCPUProfile()
+		if err := traceFile.Close(); err != nil {
+			return err
+		}
+		if err := cpuFile.Close(); err != nil {
+			return err
+		}
+
+		heapFile, err := os.Create(filepath.Join(dir, "heap.pprof"))
+		if err != nil {
+			return err
+		}
+		defer heapFile.Close()
+		// Collect first, so the profile shows what is still live at the end of the synth
+		runtime.GC()
+		if err := pprof.WriteHeapProfile(heapFile); err != nil {
+			return err
+		}
+		fmt.Printf("✓ Profiles written to %s (go tool pprof %s)\n", dir, filepath.Join(dir, "cpu.pprof"))
+		return nil
+	}, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// The providers go.mod has no Go bindings for (azurerm, cloudflare) are declared with plain
+// constructs that synthesize the settings and attributes they are given.
+
+type rawProvider struct {
+	cdktf.TerraformProvider
+	settings map[string]interface{}
+}
+
+func (p *rawProvider) SynthesizeAttributes() *map[string]interface{} {
+	return &p.settings
+}
+
+// addRawProvider declares a provider of a stack, from its registry source, with
+// provider_versions.<name> or else defaultVersion as its version constraint
+func addRawProvider(stack cdktf.TerraformStack, config Config, name string, source string, defaultVersion string,
+	settings map[string]interface{}) {
+	version := defaultVersion
+	if constraint := config.ProviderVersions[name]; constraint != "" {
+		version = constraint
+	}
+	cdktf.NewTerraformProvider_Override(&rawProvider{settings: settings}, stack, jsii.String(name), &cdktf.TerraformProviderConfig{
+		TerraformResourceType: jsii.String(name),
+		TerraformGeneratorMetadata: &cdktf.TerraformProviderGeneratorMetadata{
+			ProviderName:              jsii.String(name),
+			ProviderVersionConstraint: jsii.String(version),
+		},
+		TerraformProviderSource: jsii.String(source),
+	})
+}
+
+// escapeInterpolation escapes the ${ and %{ sequences of file content passed to an attribute, so
+// Terraform takes the file as-is instead of interpolating it
+func escapeInterpolation(content string) string {
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(content)
+}
+
+type rawResource struct {
+	cdktf.TerraformResource
+	attributes map[string]interface{}
+}
+
+func (r *rawResource) SynthesizeAttributes() *map[string]interface{} {
+	return &r.attributes
+}
+
+type rawDataSource struct {
+	cdktf.TerraformDataSource
+	attributes map[string]interface{}
+}
+
+func (d *rawDataSource) SynthesizeAttributes() *map[string]interface{} {
+	return &d.attributes
+}
+
+// rawResourceConfig is the config of a resource or data source type, whose provider is the
+// type's prefix, such as azurerm for azurerm_storage_account
+func rawResourceConfig(resourceType string) *cdktf.TerraformResourceConfig {
+	providerName, _, _ := strings.Cut(resourceType, "_")
+	return &cdktf.TerraformResourceConfig{
+		TerraformResourceType: jsii.String(resourceType),
+		TerraformGeneratorMetadata: &cdktf.TerraformProviderGeneratorMetadata{
+			ProviderName: jsii.String(providerName),
+		},
+	}
+}
+
+func newRawResource(stack cdktf.TerraformStack, resourceType string, id string, attributes map[string]interface{}) *rawResource {
+	resource := &rawResource{attributes: attributes}
+	cdktf.NewTerraformResource_Override(resource, stack, jsii.String(id), rawResourceConfig(resourceType))
+	return resource
+}
+
+func newRawDataSource(stack cdktf.TerraformStack, resourceType string, id string, attributes map[string]interface{}) *rawDataSource {
+	source := &rawDataSource{attributes: attributes}
+	cdktf.NewTerraformDataSource_Override(source, stack, jsii.String(id), rawResourceConfig(resourceType))
+	return source
+}
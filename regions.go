@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// regionPartitions maps the AWS regions to their partition. Add a region here when AWS opens one;
+// until then configs using it fail validation.
+var regionPartitions = map[string]string{
+	"af-south-1":     "aws",
+	"ap-east-1":      "aws",
+	"ap-east-2":      "aws",
+	"ap-northeast-1": "aws",
+	"ap-northeast-2": "aws",
+	"ap-northeast-3": "aws",
+	"ap-south-1":     "aws",
+	"ap-south-2":     "aws",
+	"ap-southeast-1": "aws",
+	"ap-southeast-2": "aws",
+	"ap-southeast-3": "aws",
+	"ap-southeast-4": "aws",
+	"ap-southeast-5": "aws",
+	"ap-southeast-6": "aws",
+	"ap-southeast-7": "aws",
+	"ca-central-1":   "aws",
+	"ca-west-1":      "aws",
+	"eu-central-1":   "aws",
+	"eu-central-2":   "aws",
+	"eu-north-1":     "aws",
+	"eu-south-1":     "aws",
+	"eu-south-2":     "aws",
+	"eu-west-1":      "aws",
+	"eu-west-2":      "aws",
+	"eu-west-3":      "aws",
+	"il-central-1":   "aws",
+	"me-central-1":   "aws",
+	"me-south-1":     "aws",
+	"mx-central-1":   "aws",
+	"sa-east-1":      "aws",
+	"us-east-1":      "aws",
+	"us-east-2":      "aws",
+	"us-west-1":      "aws",
+	"us-west-2":      "aws",
+	"cn-north-1":     "aws-cn",
+	"cn-northwest-1": "aws-cn",
+	"us-gov-east-1":  "aws-us-gov",
+	"us-gov-west-1":  "aws-us-gov",
+	"eusc-de-east-1": "aws-eusc",
+}
+
+// availabilityZone matches availability zone names, which are the region with a letter appended,
+// such as us-west-2a
+var availabilityZone = regexp.MustCompile(`^([a-z]{2,4}(-[a-z]+)+-\d)([a-z])$`)
+
+// validateRegion checks a region name, suggesting the closest known one
+func validateRegion(region string) error {
+	if _, ok := regionPartitions[region]; !ok {
+		return invalidValue(region, closestMatch(region, slices.Sorted(maps.Keys(regionPartitions))),
+			"%q is not an AWS region", region)
+	}
+	return nil
+}
+
+// arnPartition returns the partition of an ARN, or "" for anything else
+func arnPartition(arn string) string {
+	parts := strings.SplitN(arn, ":", 3)
+	if len(parts) < 3 || parts[0] != "arn" {
+		return ""
+	}
+	return parts[1]
+}
+
+// validateSamePartition checks that an ARN is in the partition of the config's region, as
+// credentials and roles don't work across partitions
+func validateSamePartition(field, arn, partition string) error {
+	if other := arnPartition(arn); other != "" && other != partition {
+		return invalidValue(arn, fmt.Sprintf("use an ARN starting with arn:%s:", partition),
+			"%s %q is in partition %s, but region is in %s", field, arn, other, partition)
+	}
+	return nil
+}
+
+// findZones calls found with the path of every string in a decoded JSON value that is an
+// availability zone name
+func findZones(value interface{}, path string, found func(path, zone string) error) error {
+	switch value := value.(type) {
+	case string:
+		if availabilityZone.MatchString(value) {
+			return found(path, value)
+		}
+	case map[string]interface{}:
+		for _, key := range slices.Sorted(maps.Keys(value)) {
+			if err := findZones(value[key], strings.TrimPrefix(path+"."+key, "."), found); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, element := range value {
+			if err := findZones(element, fmt.Sprintf("%s[%d]", path, i), found); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateRegions checks the region of the config and of every block with a region of its own
+// against the known AWS regions. The regions of the S3 backend, S3 remote states and deploy roles
+// must be in the same partition as the config's. Availability zones anywhere in the config, such
+// as in module inputs, must be in its region. Azure locations aren't checked.
+func validateRegions(config Config) error {
+	if usesAzure(config) {
+		return nil
+	}
+	if config.Region == "" {
+		return fmt.Errorf("region is required")
+	}
+	if err := validateRegion(config.Region); err != nil {
+		return fmt.Errorf("region: %w", err)
+	}
+	partition := regionPartitions[config.Region]
+
+	if config.Backend != nil && config.Backend.kind() == "s3" && config.Backend.Region != "" {
+		if err := validateRegion(config.Backend.Region); err != nil {
+			return fmt.Errorf("backend: region: %w", err)
+		}
+		if other := regionPartitions[config.Backend.Region]; other != partition {
+			return fmt.Errorf("backend: region %s is in partition %s, but region %s is in %s",
+				config.Backend.Region, other, config.Region, partition)
+		}
+	}
+	for _, remote := range config.RemoteState {
+		if remote.S3 == nil {
+			continue
+		}
+		if remote.S3.Region != "" {
+			if err := validateRegion(remote.S3.Region); err != nil {
+				return fmt.Errorf("remote_state: %s: region: %w", remote.Name, err)
+			}
+			if other := regionPartitions[remote.S3.Region]; other != partition {
+				return fmt.Errorf("remote_state: %s: region %s is in partition %s, but region %s is in %s",
+					remote.Name, remote.S3.Region, other, config.Region, partition)
+			}
+		}
+		if err := validateSamePartition("role_arn", remote.S3.RoleArn, partition); err != nil {
+			return fmt.Errorf("remote_state: %s: %w", remote.Name, err)
+		}
+	}
+	if config.GlobalAccelerator != nil {
+		for i, listener := range config.GlobalAccelerator.Listeners {
+			for _, group := range listener.EndpointGroups {
+				if err := validateRegion(group.Region); err != nil {
+					return fmt.Errorf("global_accelerator: listener %d: region: %w", i, err)
+				}
+			}
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(config.Environments)) {
+		environment := config.Environments[name]
+		if err := validateSamePartition("deploy_role_arn", environment.DeployRoleARN, partition); err != nil {
+			return fmt.Errorf("environments: %s: %w", name, err)
+		}
+		if environment.WebIdentity != nil {
+			if err := validateSamePartition("web_identity.role_arn", environment.WebIdentity.RoleARN, partition); err != nil {
+				return fmt.Errorf("environments: %s: %w", name, err)
+			}
+		}
+	}
+
+	// Walk the config as it was parsed, so every section is covered without listing its fields
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	var document interface{}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return err
+	}
+	return findZones(document, "", func(path, zone string) error {
+		// Other strings can look like zone names; only those of a known region are zones
+		region := availabilityZone.FindStringSubmatch(zone)[1]
+		if _, ok := regionPartitions[region]; ok && region != config.Region {
+			return fmt.Errorf("%s: %w", path, invalidValue(zone, fmt.Sprintf("use a zone of %s, such as %sa", config.Region, config.Region),
+				"availability zone %s is in %s, not region %s", zone, region, config.Region))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// RemoteStateConfig reads the outputs of a state file this config doesn't own, such as another
+// team's network stack. Exactly one of s3 or workspace says where the state lives.
+type RemoteStateConfig struct {
+	Name      string                 `json:"name"`
+	S3        *RemoteStateS3         `json:"s3,omitempty"`
+	Workspace *RemoteStateWorkspace  `json:"workspace,omitempty"`
+	Defaults  map[string]interface{} `json:"defaults,omitempty"` // used for outputs the state doesn't have yet
+}
+
+type RemoteStateS3 struct {
+	Bucket  string `json:"bucket"`
+	Key     string `json:"key"`
+	Region  string `json:"region"`   // defaults to the config region
+	RoleArn string `json:"role_arn"` // assumed to read the state from another account
+}
+
+// RemoteStateWorkspace is an HCP Terraform (Terraform Cloud) or Terraform Enterprise workspace
+type RemoteStateWorkspace struct {
+	Organization string `json:"organization"`
+	Name         string `json:"name"`
+	Hostname     string `json:"hostname"` // defaults to app.terraform.io
+}
+
+// remoteReference matches ${remote.<name>.<output>} in config values, with an optional list index
+var remoteReference = regexp.MustCompile(`\$\{remote\.([A-Za-z0-9_-]+)\.([A-Za-z0-9_]+)((?:\[\d+\])?)\}`)
+
+// expandRemoteReferences rewrites ${remote.<name>.<output>} in a raw config into the Terraform
+// expression reading that output, and returns the remote state names referenced
+func expandRemoteReferences(raw []byte) ([]byte, []string) {
+	seen := map[string]bool{}
+	var names []string
+	for _, match := range remoteReference.FindAllSubmatch(raw, -1) {
+		if name := string(match[1]); !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return remoteReference.ReplaceAll(raw, []byte("$${data.terraform_remote_state.$1.outputs.$2$3}")), names
+}
+
+func validateRemoteStates(remoteStates []RemoteStateConfig, referenced []string) error {
+	declared := map[string]bool{}
+	for _, remote := range remoteStates {
+		if !blockLabel.MatchString(remote.Name) {
+			return fmt.Errorf("remote state name %q must be letters, digits, _ or -", remote.Name)
+		}
+		if declared[remote.Name] {
+			return fmt.Errorf("remote state %s is declared twice", remote.Name)
+		}
+		declared[remote.Name] = true
+		if (remote.S3 == nil) == (remote.Workspace == nil) {
+			return fmt.Errorf("remote state %s needs exactly one of s3 or workspace", remote.Name)
+		}
+		if remote.S3 != nil && (remote.S3.Bucket == "" || remote.S3.Key == "") {
+			return fmt.Errorf("remote state %s: s3 needs a bucket and key", remote.Name)
+		}
+		if remote.Workspace != nil && (remote.Workspace.Organization == "" || remote.Workspace.Name == "") {
+			return fmt.Errorf("remote state %s: workspace needs an organization and name", remote.Name)
+		}
+	}
+	for _, name := range referenced {
+		if !declared[name] {
+			return fmt.Errorf("${remote.%s...} is used but no remote state %s is declared", name, name)
+		}
+	}
+	return nil
+}
+
+// addRemoteStates declares each remote state in the stacks whose resources reference it, so a
+// stack only reads the state files it needs
+func addRemoteStates(app cdktf.App, config Config) {
+	used := map[string][]string{}
+	for _, document := range stackDocuments(app) {
+		for _, remote := range config.RemoteState {
+			if !strings.Contains(document.json, "data.terraform_remote_state."+remote.Name+".") {
+				continue
+			}
+			addRemoteState(document.stack, config, remote)
+			used[remote.Name] = append(used[remote.Name], *document.stack.Node().Id())
+		}
+	}
+
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  ✓ Remote state %s read by %s\n", name, strings.Join(used[name], ", "))
+	}
+}
+
+func addRemoteState(stack cdktf.TerraformStack, config Config, remote RemoteStateConfig) {
+	var defaults *map[string]interface{}
+	if len(remote.Defaults) > 0 {
+		defaults = &remote.Defaults
+	}
+
+	if remote.S3 != nil {
+		region := remote.S3.Region
+		if region == "" {
+			region = config.Region
+		}
+		s3Config := &cdktf.DataTerraformRemoteStateS3Config{
+			Bucket:   jsii.String(remote.S3.Bucket),
+			Key:      jsii.String(remote.S3.Key),
+			Region:   jsii.String(region),
+			Defaults: defaults,
+		}
+		if remote.S3.RoleArn != "" {
+			s3Config.AssumeRole = &cdktf.S3BackendAssumeRoleConfig{RoleArn: jsii.String(remote.S3.RoleArn)}
+		}
+		cdktf.NewDataTerraformRemoteStateS3(stack, jsii.String(remote.Name), s3Config)
+		return
+	}
+
+	workspaceConfig := &cdktf.DataTerraformRemoteStateRemoteConfig{
+		Organization: jsii.String(remote.Workspace.Organization),
+		Workspaces:   cdktf.NewNamedRemoteWorkspace(jsii.String(remote.Workspace.Name)),
+		Defaults:     defaults,
+	}
+	if remote.Workspace.Hostname != "" {
+		workspaceConfig.Hostname = jsii.String(remote.Workspace.Hostname)
+	}
+	cdktf.NewDataTerraformRemoteState(stack, jsii.String(remote.Name), workspaceConfig)
+}
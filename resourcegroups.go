@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/resourcegroupsgroup"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// stackTag is the default tag naming the stack that created a resource, set with resource_groups
+const stackTag = "Stack"
+
+// addResourceGroups gives every created stack a resource group of what it deployed: the resources
+// tagged with the config's project and environment and with the stack's name
+func addResourceGroups(stacks *stackSet, config Config) {
+	for _, suffix := range slices.Sorted(maps.Keys(stacks.stacks)) {
+		stack := stacks.stacks[suffix]
+		stackName := *stack.Node().Id()
+		query, _ := json.Marshal(map[string]interface{}{
+			"ResourceTypeFilters": []string{"AWS::AllSupported"},
+			"TagFilters": []map[string]interface{}{
+				{"Key": "Project", "Values": []string{config.Project}},
+				{"Key": "Environment", "Values": []string{config.Environment}},
+				{"Key": stackTag, "Values": []string{stackName}},
+			},
+		})
+		group := resourcegroupsgroup.NewResourcegroupsGroup(stack, jsii.String("resource_group"), &resourcegroupsgroup.ResourcegroupsGroupConfig{
+			Name:        jsii.String(resourceName(config, "aws_resourcegroups_group", suffix)),
+			Description: jsii.String(fmt.Sprintf("Resources of %s, deployed from the %s %s config", stackName, config.Project, config.Environment)),
+			ResourceQuery: &resourcegroupsgroup.ResourcegroupsGroupResourceQuery{
+				Type:  jsii.String("TAG_FILTERS_1_0"),
+				Query: jsii.String(string(query)),
+			},
+		})
+		cdktf.NewTerraformOutput(stack, jsii.String("resource_group_arn"), &cdktf.TerraformOutputConfig{
+			Value:       group.Arn(),
+			Description: jsii.String("The resource group of the stack's resources"),
+		})
+	}
+	fmt.Printf("  ✓ Resource group for each of %d stack(s)\n", len(stacks.stacks))
+}
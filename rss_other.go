@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// peakRSS isn't reported outside unix systems
+func peakRSS(state *os.ProcessState) uint64 {
+	return 0
+}
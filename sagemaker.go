@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrolepolicy"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/sagemakerendpoint"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/sagemakerendpointconfiguration"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/sagemakermodel"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// SageMakerConfig deploys models to SageMaker real-time inference endpoints, one endpoint per model
+type SageMakerConfig struct {
+	Models []SageMakerModel `json:"models"`
+}
+
+type SageMakerModel struct {
+	Name  string `json:"name"`
+	Image string `json:"image"` // the inference container, from ECR
+	// ModelData is the model.tar.gz of the artifacts: a key in the config bucket, or an s3:// URI
+	ModelData   string            `json:"model_data"`
+	Environment map[string]string `json:"environment"`
+	// InstanceType hosts the model on instances, ml.m5.large by default; serverless endpoints
+	// scale to zero instead
+	InstanceType  string               `json:"instance_type"`
+	InstanceCount float64              `json:"instance_count"` // 1 by default
+	Serverless    *SageMakerServerless `json:"serverless,omitempty"`
+	VPC           *VPCConfig           `json:"vpc,omitempty"`
+	KMSKeyArn     string               `json:"kms_key_arn"` // encrypts the endpoint's storage volumes
+}
+
+type SageMakerServerless struct {
+	MemorySizeMB   float64 `json:"memory_size_mb"`  // 1024 to 6144 in steps of 1024
+	MaxConcurrency float64 `json:"max_concurrency"` // 1 to 200, 5 by default
+}
+
+// modelDataURL is the S3 URL of the model's artifacts
+func (m SageMakerModel) modelDataURL(bucket s3bucket.S3Bucket) string {
+	if strings.HasPrefix(m.ModelData, "s3://") {
+		return m.ModelData
+	}
+	return "s3://" + *bucket.Bucket() + "/" + strings.TrimPrefix(m.ModelData, "/")
+}
+
+func (s *SageMakerConfig) validate() error {
+	if len(s.Models) == 0 {
+		return fmt.Errorf("at least one model is required")
+	}
+	names := map[string]bool{}
+	for i, model := range s.Models {
+		if !nameSegmentPattern.MatchString(model.Name) {
+			return invalidValue(model.Name, "", "models[%d]: name %q must be lowercase letters, digits and hyphens", i, model.Name)
+		}
+		if names[model.Name] {
+			return fmt.Errorf("models[%d]: duplicate name %q", i, model.Name)
+		}
+		names[model.Name] = true
+		// SageMaker only pulls inference images from private ECR repositories
+		if !strings.Contains(model.Image, ".dkr.ecr.") {
+			return fmt.Errorf("models.%s: image must be an ECR image URI", model.Name)
+		}
+		if model.ModelData == "" {
+			return fmt.Errorf("models.%s: model_data is required", model.Name)
+		}
+		if strings.HasPrefix(model.ModelData, "s3://") && len(strings.SplitN(strings.TrimPrefix(model.ModelData, "s3://"), "/", 2)) != 2 {
+			return fmt.Errorf("models.%s: model_data %q needs a bucket and a key", model.Name, model.ModelData)
+		}
+		if serverless := model.Serverless; serverless != nil {
+			if model.InstanceType != "" || model.InstanceCount != 0 {
+				return fmt.Errorf("models.%s: serverless endpoints have no instance_type or instance_count", model.Name)
+			}
+			if serverless.MemorySizeMB < 1024 || serverless.MemorySizeMB > 6144 || int(serverless.MemorySizeMB)%1024 != 0 {
+				return fmt.Errorf("models.%s: serverless.memory_size_mb must be a multiple of 1024 between 1024 and 6144", model.Name)
+			}
+			if serverless.MaxConcurrency < 0 || serverless.MaxConcurrency > 200 {
+				return fmt.Errorf("models.%s: serverless.max_concurrency must be between 1 and 200", model.Name)
+			}
+			if model.KMSKeyArn != "" {
+				return fmt.Errorf("models.%s: serverless endpoints have no storage volumes for kms_key_arn", model.Name)
+			}
+		}
+		if model.InstanceType != "" && !strings.HasPrefix(model.InstanceType, "ml.") {
+			return invalidValue(model.InstanceType, "ml."+model.InstanceType, "models.%s: instance_type %q must be an ml. instance type", model.Name, model.InstanceType)
+		}
+		if model.VPC != nil && len(model.VPC.SubnetIDs) == 0 {
+			return fmt.Errorf("models.%s: vpc.subnet_ids is required", model.Name)
+		}
+	}
+	return nil
+}
+
+// addSageMaker creates an execution role shared by the models, then each model with its endpoint
+// configuration and endpoint
+func addSageMaker(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket) {
+	sagemaker := config.SageMaker
+
+	// The execution role pulls the images and artifacts and writes the containers' logs and metrics
+	statements := []map[string]interface{}{
+		bucketAccessStatement(bucket, false),
+		{
+			"Effect":   "Allow",
+			"Action":   []string{"ecr:GetAuthorizationToken", "ecr:BatchGetImage", "ecr:GetDownloadUrlForLayer", "ecr:BatchCheckLayerAvailability"},
+			"Resource": "*",
+		},
+		{
+			"Effect":   "Allow",
+			"Action":   []string{"logs:CreateLogGroup", "logs:CreateLogStream", "logs:PutLogEvents", "logs:DescribeLogStreams"},
+			"Resource": fmt.Sprintf("arn:aws:logs:%s:%s:log-group:/aws/sagemaker/*", config.Region, *accountID(stack)),
+		},
+		{
+			"Effect":    "Allow",
+			"Action":    "cloudwatch:PutMetricData",
+			"Resource":  "*",
+			"Condition": map[string]interface{}{"StringLike": map[string]string{"cloudwatch:namespace": "/aws/sagemaker/*"}},
+		},
+	}
+	var artifacts []string
+	inVPC := false
+	for _, model := range sagemaker.Models {
+		if strings.HasPrefix(model.ModelData, "s3://") {
+			arn := "arn:aws:s3:::" + strings.TrimPrefix(model.ModelData, "s3://")
+			if !slices.Contains(artifacts, arn) {
+				artifacts = append(artifacts, arn)
+			}
+		}
+		inVPC = inVPC || model.VPC != nil
+	}
+	if len(artifacts) > 0 {
+		statements = append(statements, map[string]interface{}{
+			"Effect":   "Allow",
+			"Action":   "s3:GetObject",
+			"Resource": artifacts,
+		})
+	}
+	if inVPC {
+		statements = append(statements, map[string]interface{}{
+			"Effect": "Allow",
+			"Action": []string{
+				"ec2:CreateNetworkInterface", "ec2:CreateNetworkInterfacePermission", "ec2:DeleteNetworkInterface",
+				"ec2:DeleteNetworkInterfacePermission", "ec2:DescribeNetworkInterfaces", "ec2:DescribeVpcs",
+				"ec2:DescribeDhcpOptions", "ec2:DescribeSubnets", "ec2:DescribeSecurityGroups",
+			},
+			"Resource": "*",
+		})
+	}
+	role := newServiceRole(stack, "sagemaker_execution_role", resourceName(config, "aws_iam_role", "sagemaker-execution"),
+		"sagemaker.amazonaws.com", config)
+	// Models are checked against the role's access when they're created, so they wait for its policy
+	rolePolicy := iamrolepolicy.NewIamRolePolicy(stack, jsii.String("sagemaker_execution_role_policy"), &iamrolepolicy.IamRolePolicyConfig{
+		Role:   role.Id(),
+		Policy: jsii.String(policyDocument(statements...)),
+	})
+
+	for _, settings := range sagemaker.Models {
+		id := "sagemaker_" + strings.ReplaceAll(settings.Name, "-", "_")
+
+		container := &sagemakermodel.SagemakerModelPrimaryContainer{
+			Image:        jsii.String(settings.Image),
+			ModelDataUrl: jsii.String(settings.modelDataURL(bucket)),
+		}
+		if len(settings.Environment) > 0 {
+			container.Environment = toStringMap(settings.Environment)
+		}
+		modelConfig := &sagemakermodel.SagemakerModelConfig{
+			Name:             jsii.String(resourceName(config, "aws_sagemaker_model", settings.Name)),
+			ExecutionRoleArn: role.Arn(),
+			PrimaryContainer: container,
+			DependsOn:        &[]cdktf.ITerraformDependable{rolePolicy},
+		}
+		if settings.VPC != nil {
+			modelConfig.VpcConfig = &sagemakermodel.SagemakerModelVpcConfig{
+				Subnets:          jsii.Strings(settings.VPC.SubnetIDs...),
+				SecurityGroupIds: jsii.Strings(settings.VPC.SecurityGroupIDs...),
+			}
+		}
+		model := sagemakermodel.NewSagemakerModel(stack, jsii.String(id+"_model"), modelConfig)
+
+		variant := &sagemakerendpointconfiguration.SagemakerEndpointConfigurationProductionVariants{
+			VariantName:          jsii.String("primary"),
+			ModelName:            model.Name(),
+			InitialVariantWeight: jsii.Number(1),
+		}
+		if serverless := settings.Serverless; serverless != nil {
+			maxConcurrency := serverless.MaxConcurrency
+			if maxConcurrency == 0 {
+				maxConcurrency = 5
+			}
+			variant.ServerlessConfig = &sagemakerendpointconfiguration.SagemakerEndpointConfigurationProductionVariantsServerlessConfig{
+				MemorySizeInMb: jsii.Number(serverless.MemorySizeMB),
+				MaxConcurrency: jsii.Number(maxConcurrency),
+			}
+		} else {
+			instanceType := settings.InstanceType
+			if instanceType == "" {
+				instanceType = "ml.m5.large"
+			}
+			instanceCount := settings.InstanceCount
+			if instanceCount == 0 {
+				instanceCount = 1
+			}
+			variant.InstanceType = jsii.String(instanceType)
+			variant.InitialInstanceCount = jsii.Number(instanceCount)
+		}
+		endpointConfig := &sagemakerendpointconfiguration.SagemakerEndpointConfigurationConfig{
+			// Endpoint configurations can't be changed in place, so a replacement is created, with a
+			// name of its own, and the endpoint switched to it before the old one is removed
+			NamePrefix:         jsii.String(resourceName(config, "aws_sagemaker_endpoint_configuration", settings.Name) + "-"),
+			ProductionVariants: &[]*sagemakerendpointconfiguration.SagemakerEndpointConfigurationProductionVariants{variant},
+			Lifecycle:          &cdktf.TerraformResourceLifecycle{CreateBeforeDestroy: jsii.Bool(true)},
+		}
+		if settings.KMSKeyArn != "" {
+			endpointConfig.KmsKeyArn = jsii.String(settings.KMSKeyArn)
+		}
+		configuration := sagemakerendpointconfiguration.NewSagemakerEndpointConfiguration(stack, jsii.String(id+"_endpoint_config"), endpointConfig)
+
+		endpoint := sagemakerendpoint.NewSagemakerEndpoint(stack, jsii.String(id+"_endpoint"), &sagemakerendpoint.SagemakerEndpointConfig{
+			Name:               jsii.String(resourceName(config, "aws_sagemaker_endpoint", settings.Name)),
+			EndpointConfigName: configuration.Name(),
+		})
+
+		cdktf.NewTerraformOutput(stack, jsii.String("sagemaker_"+settings.Name+"_endpoint_name"), &cdktf.TerraformOutputConfig{
+			Value:       endpoint.Name(),
+			Description: jsii.String("The SageMaker endpoint inference clients invoke the " + settings.Name + " model at"),
+		})
+	}
+
+	fmt.Printf("  ✓ %d SageMaker endpoint(s)\n", len(sagemaker.Models))
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ScanConfig scans the stacks after every synth, as `go run . scan` does
+type ScanConfig struct {
+	FailOn string `json:"fail_on"` // lowest severity that fails the run: low, medium, high (default), critical or none
+}
+
+var severities = []string{"low", "medium", "high", "critical"}
+
+// severityRank orders severities; none ranks above all of them so nothing fails
+func severityRank(severity string) int {
+	for i, known := range severities {
+		if known == severity {
+			return i
+		}
+	}
+	return len(severities)
+}
+
+func validateFailOn(failOn string) error {
+	if failOn != "" && failOn != "none" && severityRank(failOn) == len(severities) {
+		return invalidValue(failOn, closestMatch(failOn, append(slices.Clone(severities), "none")),
+			"fail_on %q must be one of %s or none", failOn, strings.Join(severities, ", "))
+	}
+	return nil
+}
+
+func (s *ScanConfig) validate() error {
+	return validateFailOn(s.FailOn)
+}
+
+// scanRule flags resources of one type. found reports whether the resource is misconfigured.
+type scanRule struct {
+	id           string
+	severity     string
+	resourceType string
+	title        string
+	found        func(stack *stackResources, resource tfResource) bool
+}
+
+// scanFinding is one misconfigured resource, as written to the results file
+type scanFinding struct {
+	Stack    string `json:"stack"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Resource string `json:"resource"`
+	Title    string `json:"title"`
+}
+
+// attr follows a path of attribute names into a synthesized resource, taking the first element of
+// any block that is synthesized as a list
+func attr(value interface{}, path ...string) interface{} {
+	for _, name := range path {
+		if list, ok := value.([]interface{}); ok {
+			if len(list) == 0 {
+				return nil
+			}
+			value = list[0]
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+func asList(value interface{}) []interface{} {
+	if list, ok := value.([]interface{}); ok {
+		return list
+	}
+	if value == nil {
+		return nil
+	}
+	return []interface{}{value}
+}
+
+func contains(values []interface{}, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
+
+// openIngress reports whether an ingress rule is open to the internet on any of the given ports;
+// no ports means any port at all
+func openIngress(rule interface{}, ports ...float64) bool {
+	if !contains(asList(attr(rule, "cidr_blocks")), "0.0.0.0/0") &&
+		!contains(asList(attr(rule, "ipv6_cidr_blocks")), "::/0") &&
+		attr(rule, "cidr_ipv4") != "0.0.0.0/0" {
+		return false
+	}
+	if attr(rule, "protocol") == "-1" || attr(rule, "ip_protocol") == "-1" {
+		return true
+	}
+	from, _ := attr(rule, "from_port").(float64)
+	to, _ := attr(rule, "to_port").(float64)
+	if len(ports) == 0 {
+		return from == 0 && to == 65535
+	}
+	for _, port := range ports {
+		if from <= port && port <= to {
+			return true
+		}
+	}
+	return false
+}
+
+func securityGroupIngress(resource tfResource) []interface{} {
+	switch resource.resourceType {
+	case "aws_security_group":
+		return asList(resource.attrs["ingress"])
+	case "aws_security_group_rule":
+		if resource.attrs["type"] == "ingress" {
+			return []interface{}{resource.attrs}
+		}
+	case "aws_vpc_security_group_ingress_rule":
+		return []interface{}{resource.attrs}
+	}
+	return nil
+}
+
+// allowsEverything reports whether an IAM policy document allows every action on every resource
+func allowsEverything(document interface{}) bool {
+	text, _ := document.(string)
+	var policy struct {
+		Statement []map[string]interface{}
+	}
+	if json.Unmarshal([]byte(text), &policy) != nil {
+		return false
+	}
+	for _, statement := range policy.Statement {
+		if statement["Effect"] == "Allow" && contains(asList(statement["Action"]), "*") &&
+			contains(asList(statement["Resource"]), "*") {
+			return true
+		}
+	}
+	return false
+}
+
+var scanRules = func() []scanRule {
+	rules := []scanRule{
+		{"S3-001", "high", "aws_s3_bucket", "bucket has no public access block", func(stack *stackResources, resource tfResource) bool {
+			return !stack.referenced("aws_s3_bucket_public_access_block", resource)
+		}},
+		{"S3-002", "high", "aws_s3_bucket_public_access_block", "public access block leaves a setting off", func(_ *stackResources, resource tfResource) bool {
+			for _, setting := range []string{"block_public_acls", "block_public_policy", "ignore_public_acls", "restrict_public_buckets"} {
+				if resource.attrs[setting] != true {
+					return true
+				}
+			}
+			return false
+		}},
+		{"S3-003", "critical", "aws_s3_bucket_acl", "bucket ACL grants public access", func(_ *stackResources, resource tfResource) bool {
+			acl, _ := resource.attrs["acl"].(string)
+			return acl == "public-read" || acl == "public-read-write" || acl == "authenticated-read"
+		}},
+		{"S3-004", "medium", "aws_s3_bucket", "bucket has no default encryption", func(stack *stackResources, resource tfResource) bool {
+			return !stack.referenced("aws_s3_bucket_server_side_encryption_configuration", resource)
+		}},
+		{"S3-005", "low", "aws_s3_bucket", "bucket has no versioning", func(stack *stackResources, resource tfResource) bool {
+			return !stack.referenced("aws_s3_bucket_versioning", resource)
+		}},
+		{"ENC-001", "high", "aws_ebs_volume", "volume is not encrypted", func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["encrypted"] != true
+		}},
+		{"ENC-002", "high", "aws_db_instance", "database storage is not encrypted", func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["storage_encrypted"] != true
+		}},
+		{"ENC-003", "high", "aws_rds_cluster", "cluster storage is not encrypted", func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["storage_encrypted"] != true
+		}},
+		{"ENC-004", "medium", "aws_sqs_queue", "queue is not encrypted", func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["kms_master_key_id"] == nil && resource.attrs["sqs_managed_sse_enabled"] != true
+		}},
+		{"ENC-005", "low", "aws_sns_topic", "topic is not encrypted", func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["kms_master_key_id"] == nil
+		}},
+		{"ENC-006", "high", "aws_opensearch_domain", "domain is not encrypted at rest", func(_ *stackResources, resource tfResource) bool {
+			return attr(resource.attrs, "encrypt_at_rest", "enabled") != true
+		}},
+		{"ENC-007", "medium", "aws_opensearch_domain", "domain does not encrypt node-to-node traffic", func(_ *stackResources, resource tfResource) bool {
+			return attr(resource.attrs, "node_to_node_encryption", "enabled") != true
+		}},
+		{"ENC-008", "high", "aws_opensearch_domain", "domain does not enforce HTTPS", func(_ *stackResources, resource tfResource) bool {
+			return attr(resource.attrs, "domain_endpoint_options", "enforce_https") != true
+		}},
+		{"LOG-001", "medium", "aws_cloudtrail", "trail does not validate log files", func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["enable_log_file_validation"] != true
+		}},
+		{"LOG-002", "low", "aws_opensearch_domain", "domain does not publish logs", func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["log_publishing_options"] == nil
+		}},
+		{"LOG-003", "low", "aws_msk_cluster", "cluster does not deliver broker logs", func(_ *stackResources, resource tfResource) bool {
+			return resource.attrs["logging_info"] == nil
+		}},
+	}
+
+	for _, resourceType := range []string{"aws_security_group", "aws_security_group_rule", "aws_vpc_security_group_ingress_rule"} {
+		rules = append(rules,
+			scanRule{"NET-001", "critical", resourceType, "SSH or RDP is open to the internet", func(_ *stackResources, resource tfResource) bool {
+				for _, rule := range securityGroupIngress(resource) {
+					if openIngress(rule, 22, 3389) {
+						return true
+					}
+				}
+				return false
+			}},
+			scanRule{"NET-002", "high", resourceType, "all ports are open to the internet", func(_ *stackResources, resource tfResource) bool {
+				for _, rule := range securityGroupIngress(resource) {
+					if openIngress(rule) {
+						return true
+					}
+				}
+				return false
+			}})
+	}
+	for _, resourceType := range []string{"aws_iam_policy", "aws_iam_role_policy", "aws_iam_user_policy", "aws_iam_group_policy"} {
+		rules = append(rules, scanRule{"IAM-001", "critical", resourceType, "policy allows * on *", func(_ *stackResources, resource tfResource) bool {
+			return allowsEverything(resource.attrs["policy"])
+		}})
+	}
+	return rules
+}()
+
+// scanStacks runs scanRules over every synthesized stack, prints the findings by severity and
+// writes them to <outdir>/scan-results.json
+func scanStacks(outdir string, output string) ([]scanFinding, error) {
+	stackNames, err := synthesizedStacks(outdir)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("\n🛡️  Scanning %d stack(s) for misconfigurations...\n", len(stackNames))
+	findings := []scanFinding{}
+	for _, name := range stackNames {
+		raw, err := os.ReadFile(filepath.Join(outdir, "stacks", name, "cdk.tf.json"))
+		if err != nil {
+			return nil, err
+		}
+		resources, err := parseStackResources(raw, nil)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", name, err)
+		}
+		for _, rule := range scanRules {
+			for _, resource := range resources.byType[rule.resourceType] {
+				if rule.found(resources, resource) {
+					findings = append(findings, scanFinding{
+						Stack:    name,
+						Rule:     rule.id,
+						Severity: rule.severity,
+						Resource: resource.resourceType + "." + resource.id,
+						Title:    rule.title,
+					})
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank(findings[i].Severity) > severityRank(findings[j].Severity)
+	})
+	counts := map[string]int{}
+	for _, finding := range findings {
+		counts[finding.Severity]++
+		fmt.Printf("  %-8s %-7s [%s] %s: %s\n", strings.ToUpper(finding.Severity), finding.Rule,
+			finding.Stack, finding.Resource, finding.Title)
+	}
+	fmt.Printf("  %d critical, %d high, %d medium, %d low\n",
+		counts["critical"], counts["high"], counts["medium"], counts["low"])
+
+	if output == "" {
+		output = filepath.Join(outdir, "scan-results.json")
+	}
+	data, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(output, data, 0o644); err != nil {
+		return nil, fmt.Errorf("writing %s: %w", output, err)
+	}
+	return findings, nil
+}
+
+// checkFindings fails when any finding is at or above the failOn severity
+func checkFindings(findings []scanFinding, failOn string) error {
+	if failOn == "" {
+		failOn = "high"
+	}
+	failing := 0
+	for _, finding := range findings {
+		if severityRank(finding.Severity) >= severityRank(failOn) {
+			failing++
+		}
+	}
+	if failing > 0 {
+		return fmt.Errorf("%d finding(s) at or above %s", failing, failOn)
+	}
+	return nil
+}
+
+// runScan is the scan command
+func runScan(args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	output := flags.String("output", "", "results file (default <outdir>/scan-results.json)")
+	failOn := flags.String("fail-on", "high", "lowest severity that fails: low, medium, high, critical or none")
+	flags.Parse(args)
+
+	if err := validateFailOn(*failOn); err != nil {
+		return err
+	}
+	findings, err := scanStacks(*outdir, *output)
+	if err != nil {
+		return err
+	}
+	return checkFindings(findings, *failOn)
+}
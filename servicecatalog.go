@@ -0,0 +1,253 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"time"
+)
+
+// ServiceCatalogConfig describes the Service Catalog product the service-catalog command packages
+// a synthesized stack as. Teams launch it from the console; the stack's variables are the launch
+// parameters, and each version of the product is a provisioning artifact.
+type ServiceCatalogConfig struct {
+	Product      string `json:"product"` // defaults to the project
+	Owner        string `json:"owner"`
+	Description  string `json:"description"`
+	SupportEmail string `json:"support_email"`
+	// ProductType is EXTERNAL (default) or TERRAFORM_OPEN_SOURCE, for older installs of the
+	// Terraform reference engine
+	ProductType string `json:"product_type"`
+	// ArtifactBucket is an existing bucket the packages are uploaded to, readable by the engine
+	ArtifactBucket string `json:"artifact_bucket"`
+	PortfolioID    string `json:"portfolio_id"` // new products are added to this portfolio
+}
+
+var (
+	serviceCatalogProductTypes = []string{"EXTERNAL", "TERRAFORM_OPEN_SOURCE"}
+	portfolioIDPattern         = regexp.MustCompile(`^port-[a-z0-9]{13}$`)
+	productIDPattern           = regexp.MustCompile(`^prod-[a-z0-9]{13}$`)
+)
+
+func (s *ServiceCatalogConfig) validate() error {
+	if s.Owner == "" {
+		return fmt.Errorf("owner is required")
+	}
+	if s.ProductType != "" && !slices.Contains(serviceCatalogProductTypes, s.ProductType) {
+		return invalidValue(s.ProductType, closestMatch(s.ProductType, serviceCatalogProductTypes),
+			"product_type %q must be EXTERNAL or TERRAFORM_OPEN_SOURCE", s.ProductType)
+	}
+	if s.SupportEmail != "" && !emailPattern.MatchString(s.SupportEmail) {
+		return invalidValue(s.SupportEmail, "", "support_email %q is not an email address", s.SupportEmail)
+	}
+	if s.ArtifactBucket != "" && !nameSegmentPattern.MatchString(s.ArtifactBucket) {
+		return invalidValue(s.ArtifactBucket, "", "artifact_bucket %q is not a bucket name", s.ArtifactBucket)
+	}
+	if s.PortfolioID != "" && !portfolioIDPattern.MatchString(s.PortfolioID) {
+		return invalidValue(s.PortfolioID, "", "portfolio_id %q is not a portfolio ID like port-abcdefghijklm", s.PortfolioID)
+	}
+	return nil
+}
+
+func (s *ServiceCatalogConfig) product(config Config) string {
+	if s.Product != "" {
+		return s.Product
+	}
+	return config.Project
+}
+
+func (s *ServiceCatalogConfig) productType() string {
+	if s.ProductType != "" {
+		return s.ProductType
+	}
+	return "EXTERNAL"
+}
+
+// productTemplate turns a synthesized stack into a standalone configuration: the engine keeps the
+// state of each launch itself, so the stack's backend is removed
+func productTemplate(outdir, stack string) ([]byte, error) {
+	raw, err := os.ReadFile(filepath.Join(outdir, "stacks", stack, "cdk.tf.json"))
+	if err != nil {
+		return nil, err
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", stack, err)
+	}
+	if terraform, ok := document["terraform"].(map[string]interface{}); ok {
+		delete(terraform, "backend")
+	}
+	if data, ok := document["data"].(map[string]interface{}); ok && data["terraform_remote_state"] != nil {
+		fmt.Printf("⚠️  Service Catalog: %s reads the state of other stacks, so launches need read access to them\n", stack)
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+// packageProduct writes the template into a .tar.gz as main.tf.json, the layout the engine expects
+func packageProduct(path string, template []byte) error {
+	var archive bytes.Buffer
+	compressed := gzip.NewWriter(&archive)
+	files := tar.NewWriter(compressed)
+	// A fixed time keeps the package, and its checksum, the same for the same stack
+	header := &tar.Header{Name: "main.tf.json", Mode: 0o644, Size: int64(len(template)), ModTime: time.Unix(0, 0)}
+	if err := files.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := files.Write(template); err != nil {
+		return err
+	}
+	if err := files.Close(); err != nil {
+		return err
+	}
+	if err := compressed.Close(); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, archive.Bytes(), 0o644)
+}
+
+// awsJSON runs the aws CLI in the config's region and decodes its JSON output into result
+func awsJSON(config Config, result interface{}, args ...string) error {
+	if _, err := exec.LookPath("aws"); err != nil {
+		return fmt.Errorf("aws CLI not found on PATH: %w", err)
+	}
+	command := exec.Command("aws", append(args, "--region", config.Region, "--output", "json")...)
+	var stderr bytes.Buffer
+	command.Stderr = &stderr
+	output, err := command.Output()
+	if err != nil {
+		return fmt.Errorf("aws %s %s: %w\n%s", args[0], args[1], err, stderr.Bytes())
+	}
+	return json.Unmarshal(output, result)
+}
+
+// publishProduct uploads the package and adds it as a version of the product, creating the
+// product, in the portfolio, when productID is empty. It returns the product ID.
+func publishProduct(config Config, path, version, productID string) (string, error) {
+	catalog := config.ServiceCatalog
+	key := fmt.Sprintf("service-catalog/%s/%s.tar.gz", catalog.product(config), version)
+	if err := runAWS(config, nil, "s3", "cp", path, fmt.Sprintf("s3://%s/%s", catalog.ArtifactBucket, key)); err != nil {
+		return "", fmt.Errorf("uploading the package: %w", err)
+	}
+	artifact, _ := json.Marshal(map[string]interface{}{
+		"Name":        version,
+		"Description": fmt.Sprintf("%s %s, config hash %s", config.Project, config.Environment, config.configHash),
+		"Type":        catalog.productType(),
+		"Info": map[string]string{
+			"LoadTemplateFromURL": fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", catalog.ArtifactBucket, config.Region, key),
+		},
+	})
+
+	if productID != "" {
+		var created struct{}
+		if err := awsJSON(config, &created, "servicecatalog", "create-provisioning-artifact",
+			"--product-id", productID, "--parameters", string(artifact), "--idempotency-token", version); err != nil {
+			return "", err
+		}
+		return productID, nil
+	}
+
+	args := []string{"servicecatalog", "create-product",
+		"--name", catalog.product(config),
+		"--owner", catalog.Owner,
+		"--product-type", catalog.productType(),
+		"--provisioning-artifact-parameters", string(artifact),
+		"--idempotency-token", catalog.product(config) + "-" + version,
+	}
+	if catalog.Description != "" {
+		args = append(args, "--description", catalog.Description)
+	}
+	if catalog.SupportEmail != "" {
+		args = append(args, "--support-email", catalog.SupportEmail)
+	}
+	var created struct {
+		ProductViewDetail struct {
+			ProductViewSummary struct {
+				ProductID string `json:"ProductId"`
+			} `json:"ProductViewSummary"`
+		} `json:"ProductViewDetail"`
+	}
+	if err := awsJSON(config, &created, args...); err != nil {
+		return "", err
+	}
+	productID = created.ProductViewDetail.ProductViewSummary.ProductID
+	if catalog.PortfolioID != "" {
+		if err := runAWS(config, nil, "servicecatalog", "associate-product-with-portfolio",
+			"--product-id", productID, "--portfolio-id", catalog.PortfolioID); err != nil {
+			return productID, fmt.Errorf("adding %s to portfolio %s: %w", productID, catalog.PortfolioID, err)
+		}
+	}
+	return productID, nil
+}
+
+// runServiceCatalog is the service-catalog command. It packages a synthesized stack as a Service
+// Catalog product and, with -publish, uploads it as a new product or a new version of one.
+func runServiceCatalog(args []string) error {
+	flags := flag.NewFlagSet("service-catalog", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	only := flags.String("stack", "", "stack to package (default the main stack)")
+	output := flags.String("output", "", "package file (default <outdir>/service-catalog/<stack>.tar.gz)")
+	publish := flags.Bool("publish", false, "upload the package and add it to Service Catalog")
+	version := flags.String("version", "", "provisioning artifact name (default the config hash)")
+	productID := flags.String("product-id", "", "existing product to add the version to, instead of creating one")
+	flags.Parse(args)
+
+	config, err := loadConfig("config.json")
+	if err != nil {
+		return err
+	}
+	catalog := config.ServiceCatalog
+	if catalog == nil {
+		return fmt.Errorf("config.json has no service_catalog section")
+	}
+	if config.Workspaces {
+		// The workspace guard would fail every launch, which runs in the default workspace
+		return fmt.Errorf("stacks of a config with workspaces can't be packaged as products")
+	}
+	if *productID != "" && !productIDPattern.MatchString(*productID) {
+		return fmt.Errorf("-product-id %q is not a product ID like prod-abcdefghijklm", *productID)
+	}
+	if *publish && catalog.ArtifactBucket == "" {
+		return fmt.Errorf("-publish needs service_catalog.artifact_bucket")
+	}
+
+	stack := *only
+	if stack == "" {
+		stack = stackPrefix(config) + "-stack"
+	}
+	template, err := productTemplate(*outdir, stack)
+	if err != nil {
+		return fmt.Errorf("reading stack %s (synthesize first): %w", stack, err)
+	}
+	path := *output
+	if path == "" {
+		path = filepath.Join(*outdir, "service-catalog", stack+".tar.gz")
+	}
+	if err := packageProduct(path, template); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	fmt.Printf("📦 Packaged %s as product %s in %s\n", stack, catalog.product(config), path)
+
+	if !*publish {
+		return nil
+	}
+	if *version == "" {
+		*version = config.configHash
+	}
+	id, err := publishProduct(config, path, *version, *productID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Published version %s of %s (%s)\n", *version, catalog.product(config), id)
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/transferserver"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/transfersshkey"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/transferuser"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// SFTPConfig describes a Transfer Family SFTP server that stores uploads in the config bucket
+type SFTPConfig struct {
+	Users []SFTPUser `json:"users"`
+}
+
+type SFTPUser struct {
+	Name       string   `json:"name"`
+	SSHKeys    []string `json:"ssh_keys"`
+	HomePrefix string   `json:"home_prefix"` // defaults to sftp/<name>
+}
+
+func (s *SFTPConfig) validate() error {
+	names := map[string]bool{}
+	for i, user := range s.Users {
+		if user.Name == "" {
+			return fmt.Errorf("users need a name")
+		}
+		if names[user.Name] {
+			return fmt.Errorf("users[%d].name: duplicate %q", i, user.Name)
+		}
+		names[user.Name] = true
+		if len(user.SSHKeys) == 0 {
+			return fmt.Errorf("user %s has no ssh_keys", user.Name)
+		}
+	}
+	return nil
+}
+
+// addSFTP creates the server and one user per entry, each locked into its own bucket prefix
+func addSFTP(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket) {
+	sftp := config.SFTP
+
+	loggingRole := newServiceRole(stack, "sftp_logging_role", resourceName(config, "aws_iam_role", "sftp-logging"),
+		"transfer.amazonaws.com", config,
+		"arn:aws:iam::aws:policy/service-role/AWSTransferLoggingAccess")
+
+	server := transferserver.NewTransferServer(stack, jsii.String("sftp_server"), &transferserver.TransferServerConfig{
+		Domain:               jsii.String("S3"),
+		Protocols:            jsii.Strings("SFTP"),
+		IdentityProviderType: jsii.String("SERVICE_MANAGED"),
+		EndpointType:         jsii.String("PUBLIC"),
+		SecurityPolicyName:   jsii.String("TransferSecurityPolicy-2024-01"),
+		LoggingRole:          loggingRole.Arn(),
+	})
+
+	for _, user := range sftp.Users {
+		prefix := strings.Trim(user.HomePrefix, "/")
+		if prefix == "" {
+			prefix = "sftp/" + user.Name
+		}
+
+		role := newServiceRole(stack, "sftp_user_role_"+user.Name, resourceName(config, "aws_iam_role", "sftp-"+user.Name),
+			"transfer.amazonaws.com", config)
+		addInlinePolicy(stack, "sftp_user_access_"+user.Name, role,
+			map[string]interface{}{
+				"Effect":    "Allow",
+				"Action":    "s3:ListBucket",
+				"Resource":  *bucket.Arn(),
+				"Condition": map[string]interface{}{"StringLike": map[string]interface{}{"s3:prefix": []string{prefix, prefix + "/*"}}},
+			},
+			map[string]interface{}{
+				"Effect":   "Allow",
+				"Action":   []string{"s3:GetObject", "s3:PutObject", "s3:DeleteObject", "s3:GetObjectVersion"},
+				"Resource": *bucket.Arn() + "/" + prefix + "/*",
+			})
+
+		transferUser := transferuser.NewTransferUser(stack, jsii.String("sftp_user_"+user.Name), &transferuser.TransferUserConfig{
+			ServerId:          server.Id(),
+			UserName:          jsii.String(user.Name),
+			Role:              role.Arn(),
+			HomeDirectoryType: jsii.String("LOGICAL"),
+			HomeDirectoryMappings: &[]*transferuser.TransferUserHomeDirectoryMappings{
+				{
+					Entry:  jsii.String("/"),
+					Target: jsii.String("/" + *bucket.Bucket() + "/" + prefix),
+				},
+			},
+		})
+
+		for i, key := range user.SSHKeys {
+			transfersshkey.NewTransferSshKey(stack, jsii.String(fmt.Sprintf("sftp_user_%s_key_%d", user.Name, i)),
+				&transfersshkey.TransferSshKeyConfig{
+					ServerId: server.Id(),
+					UserName: transferUser.UserName(),
+					Body:     jsii.String(key),
+				})
+		}
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("sftp_endpoint"), &cdktf.TerraformOutputConfig{
+		Value:       server.Endpoint(),
+		Description: jsii.String("The hostname of the SFTP server"),
+	})
+
+	fmt.Printf("  ✓ SFTP server with %d user(s)\n", len(sftp.Users))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSFTPDuplicateUserNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{
+  "project": "my-app",
+  "environment": "dev",
+  "region": "us-west-2",
+  "storage": {"bucket_name": "my-app-data"},
+  "sftp": {
+    "users": [
+      {"name": "acme", "ssh_keys": ["ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIA first"]},
+      {"name": "acme", "ssh_keys": ["ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIB second"]}
+    ]
+  }
+}`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := loadConfig(path)
+	if err == nil {
+		t.Fatal("expected a validation error for duplicate user names")
+	}
+	if want := `sftp: users[1].name: duplicate "acme"`; !strings.Contains(err.Error(), want) {
+		t.Fatalf("error %q does not contain %q", err, want)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+// sizePreset holds the defaults an environment size selects. Values set on a section always win.
+type sizePreset struct {
+	BatchMaxVCPUs           float64
+	GlueWorkerType          string
+	GlueWorkers             float64
+	OpenSearchInstanceType  string
+	OpenSearchInstanceCount float64
+	OpenSearchVolumeGB      float64
+	KafkaBrokerType         string
+	KafkaVolumeGB           float64
+	NeptuneInstanceClass    string
+	DocumentDBInstanceClass string
+	MemoryDBNodeType        string
+	WarehouseBaseCapacity   float64
+	AppRunnerCPU            string
+	AppRunnerMemory         string
+}
+
+const defaultSize = "medium"
+
+var sizePresets = map[string]sizePreset{
+	"small": {
+		BatchMaxVCPUs:           4,
+		GlueWorkerType:          "G.1X",
+		GlueWorkers:             2,
+		OpenSearchInstanceType:  "t3.small.search",
+		OpenSearchInstanceCount: 1,
+		OpenSearchVolumeGB:      10,
+		KafkaBrokerType:         "kafka.t3.small",
+		KafkaVolumeGB:           20,
+		NeptuneInstanceClass:    "db.t4g.medium",
+		DocumentDBInstanceClass: "db.t4g.medium",
+		MemoryDBNodeType:        "db.t4g.small",
+		WarehouseBaseCapacity:   8,
+		AppRunnerCPU:            "0.25 vCPU",
+		AppRunnerMemory:         "0.5 GB",
+	},
+	"medium": {
+		BatchMaxVCPUs:           16,
+		GlueWorkerType:          "G.1X",
+		GlueWorkers:             2,
+		OpenSearchInstanceType:  "t3.small.search",
+		OpenSearchInstanceCount: 1,
+		OpenSearchVolumeGB:      20,
+		KafkaBrokerType:         "kafka.m5.large",
+		KafkaVolumeGB:           100,
+		NeptuneInstanceClass:    "db.r6g.large",
+		DocumentDBInstanceClass: "db.r6g.large",
+		MemoryDBNodeType:        "db.r7g.large",
+		WarehouseBaseCapacity:   8,
+		AppRunnerCPU:            "1 vCPU",
+		AppRunnerMemory:         "2 GB",
+	},
+	"large": {
+		BatchMaxVCPUs:           64,
+		GlueWorkerType:          "G.2X",
+		GlueWorkers:             10,
+		OpenSearchInstanceType:  "r6g.large.search",
+		OpenSearchInstanceCount: 3,
+		OpenSearchVolumeGB:      100,
+		KafkaBrokerType:         "kafka.m5.xlarge",
+		KafkaVolumeGB:           500,
+		NeptuneInstanceClass:    "db.r6g.xlarge",
+		DocumentDBInstanceClass: "db.r6g.xlarge",
+		MemoryDBNodeType:        "db.r7g.xlarge",
+		WarehouseBaseCapacity:   32,
+		AppRunnerCPU:            "2 vCPU",
+		AppRunnerMemory:         "4 GB",
+	},
+}
+
+// sizeFor returns the preset of the active environment, medium when none is set
+func sizeFor(config Config) sizePreset {
+	size := config.Environments[config.Environment].Size
+	if size == "" {
+		size = defaultSize
+	}
+	return sizePresets[size]
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// tokenPattern matches the number in unresolved token markers, which changes between runs
+var tokenPattern = regexp.MustCompile(`TOKEN\.\d+`)
+
+// normalizeStack turns a synthesized cdk.tf.json into its golden form: keys sorted, indented,
+// and with run-specific values (working directory, output directory, token numbers) replaced
+func normalizeStack(raw []byte, outdir string) ([]byte, error) {
+	var document interface{}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+
+	normalized := buffer.String()
+	normalized = strings.ReplaceAll(normalized, outdir, "<outdir>")
+	if cwd, err := os.Getwd(); err == nil {
+		normalized = strings.ReplaceAll(normalized, cwd, "<cwd>")
+	}
+	normalized = tokenPattern.ReplaceAllString(normalized, "TOKEN.n")
+	return []byte(normalized), nil
+}
+
+// appContext returns the context from cdktf.json, so snapshots match what `cdktf synth` writes
+func appContext() *map[string]interface{} {
+	context := map[string]interface{}{}
+	if raw, err := os.ReadFile("cdktf.json"); err == nil {
+		var project struct {
+			Context map[string]interface{} `json:"context"`
+		}
+		if json.Unmarshal(raw, &project) == nil && project.Context != nil {
+			context = project.Context
+		}
+	}
+	return &context
+}
+
+// synthesizeFixture builds a fixture config into a temporary directory and returns the normalized
+// Terraform JSON of each stack, keyed by stack name
+func synthesizeFixture(configPath string) (map[string][]byte, error) {
+	config, err := loadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	outdir, err := os.MkdirTemp("", "snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(outdir)
+
+	app := cdktf.NewApp(&cdktf.AppConfig{
+		Outdir:  jsii.String(outdir),
+		Context: appContext(),
+	})
+	if _, _, err := buildStacks(app, config); err != nil {
+		return nil, err
+	}
+	if err := synth(app); err != nil {
+		return nil, err
+	}
+
+	stackNames, err := synthesizedStacks(outdir)
+	if err != nil {
+		return nil, err
+	}
+	stacks := map[string][]byte{}
+	for _, name := range stackNames {
+		raw, err := os.ReadFile(filepath.Join(outdir, "stacks", name, "cdk.tf.json"))
+		if err != nil {
+			return nil, err
+		}
+		if stacks[name], err = normalizeStack(raw, outdir); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return stacks, nil
+}
+
+// firstDifference describes where two golden files first differ, with a little context
+func firstDifference(want, got []byte) string {
+	wantLines := strings.Split(string(want), "\n")
+	gotLines := strings.Split(string(got), "\n")
+	line := 0
+	for line < len(wantLines) && line < len(gotLines) && wantLines[line] == gotLines[line] {
+		line++
+	}
+	excerpt := func(lines []string) string {
+		end := min(line+3, len(lines))
+		return strings.Join(lines[line:end], "\n        ")
+	}
+	return fmt.Sprintf("line %d\n      want: %s\n      got:  %s", line+1, excerpt(wantLines), excerpt(gotLines))
+}
+
+// checkFixture compares a fixture's stacks with its golden files, or rewrites them with update.
+// Golden files are stored next to the fixture's config.json as <stack>.tf.json.
+func checkFixture(dir string, update bool) ([]string, error) {
+	stacks, err := synthesizeFixture(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	existing, _ := filepath.Glob(filepath.Join(dir, "*.tf.json"))
+	var problems []string
+	for _, path := range existing {
+		name := strings.TrimSuffix(filepath.Base(path), ".tf.json")
+		if _, ok := stacks[name]; ok {
+			continue
+		}
+		if update {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s: golden file exists but the stack is no longer synthesized", name))
+	}
+
+	names := make([]string, 0, len(stacks))
+	for name := range stacks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name+".tf.json")
+		if update {
+			if err := os.WriteFile(path, stacks[name], 0o644); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: no golden file (run with -update)", name))
+			continue
+		}
+		if !bytes.Equal(want, stacks[name]) {
+			problems = append(problems, fmt.Sprintf("%s: differs at %s", name, firstDifference(want, stacks[name])))
+		}
+	}
+	return problems, nil
+}
+
+// pinSnapshotMetadata fixes the values that change between runs and machines, so golden files
+// change only with the generated Terraform. Fixtures name their own environment. It returns a
+// function that puts the previous values back.
+func pinSnapshotMetadata() (restore func()) {
+	environment, hadEnvironment := os.LookupEnv("CDKTF_ENVIRONMENT")
+	previousMetadata, previousVersion := buildMetadata, toolVersion
+
+	os.Unsetenv("CDKTF_ENVIRONMENT")
+	buildMetadata = func(Config) map[string]string {
+		return map[string]string{
+			"GitCommit":     "0000000000000000000000000000000000000000",
+			"GitRepository": "https://github.com/example/json-to-terraform.git",
+			"ConfigHash":    "000000000000",
+			"DeployedAt":    "2024-01-01T00:00:00Z",
+		}
+	}
+	toolVersion = func() string { return "v0.0.0" }
+
+	return func() {
+		if hadEnvironment {
+			os.Setenv("CDKTF_ENVIRONMENT", environment)
+		}
+		buildMetadata, toolVersion = previousMetadata, previousVersion
+	}
+}
+
+// snapshotFixtures returns the fixtures under root: each directory holding a config.json
+func snapshotFixtures(root string) []string {
+	var fixtures []string
+	configs, _ := filepath.Glob(filepath.Join(root, "*", "config.json"))
+	for _, config := range configs {
+		fixtures = append(fixtures, filepath.Base(filepath.Dir(config)))
+	}
+	return fixtures
+}
+
+// runSnapshot is the snapshot command. Each directory under -dir holding a config.json is a
+// fixture; its synthesized stacks must match the committed golden files. `go test` runs the same
+// check in TestSnapshots.
+func runSnapshot(args []string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	root := flags.String("dir", filepath.Join("testdata", "snapshots"), "directory of fixtures")
+	update := flags.Bool("update", false, "rewrite the golden files instead of comparing")
+	flags.Parse(args)
+
+	defer pinSnapshotMetadata()()
+
+	fixtures := flags.Args()
+	if len(fixtures) == 0 {
+		fixtures = snapshotFixtures(*root)
+	}
+	if len(fixtures) == 0 {
+		return fmt.Errorf("no fixtures found in %s", *root)
+	}
+
+	failed := 0
+	var results []string
+	for _, fixture := range fixtures {
+		problems, err := checkFixture(filepath.Join(*root, fixture), *update)
+		switch {
+		case err != nil:
+			results = append(results, fmt.Sprintf("  ✗ %s: %v", fixture, err))
+			failed++
+		case len(problems) > 0:
+			results = append(results, fmt.Sprintf("  ✗ %s\n    %s", fixture, strings.Join(problems, "\n    ")))
+			failed++
+		case *update:
+			results = append(results, fmt.Sprintf("  ✓ %s updated", fixture))
+		default:
+			results = append(results, fmt.Sprintf("  ✓ %s", fixture))
+		}
+	}
+
+	fmt.Println("\n📸 Snapshots:")
+	fmt.Println(strings.Join(results, "\n"))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fixture(s) don't match their golden files", failed, len(fixtures))
+	}
+	return nil
+}
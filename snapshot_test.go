@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"testing"
+)
+
+var updateSnapshots = flag.Bool("update", false, "rewrite the golden files under testdata/snapshots")
+
+// TestSnapshots compares every fixture under testdata/snapshots with its golden files. Run
+// `go test -run TestSnapshots -update .` to rewrite them after an intended change.
+func TestSnapshots(t *testing.T) {
+	t.Cleanup(pinSnapshotMetadata())
+
+	root := filepath.Join("testdata", "snapshots")
+	fixtures := snapshotFixtures(root)
+	if len(fixtures) == 0 {
+		t.Fatalf("no fixtures found in %s", root)
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture, func(t *testing.T) {
+			problems, err := checkFixture(filepath.Join(root, fixture), *updateSnapshots)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, problem := range problems {
+				t.Error(problem)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/ssmmaintenancewindow"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/ssmmaintenancewindowtarget"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/ssmmaintenancewindowtask"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/ssmpatchbaseline"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/ssmpatchgroup"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// SSMManagementConfig patches instances with Systems Manager. Instances join a patch group with
+// their "Patch Group" tag; a group is patched against the baselines it's registered with, or the
+// AWS default baseline, during the maintenance windows that target it.
+type SSMManagementConfig struct {
+	Baselines          map[string]PatchBaseline     `json:"baselines"`
+	MaintenanceWindows map[string]MaintenanceWindow `json:"maintenance_windows"`
+	// BatchPatchGroup puts the instances of an EC2 or SPOT batch compute environment in a patch
+	// group, and lets them register with Systems Manager
+	BatchPatchGroup string `json:"batch_patch_group"`
+}
+
+// PatchBaseline approves patches of the given classifications and severities some days after
+// their release
+type PatchBaseline struct {
+	OperatingSystem string   `json:"operating_system"` // AMAZON_LINUX_2023 by default
+	Classifications []string `json:"classifications"`  // Security and Bugfix by default; CriticalUpdates and SecurityUpdates on Windows
+	Severities      []string `json:"severities"`       // Critical and Important by default
+	// ApproveAfterDays is how long after its release a patch is approved, 7 by default
+	ApproveAfterDays *float64 `json:"approve_after_days,omitempty"`
+	ApprovedPatches  []string `json:"approved_patches"`
+	RejectedPatches  []string `json:"rejected_patches"`
+	PatchGroups      []string `json:"patch_groups"`
+}
+
+// MaintenanceWindow runs AWS-RunPatchBaseline on the instances of its patch groups
+type MaintenanceWindow struct {
+	Schedule      string   `json:"schedule"` // cron(...) or rate(...)
+	Timezone      string   `json:"timezone"` // IANA, e.g. Europe/Berlin; UTC by default
+	DurationHours float64  `json:"duration_hours"`
+	CutoffHours   float64  `json:"cutoff_hours"` // no new patching starts this close to the end, 1 by default
+	PatchGroups   []string `json:"patch_groups"`
+	// Operation is Install (the default) or Scan, which only reports missing patches
+	Operation string `json:"operation"`
+	NoReboot  bool   `json:"no_reboot"` // installs without rebooting; patches needing one stay pending
+	// MaxConcurrency and MaxErrors are instance counts or percentages, 10% by default
+	MaxConcurrency string `json:"max_concurrency"`
+	MaxErrors      string `json:"max_errors"`
+}
+
+// patchOperatingSystems are the operating systems baselines filter by classification and severity
+var patchOperatingSystems = []string{
+	"ALMA_LINUX", "AMAZON_LINUX_2", "AMAZON_LINUX_2023", "CENTOS", "ORACLE_LINUX", "REDHAT_ENTERPRISE_LINUX", "ROCKY_LINUX", "WINDOWS",
+}
+
+var (
+	maintenanceSchedule = regexp.MustCompile(`^(cron|rate)\(.+\)$`)
+	// maintenanceLimit is a number of instances or a percentage of them
+	maintenanceLimit = regexp.MustCompile(`^([1-9][0-9]*|[1-9][0-9]?%|100%)$`)
+)
+
+func (b PatchBaseline) operatingSystem() string {
+	if b.OperatingSystem != "" {
+		return b.OperatingSystem
+	}
+	return "AMAZON_LINUX_2023"
+}
+
+func (b PatchBaseline) approveAfterDays() float64 {
+	if b.ApproveAfterDays != nil {
+		return *b.ApproveAfterDays
+	}
+	return 7
+}
+
+func (w MaintenanceWindow) duration() float64 {
+	if w.DurationHours != 0 {
+		return w.DurationHours
+	}
+	return 3
+}
+
+func (w MaintenanceWindow) cutoff() float64 {
+	if w.CutoffHours != 0 {
+		return w.CutoffHours
+	}
+	return 1
+}
+
+func (s *SSMManagementConfig) validate() error {
+	if len(s.MaintenanceWindows) == 0 {
+		return fmt.Errorf("at least one maintenance window is required")
+	}
+	registered := map[string]string{}
+	for _, name := range slices.Sorted(maps.Keys(s.Baselines)) {
+		baseline := s.Baselines[name]
+		if !nameSegmentPattern.MatchString(name) {
+			return invalidValue(name, "", "baselines: name %q must be lowercase letters, digits and hyphens", name)
+		}
+		if !slices.Contains(patchOperatingSystems, baseline.operatingSystem()) {
+			return invalidValue(baseline.OperatingSystem, closestMatch(baseline.OperatingSystem, patchOperatingSystems),
+				"baselines.%s: unsupported operating_system %q", name, baseline.OperatingSystem)
+		}
+		if days := baseline.approveAfterDays(); days < 0 || days > 360 {
+			return fmt.Errorf("baselines.%s: approve_after_days must be between 0 and 360", name)
+		}
+		for _, group := range baseline.PatchGroups {
+			// A patch group has one baseline per operating system
+			key := baseline.operatingSystem() + "/" + group
+			if other, ok := registered[key]; ok {
+				return fmt.Errorf("baselines.%s: patch group %q already has the %s baseline %s", name, group, baseline.operatingSystem(), other)
+			}
+			registered[key] = name
+		}
+	}
+	targeted := map[string]bool{}
+	for _, name := range slices.Sorted(maps.Keys(s.MaintenanceWindows)) {
+		window := s.MaintenanceWindows[name]
+		if !nameSegmentPattern.MatchString(name) {
+			return invalidValue(name, "", "maintenance_windows: name %q must be lowercase letters, digits and hyphens", name)
+		}
+		if !maintenanceSchedule.MatchString(window.Schedule) {
+			return fmt.Errorf("maintenance_windows.%s: schedule %q must be a cron(...) or rate(...) expression", name, window.Schedule)
+		}
+		if window.DurationHours != 0 && (window.DurationHours < 1 || window.DurationHours > 24) {
+			return fmt.Errorf("maintenance_windows.%s: duration_hours must be between 1 and 24", name)
+		}
+		if window.CutoffHours < 0 || window.cutoff() >= window.duration() {
+			return fmt.Errorf("maintenance_windows.%s: cutoff_hours (1 by default) must be less than duration_hours (3 by default)", name)
+		}
+		if len(window.PatchGroups) == 0 {
+			return fmt.Errorf("maintenance_windows.%s: at least one patch group is required", name)
+		}
+		for _, group := range window.PatchGroups {
+			targeted[group] = true
+		}
+		if window.Operation != "" && window.Operation != "Install" && window.Operation != "Scan" {
+			return invalidValue(window.Operation, closestMatch(window.Operation, []string{"Install", "Scan"}),
+				"maintenance_windows.%s: unknown operation %q (want Install or Scan)", name, window.Operation)
+		}
+		if window.MaxConcurrency != "" && !maintenanceLimit.MatchString(window.MaxConcurrency) {
+			return fmt.Errorf("maintenance_windows.%s: max_concurrency %q must be a number of instances or a percentage", name, window.MaxConcurrency)
+		}
+		if window.MaxErrors != "" && !maintenanceLimit.MatchString(window.MaxErrors) {
+			return fmt.Errorf("maintenance_windows.%s: max_errors %q must be a number of instances or a percentage", name, window.MaxErrors)
+		}
+	}
+	if s.BatchPatchGroup != "" && !targeted[s.BatchPatchGroup] {
+		return fmt.Errorf("batch_patch_group %q is not patched by any maintenance window", s.BatchPatchGroup)
+	}
+	return nil
+}
+
+// addSSMManagement creates the patch baselines with their patch groups, and the maintenance
+// windows patching the groups
+func addSSMManagement(stack cdktf.TerraformStack, config Config) {
+	ssm := config.SSMManagement
+
+	for _, name := range slices.Sorted(maps.Keys(ssm.Baselines)) {
+		settings := ssm.Baselines[name]
+		id := "ssm_baseline_" + strings.ReplaceAll(name, "-", "_")
+
+		operatingSystem := settings.operatingSystem()
+		classifications, severityKey := []string{"Security", "Bugfix"}, "SEVERITY"
+		if operatingSystem == "WINDOWS" {
+			classifications, severityKey = []string{"CriticalUpdates", "SecurityUpdates"}, "MSRC_SEVERITY"
+		}
+		if len(settings.Classifications) > 0 {
+			classifications = settings.Classifications
+		}
+		severities := settings.Severities
+		if len(severities) == 0 {
+			severities = []string{"Critical", "Important"}
+		}
+
+		baselineConfig := &ssmpatchbaseline.SsmPatchBaselineConfig{
+			Name:            jsii.String(resourceName(config, "aws_ssm_patch_baseline", name)),
+			Description:     jsii.String(fmt.Sprintf("%s %s patches, approved after %v day(s)", strings.Join(severities, " and "), strings.Join(classifications, " and "), settings.approveAfterDays())),
+			OperatingSystem: jsii.String(operatingSystem),
+			ApprovalRule: []*ssmpatchbaseline.SsmPatchBaselineApprovalRule{{
+				ApproveAfterDays: jsii.Number(settings.approveAfterDays()),
+				ComplianceLevel:  jsii.String("HIGH"),
+				PatchFilter: []*ssmpatchbaseline.SsmPatchBaselineApprovalRulePatchFilter{
+					{Key: jsii.String("CLASSIFICATION"), Values: jsii.Strings(classifications...)},
+					{Key: jsii.String(severityKey), Values: jsii.Strings(severities...)},
+				},
+			}},
+		}
+		if len(settings.ApprovedPatches) > 0 {
+			baselineConfig.ApprovedPatches = jsii.Strings(settings.ApprovedPatches...)
+		}
+		if len(settings.RejectedPatches) > 0 {
+			// Rejected patches stay off instances even as dependencies of other patches
+			baselineConfig.RejectedPatches = jsii.Strings(settings.RejectedPatches...)
+			baselineConfig.RejectedPatchesAction = jsii.String("BLOCK")
+		}
+		baseline := ssmpatchbaseline.NewSsmPatchBaseline(stack, jsii.String(id), baselineConfig)
+
+		for i, group := range settings.PatchGroups {
+			ssmpatchgroup.NewSsmPatchGroup(stack, jsii.String(fmt.Sprintf("%s_group_%d", id, i)), &ssmpatchgroup.SsmPatchGroupConfig{
+				BaselineId: baseline.Id(),
+				PatchGroup: jsii.String(group),
+			})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(ssm.MaintenanceWindows)) {
+		settings := ssm.MaintenanceWindows[name]
+		id := "ssm_window_" + strings.ReplaceAll(name, "-", "_")
+
+		windowConfig := &ssmmaintenancewindow.SsmMaintenanceWindowConfig{
+			Name:     jsii.String(resourceName(config, "aws_ssm_maintenance_window", name)),
+			Schedule: jsii.String(settings.Schedule),
+			Duration: jsii.Number(settings.duration()),
+			Cutoff:   jsii.Number(settings.cutoff()),
+			// Instances are only patched through the targets registered below
+			AllowUnassociatedTargets: jsii.Bool(false),
+		}
+		if settings.Timezone != "" {
+			windowConfig.ScheduleTimezone = jsii.String(settings.Timezone)
+		}
+		window := ssmmaintenancewindow.NewSsmMaintenanceWindow(stack, jsii.String(id), windowConfig)
+
+		target := ssmmaintenancewindowtarget.NewSsmMaintenanceWindowTarget(stack, jsii.String(id+"_target"),
+			&ssmmaintenancewindowtarget.SsmMaintenanceWindowTargetConfig{
+				WindowId:     window.Id(),
+				ResourceType: jsii.String("INSTANCE"),
+				Description:  jsii.String("Instances in the " + strings.Join(settings.PatchGroups, ", ") + " patch group(s)"),
+				Targets: []*ssmmaintenancewindowtarget.SsmMaintenanceWindowTargetTargets{{
+					Key:    jsii.String("tag:Patch Group"),
+					Values: jsii.Strings(settings.PatchGroups...),
+				}},
+			})
+
+		operation := settings.Operation
+		if operation == "" {
+			operation = "Install"
+		}
+		reboot := "RebootIfNeeded"
+		if settings.NoReboot {
+			reboot = "NoReboot"
+		}
+		maxConcurrency := settings.MaxConcurrency
+		if maxConcurrency == "" {
+			maxConcurrency = "10%"
+		}
+		maxErrors := settings.MaxErrors
+		if maxErrors == "" {
+			maxErrors = "10%"
+		}
+		ssmmaintenancewindowtask.NewSsmMaintenanceWindowTask(stack, jsii.String(id+"_patch"), &ssmmaintenancewindowtask.SsmMaintenanceWindowTaskConfig{
+			WindowId:       window.Id(),
+			Name:           jsii.String("patch"),
+			TaskType:       jsii.String("RUN_COMMAND"),
+			TaskArn:        jsii.String("AWS-RunPatchBaseline"),
+			Priority:       jsii.Number(1),
+			MaxConcurrency: jsii.String(maxConcurrency),
+			MaxErrors:      jsii.String(maxErrors),
+			// Patching stops at the cutoff rather than running past the end of the window
+			CutoffBehavior: jsii.String("CANCEL_TASK"),
+			Targets: []*ssmmaintenancewindowtask.SsmMaintenanceWindowTaskTargets{{
+				Key:    jsii.String("WindowTargetIds"),
+				Values: &[]*string{target.Id()},
+			}},
+			TaskInvocationParameters: &ssmmaintenancewindowtask.SsmMaintenanceWindowTaskTaskInvocationParameters{
+				RunCommandParameters: &ssmmaintenancewindowtask.SsmMaintenanceWindowTaskTaskInvocationParametersRunCommandParameters{
+					Parameter: []*ssmmaintenancewindowtask.SsmMaintenanceWindowTaskTaskInvocationParametersRunCommandParametersParameter{
+						{Name: jsii.String("Operation"), Values: jsii.Strings(operation)},
+						{Name: jsii.String("RebootOption"), Values: jsii.Strings(reboot)},
+					},
+				},
+			},
+		})
+
+		cdktf.NewTerraformOutput(stack, jsii.String(id+"_id"), &cdktf.TerraformOutputConfig{
+			Value:       window.Id(),
+			Description: jsii.String("The ID of the " + name + " maintenance window"),
+		})
+	}
+
+	fmt.Printf("  ✓ SSM patching: %d baseline(s), %d maintenance window(s)\n", len(ssm.Baselines), len(ssm.MaintenanceWindows))
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// secretSetting matches backend settings that hold a credential, which READMEs leave out
+var secretSetting = regexp.MustCompile(`(?i)token|password|secret|access_key|sas|client_certificate`)
+
+// stackReadme is what a stack's README documents
+type stackReadme struct {
+	Name        string
+	Bootstrap   bool     // the backend bootstrap stack, applied once before the others
+	DependsOn   []string // stacks to apply first
+	Step, Steps int      // its place in the deploy order
+}
+
+// markdownCell escapes a value for a Markdown table cell
+func markdownCell(value string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(value)
+}
+
+// renderStackReadme documents a synthesized stack: what it is, where its state lives, its outputs
+// and how to apply it
+func renderStackReadme(outdir string, config Config, stack stackReadme) (string, error) {
+	raw, err := os.ReadFile(filepath.Join(outdir, "stacks", stack.Name, "cdk.tf.json"))
+	if err != nil {
+		return "", err
+	}
+	var document struct {
+		Terraform struct {
+			Backend map[string]map[string]interface{} `json:"backend"`
+		} `json:"terraform"`
+		Output map[string]struct {
+			Description string `json:"description"`
+			Sensitive   bool   `json:"sensitive"`
+		} `json:"output"`
+	}
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return "", fmt.Errorf("parsing %s: %w", stack.Name, err)
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "# %s\n\n", stack.Name)
+	fmt.Fprintf(&text, "Generated from config.json (config hash `%s`) for project `%s`, environment `%s`. ", config.configHash, config.Project, config.Environment)
+	text.WriteString("Every synth rewrites this file; change the config instead.\n\n")
+	if stack.Bootstrap {
+		text.WriteString("This stack creates the state bucket and lock table the other stacks keep their state in. It keeps its own state locally, so apply it once, before the first deploy of any other stack, and keep the state file listed below.\n\n")
+	}
+
+	text.WriteString("## Backend\n\n")
+	if len(document.Terraform.Backend) == 0 {
+		text.WriteString("Local state, in this directory.\n\n")
+	}
+	for _, kind := range slices.Sorted(maps.Keys(document.Terraform.Backend)) {
+		settings := document.Terraform.Backend[kind]
+		fmt.Fprintf(&text, "State is kept in the `%s` backend:\n\n", kind)
+		for _, key := range slices.Sorted(maps.Keys(settings)) {
+			value := settings[key]
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				continue
+			}
+			if secretSetting.MatchString(key) {
+				continue
+			}
+			fmt.Fprintf(&text, "- %s: `%v`\n", key, value)
+		}
+		text.WriteString("\n")
+	}
+
+	text.WriteString("## Outputs\n\n")
+	var names []string
+	for _, name := range slices.Sorted(maps.Keys(document.Output)) {
+		// cdktf passes values between stacks through these; they aren't meant to be read
+		if !strings.HasPrefix(name, crossStackOutput) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		text.WriteString("This stack has no outputs.\n\n")
+	} else {
+		text.WriteString("| Output | Description |\n|---|---|\n")
+		for _, name := range names {
+			output := document.Output[name]
+			description := markdownCell(output.Description)
+			if output.Sensitive {
+				description = strings.TrimSpace(description + " (sensitive)")
+			}
+			fmt.Fprintf(&text, "| `%s` | %s |\n", name, description)
+		}
+		text.WriteString("\nRead one after an apply with `terraform output -raw <output>` in this directory, or write them all with `go run . outputs`.\n\n")
+	}
+
+	text.WriteString("## Apply\n\n")
+	switch {
+	case stack.Bootstrap:
+		text.WriteString("Apply it once, before the other stacks:\n\n")
+	case len(stack.DependsOn) > 0:
+		fmt.Fprintf(&text, "Step %d of %d. Apply it after %s:\n\n", stack.Step, stack.Steps, "`"+strings.Join(stack.DependsOn, "`, `")+"`")
+	default:
+		fmt.Fprintf(&text, "Step %d of %d. It depends on no other stack:\n\n", stack.Step, stack.Steps)
+	}
+	fmt.Fprintf(&text, "```bash\ncd %s\nterraform init\n", filepath.ToSlash(filepath.Join(outdir, "stacks", stack.Name)))
+	if config.Workspaces && !stack.Bootstrap {
+		fmt.Fprintf(&text, "terraform workspace select -or-create %s\n", config.Environment)
+	}
+	text.WriteString("terraform plan\nterraform apply\n```\n\n")
+	if stack.Bootstrap {
+		text.WriteString("Or run `go run . deploy -bootstrap`, which applies it before the other stacks.\n")
+	} else {
+		fmt.Fprintf(&text, "Or run `go run . deploy -stack %s` from the project directory. `go run . deploy` applies every stack in dependency order.\n", stack.Name)
+	}
+	return text.String(), nil
+}
+
+// writeStackReadmes writes a README.md into the directory of every synthesized stack, listed in
+// deploy order
+func writeStackReadmes(outdir string, config Config, order []string, dependencies map[string][]string, bootstrapStackName string) error {
+	var readmes []stackReadme
+	if bootstrapStackName != "" {
+		readmes = append(readmes, stackReadme{Name: bootstrapStackName, Bootstrap: true})
+	}
+	for i, name := range order {
+		dependsOn := slices.Clone(dependencies[name])
+		slices.Sort(dependsOn)
+		readmes = append(readmes, stackReadme{Name: name, DependsOn: dependsOn, Step: i + 1, Steps: len(order)})
+	}
+	for _, readme := range readmes {
+		content, err := renderStackReadme(outdir, config, readme)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outdir, "stacks", readme.Name, "README.md"), []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
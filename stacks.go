@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/constructs-go/constructs/v10"
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// StackConfig moves a set of sections, named by their config keys, into a stack of their own.
+// Sections not listed in any stack stay in the main <project>-<environment>-stack.
+type StackConfig struct {
+	Name     string   `json:"name"`
+	Sections []string `json:"sections"`
+	// DependsOn names stacks (or "stack", the main one) deployed before this one, for
+	// dependencies without a cross-stack reference; those are recorded by cdktf
+	DependsOn []string `json:"depends_on,omitempty"`
+}
+
+// stackSections lists the config keys that can be assigned to a stack
+var stackSections = []string{
+	"storage", "batch", "glue", "athena", "warehouse", "opensearch", "kafka", "neptune", "documentdb", "timestream", "keyspaces", "memorydb", "iot", "sagemaker", "bedrock", "pipeline", "codedeploy", "ssm_management", "sftp", "apprunner",
+	"amplify", "global_accelerator", "waf", "security_baseline", "compliance", "cloudtrail",
+	"budgets", "backup", "accounts", "appconfig", "amp", "amg", "dms", "cloudflare", "kubernetes", "helm", "auth0", "atlas",
+	"fastly", "vault", "utilities", "cloudcontrol", "github", "modules",
+}
+
+func validateStacks(stacks []StackConfig) error {
+	known := map[string]bool{}
+	for _, section := range stackSections {
+		known[section] = true
+	}
+	for name := range providerRegistry {
+		known[name] = true
+	}
+	candidates := slices.Sorted(maps.Keys(known))
+
+	names := map[string]bool{}
+	assigned := map[string]string{}
+	for _, stack := range stacks {
+		if stack.Name == "" {
+			return fmt.Errorf("name is required")
+		}
+		if stack.Name == "stack" || stack.Name == "backend" {
+			return fmt.Errorf("%s: name is reserved", stack.Name)
+		}
+		if names[stack.Name] {
+			return fmt.Errorf("%s: duplicate stack name", stack.Name)
+		}
+		names[stack.Name] = true
+
+		if len(stack.Sections) == 0 {
+			return fmt.Errorf("%s: at least one section is required", stack.Name)
+		}
+		for _, section := range stack.Sections {
+			if !known[section] {
+				return fmt.Errorf("%s: %w", stack.Name, invalidValue(section, closestMatch(section, candidates), "unknown section %q", section))
+			}
+			if other, ok := assigned[section]; ok {
+				return fmt.Errorf("%s: section %q is already in stack %s", stack.Name, section, other)
+			}
+			assigned[section] = stack.Name
+		}
+	}
+
+	dependencies := map[string][]string{"stack": nil}
+	for _, stack := range stacks {
+		for _, dependency := range stack.DependsOn {
+			if dependency == stack.Name {
+				return fmt.Errorf("%s: a stack can't depend on itself", stack.Name)
+			}
+			if _, ok := dependencies[dependency]; !ok && !names[dependency] {
+				return fmt.Errorf("%s: %w", stack.Name, invalidValue(dependency, closestMatch(dependency, append(slices.Sorted(maps.Keys(names)), "stack")),
+					"depends_on unknown stack %q", dependency))
+			}
+		}
+		dependencies[stack.Name] = stack.DependsOn
+	}
+	if _, err := deployOrder(dependencies); err != nil {
+		return err
+	}
+	return nil
+}
+
+// stackSet creates the stacks of one config on demand. Every stack gets its own provider and
+// backend; values passed between stacks (such as the storage bucket) are turned into outputs and
+// remote state lookups by cdktf.
+type stackSet struct {
+	app    cdktf.App
+	config Config
+	// stackOf maps a section to the suffix of the stack it belongs to
+	stackOf map[string]string
+	stacks  map[string]cdktf.TerraformStack
+	// names lists the created stacks in creation order
+	names []string
+}
+
+func newStackSet(app cdktf.App, config Config) *stackSet {
+	set := &stackSet{
+		app:     app,
+		config:  config,
+		stackOf: map[string]string{},
+		stacks:  map[string]cdktf.TerraformStack{},
+	}
+	for _, stack := range config.Stacks {
+		for _, section := range stack.Sections {
+			set.stackOf[section] = stack.Name
+		}
+	}
+	return set
+}
+
+// forSection returns the stack a section is built in, creating it on first use
+func (s *stackSet) forSection(section string) cdktf.TerraformStack {
+	suffix, ok := s.stackOf[section]
+	if !ok {
+		suffix = "stack"
+	}
+	if stack, ok := s.stacks[suffix]; ok {
+		return stack
+	}
+
+	stackName := stackPrefix(s.config) + "-" + suffix
+	stack := cdktf.NewTerraformStack(s.app, jsii.String(stackName))
+
+	pinVersions(stack, s.config)
+	if usesAzure(s.config) {
+		addAzureProvider(stack, s.config)
+	} else {
+		newAWSProvider(stack, "aws", awsProviderConfig(stack, s.config, s.config.Region), s.config)
+	}
+	if s.config.Workspaces {
+		addWorkspaceGuard(stack, s.config)
+	}
+
+	// Keep state remotely when a backend is configured, except against LocalStack; otherwise it
+	// stays local
+	if s.config.Backend != nil && !usesLocalStack(s.config) {
+		addBackend(stack, s.config, stackName)
+	}
+
+	s.stacks[suffix] = stack
+	s.names = append(s.names, stackName)
+	return stack
+}
+
+// addDependencies records the depends_on of each configured stack. Stacks none of whose sections
+// are configured don't exist and are left out.
+func (s *stackSet) addDependencies() {
+	for _, config := range s.config.Stacks {
+		stack, ok := s.stacks[config.Name]
+		if !ok {
+			continue
+		}
+		for _, dependency := range config.DependsOn {
+			if other, ok := s.stacks[dependency]; ok {
+				stack.AddDependency(other)
+			}
+		}
+	}
+}
+
+// dependencies returns each created stack with the stacks it depends on, once the app has been
+// synthesized and cdktf has recorded the cross-stack references
+func (s *stackSet) dependencies() map[string][]string {
+	dependencies := map[string][]string{}
+	for _, stack := range s.stacks {
+		name := *stack.Node().Id()
+		dependencies[name] = []string{}
+		for _, dependency := range *stack.Dependencies() {
+			dependencies[name] = append(dependencies[name], *dependency.Node().Id())
+		}
+	}
+	return dependencies
+}
+
+// resourcesByAddress indexes the resources of every stack in app by <type>.<logical id>, the
+// address they have in cdk.tf.json. An address can occur in several stacks. Building the index
+// makes jsii calls for every construct, so passes that add no resources share one.
+func resourcesByAddress(app cdktf.App) map[string][]cdktf.TerraformResource {
+	resources := map[string][]cdktf.TerraformResource{}
+	for _, child := range *app.Node().Children() {
+		stack, ok := child.(cdktf.TerraformStack)
+		if !ok {
+			continue
+		}
+		for _, node := range *stack.Node().FindAll(constructs.ConstructOrder_PREORDER) {
+			if resource, ok := node.(cdktf.TerraformResource); ok {
+				address := *resource.TerraformResourceType() + "." + *resource.FriendlyUniqueId()
+				resources[address] = append(resources[address], resource)
+			}
+		}
+	}
+	return resources
+}
+
+// stackDocument is a stack with its Terraform JSON, synthesized in memory
+type stackDocument struct {
+	stack cdktf.TerraformStack
+	json  string
+}
+
+// stackDocuments synthesizes every stack of app in memory, in construct order, for the builders
+// that add a block to the stacks which reference it. Each call converts every stack through jsii,
+// a large part of synth time, so a pass reuses its documents instead of calling it again.
+func stackDocuments(app cdktf.App) []stackDocument {
+	var documents []stackDocument
+	for _, child := range *app.Node().Children() {
+		if stack, ok := child.(cdktf.TerraformStack); ok {
+			raw, _ := json.Marshal(stack.ToTerraform())
+			documents = append(documents, stackDocument{stack, string(raw)})
+		}
+	}
+	return documents
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// synthResult is the outcome of synthesizing one environment
+type synthResult struct {
+	environment string
+	output      []byte
+	err         error
+	elapsed     time.Duration
+}
+
+// synthesizeEnvironment runs this program for one environment in a process of its own, writing
+// to outdir. Each process has its own jsii runtime, which is what lets environments synthesize in
+// parallel: calls into one runtime are serialized.
+func synthesizeEnvironment(executable string, environment string, outdir string, args []string) synthResult {
+	start := time.Now()
+	if err := os.MkdirAll(outdir, 0o755); err != nil {
+		return synthResult{environment: environment, err: err}
+	}
+	command := exec.Command(executable, args...)
+	command.Env = append(os.Environ(), "CDKTF_ENVIRONMENT="+environment, "CDKTF_OUTDIR="+outdir)
+	// The environments synthesize in parallel, so each joins the trace under the command's span
+	command.Env = append(command.Env, traceEnvironment()...)
+	var output bytes.Buffer
+	command.Stdout = &output
+	command.Stderr = &output
+	err := command.Run()
+	return synthResult{environment: environment, output: output.Bytes(), err: err, elapsed: time.Since(start)}
+}
+
+// runSynthAll is the synth-all command. It synthesizes every environment of the config, or those
+// given with -environments, into <outdir>/<environment>, running up to -parallel at a time. The
+// stacks of one environment reference each other, so they are always synthesized together.
+func runSynthAll(args []string) error {
+	flags := flag.NewFlagSet("synth-all", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "directory the environments are synthesized into")
+	parallel := flags.Int("parallel", runtime.NumCPU(), "environments synthesized at once")
+	only := flags.String("environments", "", "comma-separated environments (default all in environments)")
+	scan := flags.Bool("scan", false, "scan each environment's stacks for misconfigurations")
+	failOn := flags.String("fail-on", "", "lowest scan severity that fails an environment (default high)")
+	profileDir := flags.String("pprof", "", "write each environment's synth profiles to <pprof>/<environment>")
+	flags.Parse(args)
+
+	if *parallel < 1 {
+		return fmt.Errorf("-parallel must be at least 1")
+	}
+	config, err := loadConfig("config.json")
+	if err != nil {
+		return err
+	}
+	environments := slices.Sorted(maps.Keys(config.Environments))
+	if *only != "" {
+		environments = strings.Split(*only, ",")
+		for _, environment := range environments {
+			if _, ok := config.Environments[environment]; !ok {
+				return fmt.Errorf("no environment %s in environments", environment)
+			}
+		}
+	}
+	if len(environments) == 0 {
+		return fmt.Errorf("synth-all needs an environments section")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	var synthArgs []string
+	if *scan {
+		synthArgs = append(synthArgs, "-scan")
+	}
+	if *failOn != "" {
+		synthArgs = append(synthArgs, "-fail-on", *failOn)
+	}
+	argsFor := func(environment string) []string {
+		if *profileDir == "" {
+			return synthArgs
+		}
+		return append(slices.Clone(synthArgs), "-pprof", filepath.Join(*profileDir, environment))
+	}
+
+	workers := min(*parallel, len(environments))
+	fmt.Printf("🏗️  Synthesizing %d environment(s), %d at a time...\n", len(environments), workers)
+	start := time.Now()
+	pending := make(chan string)
+	results := make(chan synthResult)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for environment := range pending {
+				results <- synthesizeEnvironment(executable, environment, filepath.Join(*outdir, environment), argsFor(environment))
+			}
+		}()
+	}
+	go func() {
+		for _, environment := range environments {
+			pending <- environment
+		}
+		close(pending)
+		wg.Wait()
+		close(results)
+	}()
+
+	// Print each environment's output whole as it finishes, so parallel runs don't interleave
+	var failed []string
+	for result := range results {
+		if result.err != nil {
+			failed = append(failed, result.environment)
+			fmt.Printf("\n✗ %s failed after %s:\n%s", result.environment, result.elapsed.Round(time.Millisecond), result.output)
+			continue
+		}
+		fmt.Printf("  ✓ %s synthesized into %s in %s\n", result.environment, filepath.Join(*outdir, result.environment),
+			result.elapsed.Round(time.Millisecond))
+	}
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		return fmt.Errorf("%d of %d environment(s) failed: %s", len(failed), len(environments), strings.Join(failed, ", "))
+	}
+	fmt.Printf("✓ Done in %s\n", time.Since(start).Round(time.Millisecond))
+	return nil
+}
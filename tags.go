@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxTags is the most tags an AWS resource (or an STS session) can have, default tags included
+const maxTags = 50
+
+// tagCharacters are the characters AWS allows in tag keys and values
+var tagCharacters = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// validateTag checks a tag against the limits AWS enforces at apply time. Values with a
+// ${...} reference are only known then, so only their length is checked.
+func validateTag(key, value string) error {
+	if length := utf8.RuneCountInString(key); length == 0 || length > 128 {
+		return invalidValue(key, "shorten the key to at most 128 characters", "tag key %q must be 1-128 characters", key)
+	}
+	if !tagCharacters.MatchString(key) {
+		return invalidValue(key, "use letters, digits, spaces and _.:/=+-@ only", "tag key %q has characters AWS doesn't allow", key)
+	}
+	if strings.HasPrefix(strings.ToLower(key), "aws:") {
+		return invalidValue(key, "drop the aws: prefix, which is reserved for tags AWS sets", "tag key %q uses the reserved aws: prefix", key)
+	}
+	if utf8.RuneCountInString(value) > 256 {
+		return invalidValue(value, "shorten the value to at most 256 characters", "tag %s: value must be at most 256 characters", key)
+	}
+	if !strings.Contains(value, "${") && !tagCharacters.MatchString(value) {
+		return invalidValue(value, "use letters, digits, spaces and _.:/=+-@ only", "tag %s: value %q has characters AWS doesn't allow", key, value)
+	}
+	return nil
+}
+
+// overrideTags returns the tags an overrides entry sets on its resource, from tags.<key> paths or
+// a whole tags map
+func overrideTags(values map[string]interface{}) map[string]string {
+	tags := map[string]string{}
+	for path, value := range values {
+		if key, ok := strings.CutPrefix(path, "tags."); ok {
+			tags[key] = fmt.Sprint(value)
+		}
+		if whole, ok := value.(map[string]interface{}); ok && path == "tags" {
+			for key, value := range whole {
+				tags[key] = fmt.Sprint(value)
+			}
+		}
+	}
+	return tags
+}
+
+// validateTags checks the tags every AWS resource gets (the platform's, default_tags and the build
+// tags) and, combined with them, the tags overrides add to single resources. Resources can't have
+// more than 50 tags in all. AzureRM has limits of its own, so azure configs aren't checked.
+func validateTags(config Config) error {
+	for _, key := range reservedTags {
+		if _, ok := config.DefaultTags[key]; ok {
+			return fmt.Errorf("default_tags: %s is set by the platform", key)
+		}
+	}
+	if _, ok := config.DefaultTags[stackTag]; ok && config.ResourceGroups {
+		return fmt.Errorf("default_tags: %s is set by the platform with resource_groups", stackTag)
+	}
+	if usesAzure(config) {
+		return nil
+	}
+
+	common := map[string]string{
+		"Project":     config.Project,
+		"Environment": config.Environment,
+		"ManagedBy":   "CDKTF-JSON-Platform",
+	}
+	for _, key := range slices.Sorted(maps.Keys(config.DefaultTags)) {
+		if err := validateTag(key, config.DefaultTags[key]); err != nil {
+			return fmt.Errorf("default_tags: %w", err)
+		}
+		common[key] = config.DefaultTags[key]
+	}
+	if config.ResourceGroups {
+		common[stackTag] = ""
+	}
+	if config.BuildTags != nil {
+		// Their values are only known at synth time
+		for _, name := range buildTagNames {
+			if !slices.Contains(config.BuildTags.Exclude, name) {
+				common[name] = ""
+			}
+		}
+	}
+	if len(common) > maxTags {
+		return invalidValue(len(common), "remove default_tags, or exclude build tags",
+			"default_tags: resources would get %d tags with the platform and build tags; AWS allows %d", len(common), maxTags)
+	}
+
+	for _, address := range slices.Sorted(maps.Keys(config.Overrides)) {
+		tags := overrideTags(config.Overrides[address])
+		for _, key := range slices.Sorted(maps.Keys(tags)) {
+			if err := validateTag(key, tags[key]); err != nil {
+				return fmt.Errorf("overrides: %s: %w", address, err)
+			}
+		}
+		combined := maps.Clone(common)
+		maps.Copy(combined, tags)
+		if len(combined) > maxTags {
+			return invalidValue(len(combined), "move tags into default_tags only if every resource needs them, or drop some",
+				"overrides: %s: the resource would get %d tags with the default tags; AWS allows %d", address, len(combined), maxTags)
+		}
+	}
+	return nil
+}
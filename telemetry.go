@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TelemetryConfig opts in to anonymous usage telemetry, so the platform team can see which config
+// sections and resource types are used. Every synth adds to a summary in a local file and, when an
+// endpoint is set, posts its event there. Events carry no project, environment, user or config
+// values. DO_NOT_TRACK=1 turns telemetry off whatever the config says.
+type TelemetryConfig struct {
+	Enabled     bool   `json:"enabled"`
+	File        string `json:"file"`         // defaults to telemetry.json, relative to the working directory
+	Endpoint    string `json:"endpoint"`     // https URL events are posted to as JSON
+	EndpointEnv string `json:"endpoint_env"` // environment variable holding endpoint
+}
+
+func (t *TelemetryConfig) validate() error {
+	if t.Endpoint != "" && t.EndpointEnv != "" {
+		return fmt.Errorf("endpoint and endpoint_env are mutually exclusive")
+	}
+	if t.Endpoint != "" {
+		if parsed, err := url.Parse(t.Endpoint); err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+			return fmt.Errorf("endpoint %q must be an https URL", t.Endpoint)
+		}
+	}
+	return nil
+}
+
+func (t *TelemetryConfig) file() string {
+	if t.File != "" {
+		return t.File
+	}
+	return "telemetry.json"
+}
+
+// telemetryEvent is what one synth reports
+type telemetryEvent struct {
+	Date string `json:"date"` // the day only
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	Go   string `json:"go"`
+	// Sections are the top-level config keys set
+	Sections      []string       `json:"sections"`
+	ResourceTypes map[string]int `json:"resource_types,omitempty"`
+	Stacks        int            `json:"stacks"`
+	DurationMS    int64          `json:"duration_ms"`
+	Status        string         `json:"status"` // succeeded or failed
+	// ErrorCategory is config, validation, build, synth or other, with the section of a
+	// validation error, such as validation:kafka
+	ErrorCategory string `json:"error_category,omitempty"`
+}
+
+// telemetrySummary adds up the events of every synth since the file was started
+type telemetrySummary struct {
+	Since           string         `json:"since"`
+	Updated         string         `json:"updated"`
+	Synths          int            `json:"synths"`
+	Failures        int            `json:"failures"`
+	TotalDurationMS int64          `json:"total_duration_ms"`
+	MaxDurationMS   int64          `json:"max_duration_ms"`
+	Sections        map[string]int `json:"sections"`       // synths using each section
+	ResourceTypes   map[string]int `json:"resource_types"` // resources of each type, over all synths
+	Errors          map[string]int `json:"errors"`         // failures by category
+}
+
+// readTelemetry reads the telemetry settings and the sections set from the raw config, so a synth
+// whose config fails validation is still counted. It returns nil when telemetry isn't enabled.
+func readTelemetry(path string) (*TelemetryConfig, []string) {
+	if os.Getenv("DO_NOT_TRACK") == "1" || os.Getenv("DO_NOT_TRACK") == "true" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil
+	}
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &document); err != nil {
+		return nil, nil
+	}
+	var settings TelemetryConfig
+	if err := json.Unmarshal(document["telemetry"], &settings); err != nil || !settings.Enabled {
+		return nil, nil
+	}
+	var sections []string
+	for _, key := range slices.Sorted(maps.Keys(document)) {
+		switch value := strings.TrimSpace(string(document[key])); value {
+		case "null", "false", "{}", "[]", `""`:
+		default:
+			sections = append(sections, key)
+		}
+	}
+	return &settings, sections
+}
+
+// errorCategory names the kind of a synth error without any of its details
+func errorCategory(err error) string {
+	var configError *ConfigError
+	var validationError *ValidationError
+	var synthError *SynthError
+	switch {
+	case errors.As(err, &configError):
+		return "config"
+	case errors.As(err, &validationError):
+		if section, _, _ := strings.Cut(validationError.Field, "."); section != "" {
+			return "validation:" + section
+		}
+		return "validation"
+	case errors.As(err, &synthError):
+		return synthError.Stage
+	}
+	return "other"
+}
+
+// recordTelemetry adds a synth of the config at path to the local summary and posts its event to
+// the endpoint, when the config opts in. Like the audit log, a failure is reported as a warning.
+func recordTelemetry(path string, started time.Time, outdir string, stackNames []string, err error) {
+	settings, sections := readTelemetry(path)
+	if settings == nil {
+		return
+	}
+	event := telemetryEvent{
+		Date:          started.UTC().Format("2006-01-02"),
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		Go:            runtime.Version(),
+		Sections:      sections,
+		ResourceTypes: map[string]int{},
+		Stacks:        len(stackNames),
+		DurationMS:    time.Since(started).Milliseconds(),
+		Status:        "succeeded",
+	}
+	if err != nil {
+		event.Status, event.ErrorCategory = "failed", errorCategory(err)
+	}
+	for _, name := range stackNames {
+		if stack, err := describeStack(outdir, name); err == nil {
+			for resourceType, count := range stack.ResourcesByType {
+				event.ResourceTypes[resourceType] += count
+			}
+		}
+	}
+
+	if err := addTelemetry(settings.file(), event); err != nil {
+		fmt.Printf("⚠️  Telemetry: writing %s: %v\n", settings.file(), err)
+	}
+	target := settings.Endpoint
+	if settings.EndpointEnv != "" {
+		target = os.Getenv(settings.EndpointEnv)
+	}
+	if target != "" {
+		// Short, as every synth waits for it
+		client := &http.Client{Timeout: 3 * time.Second}
+		if err := postJSON(client, target, nil, event); err != nil {
+			fmt.Printf("⚠️  Telemetry: posting the event: %v\n", err)
+		}
+	}
+}
+
+// readTelemetrySummary reads a summary file, or returns an empty summary when there is none yet
+func readTelemetrySummary(path string) (telemetrySummary, error) {
+	summary := telemetrySummary{Sections: map[string]int{}, ResourceTypes: map[string]int{}, Errors: map[string]int{}}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return summary, nil
+	}
+	if err != nil {
+		return summary, err
+	}
+	if err := json.Unmarshal(raw, &summary); err != nil {
+		return summary, fmt.Errorf("parsing: %w", err)
+	}
+	for _, counts := range []*map[string]int{&summary.Sections, &summary.ResourceTypes, &summary.Errors} {
+		if *counts == nil {
+			*counts = map[string]int{}
+		}
+	}
+	return summary, nil
+}
+
+// addTelemetry adds an event to the summary file at path
+func addTelemetry(path string, event telemetryEvent) error {
+	summary, err := readTelemetrySummary(path)
+	if err != nil {
+		return err
+	}
+	if summary.Since == "" {
+		summary.Since = event.Date
+	}
+	summary.Updated = event.Date
+	summary.Synths++
+	summary.TotalDurationMS += event.DurationMS
+	summary.MaxDurationMS = max(summary.MaxDurationMS, event.DurationMS)
+	for _, section := range event.Sections {
+		summary.Sections[section]++
+	}
+	for resourceType, count := range event.ResourceTypes {
+		summary.ResourceTypes[resourceType] += count
+	}
+	if event.Status == "failed" {
+		summary.Failures++
+		summary.Errors[event.ErrorCategory]++
+	}
+
+	raw, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
+
+// printCounts prints counts by name, most used first
+func printCounts(title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	names := slices.Sorted(maps.Keys(counts))
+	slices.SortStableFunc(names, func(a, b string) int { return counts[b] - counts[a] })
+	for _, name := range names {
+		fmt.Printf("  %6d  %s\n", counts[name], name)
+	}
+}
+
+// runTelemetry is the telemetry command. It prints the summary the synths have added up locally.
+func runTelemetry(args []string) error {
+	flags := flag.NewFlagSet("telemetry", flag.ExitOnError)
+	file := flags.String("file", "telemetry.json", "telemetry summary file")
+	flags.Parse(args)
+
+	if _, err := os.Stat(*file); err != nil {
+		return fmt.Errorf("reading %s (enable telemetry in config.json and synthesize first): %w", *file, err)
+	}
+	summary, err := readTelemetrySummary(*file)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", *file, err)
+	}
+	fmt.Printf("📊 %d synth(s) from %s to %s, %d failed\n", summary.Synths, summary.Since, summary.Updated, summary.Failures)
+	if summary.Synths > 0 {
+		average := time.Duration(summary.TotalDurationMS/int64(summary.Synths)) * time.Millisecond
+		fmt.Printf("   duration: %s average, %s max\n", average, time.Duration(summary.MaxDurationMS)*time.Millisecond)
+	}
+	printCounts("Config sections (synths using them)", summary.Sections)
+	printCounts("Resource types (resources created)", summary.ResourceTypes)
+	printCounts("Errors", summary.Errors)
+	return nil
+}
@@ -0,0 +1,7 @@
+function addRoles(user, context, callback) {
+  const namespace = 'https://my-app.example.com';
+  const roles = (context.authorization || {}).roles || [];
+  context.idToken[`${namespace}/roles`] = roles;
+  context.accessToken[`${namespace}/roles`] = roles;
+  callback(null, user, context);
+}
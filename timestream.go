@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/s3bucket"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/timestreamwritedatabase"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/timestreamwritetable"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// TimestreamConfig describes a Timestream for LiveAnalytics database and its tables
+type TimestreamConfig struct {
+	Database  string                     `json:"database"` // defaults to metrics
+	KMSKeyArn string                     `json:"kms_key_arn"`
+	Tables    map[string]TimestreamTable `json:"tables"`
+}
+
+// TimestreamTable keeps recent records in the memory store, where writes and queries are fastest,
+// and moves them to the magnetic store for the rest of their retention
+type TimestreamTable struct {
+	MemoryRetentionHours  float64 `json:"memory_retention_hours"`  // 1 to 8766, 24 by default
+	MagneticRetentionDays float64 `json:"magnetic_retention_days"` // 1 to 73000, 365 by default
+	// MagneticStoreWrites accepts records older than the memory store's retention; records
+	// Timestream still rejects are written to the config bucket
+	MagneticStoreWrites bool `json:"magnetic_store_writes"`
+	// PartitionKey is a dimension to partition the table on, e.g. device_id
+	PartitionKey        string `json:"partition_key"`
+	RequirePartitionKey bool   `json:"require_partition_key"` // reject records without the dimension
+}
+
+func (t *TimestreamConfig) database() string {
+	if t.Database != "" {
+		return t.Database
+	}
+	return "metrics"
+}
+
+func (t *TimestreamConfig) validate() error {
+	if t.Database != "" && !nameSegmentPattern.MatchString(t.Database) {
+		return invalidValue(t.Database, "", "database %q must be lowercase letters, digits and hyphens", t.Database)
+	}
+	if len(t.Tables) == 0 {
+		return fmt.Errorf("at least one table is required")
+	}
+	for name, table := range t.Tables {
+		if !blockLabel.MatchString(name) || len(name) < 3 || len(name) > 256 {
+			return fmt.Errorf("tables: name %q must be 3 to 256 letters, digits, _ or -", name)
+		}
+		if table.MemoryRetentionHours != 0 && (table.MemoryRetentionHours < 1 || table.MemoryRetentionHours > 8766) {
+			return fmt.Errorf("tables.%s: memory_retention_hours must be between 1 and 8766", name)
+		}
+		if table.MagneticRetentionDays != 0 && (table.MagneticRetentionDays < 1 || table.MagneticRetentionDays > 73000) {
+			return fmt.Errorf("tables.%s: magnetic_retention_days must be between 1 and 73000", name)
+		}
+		if table.RequirePartitionKey && table.PartitionKey == "" {
+			return fmt.Errorf("tables.%s: require_partition_key needs partition_key", name)
+		}
+	}
+	return nil
+}
+
+// addTimestream creates the database and its tables, and exports the name of each
+func addTimestream(stack cdktf.TerraformStack, config Config, bucket s3bucket.S3Bucket) {
+	timestream := config.Timestream
+
+	databaseConfig := &timestreamwritedatabase.TimestreamwriteDatabaseConfig{
+		DatabaseName: jsii.String(resourceName(config, "aws_timestreamwrite_database", timestream.database())),
+	}
+	if timestream.KMSKeyArn != "" {
+		databaseConfig.KmsKeyId = jsii.String(timestream.KMSKeyArn)
+	}
+	database := timestreamwritedatabase.NewTimestreamwriteDatabase(stack, jsii.String("timestream"), databaseConfig)
+
+	for _, name := range slices.Sorted(maps.Keys(timestream.Tables)) {
+		settings := timestream.Tables[name]
+		memoryRetention := settings.MemoryRetentionHours
+		if memoryRetention == 0 {
+			memoryRetention = 24
+		}
+		magneticRetention := settings.MagneticRetentionDays
+		if magneticRetention == 0 {
+			magneticRetention = 365
+		}
+
+		tableConfig := &timestreamwritetable.TimestreamwriteTableConfig{
+			DatabaseName: database.DatabaseName(),
+			TableName:    jsii.String(name),
+			RetentionProperties: &timestreamwritetable.TimestreamwriteTableRetentionProperties{
+				MemoryStoreRetentionPeriodInHours:  jsii.Number(memoryRetention),
+				MagneticStoreRetentionPeriodInDays: jsii.Number(magneticRetention),
+			},
+		}
+		if settings.MagneticStoreWrites {
+			tableConfig.MagneticStoreWriteProperties = &timestreamwritetable.TimestreamwriteTableMagneticStoreWriteProperties{
+				EnableMagneticStoreWrites: jsii.Bool(true),
+				MagneticStoreRejectedDataLocation: &timestreamwritetable.TimestreamwriteTableMagneticStoreWritePropertiesMagneticStoreRejectedDataLocation{
+					S3Configuration: &timestreamwritetable.TimestreamwriteTableMagneticStoreWritePropertiesMagneticStoreRejectedDataLocationS3Configuration{
+						BucketName:       bucket.Bucket(),
+						ObjectKeyPrefix:  jsii.String("timestream-rejected/" + name + "/"),
+						EncryptionOption: jsii.String("SSE_S3"),
+					},
+				},
+			}
+		}
+		if settings.PartitionKey != "" {
+			enforcement := "OPTIONAL"
+			if settings.RequirePartitionKey {
+				enforcement = "REQUIRED"
+			}
+			tableConfig.Schema = &timestreamwritetable.TimestreamwriteTableSchema{
+				CompositePartitionKey: &timestreamwritetable.TimestreamwriteTableSchemaCompositePartitionKey{
+					Type:                jsii.String("DIMENSION"),
+					Name:                jsii.String(settings.PartitionKey),
+					EnforcementInRecord: jsii.String(enforcement),
+				},
+			}
+		}
+		table := timestreamwritetable.NewTimestreamwriteTable(stack, jsii.String("timestream_"+name), tableConfig)
+
+		cdktf.NewTerraformOutput(stack, jsii.String("timestream_"+name+"_table_name"), &cdktf.TerraformOutputConfig{
+			Value:       table.TableName(),
+			Description: jsii.String("The name of the " + name + " Timestream table"),
+		})
+	}
+
+	cdktf.NewTerraformOutput(stack, jsii.String("timestream_database_name"), &cdktf.TerraformOutputConfig{
+		Value:       database.DatabaseName(),
+		Description: jsii.String("The name of the Timestream database"),
+	})
+
+	fmt.Printf("  ✓ Timestream database with %d table(s)\n", len(timestream.Tables))
+}
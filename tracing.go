@@ -0,0 +1,231 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tracer collects the spans of a run and exports them over OTLP/HTTP, in its JSON encoding, when
+// the run ends. It is configured with the standard OpenTelemetry variables: tracing is on when
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT is set, and a TRACEPARENT
+// from a CI job makes the run part of the job's trace.
+type tracer struct {
+	mu       sync.Mutex
+	endpoint string
+	headers  map[string]string
+	service  string
+	traceID  string
+	// parentID is the span of the TRACEPARENT the run was started with, or ""
+	parentID string
+	// open are the spans started and not yet ended, innermost last
+	open  []*span
+	ended []*span
+}
+
+// activeTracer is the run's tracer, or nil when tracing is off
+var activeTracer *tracer
+
+// span is one timed phase of a run. Spans nest in the order they are started; a nil span, as
+// startSpan returns with tracing off, does nothing.
+type span struct {
+	tracer     *tracer
+	name       string
+	id         string
+	parentID   string
+	start, end time.Time
+	attributes map[string]string
+	err        error
+}
+
+// traceparentHeader matches a W3C trace context: version, trace ID, parent span ID and flags
+var traceparentHeader = regexp.MustCompile(`^00-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+func randomID(bytes int) string {
+	id := make([]byte, bytes)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// startTracing turns tracing on for the run when an OTLP endpoint is configured
+func startTracing() {
+	if os.Getenv("OTEL_SDK_DISABLED") == "true" {
+		return
+	}
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint == "" {
+		base := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+		if base == "" {
+			return
+		}
+		endpoint = strings.TrimSuffix(base, "/") + "/v1/traces"
+	}
+	t := &tracer{endpoint: endpoint, headers: map[string]string{}, service: os.Getenv("OTEL_SERVICE_NAME"), traceID: randomID(16)}
+	if t.service == "" {
+		t.service = "json-to-terraform"
+	}
+	for _, variable := range []string{"OTEL_EXPORTER_OTLP_HEADERS", "OTEL_EXPORTER_OTLP_TRACES_HEADERS"} {
+		for _, pair := range strings.Split(os.Getenv(variable), ",") {
+			if key, value, ok := strings.Cut(pair, "="); ok {
+				value, _ = url.QueryUnescape(strings.TrimSpace(value))
+				t.headers[strings.TrimSpace(key)] = value
+			}
+		}
+	}
+	if match := traceparentHeader.FindStringSubmatch(os.Getenv("TRACEPARENT")); match != nil {
+		t.traceID, t.parentID = match[1], match[2]
+	}
+	activeTracer = t
+}
+
+// startSpan starts a span inside the innermost open one, with attributes given as key, value pairs
+func startSpan(name string, attributes ...string) *span {
+	t := activeTracer
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := &span{tracer: t, name: name, id: randomID(8), parentID: t.parentID, start: time.Now(), attributes: map[string]string{}}
+	if len(t.open) > 0 {
+		s.parentID = t.open[len(t.open)-1].id
+	}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		s.attributes[attributes[i]] = attributes[i+1]
+	}
+	t.open = append(t.open, s)
+	return s
+}
+
+// setAttribute adds an attribute once the span has started
+func (s *span) setAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// finish ends the span, as failed when err isn't nil
+func (s *span) finish(err error) {
+	if s == nil {
+		return
+	}
+	t := s.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.end, s.err = time.Now(), err
+	for i := len(t.open) - 1; i >= 0; i-- {
+		if t.open[i] == s {
+			t.open = append(t.open[:i], t.open[i+1:]...)
+			break
+		}
+	}
+	t.ended = append(t.ended, s)
+}
+
+// traceparent is the W3C trace context of the span, for the commands it runs to join the trace
+func (s *span) traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.tracer.traceID, s.id)
+}
+
+// traceEnvironment returns the TRACEPARENT variable of the innermost open span, for commands that
+// run without a span of their own, or nothing with tracing off
+func traceEnvironment() []string {
+	t := activeTracer
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.open) == 0 {
+		return nil
+	}
+	return []string{"TRACEPARENT=" + t.open[len(t.open)-1].traceparent()}
+}
+
+// startCommandSpan starts a span for a command, such as a terraform subcommand, and passes it the
+// trace context; terraform, with OTEL_TRACES_EXPORTER=otlp, adds its own spans under it
+func startCommandSpan(command *exec.Cmd) *span {
+	name := filepath.Base(command.Path)
+	if len(command.Args) > 1 {
+		name += " " + command.Args[1]
+	}
+	s := startSpan(name, "process.command_args", strings.Join(command.Args, " "), "process.working_directory", command.Dir)
+	if s != nil {
+		if command.Env == nil {
+			command.Env = os.Environ()
+		}
+		command.Env = append(command.Env, "TRACEPARENT="+s.traceparent())
+	}
+	return s
+}
+
+// otlpAttributes turns attributes into OTLP key-value pairs
+func otlpAttributes(attributes map[string]string) []map[string]interface{} {
+	pairs := []map[string]interface{}{}
+	for key, value := range attributes {
+		pairs = append(pairs, map[string]interface{}{"key": key, "value": map[string]string{"stringValue": value}})
+	}
+	return pairs
+}
+
+// stopTracing ends the spans still open and exports every span of the run. A failed export is
+// reported without failing the command.
+func stopTracing() {
+	t := activeTracer
+	if t == nil {
+		return
+	}
+	for len(t.open) > 0 {
+		t.open[len(t.open)-1].finish(nil)
+	}
+
+	spans := []map[string]interface{}{}
+	for _, s := range t.ended {
+		// Status codes: 1 is ok, 2 is error
+		status := map[string]interface{}{"code": 1}
+		if s.err != nil {
+			status = map[string]interface{}{"code": 2, "message": s.err.Error()}
+		}
+		encoded := map[string]interface{}{
+			"traceId":           t.traceID,
+			"spanId":            s.id,
+			"name":              s.name,
+			"kind":              1, // internal
+			"startTimeUnixNano": fmt.Sprint(s.start.UnixNano()),
+			"endTimeUnixNano":   fmt.Sprint(s.end.UnixNano()),
+			"attributes":        otlpAttributes(s.attributes),
+			"status":            status,
+		}
+		if s.parentID != "" {
+			encoded["parentSpanId"] = s.parentID
+		}
+		spans = append(spans, encoded)
+	}
+	request := map[string]interface{}{
+		"resourceSpans": []map[string]interface{}{{
+			"resource": map[string]interface{}{
+				"attributes": otlpAttributes(map[string]string{"service.name": t.service}),
+			},
+			"scopeSpans": []map[string]interface{}{{
+				"scope": map[string]string{"name": "github.com/example/json-to-terraform"},
+				"spans": spans,
+			}},
+		}},
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	if err := postJSON(client, t.endpoint, t.headers, request); err != nil {
+		fmt.Printf("⚠️  Tracing: exporting %d span(s): %v\n", len(spans), err)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// UtilitiesConfig declares the random, time and null primitives the other sections of its stack
+// refer to by address, e.g. ${random_pet.suffix.id} in a name or time_sleep.iam_propagation in
+// an override's depends_on
+type UtilitiesConfig struct {
+	RandomPasswords map[string]RandomPassword `json:"random_passwords"`
+	RandomPets      map[string]RandomPet      `json:"random_pets"`
+	Sleeps          map[string]Sleep          `json:"sleeps"`
+	NullResources   map[string]NullResource   `json:"null_resources"`
+}
+
+// RandomPassword is generated once and kept in the state until a keeper changes
+type RandomPassword struct {
+	Length  int               `json:"length"`  // defaults to 32
+	Special *bool             `json:"special"` // include symbols; defaults to true
+	Keepers map[string]string `json:"keepers"` // values that generate a new password when changed
+}
+
+// RandomPet is a readable random name such as "brave-otter", for names that must be unique
+type RandomPet struct {
+	Length    int               `json:"length"` // words; defaults to 2
+	Prefix    string            `json:"prefix"`
+	Separator string            `json:"separator"` // defaults to -
+	Keepers   map[string]string `json:"keepers"`
+}
+
+// Sleep waits after the resources it depends on are created or before they are destroyed, for
+// APIs that are eventually consistent, such as IAM
+type Sleep struct {
+	CreateDuration  string            `json:"create_duration"` // e.g. 30s
+	DestroyDuration string            `json:"destroy_duration"`
+	DependsOn       []string          `json:"depends_on"` // resource addresses in the same stack
+	Triggers        map[string]string `json:"triggers"`   // values that wait again when changed
+}
+
+// NullResource is replaced whenever a trigger changes, so other resources can be made to follow it
+// with replace_triggered_by
+type NullResource struct {
+	Triggers  map[string]string `json:"triggers"`
+	DependsOn []string          `json:"depends_on"`
+}
+
+// Default version constraints of the utility providers unless provider_versions sets them
+const (
+	defaultRandomVersion = "~> 3.0"
+	defaultTimeVersion   = "~> 0.9"
+	defaultNullVersion   = "~> 3.0"
+)
+
+// durationPattern matches the durations time_sleep takes, e.g. 30s or 1m30s
+var durationPattern = regexp.MustCompile(`^(\d+h)?(\d+m)?(\d+s)?$`)
+
+func validateUtilityName(name string) error {
+	if !blockLabel.MatchString(name) {
+		return fmt.Errorf("name %q must be letters, digits, _ or -", name)
+	}
+	return nil
+}
+
+func validateDependsOn(name string, dependsOn []string) error {
+	for _, address := range dependsOn {
+		if !resourceAddress.MatchString(address) {
+			return fmt.Errorf("%s: depends_on %q is not a resource address like aws_iam_role.glue_role", name, address)
+		}
+	}
+	return nil
+}
+
+func (u *UtilitiesConfig) validate() error {
+	if len(u.RandomPasswords)+len(u.RandomPets)+len(u.Sleeps)+len(u.NullResources) == 0 {
+		return fmt.Errorf("at least one of random_passwords, random_pets, sleeps and null_resources is required")
+	}
+	for name, password := range u.RandomPasswords {
+		if err := validateUtilityName(name); err != nil {
+			return fmt.Errorf("random_passwords: %w", err)
+		}
+		// Providers and databases reject shorter passwords than this often enough to rule them out
+		if password.Length != 0 && (password.Length < 16 || password.Length > 128) {
+			return fmt.Errorf("random_passwords: %s: length must be 16 to 128", name)
+		}
+	}
+	for name, pet := range u.RandomPets {
+		if err := validateUtilityName(name); err != nil {
+			return fmt.Errorf("random_pets: %w", err)
+		}
+		if pet.Length < 0 || pet.Length > 5 {
+			return fmt.Errorf("random_pets: %s: length must be 1 to 5", name)
+		}
+	}
+	for name, sleep := range u.Sleeps {
+		if err := validateUtilityName(name); err != nil {
+			return fmt.Errorf("sleeps: %w", err)
+		}
+		if sleep.CreateDuration == "" && sleep.DestroyDuration == "" {
+			return fmt.Errorf("sleeps: %s: create_duration or destroy_duration is required", name)
+		}
+		for _, duration := range []string{sleep.CreateDuration, sleep.DestroyDuration} {
+			if duration != "" && !durationPattern.MatchString(duration) {
+				return fmt.Errorf("sleeps: %s: %q is not a duration like 30s or 1m30s", name, duration)
+			}
+		}
+		if err := validateDependsOn(name, sleep.DependsOn); err != nil {
+			return fmt.Errorf("sleeps: %w", err)
+		}
+	}
+	for name, resource := range u.NullResources {
+		if err := validateUtilityName(name); err != nil {
+			return fmt.Errorf("null_resources: %w", err)
+		}
+		if len(resource.Triggers) == 0 {
+			return fmt.Errorf("null_resources: %s: at least one trigger is required", name)
+		}
+		if err := validateDependsOn(name, resource.DependsOn); err != nil {
+			return fmt.Errorf("null_resources: %w", err)
+		}
+	}
+	return nil
+}
+
+// addUtility creates a utility resource under its config name, so the address other sections use
+// is <type>.<name>. The resources it depends on must be in the stack.
+func addUtility(stack cdktf.TerraformStack, resources map[string][]cdktf.TerraformResource, resourceType string,
+	name string, attributes map[string]interface{}, dependsOn []string) error {
+	if stack.Node().TryFindChild(jsii.String(name)) != nil {
+		return fmt.Errorf("utilities: %s.%s: the stack already has a construct named %s", resourceType, name, name)
+	}
+	var dependencies []*string
+	for _, address := range dependsOn {
+		inStack := slices.ContainsFunc(resources[address], func(resource cdktf.TerraformResource) bool {
+			return *cdktf.TerraformStack_Of(resource).Node().Id() == *stack.Node().Id()
+		})
+		if !inStack {
+			return fmt.Errorf("utilities: %s.%s: depends_on %s, which isn't in stack %s", resourceType, name, address,
+				*stack.Node().Id())
+		}
+		dependencies = append(dependencies, jsii.String(address))
+	}
+	resource := newRawResource(stack, resourceType, name, attributes)
+	if len(dependencies) > 0 {
+		resource.SetDependsOn(&dependencies)
+	}
+	return nil
+}
+
+// addUtilities declares the utilities once every resource exists, so depends_on can be checked,
+// along with the providers of the kinds used
+func addUtilities(app cdktf.App, stack cdktf.TerraformStack, config Config) error {
+	utilities := config.Utilities
+	resources := resourcesByAddress(app)
+	if len(utilities.RandomPasswords)+len(utilities.RandomPets) > 0 {
+		addRawProvider(stack, config, "random", "hashicorp/random", defaultRandomVersion, map[string]interface{}{})
+	}
+	if len(utilities.Sleeps) > 0 {
+		addRawProvider(stack, config, "time", "hashicorp/time", defaultTimeVersion, map[string]interface{}{})
+	}
+	if len(utilities.NullResources) > 0 {
+		addRawProvider(stack, config, "null", "hashicorp/null", defaultNullVersion, map[string]interface{}{})
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(utilities.RandomPasswords)) {
+		password := utilities.RandomPasswords[name]
+		length := password.Length
+		if length == 0 {
+			length = 32
+		}
+		attributes := map[string]interface{}{"length": length}
+		if password.Special != nil {
+			attributes["special"] = *password.Special
+		}
+		if len(password.Keepers) > 0 {
+			attributes["keepers"] = password.Keepers
+		}
+		if err := addUtility(stack, resources, "random_password", name, attributes, nil); err != nil {
+			return err
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(utilities.RandomPets)) {
+		pet := utilities.RandomPets[name]
+		length := pet.Length
+		if length == 0 {
+			length = 2
+		}
+		attributes := map[string]interface{}{"length": length}
+		if pet.Prefix != "" {
+			attributes["prefix"] = pet.Prefix
+		}
+		if pet.Separator != "" {
+			attributes["separator"] = pet.Separator
+		}
+		if len(pet.Keepers) > 0 {
+			attributes["keepers"] = pet.Keepers
+		}
+		if err := addUtility(stack, resources, "random_pet", name, attributes, nil); err != nil {
+			return err
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(utilities.Sleeps)) {
+		sleep := utilities.Sleeps[name]
+		attributes := map[string]interface{}{}
+		if sleep.CreateDuration != "" {
+			attributes["create_duration"] = sleep.CreateDuration
+		}
+		if sleep.DestroyDuration != "" {
+			attributes["destroy_duration"] = sleep.DestroyDuration
+		}
+		if len(sleep.Triggers) > 0 {
+			attributes["triggers"] = sleep.Triggers
+		}
+		if err := addUtility(stack, resources, "time_sleep", name, attributes, sleep.DependsOn); err != nil {
+			return err
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(utilities.NullResources)) {
+		resource := utilities.NullResources[name]
+		attributes := map[string]interface{}{"triggers": resource.Triggers}
+		if err := addUtility(stack, resources, "null_resource", name, attributes, resource.DependsOn); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("  ✓ Utilities: %d random password(s), %d random pet(s), %d sleep(s), %d null resource(s)\n",
+		len(utilities.RandomPasswords), len(utilities.RandomPets), len(utilities.Sleeps), len(utilities.NullResources))
+	return nil
+}
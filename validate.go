@@ -0,0 +1,327 @@
+package main
+
+import "fmt"
+
+// validateConfig checks the optional sections before any constructs are created
+func validateConfig(config Config) error {
+	if config.Batch != nil {
+		if err := config.Batch.validate(); err != nil {
+			return fmt.Errorf("batch: %w", err)
+		}
+	}
+	if config.Glue != nil {
+		if err := config.Glue.validate(); err != nil {
+			return fmt.Errorf("glue: %w", err)
+		}
+	}
+	if config.Athena != nil {
+		if err := config.Athena.validate(); err != nil {
+			return fmt.Errorf("athena: %w", err)
+		}
+	}
+	if config.Warehouse != nil {
+		if err := config.Warehouse.validate(); err != nil {
+			return fmt.Errorf("warehouse: %w", err)
+		}
+	}
+	if config.OpenSearch != nil {
+		if err := config.OpenSearch.validate(); err != nil {
+			return fmt.Errorf("opensearch: %w", err)
+		}
+	}
+	if config.Kafka != nil {
+		if err := config.Kafka.validate(); err != nil {
+			return fmt.Errorf("kafka: %w", err)
+		}
+	}
+	if config.Neptune != nil {
+		if err := config.Neptune.validate(); err != nil {
+			return fmt.Errorf("neptune: %w", err)
+		}
+	}
+	if config.DocumentDB != nil {
+		if err := config.DocumentDB.validate(); err != nil {
+			return fmt.Errorf("documentdb: %w", err)
+		}
+	}
+	if config.Timestream != nil {
+		if err := config.Timestream.validate(); err != nil {
+			return fmt.Errorf("timestream: %w", err)
+		}
+	}
+	if err := validateKeyspaces(config.Keyspaces); err != nil {
+		return fmt.Errorf("keyspaces: %w", err)
+	}
+	if config.MemoryDB != nil {
+		if err := config.MemoryDB.validate(); err != nil {
+			return fmt.Errorf("memorydb: %w", err)
+		}
+	}
+	if config.IoT != nil {
+		if err := config.IoT.validate(); err != nil {
+			return fmt.Errorf("iot: %w", err)
+		}
+	}
+	if config.SageMaker != nil {
+		if err := config.SageMaker.validate(); err != nil {
+			return fmt.Errorf("sagemaker: %w", err)
+		}
+	}
+	if config.Bedrock != nil {
+		if err := config.Bedrock.validate(); err != nil {
+			return fmt.Errorf("bedrock: %w", err)
+		}
+	}
+	if config.Pipeline != nil {
+		if err := config.Pipeline.validate(); err != nil {
+			return fmt.Errorf("pipeline: %w", err)
+		}
+	}
+	if config.CodeDeploy != nil {
+		if err := config.CodeDeploy.validate(); err != nil {
+			return fmt.Errorf("codedeploy: %w", err)
+		}
+	}
+	if config.SSMManagement != nil {
+		if err := config.SSMManagement.validate(); err != nil {
+			return fmt.Errorf("ssm_management: %w", err)
+		}
+		if config.SSMManagement.BatchPatchGroup != "" && (config.Batch == nil || config.Batch.isFargate()) {
+			return fmt.Errorf("ssm_management: batch_patch_group needs a batch section with EC2 or SPOT compute")
+		}
+	}
+	if config.SFTP != nil {
+		if err := config.SFTP.validate(); err != nil {
+			return fmt.Errorf("sftp: %w", err)
+		}
+	}
+	if config.AppRunner != nil {
+		if err := config.AppRunner.validate(); err != nil {
+			return fmt.Errorf("apprunner: %w", err)
+		}
+	}
+	if config.Amplify != nil {
+		if err := config.Amplify.validate(); err != nil {
+			return fmt.Errorf("amplify: %w", err)
+		}
+	}
+	if config.GlobalAccelerator != nil {
+		if err := config.GlobalAccelerator.validate(); err != nil {
+			return fmt.Errorf("global_accelerator: %w", err)
+		}
+	}
+	if config.WAF != nil {
+		if err := config.WAF.validate(); err != nil {
+			return fmt.Errorf("waf: %w", err)
+		}
+	}
+	if config.SecurityBaseline != nil {
+		if err := config.SecurityBaseline.validate(); err != nil {
+			return fmt.Errorf("security_baseline: %w", err)
+		}
+	}
+	if config.Compliance != nil {
+		if err := config.Compliance.validate(); err != nil {
+			return fmt.Errorf("compliance: %w", err)
+		}
+	}
+	if config.CloudTrail != nil {
+		if err := config.CloudTrail.validate(); err != nil {
+			return fmt.Errorf("cloudtrail: %w", err)
+		}
+	}
+	if config.Budgets != nil {
+		if err := config.Budgets.validate(); err != nil {
+			return fmt.Errorf("budgets: %w", err)
+		}
+	}
+	if config.Backup != nil {
+		if err := config.Backup.validate(); err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+	}
+	if config.Accounts != nil {
+		if err := config.Accounts.validate(); err != nil {
+			return fmt.Errorf("accounts: %w", err)
+		}
+	}
+	if config.AppConfig != nil {
+		if err := config.AppConfig.validate(); err != nil {
+			return fmt.Errorf("appconfig: %w", err)
+		}
+	}
+	if config.AMP != nil {
+		if err := config.AMP.validate(); err != nil {
+			return fmt.Errorf("amp: %w", err)
+		}
+	}
+	if config.AMG != nil {
+		if err := config.AMG.validate(); err != nil {
+			return fmt.Errorf("amg: %w", err)
+		}
+	}
+	if config.DMS != nil {
+		if err := validateDMS(config); err != nil {
+			return fmt.Errorf("dms: %w", err)
+		}
+	}
+	if config.Cloudflare != nil {
+		if err := config.Cloudflare.validate(); err != nil {
+			return fmt.Errorf("cloudflare: %w", err)
+		}
+	}
+	if config.Monitoring != nil {
+		if err := config.Monitoring.validate(); err != nil {
+			return fmt.Errorf("monitoring: %w", err)
+		}
+	}
+	if config.Kubernetes != nil {
+		if err := config.Kubernetes.validate(); err != nil {
+			return fmt.Errorf("kubernetes: %w", err)
+		}
+	}
+	if config.Helm != nil {
+		if err := validateHelm(config); err != nil {
+			return fmt.Errorf("helm: %w", err)
+		}
+	}
+	if config.Auth0 != nil {
+		if err := config.Auth0.validate(); err != nil {
+			return fmt.Errorf("auth0: %w", err)
+		}
+	}
+	if config.Atlas != nil {
+		if err := config.Atlas.validate(); err != nil {
+			return fmt.Errorf("atlas: %w", err)
+		}
+	}
+	if config.Fastly != nil {
+		if err := validateFastly(config); err != nil {
+			return fmt.Errorf("fastly: %w", err)
+		}
+	}
+	if config.Vault != nil {
+		if err := config.Vault.validate(); err != nil {
+			return fmt.Errorf("vault: %w", err)
+		}
+	}
+	if config.Utilities != nil {
+		if err := config.Utilities.validate(); err != nil {
+			return fmt.Errorf("utilities: %w", err)
+		}
+	}
+	if err := validateCloudControl(config.CloudControl); err != nil {
+		return fmt.Errorf("cloudcontrol: %w", err)
+	}
+	if config.Tracing && config.AppRunner == nil && config.Batch == nil {
+		return fmt.Errorf("tracing: the config has no apprunner or batch section to trace")
+	}
+	if len(config.Plugins) > 0 {
+		if err := validatePlugins(config.Plugins); err != nil {
+			return fmt.Errorf("plugins: %w", err)
+		}
+	}
+	if config.GitHub != nil {
+		if err := validateGitHub(config); err != nil {
+			return fmt.Errorf("github: %w", err)
+		}
+	}
+	if config.PagerDuty != nil {
+		if err := validatePagerDuty(config); err != nil {
+			return fmt.Errorf("pagerduty: %w", err)
+		}
+	}
+	if err := validateModules(config.Modules); err != nil {
+		return fmt.Errorf("modules: %w", err)
+	}
+	if config.Scan != nil {
+		if err := config.Scan.validate(); err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+	}
+	if config.Outputs != nil {
+		if err := config.Outputs.validate(); err != nil {
+			return fmt.Errorf("outputs: %w", err)
+		}
+	}
+	if config.BuildTags != nil {
+		if err := config.BuildTags.validate(); err != nil {
+			return fmt.Errorf("build_tags: %w", err)
+		}
+	}
+	if config.Cost != nil {
+		if err := config.Cost.validate(); err != nil {
+			return fmt.Errorf("cost: %w", err)
+		}
+	}
+	if config.Audit != nil {
+		if err := config.Audit.validate(); err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+	}
+	if config.Notifications != nil {
+		if err := config.Notifications.validate(); err != nil {
+			return fmt.Errorf("notifications: %w", err)
+		}
+	}
+	if config.ServiceCatalog != nil {
+		if err := config.ServiceCatalog.validate(); err != nil {
+			return fmt.Errorf("service_catalog: %w", err)
+		}
+	}
+	if config.Telemetry != nil {
+		if err := config.Telemetry.validate(); err != nil {
+			return fmt.Errorf("telemetry: %w", err)
+		}
+	}
+	if config.Backend != nil {
+		if err := config.Backend.validate(); err != nil {
+			return fmt.Errorf("backend: %w", err)
+		}
+	}
+	if err := validateComplianceProfile(config.ComplianceProfile); err != nil {
+		return err
+	}
+	if err := validateNaming(config); err != nil {
+		return fmt.Errorf("naming: %w", err)
+	}
+	if err := validateTags(config); err != nil {
+		return err
+	}
+	if err := validateVersions(config); err != nil {
+		return err
+	}
+	if config.AWSProvider != nil {
+		if err := config.AWSProvider.validate(); err != nil {
+			return fmt.Errorf("aws_provider: %w", err)
+		}
+	}
+	if err := validateEnvironments(config); err != nil {
+		return fmt.Errorf("environments: %w", err)
+	}
+	if err := validateOverrides(config.Overrides); err != nil {
+		return fmt.Errorf("overrides: %w", err)
+	}
+	if err := validateLifecycles(config.Lifecycle); err != nil {
+		return fmt.Errorf("lifecycle: %w", err)
+	}
+	if err := validateImports(config.Imports); err != nil {
+		return fmt.Errorf("imports: %w", err)
+	}
+	if err := validateChecks(config.Checks); err != nil {
+		return fmt.Errorf("checks: %w", err)
+	}
+	if err := validateWorkspaces(config); err != nil {
+		return fmt.Errorf("workspaces: %w", err)
+	}
+	if err := validateCloud(config); err != nil {
+		return err
+	}
+	if err := validateRegions(config); err != nil {
+		return err
+	}
+	if err := validateStacks(config.Stacks); err != nil {
+		return fmt.Errorf("stacks: %w", err)
+	}
+	return nil
+}
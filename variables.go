@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// VariableConfig declares a Terraform input variable, so a value can be changed at apply time
+// instead of being baked into the synthesized JSON
+type VariableConfig struct {
+	Type        string      `json:"type"` // string, number, bool or a type expression such as list(string)
+	Default     interface{} `json:"default"`
+	Description string      `json:"description"`
+	Sensitive   bool        `json:"sensitive"`
+}
+
+var variableTypes = regexp.MustCompile(`^(string|number|bool|any|(list|set|map|object|tuple)\(.*\))$`)
+
+// variableReference matches var.<name> and local.<name> in a Terraform expression, but not as the
+// tail of a longer address such as data.x.outputs.var
+var variableReference = regexp.MustCompile(`(^|[^A-Za-z0-9_.])(var|local)\.([A-Za-z_][A-Za-z0-9_-]*)`)
+
+// expressions matches the ${...} expressions in a config
+var expressions = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// configReferences returns the variables and locals a raw config references
+func configReferences(raw []byte) (variables []string, locals []string) {
+	seen := map[string]bool{}
+	for _, expression := range expressions.FindAll(raw, -1) {
+		for _, match := range variableReference.FindAllSubmatch(expression, -1) {
+			kind, name := string(match[2]), string(match[3])
+			if seen[kind+"."+name] {
+				continue
+			}
+			seen[kind+"."+name] = true
+			if kind == "var" {
+				variables = append(variables, name)
+			} else {
+				locals = append(locals, name)
+			}
+		}
+	}
+	return variables, locals
+}
+
+func validateVariables(config Config, raw []byte) error {
+	for name, variable := range config.Variables {
+		if !blockLabel.MatchString(name) {
+			return fmt.Errorf("variable name %q must be letters, digits, _ or -", name)
+		}
+		if variable.Type != "" && !variableTypes.MatchString(variable.Type) {
+			return fmt.Errorf("variable %s: %q is not a Terraform type", name, variable.Type)
+		}
+	}
+	for name := range config.Locals {
+		if !blockLabel.MatchString(name) {
+			return fmt.Errorf("local name %q must be letters, digits, _ or -", name)
+		}
+	}
+
+	variables, locals := configReferences(raw)
+	for _, name := range variables {
+		if _, ok := config.Variables[name]; !ok {
+			return fmt.Errorf("${var.%s} is used but no variable %s is declared", name, name)
+		}
+	}
+	for _, name := range locals {
+		if _, ok := config.Locals[name]; !ok {
+			return fmt.Errorf("${local.%s} is used but no local %s is declared", name, name)
+		}
+	}
+	return nil
+}
+
+// referencedIn reports whether a synthesized stack uses var.<name> or local.<name>
+func referencedIn(document string, kind string, name string) bool {
+	for _, match := range variableReference.FindAllStringSubmatch(document, -1) {
+		if match[2] == kind && match[3] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// addVariables declares each local and variable in the stacks that reference it. Locals come
+// first, since their values can reference variables; the values of the locals a stack declares
+// are searched along with its document, instead of synthesizing every stack again. A local can
+// be used only by another local, so the locals are scanned again until none is added.
+func addVariables(app cdktf.App, config Config) {
+	locals, variables := 0, 0
+	documents := stackDocuments(app)
+	for i, document := range documents {
+		declared := map[string]bool{}
+		for added := true; added; {
+			added = false
+			for _, name := range slices.Sorted(maps.Keys(config.Locals)) {
+				if !declared[name] && referencedIn(documents[i].json, "local", name) {
+					cdktf.NewTerraformLocal(document.stack, jsii.String(name), config.Locals[name])
+					value, _ := json.Marshal(config.Locals[name])
+					documents[i].json += string(value)
+					declared[name] = true
+					added = true
+					locals++
+				}
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(config.Variables)) {
+		variable := config.Variables[name]
+		variableConfig := &cdktf.TerraformVariableConfig{
+			Default: variable.Default,
+		}
+		if variable.Type != "" {
+			variableConfig.Type = jsii.String(variable.Type)
+		}
+		if variable.Description != "" {
+			variableConfig.Description = jsii.String(variable.Description)
+		}
+		if variable.Sensitive {
+			variableConfig.Sensitive = jsii.Bool(true)
+		}
+		for _, document := range documents {
+			// Builders declare some variables themselves, such as amplify_access_token
+			if referencedIn(document.json, "var", name) && document.stack.Node().TryFindChild(jsii.String(name)) == nil {
+				cdktf.NewTerraformVariable(document.stack, jsii.String(name), variableConfig)
+				variables++
+			}
+		}
+	}
+	fmt.Printf("  ✓ %d variable(s) and %d local(s) declared across stacks\n", variables, locals)
+}
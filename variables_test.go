@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/example/json-to-terraform/cdktftest"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+func TestAddVariablesDeclaresChainedLocals(t *testing.T) {
+	config := Config{Locals: map[string]interface{}{
+		"a":      "base",
+		"b":      "${local.a}-suffix",
+		"unused": "ignored",
+	}}
+
+	app := cdktftest.App(t)
+	stack := cdktf.NewTerraformStack(app, jsii.String("test"))
+	cdktf.NewTerraformOutput(stack, jsii.String("name"), &cdktf.TerraformOutputConfig{
+		Value: jsii.String("${local.b}"),
+	})
+	addVariables(app, config)
+
+	var document struct {
+		Locals map[string]interface{} `json:"locals"`
+	}
+	if err := json.Unmarshal([]byte(cdktftest.Synth(t, stack)), &document); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, ok := document.Locals[name]; !ok {
+			t.Errorf("local %s is not declared; locals: %v", name, document.Locals)
+		}
+	}
+	if _, ok := document.Locals["unused"]; ok {
+		t.Errorf("local unused is declared but nothing references it")
+	}
+}
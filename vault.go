@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/iamrole"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// VaultConfig provisions the Vault side of the app's secrets with the infrastructure: KV mounts,
+// the policies reading them, and AWS secrets engine roles handing out credentials for the config's
+// IAM roles. The provider reads its token from VAULT_TOKEN.
+type VaultConfig struct {
+	Address   string          `json:"address"`
+	Namespace string          `json:"namespace"` // for HCP Vault and Vault Enterprise, e.g. admin
+	KVMounts  []VaultKVMount  `json:"kv_mounts"`
+	Policies  []VaultPolicy   `json:"policies"`
+	AWS       *VaultAWSConfig `json:"aws"`
+}
+
+// VaultKVMount is a key/value secrets engine
+type VaultKVMount struct {
+	Path        string `json:"path"`
+	Version     int    `json:"version"` // 1 or 2 (default)
+	Description string `json:"description"`
+}
+
+// VaultPolicy is an ACL policy granting capabilities on paths
+type VaultPolicy struct {
+	Name  string            `json:"name"`
+	Paths map[string]string `json:"paths"` // path -> comma-separated capabilities, e.g. "read,list"
+}
+
+// VaultAWSConfig mounts the AWS secrets engine. Vault signs in to AWS with its own credentials,
+// such as its instance profile, as principal_arn; no access key is kept in the state.
+type VaultAWSConfig struct {
+	Path         string         `json:"path"` // defaults to aws
+	PrincipalARN string         `json:"principal_arn"`
+	Roles        []VaultAWSRole `json:"roles"`
+}
+
+// VaultAWSRole hands out STS credentials for an IAM role. A role created by the config, given by
+// its address, is made assumable by principal_arn.
+type VaultAWSRole struct {
+	Name string `json:"name"`
+	Role string `json:"role"` // an aws_iam_role address such as aws_iam_role.glue_role, or a role ARN
+	TTL  int    `json:"ttl"`  // seconds the credentials last; defaults to 3600
+}
+
+// defaultVaultVersion is the vault provider constraint unless provider_versions sets one
+const defaultVaultVersion = "~> 4.0"
+
+var (
+	vaultPathPattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*(/[a-z0-9][a-z0-9_-]*)*$`)
+	vaultNamePattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	iamPrincipalARN    = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:(role|user)/[\w+=,.@/-]+$`)
+	vaultCapabilities  = []string{"create", "read", "update", "patch", "delete", "list", "sudo", "deny"}
+	vaultRoleAddresses = regexp.MustCompile(`^aws_iam_role\.[A-Za-z0-9_-]+$`)
+)
+
+func (v *VaultPolicy) validate() error {
+	if !vaultNamePattern.MatchString(v.Name) {
+		return fmt.Errorf("name %q may only use lowercase letters, digits, _ and -", v.Name)
+	}
+	if len(v.Paths) == 0 {
+		return fmt.Errorf("%s: at least one path is required", v.Name)
+	}
+	for path, capabilities := range v.Paths {
+		if path == "" || strings.ContainsAny(path, " \t\n\"") {
+			return fmt.Errorf("%s: path %q is not a Vault path", v.Name, path)
+		}
+		for _, capability := range strings.Split(capabilities, ",") {
+			if !slices.Contains(vaultCapabilities, strings.TrimSpace(capability)) {
+				return fmt.Errorf("%s: %s: capability %q must be one of %s", v.Name, path, capability,
+					strings.Join(vaultCapabilities, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func (v *VaultAWSConfig) validate() error {
+	if v.Path != "" && !vaultPathPattern.MatchString(v.Path) {
+		return fmt.Errorf("path %q may only use lowercase letters, digits, _, - and /", v.Path)
+	}
+	if !iamPrincipalARN.MatchString(v.PrincipalARN) {
+		return fmt.Errorf("principal_arn %q must be an IAM role or user ARN", v.PrincipalARN)
+	}
+	if len(v.Roles) == 0 {
+		return fmt.Errorf("at least one role is required")
+	}
+	names := map[string]bool{}
+	for i, role := range v.Roles {
+		if !vaultNamePattern.MatchString(role.Name) {
+			return fmt.Errorf("roles[%d]: name %q may only use lowercase letters, digits, _ and -", i, role.Name)
+		}
+		if !vaultRoleAddresses.MatchString(role.Role) && !iamRoleARNPattern.MatchString(role.Role) {
+			return fmt.Errorf("roles[%d]: %s: role %q must be an aws_iam_role address or a role ARN", i, role.Name, role.Role)
+		}
+		// STS caps credentials of a role at 12 hours, and Vault asks for at least 15 minutes
+		if role.TTL != 0 && (role.TTL < 900 || role.TTL > 43200) {
+			return fmt.Errorf("roles[%d]: %s: ttl must be 900 to 43200 seconds", i, role.Name)
+		}
+		if names[role.Name] {
+			return fmt.Errorf("roles[%d]: duplicate role %s", i, role.Name)
+		}
+		names[role.Name] = true
+	}
+	return nil
+}
+
+func (v *VaultConfig) validate() error {
+	if !strings.HasPrefix(v.Address, "https://") {
+		return fmt.Errorf("address %q must be an https URL", v.Address)
+	}
+	if len(v.KVMounts) == 0 && len(v.Policies) == 0 && v.AWS == nil {
+		return fmt.Errorf("at least one of kv_mounts, policies and aws is required")
+	}
+	paths := map[string]bool{}
+	for i, mount := range v.KVMounts {
+		if !vaultPathPattern.MatchString(mount.Path) {
+			return fmt.Errorf("kv_mounts[%d]: path %q may only use lowercase letters, digits, _, - and /", i, mount.Path)
+		}
+		if mount.Version != 0 && mount.Version != 1 && mount.Version != 2 {
+			return fmt.Errorf("kv_mounts[%d]: %s: version must be 1 or 2", i, mount.Path)
+		}
+		if paths[mount.Path] {
+			return fmt.Errorf("kv_mounts[%d]: duplicate mount %s", i, mount.Path)
+		}
+		paths[mount.Path] = true
+	}
+	policies := map[string]bool{}
+	for i, policy := range v.Policies {
+		if err := policy.validate(); err != nil {
+			return fmt.Errorf("policies[%d]: %w", i, err)
+		}
+		if policies[policy.Name] {
+			return fmt.Errorf("policies[%d]: duplicate policy %s", i, policy.Name)
+		}
+		policies[policy.Name] = true
+	}
+	if v.AWS != nil {
+		if err := v.AWS.validate(); err != nil {
+			return fmt.Errorf("aws: %w", err)
+		}
+		if paths[v.AWS.path()] {
+			return fmt.Errorf("aws: path %s is also a kv mount", v.AWS.path())
+		}
+	}
+	return nil
+}
+
+func (v *VaultAWSConfig) path() string {
+	if v.Path == "" {
+		return "aws"
+	}
+	return v.Path
+}
+
+// vaultID is the logical id form of a Vault path or name, e.g. my_app_config for my-app/config
+func vaultID(name string) string {
+	return strings.Trim(logicalIDInvalid.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// policyJSON renders a policy in Vault's JSON policy syntax
+func (v *VaultPolicy) policyJSON() string {
+	paths := map[string]interface{}{}
+	for path, capabilities := range v.Paths {
+		list := []string{}
+		for _, capability := range strings.Split(capabilities, ",") {
+			list = append(list, strings.TrimSpace(capability))
+		}
+		paths[path] = map[string]interface{}{"capabilities": list}
+	}
+	policy, _ := json.Marshal(map[string]interface{}{"path": paths})
+	return string(policy)
+}
+
+// trustVault lets principal assume role, next to whatever its trust policy already allows
+func trustVault(role iamrole.IamRole, principal string) error {
+	var trust map[string]interface{}
+	if err := json.Unmarshal([]byte(*role.AssumeRolePolicyInput()), &trust); err != nil {
+		return fmt.Errorf("its trust policy is computed, so Vault can't be added to it")
+	}
+	statements, _ := trust["Statement"].([]interface{})
+	trust["Statement"] = append(statements, map[string]interface{}{
+		"Effect":    "Allow",
+		"Principal": map[string]string{"AWS": principal},
+		"Action":    "sts:AssumeRole",
+	})
+	policy, _ := json.Marshal(trust)
+	role.SetAssumeRolePolicy(jsii.String(string(policy)))
+	return nil
+}
+
+// addVault creates the mounts, policies and AWS secrets engine roles. It runs once every stack
+// exists, so the roles can point at IAM roles anywhere in the config; their ARNs are read across
+// stacks.
+func addVault(app cdktf.App, stack cdktf.TerraformStack, config Config) error {
+	vault := config.Vault
+	settings := map[string]interface{}{"address": vault.Address}
+	if vault.Namespace != "" {
+		settings["namespace"] = vault.Namespace
+	}
+	addRawProvider(stack, config, "vault", "hashicorp/vault", defaultVaultVersion, settings)
+
+	for _, mount := range vault.KVMounts {
+		version := mount.Version
+		if version == 0 {
+			version = 2
+		}
+		attributes := map[string]interface{}{
+			"path":    mount.Path,
+			"type":    "kv",
+			"options": map[string]string{"version": fmt.Sprint(version)},
+		}
+		if mount.Description != "" {
+			attributes["description"] = mount.Description
+		}
+		newRawResource(stack, "vault_mount", "kv_"+vaultID(mount.Path), attributes)
+	}
+
+	for _, policy := range vault.Policies {
+		newRawResource(stack, "vault_policy", "policy_"+vaultID(policy.Name), map[string]interface{}{
+			"name":   policy.Name,
+			"policy": policy.policyJSON(),
+		})
+	}
+
+	if vault.AWS != nil {
+		backend := newRawResource(stack, "vault_aws_secret_backend", "aws_secret_backend", map[string]interface{}{
+			"path":        vault.AWS.path(),
+			"region":      config.Region,
+			"description": "Managed by CDKTF-JSON-Platform from config.json",
+		})
+		resources := resourcesByAddress(app)
+		for _, role := range vault.AWS.Roles {
+			var arn interface{} = role.Role
+			if vaultRoleAddresses.MatchString(role.Role) {
+				found := resources[role.Role]
+				switch {
+				case len(found) == 0:
+					return fmt.Errorf("vault: aws: %s: %s isn't in any stack", role.Name, role.Role)
+				case len(found) > 1:
+					return fmt.Errorf("vault: aws: %s: %s is in several stacks", role.Name, role.Role)
+				}
+				iamRole := found[0].(iamrole.IamRole)
+				if err := trustVault(iamRole, vault.AWS.PrincipalARN); err != nil {
+					return fmt.Errorf("vault: aws: %s: %s: %w", role.Name, role.Role, err)
+				}
+				arn = iamRole.Arn()
+			}
+			ttl := role.TTL
+			if ttl == 0 {
+				ttl = 3600
+			}
+			newRawResource(stack, "vault_aws_secret_backend_role", "aws_role_"+vaultID(role.Name), map[string]interface{}{
+				"backend":         *backend.GetStringAttribute(jsii.String("path")),
+				"name":            role.Name,
+				"credential_type": "assumed_role",
+				"role_arns":       []interface{}{arn},
+				"default_sts_ttl": ttl,
+				"max_sts_ttl":     ttl,
+			})
+		}
+	}
+
+	roles := 0
+	if vault.AWS != nil {
+		roles = len(vault.AWS.Roles)
+	}
+	fmt.Printf("  ✓ Vault: %d KV mount(s), %d policy(ies), %d AWS role(s)\n", len(vault.KVMounts), len(vault.Policies), roles)
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// versionedProviders lists the providers whose version constraint can be set in provider_versions
+var versionedProviders = map[string]bool{
+	"auth0":        true,
+	"aws":          true,
+	"awscc":        true,
+	"azurerm":      true,
+	"cloudflare":   true,
+	"datadog":      true,
+	"fastly":       true,
+	"github":       true,
+	"grafana":      true,
+	"helm":         true,
+	"kubernetes":   true,
+	"mongodbatlas": true,
+	"null":         true,
+	"pagerduty":    true,
+	"random":       true,
+	"time":         true,
+	"vault":        true,
+}
+
+// versionConstraintPattern matches one Terraform version constraint, such as "~> 5.99" or ">= 1.6.0"
+var versionConstraintPattern = regexp.MustCompile(`^(=|!=|>|>=|<|<=|~>)?\s*v?\d+(\.\d+){0,2}(-[0-9A-Za-z.]+)?$`)
+
+func validateVersionConstraint(constraint string) error {
+	for _, part := range strings.Split(constraint, ",") {
+		if !versionConstraintPattern.MatchString(strings.TrimSpace(part)) {
+			return fmt.Errorf("%q is not a valid version constraint", constraint)
+		}
+	}
+	return nil
+}
+
+func validateVersions(config Config) error {
+	if config.TerraformVersion != "" {
+		if err := validateVersionConstraint(config.TerraformVersion); err != nil {
+			return fmt.Errorf("terraform_version: %w", err)
+		}
+	}
+	for name, constraint := range config.ProviderVersions {
+		if !versionedProviders[name] {
+			return fmt.Errorf("provider_versions: %w", invalidValue(name, closestMatch(name, slices.Sorted(maps.Keys(versionedProviders))),
+				"unknown provider %q", name))
+		}
+		if err := validateVersionConstraint(constraint); err != nil {
+			return fmt.Errorf("provider_versions.%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// pinVersions declares the Terraform and provider version constraints of a stack. Without them
+// the aws provider is pinned to the exact version the Go bindings were generated from.
+func pinVersions(stack cdktf.TerraformStack, config Config) {
+	if config.TerraformVersion != "" {
+		stack.AddOverride(jsii.String("terraform.required_version"), config.TerraformVersion)
+	}
+
+	names := make([]string, 0, len(config.ProviderVersions))
+	for name := range config.ProviderVersions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Providers without Go bindings declare their own constraint; see addRawProvider
+		if name != "aws" {
+			continue
+		}
+		stack.AddOverride(jsii.String("terraform.required_providers."+name+".version"), config.ProviderVersions[name])
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/redshiftserverlessnamespace"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/redshiftserverlessworkgroup"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// WarehouseConfig describes a Redshift Serverless namespace and the workgroup serving it
+type WarehouseConfig struct {
+	Database      string    `json:"database"`
+	AdminUsername string    `json:"admin_username"`
+	BaseCapacity  float64   `json:"base_capacity"` // RPUs, 8 to 512 in steps of 8
+	MaxCapacity   float64   `json:"max_capacity"`
+	VPC           VPCConfig `json:"vpc"`
+}
+
+func (w *WarehouseConfig) validate() error {
+	if len(w.VPC.SubnetIDs) == 0 {
+		return fmt.Errorf("vpc.subnet_ids is required")
+	}
+	if w.BaseCapacity != 0 && (w.BaseCapacity < 8 || w.BaseCapacity > 512 || int(w.BaseCapacity)%8 != 0) {
+		return fmt.Errorf("base_capacity must be a multiple of 8 between 8 and 512")
+	}
+	return nil
+}
+
+func (w *WarehouseConfig) database() string {
+	if w.Database != "" {
+		return w.Database
+	}
+	return "dev"
+}
+
+// addWarehouse creates the namespace and workgroup; the admin password is generated and
+// kept in Secrets Manager by Redshift itself so it never appears in the synthesized JSON
+func addWarehouse(stack cdktf.TerraformStack, config Config) redshiftserverlessworkgroup.RedshiftserverlessWorkgroup {
+	warehouse := config.Warehouse
+
+	database := warehouse.database()
+	adminUsername := warehouse.AdminUsername
+	if adminUsername == "" {
+		adminUsername = "admin"
+	}
+	baseCapacity := warehouse.BaseCapacity
+	if baseCapacity == 0 {
+		baseCapacity = sizeFor(config).WarehouseBaseCapacity
+	}
+
+	namespace := redshiftserverlessnamespace.NewRedshiftserverlessNamespace(stack, jsii.String("warehouse_namespace"),
+		&redshiftserverlessnamespace.RedshiftserverlessNamespaceConfig{
+			NamespaceName:       jsii.String(resourceName(config, "aws_redshiftserverless_namespace", "warehouse")),
+			DbName:              jsii.String(database),
+			AdminUsername:       jsii.String(adminUsername),
+			ManageAdminPassword: jsii.Bool(true),
+			LogExports:          jsii.Strings("userlog", "connectionlog", "useractivitylog"),
+		})
+
+	workgroupConfig := &redshiftserverlessworkgroup.RedshiftserverlessWorkgroupConfig{
+		WorkgroupName:      jsii.String(resourceName(config, "aws_redshiftserverless_workgroup", "warehouse")),
+		NamespaceName:      namespace.NamespaceName(),
+		BaseCapacity:       jsii.Number(baseCapacity),
+		SubnetIds:          jsii.Strings(warehouse.VPC.SubnetIDs...),
+		SecurityGroupIds:   jsii.Strings(warehouse.VPC.SecurityGroupIDs...),
+		PubliclyAccessible: jsii.Bool(false),
+		EnhancedVpcRouting: jsii.Bool(true),
+	}
+	if warehouse.MaxCapacity > 0 {
+		workgroupConfig.MaxCapacity = jsii.Number(warehouse.MaxCapacity)
+	}
+	workgroup := redshiftserverlessworkgroup.NewRedshiftserverlessWorkgroup(stack, jsii.String("warehouse_workgroup"),
+		workgroupConfig)
+
+	endpoint := workgroup.Endpoint().Get(jsii.Number(0))
+	jdbcURL := fmt.Sprintf("jdbc:redshift://%s:%s/%s",
+		*endpoint.Address(), *cdktf.Token_AsString(endpoint.Port(), nil), database)
+
+	cdktf.NewTerraformOutput(stack, jsii.String("warehouse_jdbc_url"), &cdktf.TerraformOutputConfig{
+		Value:       jsii.String(jdbcURL),
+		Description: jsii.String("The JDBC URL of the Redshift Serverless workgroup"),
+	})
+
+	cdktf.NewTerraformOutput(stack, jsii.String("warehouse_admin_secret_arn"), &cdktf.TerraformOutputConfig{
+		Value:       namespace.AdminPasswordSecretArn(),
+		Description: jsii.String("The Secrets Manager secret holding the warehouse admin credentials"),
+	})
+
+	fmt.Printf("  ✓ Redshift Serverless warehouse (%v RPUs)\n", baseCapacity)
+	return workgroup
+}
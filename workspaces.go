@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// With "workspaces": true the stacks are named without the environment and share one state key
+// per stack; each environment's state lives in a Terraform workspace named after it instead.
+
+func validateWorkspaces(config Config) error {
+	if config.Workspaces && config.Backend != nil && config.Backend.kind() == "http" {
+		return fmt.Errorf("the http backend doesn't support workspaces")
+	}
+	return nil
+}
+
+// stackPrefix is the start of every stack name: <project>-<environment>, or just <project> when
+// environments are kept apart by workspaces
+func stackPrefix(config Config) string {
+	if config.Workspaces {
+		return config.Project
+	}
+	return config.Project + "-" + config.Environment
+}
+
+// addWorkspaceGuard fails the plan when the selected workspace isn't the environment the stack
+// was synthesized for, so one environment's config is never applied to another's state
+func addWorkspaceGuard(stack cdktf.TerraformStack, config Config) {
+	cdktf.NewTerraformResource(stack, jsii.String("workspace_guard"), &cdktf.TerraformResourceConfig{
+		TerraformResourceType: jsii.String("terraform_data"),
+		TerraformGeneratorMetadata: &cdktf.TerraformProviderGeneratorMetadata{
+			ProviderName: jsii.String("terraform"),
+		},
+		Lifecycle: &cdktf.TerraformResourceLifecycle{
+			Precondition: &[]*cdktf.Precondition{{
+				Condition: jsii.String(fmt.Sprintf("${terraform.workspace == %q}", config.Environment)),
+				ErrorMessage: jsii.String(fmt.Sprintf(
+					"This stack was synthesized for %s; run terraform workspace select %s first.",
+					config.Environment, config.Environment)),
+			}},
+		},
+	})
+}
+
+// selectWorkspace selects the environment's workspace in a stack directory, creating it if needed
+func selectWorkspace(dir string, environment string) error {
+	command := exec.Command("terraform", "workspace", "select", "-or-create", "-no-color", environment)
+	command.Dir = dir
+	span := startCommandSpan(command)
+	output, err := command.CombinedOutput()
+	span.finish(err)
+	if err != nil {
+		return fmt.Errorf("terraform workspace select: %w\n%s", err, output)
+	}
+	return nil
+}
+
+// runWorkspace is the workspace command. It initializes every synthesized stack of the project and
+// selects the workspace of the config's environment, creating it on first use.
+func runWorkspace(args []string) error {
+	flags := flag.NewFlagSet("workspace", flag.ExitOnError)
+	outdir := flags.String("outdir", "cdktf.out", "cdktf output directory")
+	skipInit := flags.Bool("skip-init", false, "don't run terraform init first")
+	flags.Parse(args)
+
+	config, err := loadConfig("config.json")
+	if err != nil {
+		return err
+	}
+	if !config.Workspaces {
+		return fmt.Errorf("config.json doesn't set workspaces")
+	}
+	if _, err := exec.LookPath("terraform"); err != nil {
+		return fmt.Errorf("terraform not found on PATH: %w", err)
+	}
+	stackNames, err := synthesizedStacks(*outdir)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🗂️  Selecting workspace %s...\n", config.Environment)
+	backendStack := stackPrefix(config) + "-backend"
+	var failed []error
+	for _, name := range stackNames {
+		// The bootstrap stack is shared by every environment and keeps the default workspace
+		if !strings.HasPrefix(name, stackPrefix(config)+"-") || name == backendStack {
+			continue
+		}
+		dir := filepath.Join(*outdir, "stacks", name)
+		if !*skipInit {
+			if err := terraformInit(dir); err != nil {
+				failed = append(failed, fmt.Errorf("%s: %w", name, err))
+				fmt.Printf("  ✗ %s\n", name)
+				continue
+			}
+		}
+		if err := selectWorkspace(dir, config.Environment); err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", name, err))
+			fmt.Printf("  ✗ %s\n", name)
+			continue
+		}
+		fmt.Printf("  ✓ %s\n", name)
+	}
+	return errors.Join(failed...)
+}
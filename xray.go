@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/aws/jsii-runtime-go"
+	"github.com/cdktf/cdktf-provider-aws-go/aws/v19/xraysamplingrule"
+	"github.com/hashicorp/terraform-cdk-go/cdktf"
+)
+
+// xrayWriteAccess is the managed policy letting a workload, or the daemon or ADOT collector next to
+// it, send traces to X-Ray
+const xrayWriteAccess = "arn:aws:iam::aws:policy/AWSXRayDaemonWriteAccess"
+
+// X-Ray's default rule samples the first request each second and 5% of the rest; the project's rule
+// keeps those rates, at a priority ahead of it, so they can be tuned per config with an override
+const (
+	samplingReservoirSize = 1
+	samplingFixedRate     = 0.05
+	samplingPriority      = 9000
+)
+
+// tracingSection is the section whose stack holds the sampling rule when tracing is on
+func tracingSection(config Config) string {
+	if config.AppRunner != nil {
+		return "apprunner"
+	}
+	return "batch"
+}
+
+// addSamplingRule creates the sampling rule of the project's traced services. Services are matched
+// by name with the naming template, so the rule covers every App Runner service of the config.
+func addSamplingRule(stack cdktf.TerraformStack, config Config) {
+	serviceNames := resourceName(config, "", "*")
+	xraysamplingrule.NewXraySamplingRule(stack, jsii.String("xray_sampling_rule"), &xraysamplingrule.XraySamplingRuleConfig{
+		RuleName:      jsii.String(resourceName(config, "aws_xray_sampling_rule", "tracing")),
+		Priority:      jsii.Number(samplingPriority),
+		ReservoirSize: jsii.Number(samplingReservoirSize),
+		FixedRate:     jsii.Number(samplingFixedRate),
+		ServiceName:   jsii.String(serviceNames),
+		ServiceType:   jsii.String("*"),
+		Host:          jsii.String("*"),
+		HttpMethod:    jsii.String("*"),
+		UrlPath:       jsii.String("*"),
+		ResourceArn:   jsii.String("*"),
+		Version:       jsii.Number(1),
+	})
+
+	fmt.Printf("  ✓ X-Ray sampling rule for %s\n", serviceNames)
+}